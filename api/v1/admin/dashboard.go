@@ -6,14 +6,16 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/rollups"
 )
 
 type DashboardHandlers struct {
-	db *sql.DB
+	db          *sql.DB
+	rollupsRepo *rollups.Repository
 }
 
-func NewDashboardHandlers(db *sql.DB) *DashboardHandlers {
-	return &DashboardHandlers{db: db}
+func NewDashboardHandlers(db *sql.DB, rollupsRepo *rollups.Repository) *DashboardHandlers {
+	return &DashboardHandlers{db: db, rollupsRepo: rollupsRepo}
 }
 
 // GetDashboard retorna analytics para las gráficas del dashboard.
@@ -66,83 +68,38 @@ func (h *DashboardHandlers) GetDashboard(c *gin.Context) {
 		h.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE is_premium = TRUE`).Scan(&premiumUsers)
 	}
 
-	// ── Top dramas ────────────────────────────────────────────────────────────
+	// ── Top dramas (desde rollups diarios) ───────────────────────────────────
 	type TopDrama struct {
 		SeriesID uuid.UUID `json:"series_id"`
 		Title    string    `json:"title"`
 		Views    int       `json:"views"`
 	}
 
-	var topDramasRows *sql.Rows
-	var tdErr error
-	if producerID != nil {
-		topDramasRows, tdErr = h.db.QueryContext(ctx,
-			`SELECT s.id, s.title, COUNT(v.id) as views
-			 FROM series s
-			 JOIN episodes e ON e.series_id = s.id
-			 LEFT JOIN views v ON v.episode_id = e.id AND v.created_at > NOW() - INTERVAL '30 days'
-			 WHERE s.is_active = TRUE AND s.producer_id = $1
-			 GROUP BY s.id, s.title
-			 ORDER BY views DESC LIMIT 10`, *producerID)
-	} else {
-		topDramasRows, tdErr = h.db.QueryContext(ctx,
-			`SELECT s.id, s.title, COUNT(v.id) as views
-			 FROM series s
-			 JOIN episodes e ON e.series_id = s.id
-			 LEFT JOIN views v ON v.episode_id = e.id AND v.created_at > NOW() - INTERVAL '30 days'
-			 WHERE s.is_active = TRUE
-			 GROUP BY s.id, s.title
-			 ORDER BY views DESC LIMIT 10`)
-	}
 	var topDramas []TopDrama
-	if tdErr == nil {
-		defer topDramasRows.Close()
-		for topDramasRows.Next() {
-			var td TopDrama
-			if topDramasRows.Scan(&td.SeriesID, &td.Title, &td.Views) == nil {
-				topDramas = append(topDramas, td)
-			}
+	if totals, err := h.rollupsRepo.TopSeries(ctx, producerID, 30, 10); err == nil {
+		for _, t := range totals {
+			topDramas = append(topDramas, TopDrama{SeriesID: t.SeriesID, Title: t.Title, Views: t.Views})
 		}
 	}
 
-	// ── Retención por episodio ────────────────────────────────────────────────
+	// ── Retención por episodio (desde rollups diarios) ───────────────────────
 	type RetentionData struct {
 		EpisodeNumber  int     `json:"episode_number"`
 		CompletionRate float64 `json:"completion_rate"`
 	}
 
-	var retRows *sql.Rows
-	var retErr error
-	if producerID != nil {
-		retRows, retErr = h.db.QueryContext(ctx,
-			`SELECT e.episode_number,
-			        COALESCE(COUNT(CASE WHEN v.completed = TRUE THEN 1 END)::float / NULLIF(COUNT(v.id), 0), 0)
-			 FROM episodes e
-			 JOIN series s ON s.id = e.series_id
-			 LEFT JOIN views v ON v.episode_id = e.id
-			 WHERE s.producer_id = $1
-			 GROUP BY e.series_id, e.episode_number
-			 ORDER BY e.series_id, e.episode_number LIMIT 20`, *producerID)
-	} else {
-		retRows, retErr = h.db.QueryContext(ctx,
-			`SELECT e.episode_number,
-			        COALESCE(COUNT(CASE WHEN v.completed = TRUE THEN 1 END)::float / NULLIF(COUNT(v.id), 0), 0)
-			 FROM episodes e
-			 LEFT JOIN views v ON v.episode_id = e.id
-			 GROUP BY e.series_id, e.episode_number
-			 ORDER BY e.series_id, e.episode_number LIMIT 20`)
-	}
 	var retentionData []RetentionData
-	if retErr == nil {
-		defer retRows.Close()
-		for retRows.Next() {
-			var rd RetentionData
-			if retRows.Scan(&rd.EpisodeNumber, &rd.CompletionRate) == nil {
-				retentionData = append(retentionData, rd)
-			}
+	if completions, err := h.rollupsRepo.CompletionByEpisode(ctx, producerID, 20); err == nil {
+		for _, ec := range completions {
+			retentionData = append(retentionData, RetentionData{
+				EpisodeNumber:  ec.EpisodeNumber,
+				CompletionRate: ec.CompletionRate,
+			})
 		}
 	}
 
+	coinsSpent30d, _ := h.rollupsRepo.CoinsSpent(ctx, producerID, 30)
+
 	// Usuarios activos 30d (global o por producer)
 	var activeUsers30d int
 	if producerID != nil {
@@ -167,6 +124,7 @@ func (h *DashboardHandlers) GetDashboard(c *gin.Context) {
 			"active_users_30d":  activeUsers30d,
 			"premium_users":     premiumUsers,
 			"total_revenue_30d": 0,
+			"coins_spent_30d":   coinsSpent30d,
 		},
 		"charts": gin.H{
 			"retention_by_episode": retentionData,
@@ -0,0 +1,334 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/feed"
+	"github.com/qenti/qenti/internal/pkg/images"
+	"github.com/qenti/qenti/internal/pkg/series"
+	"github.com/qenti/qenti/internal/pkg/taxonomy"
+	"github.com/qenti/qenti/internal/pkg/translations"
+)
+
+// Límites de una fila del home.
+const (
+	maxFeedRowItems   = 50
+	defaultFeedLimit  = 20
+	maxFeedTitleRunes = 80
+)
+
+// FeedHandlers gestiona las filas del home de la app: cada productor arma el de su
+// app y el super_admin el de la app de plataforma.
+type FeedHandlers struct {
+	feedRepo     *feed.Repository
+	seriesRepo   *series.Repository
+	taxonomyRepo *taxonomy.Repository
+	imagesRepo   *images.Repository
+}
+
+func NewFeedHandlers(feedRepo *feed.Repository, seriesRepo *series.Repository, taxonomyRepo *taxonomy.Repository, imagesRepo *images.Repository) *FeedHandlers {
+	return &FeedHandlers{feedRepo: feedRepo, seriesRepo: seriesRepo, taxonomyRepo: taxonomyRepo, imagesRepo: imagesRepo}
+}
+
+// FeedRowRequest payload para crear o editar una fila. items son las series de las
+// filas curated y hero (en orden; image_id es el arte del banner hero); genre_id el
+// género de las filas genre. countries (ISO alpha-2) y platforms vacíos = todos.
+type FeedRowRequest struct {
+	Kind              string            `json:"kind" binding:"required"`
+	Title             string            `json:"title"`
+	TitleTranslations map[string]string `json:"title_translations"`
+	GenreID           *uuid.UUID        `json:"genre_id"`
+	ItemLimit         int               `json:"item_limit"`
+	Items             []feed.Item       `json:"items"`
+	Countries         []string          `json:"countries"`
+	Platforms         []string          `json:"platforms"`
+	Audience          string            `json:"audience"`
+	StartsAt          *time.Time        `json:"starts_at"`
+	EndsAt            *time.Time        `json:"ends_at"`
+	Position          int               `json:"position"`
+	IsActive          *bool             `json:"is_active"`
+}
+
+// FeedOrderRequest IDs de las filas de la app en el orden nuevo.
+type FeedOrderRequest struct {
+	RowIDs []uuid.UUID `json:"row_ids" binding:"required"`
+}
+
+// feedRowFromRequest valida el payload y lo vuelca en row.
+func (h *FeedHandlers) feedRowFromRequest(c *gin.Context, req FeedRowRequest, row *feed.Row) error {
+	ctx := c.Request.Context()
+	if !feed.ValidKind(req.Kind) {
+		return fmt.Errorf("invalid kind %q", req.Kind)
+	}
+	row.Kind = req.Kind
+
+	row.Title = strings.TrimSpace(req.Title)
+	if row.Title == "" && row.Kind != feed.KindHero && row.Kind != feed.KindGenre {
+		return errors.New("title is required")
+	}
+	if utf8.RuneCountInString(row.Title) > maxFeedTitleRunes {
+		return fmt.Errorf("title must have at most %d characters", maxFeedTitleRunes)
+	}
+	row.TitleTranslations = make(map[string]string, len(req.TitleTranslations))
+	for code, title := range req.TitleTranslations {
+		locale, ok := translations.NormalizeLocale(code)
+		if !ok {
+			return fmt.Errorf("invalid locale %q (BCP 47, ej. pt-BR, en, es-419)", code)
+		}
+		if title = strings.TrimSpace(title); title != "" {
+			if utf8.RuneCountInString(title) > maxFeedTitleRunes {
+				return fmt.Errorf("translation %s must have at most %d characters", locale, maxFeedTitleRunes)
+			}
+			row.TitleTranslations[locale] = title
+		}
+	}
+
+	row.GenreID = nil
+	if row.Kind == feed.KindGenre {
+		if req.GenreID == nil {
+			return errors.New("genre_id is required for genre rows")
+		}
+		genre, err := h.taxonomyRepo.GetGenre(ctx, *req.GenreID)
+		if err != nil {
+			return err
+		}
+		if genre == nil {
+			return errors.New("genre not found")
+		}
+		row.GenreID = req.GenreID
+	}
+
+	row.Items = []feed.Item{}
+	if feed.HasItems(row.Kind) {
+		if len(req.Items) == 0 {
+			return fmt.Errorf("%s rows need at least one series", row.Kind)
+		}
+		if len(req.Items) > maxFeedRowItems {
+			return fmt.Errorf("rows can have at most %d series", maxFeedRowItems)
+		}
+		seen := make(map[uuid.UUID]bool, len(req.Items))
+		var imageIDs []uuid.UUID
+		for _, item := range req.Items {
+			if seen[item.SeriesID] {
+				continue
+			}
+			seen[item.SeriesID] = true
+			if row.ProducerID != nil {
+				owns, err := h.seriesRepo.BelongsToProducer(ctx, item.SeriesID, row.ProducerID.String())
+				if err != nil {
+					return err
+				}
+				if !owns {
+					return fmt.Errorf("series %s not found", item.SeriesID)
+				}
+			} else if s, err := h.seriesRepo.GetByID(ctx, item.SeriesID); err != nil || s == nil {
+				return fmt.Errorf("series %s not found", item.SeriesID)
+			}
+			if row.Kind != feed.KindHero {
+				item.ImageID = nil
+			} else if item.ImageID != nil {
+				imageIDs = append(imageIDs, *item.ImageID)
+			}
+			row.Items = append(row.Items, item)
+		}
+		found, err := h.imagesRepo.GetMany(ctx, imageIDs)
+		if err != nil {
+			return err
+		}
+		for _, id := range imageIDs {
+			if found[id] == nil {
+				return fmt.Errorf("image %s not found", id)
+			}
+		}
+	}
+
+	row.ItemLimit = req.ItemLimit
+	if row.ItemLimit == 0 {
+		row.ItemLimit = defaultFeedLimit
+	}
+	if row.ItemLimit < 1 || row.ItemLimit > maxFeedRowItems {
+		return fmt.Errorf("item_limit must be between 1 and %d", maxFeedRowItems)
+	}
+
+	row.Countries = make([]string, 0, len(req.Countries))
+	for _, country := range req.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+			return fmt.Errorf("invalid country %q (ISO-3166-1 alpha-2)", country)
+		}
+		row.Countries = append(row.Countries, country)
+	}
+	row.Platforms = make([]string, 0, len(req.Platforms))
+	for _, platform := range req.Platforms {
+		platform = strings.ToLower(strings.TrimSpace(platform))
+		valid := false
+		for _, p := range feed.Platforms {
+			valid = valid || p == platform
+		}
+		if !valid {
+			return fmt.Errorf("invalid platform %q (%s)", platform, strings.Join(feed.Platforms, ", "))
+		}
+		row.Platforms = append(row.Platforms, platform)
+	}
+	row.Audience = req.Audience
+	if row.Audience == "" {
+		row.Audience = feed.AudienceAll
+	}
+	if row.Audience != feed.AudienceAll && row.Audience != feed.AudiencePremium && row.Audience != feed.AudienceFree {
+		return fmt.Errorf("invalid audience %q (all, premium, free)", row.Audience)
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.StartsAt.Before(*req.EndsAt) {
+		return errors.New("starts_at must be before ends_at")
+	}
+	row.StartsAt, row.EndsAt = req.StartsAt, req.EndsAt
+	if req.Position < 0 {
+		return errors.New("position must not be negative")
+	}
+	row.Position = req.Position
+	if req.IsActive != nil {
+		row.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// getOwnRow devuelve la fila si es de la app de quien hace la request, o nil.
+func (h *FeedHandlers) getOwnRow(c *gin.Context, id uuid.UUID) (*feed.Row, error) {
+	row, err := h.feedRepo.Get(c.Request.Context(), id)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	producerID := producerIDFromContext(c)
+	if (producerID == nil) != (row.ProducerID == nil) || (producerID != nil && *producerID != *row.ProducerID) {
+		return nil, nil
+	}
+	return row, nil
+}
+
+// ListFeedRows lista las filas del home de la app en orden, incluidas las
+// inactivas y las programadas.
+// Endpoint: GET /admin/feed/rows
+func (h *FeedHandlers) ListFeedRows(c *gin.Context) {
+	rows, err := h.feedRepo.List(c.Request.Context(), producerIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list feed rows"})
+		return
+	}
+	if rows == nil {
+		rows = []feed.Row{}
+	}
+	c.JSON(http.StatusOK, gin.H{"rows": rows})
+}
+
+// CreateFeedRow agrega una fila al home de la app (al final si no trae position).
+// Endpoint: POST /admin/feed/rows
+func (h *FeedHandlers) CreateFeedRow(c *gin.Context) {
+	var req FeedRowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	row := &feed.Row{ProducerID: producerIDFromContext(c), IsActive: true, CreatedBy: currentUserID(c)}
+	if err := h.feedRowFromRequest(c, req, row); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feed row", "details": err.Error()})
+		return
+	}
+	if err := h.feedRepo.Create(c.Request.Context(), row); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create feed row"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"row": row})
+}
+
+// UpdateFeedRow reemplaza la configuración de la fila.
+// Endpoint: PUT /admin/feed/rows/{id}
+func (h *FeedHandlers) UpdateFeedRow(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid row ID"})
+		return
+	}
+	var req FeedRowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	row, err := h.getOwnRow(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get feed row"})
+		return
+	}
+	if row == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed row not found"})
+		return
+	}
+	if req.Position == 0 {
+		req.Position = row.Position
+	}
+	if err := h.feedRowFromRequest(c, req, row); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feed row", "details": err.Error()})
+		return
+	}
+	if err := h.feedRepo.Update(c.Request.Context(), row); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feed row"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"row": row})
+}
+
+// DeleteFeedRow quita la fila del home.
+// Endpoint: DELETE /admin/feed/rows/{id}
+func (h *FeedHandlers) DeleteFeedRow(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid row ID"})
+		return
+	}
+	row, err := h.getOwnRow(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get feed row"})
+		return
+	}
+	if row == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed row not found"})
+		return
+	}
+	if _, err := h.feedRepo.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete feed row"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Feed row deleted"})
+}
+
+// ReorderFeedRows ordena las filas del home según row_ids; las que no estén en la
+// lista quedan después, en su orden actual.
+// Endpoint: PUT /admin/feed/order
+func (h *FeedHandlers) ReorderFeedRows(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req FeedOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	producerID := producerIDFromContext(c)
+	if err := h.feedRepo.Reorder(ctx, producerID, req.RowIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder feed rows"})
+		return
+	}
+	rows, err := h.feedRepo.List(ctx, producerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list feed rows"})
+		return
+	}
+	if rows == nil {
+		rows = []feed.Row{}
+	}
+	c.JSON(http.StatusOK, gin.H{"rows": rows})
+}
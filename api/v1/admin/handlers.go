@@ -3,23 +3,38 @@ package admin
 import (
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/images"
+	"github.com/qenti/qenti/internal/pkg/media"
 	"github.com/qenti/qenti/internal/pkg/models"
-	"github.com/qenti/qenti/internal/pkg/notifications"
+	"github.com/qenti/qenti/internal/pkg/pagination"
 	"github.com/qenti/qenti/internal/pkg/series"
 	"github.com/qenti/qenti/internal/pkg/storage"
+	"github.com/qenti/qenti/internal/pkg/transcode"
+	"github.com/qenti/qenti/internal/pkg/videostatus"
 )
 
 type Handlers struct {
 	seriesRepo     *series.Repository
 	episodesRepo   *episodes.Repository
 	videoProvider  storage.VideoProvider
-	notifService   *notifications.Service
+	// videoStatus ciclo de vida del video; notifica a los fans cuando queda listo
+	videoStatus    *videostatus.Service
+	// transcodeService nil si la transcodificación propia está deshabilitada
+	transcodeService *transcode.Service
+	// mediaService nil si el análisis de los uploads (MEDIA_PROBE_ENABLED) está deshabilitado
+	mediaService *media.Service
+	// imagesRepo imágenes subidas (POST /admin/images) que se referencian como posters
+	imagesRepo *images.Repository
 	// maxFileSizeMB límite de tamaño en MB para uploads (configurable vía VideoUploadConfig)
 	maxFileSizeMB  int64
 	warnFileSizeMB int64
@@ -33,7 +48,10 @@ func NewHandlers(
 	seriesRepo *series.Repository,
 	episodesRepo *episodes.Repository,
 	videoProvider storage.VideoProvider,
-	notifService *notifications.Service,
+	videoStatus *videostatus.Service,
+	transcodeService *transcode.Service,
+	mediaService *media.Service,
+	imagesRepo *images.Repository,
 	maxFileSizeMB int64,
 	warnFileSizeMB int64,
 	cliffStart int,
@@ -44,7 +62,10 @@ func NewHandlers(
 		seriesRepo:     seriesRepo,
 		episodesRepo:   episodesRepo,
 		videoProvider:  videoProvider,
-		notifService:   notifService,
+		videoStatus:    videoStatus,
+		transcodeService: transcodeService,
+		mediaService:   mediaService,
+		imagesRepo:     imagesRepo,
 		maxFileSizeMB:  maxFileSizeMB,
 		warnFileSizeMB: warnFileSizeMB,
 		cliffStart:     cliffStart,
@@ -59,6 +80,9 @@ type CreateSeriesRequest struct {
 	Description     string `json:"description"`
 	HorizontalPoster string `json:"horizontal_poster"`
 	VerticalPoster  string `json:"vertical_poster"`
+	// HorizontalPosterImageID y VerticalPosterImageID imágenes subidas; reemplazan a las URLs
+	HorizontalPosterImageID *uuid.UUID `json:"horizontal_poster_image_id"`
+	VerticalPosterImageID   *uuid.UUID `json:"vertical_poster_image_id"`
 	IsActive        bool   `json:"is_active"`
 }
 
@@ -68,6 +92,8 @@ type UpdateSeriesRequest struct {
 	Description     string `json:"description"`
 	HorizontalPoster string `json:"horizontal_poster"`
 	VerticalPoster  string `json:"vertical_poster"`
+	HorizontalPosterImageID *uuid.UUID `json:"horizontal_poster_image_id"`
+	VerticalPosterImageID   *uuid.UUID `json:"vertical_poster_image_id"`
 	IsActive        *bool  `json:"is_active"`
 }
 
@@ -103,12 +129,17 @@ func producerIDFromContext(c *gin.Context) *uuid.UUID {
 	return &id
 }
 
-// GetSeries lista las series del panel: filtra por producer si aplica
+// GetSeries lista las series del panel: filtra por producer si aplica.
+// Paginado con ?limit= y ?cursor= (next_cursor de la página anterior).
 func (h *Handlers) GetSeries(c *gin.Context) {
 	ctx := c.Request.Context()
 	producerID := producerIDFromContext(c)
+	page, ok := pagination.FromQuery(c)
+	if !ok {
+		return
+	}
 
-	seriesList, err := h.seriesRepo.GetAllAdmin(ctx, producerID)
+	seriesList, err := h.seriesRepo.GetAllAdmin(ctx, producerID, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
 		return
@@ -116,7 +147,8 @@ func (h *Handlers) GetSeries(c *gin.Context) {
 	if seriesList == nil {
 		seriesList = []models.Series{}
 	}
-	c.JSON(http.StatusOK, gin.H{"series": seriesList})
+	seriesList, next := pagination.Slice(seriesList, page, pagination.SeriesCursor)
+	c.JSON(http.StatusOK, gin.H{"series": seriesList, "next_cursor": next})
 }
 
 // GetSeriesByID obtiene una serie por ID
@@ -139,6 +171,9 @@ func (h *Handlers) GetSeriesByID(c *gin.Context) {
 		})
 		return
 	}
+	if err := h.imagesRepo.AttachSeries(ctx, s); err != nil {
+		log.Printf("admin: series %s images: %v", s.ID, err)
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"series": s,
@@ -163,6 +198,9 @@ func (h *Handlers) CreateSeries(c *gin.Context) {
 		IsActive:         req.IsActive,
 		ProducerID:       producerIDFromContext(c), // nil para super_admin
 	}
+	if !h.setSeriesImages(c, series, req.HorizontalPosterImageID, req.VerticalPosterImageID) {
+		return
+	}
 
 	if err := h.seriesRepo.Create(ctx, series); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series"})
@@ -211,15 +249,21 @@ func (h *Handlers) UpdateSeries(c *gin.Context) {
 	if req.Description != "" {
 		series.Description = req.Description
 	}
+	// Una URL explícita reemplaza a la imagen subida que tuviera el poster
 	if req.HorizontalPoster != "" {
 		series.HorizontalPoster = req.HorizontalPoster
+		series.HorizontalPosterImageID = nil
 	}
 	if req.VerticalPoster != "" {
 		series.VerticalPoster = req.VerticalPoster
+		series.VerticalPosterImageID = nil
 	}
 	if req.IsActive != nil {
 		series.IsActive = *req.IsActive
 	}
+	if !h.setSeriesImages(c, series, req.HorizontalPosterImageID, req.VerticalPosterImageID) {
+		return
+	}
 	
 	if err := h.seriesRepo.Update(ctx, series); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -360,7 +404,9 @@ func (h *Handlers) UpdateEpisode(c *gin.Context) {
 }
 
 // GetUploadURL genera una URL de upload directo al proveedor de video activo.
-// El cliente hace PUT directo a esa URL (no pasa por el servidor).
+// El cliente sube directo a esa URL (no pasa por el servidor) según upload_method
+// (PUT, POST multipart o TUS). Con ?upload_length=<bytes> y un proveedor que lo
+// soporte, devuelve una URL TUS reanudable.
 // Endpoint: POST /admin/episodes/{id}/upload-url
 func (h *Handlers) GetUploadURL(c *gin.Context) {
 	episodeIDStr := c.Param("id")
@@ -378,7 +424,20 @@ func (h *Handlers) GetUploadURL(c *gin.Context) {
 		return
 	}
 
-	uploadResult, err := h.videoProvider.CreateVideo(episode.Title)
+	var uploadResult *storage.UploadResult
+	uploadLength, _ := strconv.ParseInt(c.Query("upload_length"), 10, 64)
+	if uploadLength > h.maxFileSizeMB*1024*1024 {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":  fmt.Sprintf("El archivo supera el límite de %d MB", h.maxFileSizeMB),
+			"max_mb": h.maxFileSizeMB,
+		})
+		return
+	}
+	if tusProvider, ok := h.videoProvider.(storage.TUSProvider); ok && uploadLength > 0 {
+		uploadResult, err = tusProvider.CreateTUSUpload(episode.Title, uploadLength)
+	} else {
+		uploadResult, err = h.videoProvider.CreateVideo(episode.Title)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to generate upload URL",
@@ -387,11 +446,20 @@ func (h *Handlers) GetUploadURL(c *gin.Context) {
 		return
 	}
 
+	if err := h.videoStatus.MarkUploading(ctx, episode); err != nil {
+		log.Printf("admin: %v", err) // no crítico
+	}
+
+	uploadMethod := uploadResult.UploadMethod
+	if uploadMethod == "" {
+		uploadMethod = storage.UploadMethodPUT
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"upload_url": uploadResult.UploadURL,
-		"video_id":   uploadResult.ExternalID,
-		"episode_id": episodeID,
-		"provider":   h.videoProvider.ProviderName(),
+		"upload_url":    uploadResult.UploadURL,
+		"upload_method": uploadMethod,
+		"video_id":      uploadResult.ExternalID,
+		"episode_id":    episodeID,
+		"provider":      h.videoProvider.ProviderName(),
 	})
 }
 
@@ -445,13 +513,39 @@ func (h *Handlers) UploadVideo(c *gin.Context) {
 		return
 	}
 
-	src, err := file.Open()
+	// Con MEDIA_PROBE_ENABLED el video se analiza (y puede rechazarse) antes de entregarlo;
+	// las subidas siguientes leen la copia temporal
+	open := func() (io.ReadCloser, error) { return file.Open() }
+	delivered := false
+	if h.mediaService != nil {
+		upload, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file", "details": err.Error()})
+			return
+		}
+		staged, err := h.mediaService.Stage(ctx, upload)
+		upload.Close()
+		if err != nil {
+			mediaError(c, err)
+			return
+		}
+		defer func() { h.mediaService.Done(staged, episodeID, delivered) }()
+		open = func() (io.ReadCloser, error) { return os.Open(staged.Path) }
+	}
+
+	src, err := open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file", "details": err.Error()})
 		return
 	}
 	defer src.Close()
 
+	// Con transcodificación propia el worker genera la escalera y la entrega al proveedor
+	if h.transcodeService != nil {
+		delivered = h.enqueueTranscode(c, episodeID, src)
+		return
+	}
+
 	// Crear o reutilizar el ID externo del video
 	var externalID string
 	if episode.VideoIDBunny != "" {
@@ -482,7 +576,7 @@ func (h *Handlers) UploadVideo(c *gin.Context) {
 		externalID = uploadResult.ExternalID
 		// Reabrir el archivo para el segundo intento
 		src.Close()
-		src2, err2 := file.Open()
+		src2, err2 := open()
 		if err2 != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reopen file", "details": err2.Error()})
 			return
@@ -503,6 +597,8 @@ func (h *Handlers) UploadVideo(c *gin.Context) {
 	}
 
 	h.videoProvider.CompleteUpload(externalID) // no crítico
+	videoStatus := h.syncVideoStatus(ctx, episodeID)
+	delivered = true
 
 	// Avisar si el video es más grande de lo recomendado
 	warning := ""
@@ -515,10 +611,11 @@ func (h *Handlers) UploadVideo(c *gin.Context) {
 	}
 
 	resp := gin.H{
-		"message":   "Video uploaded successfully",
-		"video_id":  externalID,
-		"provider":  h.videoProvider.ProviderName(),
-		"size_mb":   float64(file.Size) / 1024 / 1024,
+		"message":      "Video uploaded successfully",
+		"video_id":     externalID,
+		"video_status": videoStatus,
+		"provider":     h.videoProvider.ProviderName(),
+		"size_mb":      float64(file.Size) / 1024 / 1024,
 	}
 	if warning != "" {
 		resp["warning"] = warning
@@ -555,30 +652,26 @@ func (h *Handlers) CompleteUpload(c *gin.Context) {
 
 	h.videoProvider.CompleteUpload(req.VideoIDBunny) // no crítico
 
-	// Ahora que el video está listo, notificar a los fans de la serie (best-effort)
-	go func() {
-		if h.notifService == nil {
-			return
-		}
-		bgCtx := context.Background()
-		ep, err := h.episodesRepo.GetByID(bgCtx, episodeID)
-		if err != nil || ep == nil {
-			return
-		}
-		s, err := h.seriesRepo.GetByID(bgCtx, ep.SeriesID)
-		seriesTitle := "Nueva actualización"
-		if err == nil && s != nil {
-			seriesTitle = s.Title
-		}
-		h.notifService.NotifyNewEpisode(bgCtx, ep.SeriesID, seriesTitle, ep.EpisodeNumber, ep.Title)
-	}()
-
+	// El proveedor puede seguir codificando: los fans se notifican cuando quede listo
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Upload completed successfully",
-		"provider": h.videoProvider.ProviderName(),
+		"message":      "Upload completed successfully",
+		"video_status": h.syncVideoStatus(ctx, episodeID),
+		"provider":     h.videoProvider.ProviderName(),
 	})
 }
 
+// syncVideoStatus consulta el estado del video recién subido (la notificación a los
+// fans sale cuando queda listo). Un error del proveedor no hace fallar el upload: el
+// poller lo reintenta.
+func (h *Handlers) syncVideoStatus(ctx context.Context, episodeID uuid.UUID) string {
+	status, err := h.videoStatus.SyncEpisode(ctx, episodeID)
+	if err != nil {
+		log.Printf("admin: sync video status of episode %s: %v", episodeID, err)
+		return storage.VideoStatusProcessing
+	}
+	return status.Status
+}
+
 // ValidateStorageConnection valida la conexión con el proveedor de video activo.
 // Endpoint: GET /admin/validate/bunny
 func (h *Handlers) ValidateBunnyConnection(c *gin.Context) {
@@ -599,7 +692,8 @@ func (h *Handlers) ValidateBunnyConnection(c *gin.Context) {
 	})
 }
 
-// GetEpisodes lista episodios con filtros opcionales
+// GetEpisodes lista episodios, opcionalmente de una sola serie (?series_id=).
+// Pagina con ?limit= y ?cursor=, del más nuevo al más viejo.
 func (h *Handlers) GetEpisodes(c *gin.Context) {
 	ctx := c.Request.Context()
 	
@@ -612,8 +706,12 @@ func (h *Handlers) GetEpisodes(c *gin.Context) {
 			seriesID = &parsedID
 		}
 	}
+	page, ok := pagination.FromQuery(c)
+	if !ok {
+		return
+	}
 	
-	episodes, err := h.episodesRepo.GetAll(ctx, seriesID)
+	episodes, err := h.episodesRepo.List(ctx, seriesID, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch episodes",
@@ -625,9 +723,11 @@ func (h *Handlers) GetEpisodes(c *gin.Context) {
 	if episodes == nil {
 		episodes = []models.Episode{}
 	}
+	episodes, next := pagination.Slice(episodes, page, pagination.EpisodeCursor)
 	
 	c.JSON(http.StatusOK, gin.H{
-		"episodes": episodes,
+		"episodes":    episodes,
+		"next_cursor": next,
 	})
 }
 
@@ -0,0 +1,133 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/images"
+	"github.com/qenti/qenti/internal/pkg/models"
+)
+
+// ImageHandlers sube las imágenes (posters, banners, logos) que después se
+// referencian por ID desde series y productores.
+type ImageHandlers struct {
+	images *images.Service
+}
+
+func NewImageHandlers(imagesService *images.Service) *ImageHandlers {
+	return &ImageHandlers{images: imagesService}
+}
+
+// UploadImage recibe una imagen (campo "image" multipart), genera sus variantes y
+// devuelve la URL de cada una. Subir un archivo ya subido devuelve la imagen
+// existente (200 en vez de 201).
+// Endpoint: POST /admin/images
+func (h *ImageHandlers) UploadImage(c *gin.Context) {
+	maxBytes := h.images.MaxBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20) // margen para el multipart
+	file, header, err := c.Request.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Falta el archivo de imagen (campo 'image')", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+	if header.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":  fmt.Sprintf("La imagen pesa %.1f MB y supera el límite de %d MB.", float64(header.Size)/1024/1024, maxBytes/1024/1024),
+			"max_mb": maxBytes / 1024 / 1024,
+		})
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read image", "details": err.Error()})
+		return
+	}
+
+	img, created, err := h.images.Upload(c.Request.Context(), data, currentUserID(c))
+	switch {
+	case errors.Is(err, images.ErrUnsupportedFormat):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Formato de imagen no permitido. Solo se aceptan JPEG, PNG, GIF o WebP."})
+	case errors.Is(err, images.ErrInvalidImage):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "La imagen está dañada o no se puede leer", "details": err.Error()})
+	case errors.Is(err, images.ErrTooLarge):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "La imagen tiene demasiados píxeles", "details": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process image", "details": err.Error()})
+	case created:
+		c.JSON(http.StatusCreated, gin.H{"image": img, "deduplicated": false})
+	default:
+		c.JSON(http.StatusOK, gin.H{"image": img, "deduplicated": true})
+	}
+}
+
+// GetImage devuelve una imagen con la URL de cada variante.
+// Endpoint: GET /admin/images/{id}
+func (h *ImageHandlers) GetImage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+	img, err := h.images.Repo().Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get image"})
+		return
+	}
+	if img == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"image": img})
+}
+
+// resolveImage busca la imagen referenciada en un payload y devuelve la URL de la
+// variante que se guarda en la columna de URL (compatibilidad con los clientes que
+// solo leen vertical_poster, horizontal_poster o logo_url). Responde 400 si no existe.
+func resolveImage(c *gin.Context, repo *images.Repository, id uuid.UUID, variant string) (*models.Image, string, bool) {
+	img, err := repo.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get image"})
+		return nil, "", false
+	}
+	if img == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image not found", "image_id": id})
+		return nil, "", false
+	}
+	return img, img.Variants[variant].URL, true
+}
+
+// setSeriesImages asigna los posters de la serie a partir de imágenes subidas:
+// el vertical usa la variante "poster" y el horizontal la "banner".
+func (h *Handlers) setSeriesImages(c *gin.Context, s *models.Series, horizontalID, verticalID *uuid.UUID) bool {
+	if horizontalID != nil {
+		img, url, ok := resolveImage(c, h.imagesRepo, *horizontalID, "banner")
+		if !ok {
+			return false
+		}
+		s.HorizontalPosterImageID, s.HorizontalPoster, s.HorizontalPosterImage = horizontalID, url, img
+	}
+	if verticalID != nil {
+		img, url, ok := resolveImage(c, h.imagesRepo, *verticalID, "poster")
+		if !ok {
+			return false
+		}
+		s.VerticalPosterImageID, s.VerticalPoster, s.VerticalPosterImage = verticalID, url, img
+	}
+	return true
+}
+
+// setProducerLogo asigna como logo del productor una imagen subida (variante
+// "thumbnail", cuadrada).
+func setProducerLogo(c *gin.Context, repo *images.Repository, p *models.Producer, id uuid.UUID) bool {
+	img, url, ok := resolveImage(c, repo, id, "thumbnail")
+	if !ok {
+		return false
+	}
+	p.LogoImageID, p.LogoURL, p.LogoImage = &id, url, img
+	return true
+}
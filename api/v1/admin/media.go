@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/media"
+	"github.com/qenti/qenti/internal/pkg/transcode"
+)
+
+// mediaError responde el rechazo de un video por media.Service.Inspect.
+func mediaError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, media.ErrTooLong):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "El video supera la duración máxima permitida", "details": err.Error()})
+	case errors.Is(err, media.ErrNotVertical):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "El video debe ser vertical (9:16)", "details": err.Error()})
+	case errors.Is(err, transcode.ErrNotVideo):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "El archivo no es un video válido", "details": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to analyze video", "details": err.Error()})
+	}
+}
+
+// mediaEnabled responde 501 si el análisis de los uploads está deshabilitado.
+func (h *Handlers) mediaEnabled(c *gin.Context) bool {
+	if h.mediaService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Media probing is disabled (MEDIA_PROBE_ENABLED)"})
+		return false
+	}
+	return true
+}
+
+// ListEpisodeThumbnails lista las portadas candidatas extraídas del video del
+// episodio y la portada actual.
+// Endpoint: GET /admin/episodes/{id}/thumbnails
+func (h *Handlers) ListEpisodeThumbnails(c *gin.Context) {
+	if !h.mediaEnabled(c) {
+		return
+	}
+	ctx := c.Request.Context()
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	episode, err := h.episodesRepo.GetByID(ctx, episodeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Episode not found"})
+		return
+	}
+
+	thumbs, err := h.mediaService.Repo().ListByEpisode(ctx, episodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list thumbnails"})
+		return
+	}
+	if thumbs == nil {
+		thumbs = []media.Thumbnail{}
+	}
+	c.JSON(http.StatusOK, gin.H{"thumbnails": thumbs, "cover_url": episode.CoverURL})
+}
+
+// SetEpisodeCoverRequest portada elegida entre las candidatas del episodio.
+type SetEpisodeCoverRequest struct {
+	ThumbnailID uuid.UUID `json:"thumbnail_id" binding:"required"`
+}
+
+// SetEpisodeCover asigna como portada del episodio una de sus candidatas.
+// Endpoint: PUT /admin/episodes/{id}/cover
+func (h *Handlers) SetEpisodeCover(c *gin.Context) {
+	if !h.mediaEnabled(c) {
+		return
+	}
+	ctx := c.Request.Context()
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	var req SetEpisodeCoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	thumb, err := h.mediaService.Repo().Get(ctx, req.ThumbnailID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get thumbnail"})
+		return
+	}
+	if thumb == nil || thumb.EpisodeID != episodeID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found for this episode"})
+		return
+	}
+	if err := h.episodesRepo.SetCover(ctx, episodeID, thumb.URL); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set episode cover", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cover updated", "cover_url": thumb.URL})
+}
@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+// CreateMultipartRequest datos del archivo a subir en partes.
+type CreateMultipartRequest struct {
+	Size        int64  `json:"size" binding:"required,min=1"`
+	ContentType string `json:"content_type"`
+}
+
+// CompleteMultipartRequest partes subidas, con el ETag de cada respuesta del bucket.
+type CompleteMultipartRequest struct {
+	VideoID  string                  `json:"video_id" binding:"required"`
+	UploadID string                  `json:"upload_id" binding:"required"`
+	Parts    []storage.CompletedPart `json:"parts" binding:"required,min=1,dive"`
+}
+
+// AbortMultipartRequest upload multiparte a descartar.
+type AbortMultipartRequest struct {
+	VideoID  string `json:"video_id" binding:"required"`
+	UploadID string `json:"upload_id" binding:"required"`
+}
+
+// multipartProvider devuelve el proveedor activo si soporta uploads multiparte
+// directos; si no, responde 501.
+func (h *Handlers) multipartProvider(c *gin.Context) (storage.MultipartProvider, bool) {
+	mp, ok := h.videoProvider.(storage.MultipartProvider)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"error":    fmt.Sprintf("Provider %s does not support multipart uploads", h.videoProvider.ProviderName()),
+			"provider": h.videoProvider.ProviderName(),
+		})
+	}
+	return mp, ok
+}
+
+// CreateMultipartUpload inicia un upload multiparte directo al almacenamiento: devuelve
+// una URL prefirmada por parte. El cliente sube cada parte por PUT (guardando el ETag
+// de la respuesta) y confirma con /multipart/complete.
+// Endpoint: POST /admin/episodes/{id}/multipart
+func (h *Handlers) CreateMultipartUpload(c *gin.Context) {
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	mp, ok := h.multipartProvider(c)
+	if !ok {
+		return
+	}
+
+	var req CreateMultipartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.ContentType == "" {
+		req.ContentType = "video/mp4"
+	}
+	if !strings.HasPrefix(req.ContentType, "video/") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Tipo de archivo no permitido: %s. Solo se aceptan videos.", req.ContentType),
+		})
+		return
+	}
+	if req.Size > h.maxFileSizeMB*1024*1024 {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":  fmt.Sprintf("El archivo supera el límite de %d MB", h.maxFileSizeMB),
+			"max_mb": h.maxFileSizeMB,
+		})
+		return
+	}
+
+	episode, err := h.episodesRepo.GetByID(c.Request.Context(), episodeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Episode not found"})
+		return
+	}
+
+	upload, err := mp.CreateMultipartUpload(episode.Title, req.ContentType, req.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create multipart upload",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := h.videoStatus.MarkUploading(c.Request.Context(), episode); err != nil {
+		log.Printf("admin: %v", err) // no crítico
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"upload":     upload,
+		"episode_id": episodeID,
+		"provider":   h.videoProvider.ProviderName(),
+	})
+}
+
+// CompleteMultipartUpload ensambla las partes, asigna el video al episodio y
+// notifica a los fans (igual que CompleteUpload).
+// Endpoint: POST /admin/episodes/{id}/multipart/complete
+func (h *Handlers) CompleteMultipartUpload(c *gin.Context) {
+	ctx := c.Request.Context()
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	mp, ok := h.multipartProvider(c)
+	if !ok {
+		return
+	}
+
+	var req CompleteMultipartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if err := mp.CompleteMultipartUpload(req.VideoID, req.UploadID, req.Parts); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to complete multipart upload",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := h.episodesRepo.UpdateVideoID(ctx, episodeID, req.VideoID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update episode video ID"})
+		return
+	}
+
+	h.videoProvider.CompleteUpload(req.VideoID) // no crítico
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Upload completed successfully",
+		"video_id":     req.VideoID,
+		"video_status": h.syncVideoStatus(ctx, episodeID),
+		"provider":     h.videoProvider.ProviderName(),
+	})
+}
+
+// AbortMultipartUpload descarta un upload multiparte sin completar.
+// Endpoint: POST /admin/episodes/{id}/multipart/abort
+func (h *Handlers) AbortMultipartUpload(c *gin.Context) {
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	mp, ok := h.multipartProvider(c)
+	if !ok {
+		return
+	}
+
+	var req AbortMultipartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if err := mp.AbortMultipartUpload(req.VideoID, req.UploadID); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to abort multipart upload",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Multipart upload aborted"})
+}
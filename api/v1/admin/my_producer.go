@@ -1,20 +1,23 @@
 package admin
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/images"
 	"github.com/qenti/qenti/internal/pkg/producers"
 )
 
 // MyProducerHandlers gestiona los endpoints de configuración de la propia productora.
 type MyProducerHandlers struct {
-	repo *producers.Repository
+	repo       *producers.Repository
+	imagesRepo *images.Repository
 }
 
-func NewMyProducerHandlers(repo *producers.Repository) *MyProducerHandlers {
-	return &MyProducerHandlers{repo: repo}
+func NewMyProducerHandlers(repo *producers.Repository, imagesRepo *images.Repository) *MyProducerHandlers {
+	return &MyProducerHandlers{repo: repo, imagesRepo: imagesRepo}
 }
 
 // GetMyProducer devuelve los datos de la productora del usuario autenticado.
@@ -42,15 +45,20 @@ func (h *MyProducerHandlers) GetMyProducer(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "No tienes una productora vinculada"})
 		return
 	}
+	if err := h.imagesRepo.AttachProducer(ctx, p); err != nil {
+		log.Printf("admin: producer %s logo: %v", p.ID, err)
+	}
 
 	c.JSON(http.StatusOK, p)
 }
 
 // UpdateMyProducerRequest payload para actualizar datos de la propia productora.
 type UpdateMyProducerRequest struct {
-	Name        string `json:"name"`
-	LogoURL     string `json:"logo_url"`
-	Description string `json:"description"`
+	Name    string `json:"name"`
+	LogoURL string `json:"logo_url"`
+	// LogoImageID imagen subida (POST /admin/images); reemplaza a logo_url
+	LogoImageID *uuid.UUID `json:"logo_image_id"`
+	Description string     `json:"description"`
 }
 
 // UpdateMyProducer actualiza nombre, logo y descripción de la productora propia.
@@ -87,10 +95,14 @@ func (h *MyProducerHandlers) UpdateMyProducer(c *gin.Context) {
 	}
 	if req.LogoURL != "" {
 		p.LogoURL = req.LogoURL
+		p.LogoImageID = nil
 	}
 	if req.Description != "" {
 		p.Description = req.Description
 	}
+	if req.LogoImageID != nil && !setProducerLogo(c, h.imagesRepo, p, *req.LogoImageID) {
+		return
+	}
 
 	if err := h.repo.Update(ctx, p); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar la productora"})
@@ -0,0 +1,186 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/privacy"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+// PrivacyHandlers herramienta de super admin para solicitudes de titulares de datos
+// (GDPR/LGPD): exportar los datos de un usuario y gestionar borrados de cuenta.
+type PrivacyHandlers struct {
+	privacyService *privacy.Service
+}
+
+func NewPrivacyHandlers(privacyService *privacy.Service) *PrivacyHandlers {
+	return &PrivacyHandlers{privacyService: privacyService}
+}
+
+// AdminDeleteUserRequest opciones del borrado iniciado por un super admin.
+type AdminDeleteUserRequest struct {
+	// Immediate borra en el acto en lugar de respetar el período de gracia
+	Immediate bool `json:"immediate"`
+}
+
+// ListExports lista exportaciones (filtro opcional ?user_id=).
+//
+// GET /api/v1/admin/privacy/exports
+func (h *PrivacyHandlers) ListExports(c *gin.Context) {
+	var userID *uuid.UUID
+	if s := c.Query("user_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		userID = &id
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	jobs, err := h.privacyService.Repo().ListExports(c.Request.Context(), userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exports"})
+		return
+	}
+	if jobs == nil {
+		jobs = []privacy.ExportJob{}
+	}
+	c.JSON(http.StatusOK, gin.H{"exports": jobs})
+}
+
+// ListDeletions lista solicitudes de borrado (filtro opcional ?status=pending|cancelled|completed|failed).
+//
+// GET /api/v1/admin/privacy/deletions
+func (h *PrivacyHandlers) ListDeletions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	list, err := h.privacyService.Repo().ListDeletions(c.Request.Context(), c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deletions"})
+		return
+	}
+	if list == nil {
+		list = []privacy.DeletionRequest{}
+	}
+	c.JSON(http.StatusOK, gin.H{"deletions": list})
+}
+
+// ExportUser encola la exportación de datos de un usuario en su nombre.
+//
+// POST /api/v1/admin/privacy/users/:id/export
+func (h *PrivacyHandlers) ExportUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	adminID := c.MustGet("user_id").(uuid.UUID)
+
+	job, err := h.privacyService.RequestExport(c.Request.Context(), userID, &adminID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request data export"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"export": job})
+}
+
+// DownloadExport descarga el ZIP de cualquier exportación lista.
+//
+// GET /api/v1/admin/privacy/exports/:id/download
+func (h *PrivacyHandlers) DownloadExport(c *gin.Context) {
+	exportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	job, err := h.privacyService.Repo().GetExport(c.Request.Context(), exportID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch export"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	if job.Status != privacy.ExportReady {
+		c.JSON(http.StatusConflict, gin.H{"error": "Export not ready", "status": job.Status})
+		return
+	}
+	archive, err := h.privacyService.OpenExport(job)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		c.JSON(http.StatusGone, gin.H{"error": "Export file no longer available"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open export"})
+		return
+	}
+	defer archive.Close()
+	c.DataFromReader(http.StatusOK, job.SizeBytes, "application/zip", archive, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="qenti-data-%s-%s.zip"`, job.UserID, job.CreatedAt.Format("2006-01-02")),
+	})
+}
+
+// DeleteUser programa (o ejecuta en el acto con immediate=true) el borrado de la
+// cuenta de un usuario.
+//
+// POST /api/v1/admin/privacy/users/:id/delete
+func (h *PrivacyHandlers) DeleteUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var req AdminDeleteUserRequest
+	_ = c.ShouldBindJSON(&req) // body opcional
+	adminID := c.MustGet("user_id").(uuid.UUID)
+
+	deletion, err := h.privacyService.RequestDeletion(c.Request.Context(), userID, &adminID, req.Immediate)
+	if errors.Is(err, privacy.ErrOwnsProducer) {
+		c.JSON(http.StatusConflict, gin.H{"error": "User owns a producer; transfer or delete it first"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete user",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deletion": deletion})
+}
+
+// CancelDeletion cancela el borrado pendiente de un usuario.
+//
+// POST /api/v1/admin/privacy/users/:id/delete/cancel
+func (h *PrivacyHandlers) CancelDeletion(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cancelled, err := h.privacyService.Repo().CancelDeletionAdmin(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel deletion"})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending deletion"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
@@ -1,10 +1,13 @@
 package admin
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/auth"
+	"github.com/qenti/qenti/internal/pkg/images"
 	"github.com/qenti/qenti/internal/pkg/models"
 	"github.com/qenti/qenti/internal/pkg/notifications"
 	"github.com/qenti/qenti/internal/pkg/producers"
@@ -12,13 +15,17 @@ import (
 
 type ProducersHandlers struct {
 	producersRepo *producers.Repository
+	imagesRepo    *images.Repository
 	notifService  *notifications.Service
+	authService   *auth.Service
 }
 
-func NewProducersHandlers(producersRepo *producers.Repository, notifService *notifications.Service) *ProducersHandlers {
+func NewProducersHandlers(producersRepo *producers.Repository, imagesRepo *images.Repository, notifService *notifications.Service, authService *auth.Service) *ProducersHandlers {
 	return &ProducersHandlers{
 		producersRepo: producersRepo,
+		imagesRepo:    imagesRepo,
 		notifService:  notifService,
+		authService:   authService,
 	}
 }
 
@@ -29,15 +36,18 @@ type CreateProducerRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Slug        string `json:"slug"`
 	LogoURL     string `json:"logo_url"`
-	Description string `json:"description"`
+	// LogoImageID imagen subida (POST /admin/images); reemplaza a logo_url
+	LogoImageID *uuid.UUID `json:"logo_image_id"`
+	Description string     `json:"description"`
 }
 
 // UpdateProducerRequest representa el payload para actualizar un productor
 type UpdateProducerRequest struct {
-	Name        string `json:"name"`
-	LogoURL     string `json:"logo_url"`
-	Description string `json:"description"`
-	IsActive    *bool  `json:"is_active"`
+	Name        string     `json:"name"`
+	LogoURL     string     `json:"logo_url"`
+	LogoImageID *uuid.UUID `json:"logo_image_id"`
+	Description string     `json:"description"`
+	IsActive    *bool      `json:"is_active"`
 }
 
 // GetProducers lista todos los productores (super_admin only)
@@ -67,6 +77,9 @@ func (h *ProducersHandlers) GetProducerByID(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Producer not found"})
 		return
 	}
+	if err := h.imagesRepo.AttachProducer(ctx, &p.Producer); err != nil {
+		log.Printf("admin: producer %s logo: %v", p.ID, err)
+	}
 	c.JSON(http.StatusOK, gin.H{"producer": p})
 }
 
@@ -96,11 +109,16 @@ func (h *ProducersHandlers) CreateProducer(c *gin.Context) {
 		// Super_admin crea productores ya aprobados (sin pasar por flujo de onboarding)
 		Status:      "active",
 	}
+	if req.LogoImageID != nil && !setProducerLogo(c, h.imagesRepo, p, *req.LogoImageID) {
+		return
+	}
 
 	if err := h.producersRepo.Create(ctx, p); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create producer", "details": err.Error()})
 		return
 	}
+	// El producer_id del rol en cache cambia con la nueva productora
+	h.authService.InvalidateRoles(ctx)
 
 	c.JSON(http.StatusCreated, gin.H{"producer": p})
 }
@@ -132,6 +150,7 @@ func (h *ProducersHandlers) UpdateProducer(c *gin.Context) {
 	}
 	if req.LogoURL != "" {
 		p.LogoURL = req.LogoURL
+		p.LogoImageID = nil
 	}
 	if req.Description != "" {
 		p.Description = req.Description
@@ -139,6 +158,9 @@ func (h *ProducersHandlers) UpdateProducer(c *gin.Context) {
 	if req.IsActive != nil {
 		p.IsActive = *req.IsActive
 	}
+	if req.LogoImageID != nil && !setProducerLogo(c, h.imagesRepo, p, *req.LogoImageID) {
+		return
+	}
 
 	if err := h.producersRepo.Update(ctx, p); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update producer"})
@@ -159,6 +181,8 @@ func (h *ProducersHandlers) DeleteProducer(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete producer"})
 		return
 	}
+	// Los roles en cache pueden seguir apuntando al producer_id borrado
+	h.authService.InvalidateRoles(ctx)
 	c.JSON(http.StatusOK, gin.H{"message": "Producer deleted successfully"})
 }
 
@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qenti/qenti/internal/pkg/search"
+)
+
+// SearchHandlers reportes de las búsquedas hechas en la app.
+type SearchHandlers struct {
+	search *search.Service
+}
+
+func NewSearchHandlers(searchService *search.Service) *SearchHandlers {
+	return &SearchHandlers{search: searchService}
+}
+
+// GetSearchQueries devuelve las búsquedas más frecuentes de los últimos days días
+// (con clics y promedio de resultados) y las más frecuentes que no encontraron
+// nada, útiles para detectar contenido o traducciones que faltan. Si el rol es
+// "producer" solo cuenta las búsquedas hechas en su app.
+// Endpoint: GET /admin/search/queries?days=30&limit=50
+func (h *SearchHandlers) GetSearchQueries(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	days := 30
+	if d, err := strconv.Atoi(c.Query("days")); err == nil && d > 0 && d <= 365 {
+		days = d
+	}
+	limit := 50
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+	producerID := producerIDFromContext(c)
+
+	top, err := h.search.QueryStats(ctx, days, limit, false, producerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get search queries", "details": err.Error()})
+		return
+	}
+	zero, err := h.search.QueryStats(ctx, days, limit, true, producerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get search queries", "details": err.Error()})
+		return
+	}
+	if top == nil {
+		top = []search.QueryStat{}
+	}
+	if zero == nil {
+		zero = []search.QueryStat{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":                days,
+		"top_queries":         top,
+		"zero_result_queries": zero,
+	})
+}
+
+// GetBlockedQueries lista las búsquedas que nunca se sugieren en el autocompletado.
+// Endpoint: GET /admin/search/blocked-queries
+func (h *SearchHandlers) GetBlockedQueries(c *gin.Context) {
+	blocked, err := h.search.BlockedQueries(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get blocked queries", "details": err.Error()})
+		return
+	}
+	if blocked == nil {
+		blocked = []search.BlockedQuery{}
+	}
+	c.JSON(http.StatusOK, gin.H{"blocked_queries": blocked})
+}
+
+// BlockQueryRequest búsqueda a bloquear (se normaliza como las búsquedas registradas).
+type BlockQueryRequest struct {
+	Query string `json:"query" binding:"required"`
+}
+
+// BlockQuery excluye una búsqueda de las sugerencias aunque sea popular.
+// Endpoint: POST /admin/search/blocked-queries
+func (h *SearchHandlers) BlockQuery(c *gin.Context) {
+	var req BlockQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if search.Normalize(req.Query) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query has no letters or digits"})
+		return
+	}
+	normalized, err := h.search.BlockQuery(c.Request.Context(), req.Query, currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block query", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"query": normalized})
+}
+
+// UnblockQuery vuelve a permitir una búsqueda en las sugerencias.
+// Endpoint: DELETE /admin/search/blocked-queries?query=texto
+func (h *SearchHandlers) UnblockQuery(c *gin.Context) {
+	removed, err := h.search.UnblockQuery(c.Request.Context(), c.Query("query"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock query", "details": err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Query is not blocked"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Query unblocked"})
+}
@@ -0,0 +1,319 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/series"
+	"github.com/qenti/qenti/internal/pkg/taxonomy"
+	"github.com/qenti/qenti/internal/pkg/translations"
+)
+
+// TaxonomyHandlers gestiona los géneros (super_admin), las etiquetas y su
+// asignación a las series.
+type TaxonomyHandlers struct {
+	taxonomyRepo *taxonomy.Repository
+	seriesRepo   *series.Repository
+}
+
+func NewTaxonomyHandlers(taxonomyRepo *taxonomy.Repository, seriesRepo *series.Repository) *TaxonomyHandlers {
+	return &TaxonomyHandlers{taxonomyRepo: taxonomyRepo, seriesRepo: seriesRepo}
+}
+
+// GenreRequest payload para crear o editar un género. Sin slug se genera desde el
+// nombre; translations lleva el nombre en otros idiomas ({"pt-BR": "Vingança"}).
+type GenreRequest struct {
+	Name         string            `json:"name" binding:"required"`
+	Slug         string            `json:"slug"`
+	Translations map[string]string `json:"translations"`
+	Position     int               `json:"position"`
+	IsActive     *bool             `json:"is_active"`
+}
+
+// SeriesTaxonomyRequest géneros (slugs) y etiquetas (nombres libres) de una serie;
+// reemplaza los anteriores.
+type SeriesTaxonomyRequest struct {
+	Genres []string `json:"genres"`
+	Tags   []string `json:"tags"`
+}
+
+// genreFromRequest valida el payload y lo vuelca en g.
+func genreFromRequest(req GenreRequest, g *models.Genre) error {
+	g.Name = strings.TrimSpace(req.Name)
+	if g.Name == "" || utf8.RuneCountInString(g.Name) > 100 {
+		return errors.New("name must have between 1 and 100 characters")
+	}
+	slug := req.Slug
+	if slug == "" {
+		slug = g.Name
+	}
+	g.Slug = taxonomy.Slugify(slug)
+	if g.Slug == "" || len(g.Slug) > 60 {
+		return errors.New("slug must have between 1 and 60 letters or digits")
+	}
+	g.Translations = make(map[string]string, len(req.Translations))
+	for code, name := range req.Translations {
+		locale, ok := translations.NormalizeLocale(code)
+		if !ok {
+			return fmt.Errorf("invalid locale %q (BCP 47, ej. pt-BR, en, es-419)", code)
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			if utf8.RuneCountInString(name) > 100 {
+				return fmt.Errorf("translation %s must have at most 100 characters", locale)
+			}
+			g.Translations[locale] = name
+		}
+	}
+	g.Position = req.Position
+	if req.IsActive != nil {
+		g.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// ListGenres lista los géneros para asignarlos a las series. Los productores solo
+// ven los activos.
+// Endpoint: GET /admin/genres
+func (h *TaxonomyHandlers) ListGenres(c *gin.Context) {
+	list, err := h.taxonomyRepo.ListGenres(c.Request.Context(), producerIDFromContext(c) != nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list genres"})
+		return
+	}
+	if list == nil {
+		list = []models.Genre{}
+	}
+	c.JSON(http.StatusOK, gin.H{"genres": list})
+}
+
+// CreateGenre crea un género (super_admin).
+// Endpoint: POST /admin/genres
+func (h *TaxonomyHandlers) CreateGenre(c *gin.Context) {
+	var req GenreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	g := &models.Genre{IsActive: true}
+	if err := genreFromRequest(req, g); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre", "details": err.Error()})
+		return
+	}
+	if err := h.taxonomyRepo.CreateGenre(c.Request.Context(), g); err != nil {
+		if errors.Is(err, taxonomy.ErrSlugTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Genre slug already in use"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create genre"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"genre": g})
+}
+
+// UpdateGenre reemplaza nombre, slug, traducciones, posición y estado del género
+// (super_admin). Desactivarlo lo oculta de la app sin quitarlo de las series.
+// Endpoint: PUT /admin/genres/{id}
+func (h *TaxonomyHandlers) UpdateGenre(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre ID"})
+		return
+	}
+	var req GenreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	g, err := h.taxonomyRepo.GetGenre(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get genre"})
+		return
+	}
+	if g == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Genre not found"})
+		return
+	}
+	if err := genreFromRequest(req, g); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre", "details": err.Error()})
+		return
+	}
+	if err := h.taxonomyRepo.UpdateGenre(ctx, g); err != nil {
+		if errors.Is(err, taxonomy.ErrSlugTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Genre slug already in use"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update genre"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"genre": g})
+}
+
+// DeleteGenre borra el género y lo quita de todas las series (super_admin).
+// Endpoint: DELETE /admin/genres/{id}
+func (h *TaxonomyHandlers) DeleteGenre(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre ID"})
+		return
+	}
+	deleted, err := h.taxonomyRepo.DeleteGenre(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete genre"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Genre not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Genre deleted"})
+}
+
+// ListTags lista las etiquetas en uso que empiezan con q (para autocompletar al
+// etiquetar), con cuántas series las usan. Los productores ven solo las de sus series.
+// Endpoint: GET /admin/tags?q=seg&limit=20
+func (h *TaxonomyHandlers) ListTags(c *gin.Context) {
+	limit := 20
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	list, err := h.taxonomyRepo.ListTags(c.Request.Context(), c.Query("q"), producerIDFromContext(c), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+	if list == nil {
+		list = []models.Term{}
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": list})
+}
+
+// DeleteTag borra la etiqueta de todas las series (super_admin).
+// Endpoint: DELETE /admin/tags/{id}
+func (h *TaxonomyHandlers) DeleteTag(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+	deleted, err := h.taxonomyRepo.DeleteTag(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted"})
+}
+
+// ownedSeries parsea el ID de la serie y verifica que sea del productor del admin.
+func (h *TaxonomyHandlers) ownedSeries(c *gin.Context) (uuid.UUID, bool) {
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return uuid.Nil, false
+	}
+	pidStr, _ := c.Get("producer_id")
+	if owns, err := h.seriesRepo.BelongsToProducer(c.Request.Context(), seriesID, fmt.Sprintf("%v", pidStr)); err != nil || !owns {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Series not found or not owned by you"})
+		return uuid.Nil, false
+	}
+	return seriesID, true
+}
+
+// GetSeriesTaxonomy devuelve los géneros y etiquetas de la serie.
+// Endpoint: GET /admin/series/{id}/taxonomy
+func (h *TaxonomyHandlers) GetSeriesTaxonomy(c *gin.Context) {
+	seriesID, ok := h.ownedSeries(c)
+	if !ok {
+		return
+	}
+	genres, tags, err := h.taxonomyRepo.SeriesTerms(c.Request.Context(), nil, []uuid.UUID{seriesID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get series taxonomy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"genres": nonNilTerms(genres[seriesID]),
+		"tags":   nonNilTerms(tags[seriesID]),
+	})
+}
+
+// PutSeriesTaxonomy reemplaza los géneros y etiquetas de la serie. Las etiquetas
+// que no existen se crean.
+// Endpoint: PUT /admin/series/{id}/taxonomy
+func (h *TaxonomyHandlers) PutSeriesTaxonomy(c *gin.Context) {
+	ctx := c.Request.Context()
+	seriesID, ok := h.ownedSeries(c)
+	if !ok {
+		return
+	}
+	var req SeriesTaxonomyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	var genreSlugs []string
+	seen := make(map[string]bool)
+	for _, g := range req.Genres {
+		if slug := taxonomy.Slugify(g); slug != "" && !seen[slug] {
+			seen[slug] = true
+			genreSlugs = append(genreSlugs, slug)
+		}
+	}
+	var tagNames []string
+	seen = make(map[string]bool)
+	for _, t := range req.Tags {
+		name := strings.TrimSpace(t)
+		slug := taxonomy.Slugify(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		if utf8.RuneCountInString(name) > taxonomy.MaxTagLength || len(slug) > 60 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Tag %q is too long (max %d characters)", name, taxonomy.MaxTagLength)})
+			return
+		}
+		seen[slug] = true
+		tagNames = append(tagNames, name)
+	}
+	if len(genreSlugs) > taxonomy.MaxSeriesGenres || len(tagNames) > taxonomy.MaxSeriesTags {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A series can have up to %d genres and %d tags", taxonomy.MaxSeriesGenres, taxonomy.MaxSeriesTags)})
+		return
+	}
+
+	if err := h.taxonomyRepo.SetSeriesTaxonomy(ctx, seriesID, genreSlugs, tagNames); err != nil {
+		if errors.Is(err, taxonomy.ErrUnknownGenre) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown or inactive genre"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series taxonomy", "details": err.Error()})
+		return
+	}
+
+	genres, tags, err := h.taxonomyRepo.SeriesTerms(ctx, nil, []uuid.UUID{seriesID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get series taxonomy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"genres": nonNilTerms(genres[seriesID]),
+		"tags":   nonNilTerms(tags[seriesID]),
+	})
+}
+
+func nonNilTerms(list []models.Term) []models.Term {
+	if list == nil {
+		return []models.Term{}
+	}
+	return list
+}
@@ -7,15 +7,17 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/auth"
 	"github.com/qenti/qenti/internal/pkg/jwt"
 )
 
 type TeamHandlers struct {
-	db *sql.DB
+	db          *sql.DB
+	authService *auth.Service
 }
 
-func NewTeamHandlers(db *sql.DB) *TeamHandlers {
-	return &TeamHandlers{db: db}
+func NewTeamHandlers(db *sql.DB, authService *auth.Service) *TeamHandlers {
+	return &TeamHandlers{db: db, authService: authService}
 }
 
 type TeamMember struct {
@@ -126,6 +128,7 @@ func (h *TeamHandlers) RemoveMember(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found in this team"})
 		return
 	}
+	h.authService.InvalidateRoles(ctx)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
 }
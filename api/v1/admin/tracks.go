@@ -0,0 +1,167 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/storage"
+	"github.com/qenti/qenti/internal/pkg/tracks"
+)
+
+// maxSubtitlesBytes tamaño máximo de un archivo de subtítulos.
+const maxSubtitlesBytes = 2 << 20
+
+// TrackHandlers gestiona las pistas adicionales de los episodios: subtítulos y
+// audio alternativo en otros idiomas.
+type TrackHandlers struct {
+	tracks        *tracks.Service
+	episodesRepo  *episodes.Repository
+	maxFileSizeMB int64
+}
+
+func NewTrackHandlers(tracksService *tracks.Service, episodesRepo *episodes.Repository, maxFileSizeMB int64) *TrackHandlers {
+	return &TrackHandlers{tracks: tracksService, episodesRepo: episodesRepo, maxFileSizeMB: maxFileSizeMB}
+}
+
+// ListTracks lista las pistas del episodio. current indica si la pista es del
+// video actual (las de un video reemplazado no se ofrecen al reproducir).
+// Endpoint: GET /admin/episodes/{id}/tracks
+func (h *TrackHandlers) ListTracks(c *gin.Context) {
+	ctx := c.Request.Context()
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	episode, err := h.episodesRepo.GetByID(ctx, episodeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Episode not found"})
+		return
+	}
+
+	list, err := h.tracks.Repo().ListByEpisode(ctx, episodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tracks"})
+		return
+	}
+	result := make([]gin.H, 0, len(list))
+	for _, t := range list {
+		result = append(result, gin.H{"track": t, "current": t.VideoID == episode.VideoIDBunny})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tracks": result,
+		"supports": gin.H{
+			storage.TrackKindSubtitles: h.tracks.Supports(storage.TrackKindSubtitles),
+			storage.TrackKindAudio:     h.tracks.Supports(storage.TrackKindAudio),
+		},
+	})
+}
+
+// UploadTrack sube una pista del episodio (multipart): "file", "kind" (subtitles |
+// audio), "language" (BCP 47: es-419, pt-BR, en), "label" e "is_default" opcionales.
+// Los subtítulos se aceptan en SRT o WebVTT y se guardan en WebVTT; el audio en
+// AAC (M4A/ADTS) o MP3. Reemplaza la pista del mismo tipo e idioma.
+// Endpoint: POST /admin/episodes/{id}/tracks
+func (h *TrackHandlers) UploadTrack(c *gin.Context) {
+	ctx := c.Request.Context()
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	episode, err := h.episodesRepo.GetByID(ctx, episodeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Episode not found"})
+		return
+	}
+
+	kind := c.PostForm("kind")
+	maxBytes := h.maxFileSizeMB * 1024 * 1024
+	switch kind {
+	case storage.TrackKindSubtitles:
+		maxBytes = maxSubtitlesBytes
+	case storage.TrackKindAudio:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be 'subtitles' or 'audio'"})
+		return
+	}
+	if !h.tracks.Supports(kind) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": fmt.Sprintf("El proveedor de video no soporta pistas de tipo %s", kind)})
+		return
+	}
+	isDefault, _ := strconv.ParseBool(c.PostForm("is_default"))
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Falta el archivo (campo 'file')", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+	if header.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":  fmt.Sprintf("El archivo pesa %.1f MB y supera el límite de %.0f MB.", float64(header.Size)/1024/1024, float64(maxBytes)/1024/1024),
+			"max_mb": float64(maxBytes) / 1024 / 1024,
+		})
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file", "details": err.Error()})
+		return
+	}
+
+	track, err := h.tracks.Upload(ctx, episode, tracks.Upload{
+		Kind:      kind,
+		Language:  c.PostForm("language"),
+		Label:     c.PostForm("label"),
+		IsDefault: isDefault,
+		CreatedBy: currentUserID(c),
+	}, data)
+	switch {
+	case errors.Is(err, tracks.ErrNoVideo):
+		c.JSON(http.StatusConflict, gin.H{"error": "El episodio todavía no tiene video; sube el video antes que las pistas"})
+	case errors.Is(err, tracks.ErrInvalidLanguage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idioma inválido (código BCP 47, ej. es-419, pt-BR, en)", "details": err.Error()})
+	case errors.Is(err, tracks.ErrInvalidSubtitles):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Los subtítulos no son un SRT o WebVTT válido", "details": err.Error()})
+	case errors.Is(err, tracks.ErrInvalidAudio):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "El audio debe ser AAC (M4A) o MP3"})
+	case errors.Is(err, tracks.ErrNotSupported):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": fmt.Sprintf("El proveedor de video no soporta pistas de tipo %s", kind)})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload track", "details": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, gin.H{"track": track})
+	}
+}
+
+// DeleteTrack borra una pista del episodio.
+// Endpoint: DELETE /admin/episodes/{id}/tracks/{trackId}
+func (h *TrackHandlers) DeleteTrack(c *gin.Context) {
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	trackID, err := uuid.Parse(c.Param("trackId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid track ID"})
+		return
+	}
+	found, err := h.tracks.Delete(c.Request.Context(), episodeID, trackID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete track", "details": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Track not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Track deleted"})
+}
@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/transcode"
+)
+
+// enqueueTranscode guarda el upload y encola su transcodificación (UploadVideo con
+// TRANSCODE_ENABLED). Responde 202 con el trabajo; el episodio recibe el video
+// cuando el worker termina. Devuelve si el trabajo quedó encolado.
+func (h *Handlers) enqueueTranscode(c *gin.Context, episodeID uuid.UUID, src io.Reader) bool {
+	job, err := h.transcodeService.Enqueue(c.Request.Context(), episodeID, currentUserID(c), src)
+	switch {
+	case errors.Is(err, transcode.ErrTooLong):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "El video supera la duración máxima permitida", "details": err.Error()})
+		return false
+	case errors.Is(err, transcode.ErrNotVideo):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "El archivo no es un video válido", "details": err.Error()})
+		return false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue transcode job", "details": err.Error()})
+		return false
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Video queued for transcoding",
+		"job":      job,
+		"provider": h.videoProvider.ProviderName(),
+	})
+	return true
+}
+
+// transcodeEnabled responde 501 si la transcodificación propia está deshabilitada.
+func (h *Handlers) transcodeEnabled(c *gin.Context) bool {
+	if h.transcodeService == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Transcoding is disabled (TRANSCODE_ENABLED)"})
+		return false
+	}
+	return true
+}
+
+// GetTranscodeJob devuelve el estado y avance de un trabajo de transcodificación.
+// Endpoint: GET /admin/transcode/jobs/{id}
+func (h *Handlers) GetTranscodeJob(c *gin.Context) {
+	if !h.transcodeEnabled(c) {
+		return
+	}
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := h.transcodeService.Repo().Get(c.Request.Context(), jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get transcode job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transcode job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// ListTranscodeJobs lista los últimos trabajos de transcodificación de un episodio.
+// Endpoint: GET /admin/episodes/{id}/transcode-jobs
+func (h *Handlers) ListTranscodeJobs(c *gin.Context) {
+	if !h.transcodeEnabled(c) {
+		return
+	}
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+
+	jobs, err := h.transcodeService.Repo().ListByEpisode(c.Request.Context(), episodeID, 20)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list transcode jobs"})
+		return
+	}
+	if jobs == nil {
+		jobs = []transcode.Job{}
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// currentUserID devuelve el usuario autenticado del request, o nil.
+func currentUserID(c *gin.Context) *uuid.UUID {
+	if v, ok := c.Get("user_id"); ok {
+		if id, ok := v.(uuid.UUID); ok {
+			return &id
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/series"
+	"github.com/qenti/qenti/internal/pkg/translations"
+)
+
+// TranslationHandlers gestiona los títulos y descripciones de series y episodios en
+// otros idiomas. Los textos del idioma por defecto (DEFAULT_LOCALE) son los de la
+// serie o el episodio y se editan con su PUT.
+type TranslationHandlers struct {
+	translations *translations.Service
+	seriesRepo   *series.Repository
+	episodesRepo *episodes.Repository
+}
+
+func NewTranslationHandlers(translationsService *translations.Service, seriesRepo *series.Repository, episodesRepo *episodes.Repository) *TranslationHandlers {
+	return &TranslationHandlers{translations: translationsService, seriesRepo: seriesRepo, episodesRepo: episodesRepo}
+}
+
+// SeriesTranslationRequest textos de una serie en un idioma. Un campo vacío u
+// omitido no se traduce y cae al siguiente idioma de la cadena de fallback.
+type SeriesTranslationRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// EpisodeTranslationRequest título de un episodio en un idioma.
+type EpisodeTranslationRequest struct {
+	Title string `json:"title" binding:"required"`
+}
+
+// ownsSeries verifica que la serie sea del productor del admin (super_admin: todas).
+func (h *TranslationHandlers) ownsSeries(c *gin.Context, seriesID uuid.UUID) bool {
+	pidStr, _ := c.Get("producer_id")
+	if owns, err := h.seriesRepo.BelongsToProducer(c.Request.Context(), seriesID, fmt.Sprintf("%v", pidStr)); err != nil || !owns {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Series not found or not owned by you"})
+		return false
+	}
+	return true
+}
+
+// ownedEpisode parsea el ID del episodio y verifica que su serie sea del productor.
+func (h *TranslationHandlers) ownedEpisode(c *gin.Context) (uuid.UUID, bool) {
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return uuid.Nil, false
+	}
+	episode, err := h.episodesRepo.GetByID(c.Request.Context(), episodeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Episode not found"})
+		return uuid.Nil, false
+	}
+	return episodeID, h.ownsSeries(c, episode.SeriesID)
+}
+
+// translationLocale valida el locale de la URL. El idioma por defecto no se traduce.
+func (h *TranslationHandlers) translationLocale(c *gin.Context) (string, bool) {
+	locale, ok := translations.NormalizeLocale(c.Param("locale"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idioma inválido (código BCP 47, ej. pt-BR, en, es-419)"})
+		return "", false
+	}
+	if def := h.translations.Resolver().DefaultLocale(); locale == def {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s es el idioma por defecto: edita los textos de la serie o el episodio", def)})
+		return "", false
+	}
+	return locale, true
+}
+
+// optionalText devuelve nil para los textos vacíos (campo sin traducir).
+func optionalText(s string) *string {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	return &s
+}
+
+// ListSeriesTranslations lista las traducciones de la serie.
+// Endpoint: GET /admin/series/{id}/translations
+func (h *TranslationHandlers) ListSeriesTranslations(c *gin.Context) {
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+	if !h.ownsSeries(c, seriesID) {
+		return
+	}
+	list, err := h.translations.Repo().ListSeries(c.Request.Context(), seriesID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list translations"})
+		return
+	}
+	if list == nil {
+		list = []translations.SeriesTranslation{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"default_locale": h.translations.Resolver().DefaultLocale(),
+		"translations":   list,
+	})
+}
+
+// PutSeriesTranslation crea o reemplaza la traducción de la serie en un idioma.
+// Endpoint: PUT /admin/series/{id}/translations/{locale}
+func (h *TranslationHandlers) PutSeriesTranslation(c *gin.Context) {
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+	if !h.ownsSeries(c, seriesID) {
+		return
+	}
+	locale, ok := h.translationLocale(c)
+	if !ok {
+		return
+	}
+	var req SeriesTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	t := &translations.SeriesTranslation{
+		SeriesID:    seriesID,
+		Locale:      locale,
+		Title:       optionalText(req.Title),
+		Description: optionalText(req.Description),
+		UpdatedBy:   currentUserID(c),
+	}
+	if t.Title == nil && t.Description == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title or description is required"})
+		return
+	}
+	if err := h.translations.Repo().UpsertSeries(c.Request.Context(), t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save translation", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"translation": t})
+}
+
+// DeleteSeriesTranslation borra la traducción de la serie en un idioma.
+// Endpoint: DELETE /admin/series/{id}/translations/{locale}
+func (h *TranslationHandlers) DeleteSeriesTranslation(c *gin.Context) {
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+	if !h.ownsSeries(c, seriesID) {
+		return
+	}
+	locale, ok := h.translationLocale(c)
+	if !ok {
+		return
+	}
+	found, err := h.translations.Repo().DeleteSeries(c.Request.Context(), seriesID, locale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete translation"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Translation deleted"})
+}
+
+// ListEpisodeTranslations lista las traducciones del episodio.
+// Endpoint: GET /admin/episodes/{id}/translations
+func (h *TranslationHandlers) ListEpisodeTranslations(c *gin.Context) {
+	episodeID, ok := h.ownedEpisode(c)
+	if !ok {
+		return
+	}
+	list, err := h.translations.Repo().ListEpisode(c.Request.Context(), episodeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list translations"})
+		return
+	}
+	if list == nil {
+		list = []translations.EpisodeTranslation{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"default_locale": h.translations.Resolver().DefaultLocale(),
+		"translations":   list,
+	})
+}
+
+// PutEpisodeTranslation crea o reemplaza el título del episodio en un idioma.
+// Endpoint: PUT /admin/episodes/{id}/translations/{locale}
+func (h *TranslationHandlers) PutEpisodeTranslation(c *gin.Context) {
+	episodeID, ok := h.ownedEpisode(c)
+	if !ok {
+		return
+	}
+	locale, ok := h.translationLocale(c)
+	if !ok {
+		return
+	}
+	var req EpisodeTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	t := &translations.EpisodeTranslation{
+		EpisodeID: episodeID,
+		Locale:    locale,
+		Title:     optionalText(req.Title),
+		UpdatedBy: currentUserID(c),
+	}
+	if t.Title == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return
+	}
+	if err := h.translations.Repo().UpsertEpisode(c.Request.Context(), t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save translation", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"translation": t})
+}
+
+// DeleteEpisodeTranslation borra la traducción del episodio en un idioma.
+// Endpoint: DELETE /admin/episodes/{id}/translations/{locale}
+func (h *TranslationHandlers) DeleteEpisodeTranslation(c *gin.Context) {
+	episodeID, ok := h.ownedEpisode(c)
+	if !ok {
+		return
+	}
+	locale, ok := h.translationLocale(c)
+	if !ok {
+		return
+	}
+	found, err := h.translations.Repo().DeleteEpisode(c.Request.Context(), episodeID, locale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete translation"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Translation deleted"})
+}
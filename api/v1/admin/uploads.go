@@ -0,0 +1,269 @@
+package admin
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/uploads"
+)
+
+const (
+	// tusVersion versión del protocolo TUS soportada (https://tus.io/protocols/resumable-upload).
+	tusVersion = "1.0.0"
+	// tusExtensions extensiones TUS soportadas.
+	tusExtensions = "creation,termination,expiration"
+	// uploadsPath ruta base de las sesiones (Location de POST).
+	uploadsPath = "/api/v1/admin/uploads/"
+)
+
+// UploadHandlers implementa el upload reanudable de videos con el protocolo TUS
+// 1.0.0 (extensiones creation, termination y expiration): el cliente crea la
+// sesión con el tamaño total, envía el archivo en PATCHes y, tras un corte,
+// consulta con HEAD el offset confirmado y sigue desde ahí. Cualquier cliente TUS
+// (tus-js-client, TUSKit, tus-android-client) funciona contra estos endpoints.
+type UploadHandlers struct {
+	uploads       *uploads.Service
+	episodesRepo  *episodes.Repository
+	maxFileSizeMB int64
+}
+
+func NewUploadHandlers(uploadsService *uploads.Service, episodesRepo *episodes.Repository, maxFileSizeMB int64) *UploadHandlers {
+	return &UploadHandlers{uploads: uploadsService, episodesRepo: episodesRepo, maxFileSizeMB: maxFileSizeMB}
+}
+
+// CreateUpload abre una sesión de upload reanudable para el video del episodio.
+// Headers: Upload-Length (obligatorio) y Upload-Metadata (filename, filetype en base64).
+// Endpoint: POST /admin/episodes/{id}/uploads
+func (h *UploadHandlers) CreateUpload(c *gin.Context) {
+	if !h.tusResumable(c) {
+		return
+	}
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	episode, err := h.episodesRepo.GetByID(c.Request.Context(), episodeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Episode not found"})
+		return
+	}
+
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Defer-Length is not supported; send Upload-Length"})
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing Upload-Length header"})
+		return
+	}
+	if maxBytes := h.maxFileSizeMB * 1024 * 1024; length > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf(
+				"El archivo pesa %.1f MB y supera el límite de %d MB. Comprimir el video antes de subir.",
+				float64(length)/1024/1024, h.maxFileSizeMB,
+			),
+			"max_mb":  h.maxFileSizeMB,
+			"size_mb": float64(length) / 1024 / 1024,
+		})
+		return
+	}
+
+	metadata, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Upload-Metadata header", "details": err.Error()})
+		return
+	}
+	contentType := metadata["filetype"]
+	if contentType == "" {
+		contentType = "video/mp4"
+	}
+	if !strings.HasPrefix(contentType, "video/") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Tipo de archivo no permitido: %s. Solo se aceptan videos.", contentType),
+		})
+		return
+	}
+	filename := metadata["filename"]
+	if len(filename) > 255 {
+		filename = filename[:255]
+	}
+
+	session, err := h.uploads.Create(c.Request.Context(), episode, currentUserID(c), length, filename, contentType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload session", "details": err.Error()})
+		return
+	}
+
+	c.Header("Location", uploadsPath+session.ID.String())
+	h.sessionHeaders(c, session)
+	c.JSON(http.StatusCreated, gin.H{"upload": session})
+}
+
+// HeadUpload devuelve el offset confirmado de la sesión para retomar el upload.
+// Endpoint: HEAD /admin/uploads/{id}
+func (h *UploadHandlers) HeadUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Cache-Control", "no-store")
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	session, err := h.uploads.Repo().Get(c.Request.Context(), id)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if closedStatus(session.Status) {
+		c.Status(http.StatusGone)
+		return
+	}
+	h.sessionHeaders(c, session)
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload agrega un tramo del archivo a partir de Upload-Offset. Con el último
+// byte la sesión se entrega al proveedor en segundo plano (ver GetUpload).
+// Endpoint: PATCH /admin/uploads/{id}
+func (h *UploadHandlers) PatchUpload(c *gin.Context) {
+	if !h.tusResumable(c) {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing Upload-Offset header"})
+		return
+	}
+
+	session, err := h.uploads.Write(c.Request.Context(), id, offset, c.Request.Body)
+	if session != nil {
+		h.sessionHeaders(c, session)
+	}
+	switch {
+	case errors.Is(err, uploads.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+	case errors.Is(err, uploads.ErrBusy):
+		c.JSON(http.StatusLocked, gin.H{"error": "Another request is writing to this upload"})
+	case errors.Is(err, uploads.ErrOffsetMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload-Offset does not match the current offset", "upload_offset": session.Offset})
+	case errors.Is(err, uploads.ErrClosed):
+		c.JSON(http.StatusGone, gin.H{"error": "Upload is no longer accepting data", "status": session.Status})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write upload data", "details": err.Error()})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// DeleteUpload cancela una sesión que sigue subiendo y descarta lo recibido.
+// Endpoint: DELETE /admin/uploads/{id}
+func (h *UploadHandlers) DeleteUpload(c *gin.Context) {
+	if !h.tusResumable(c) {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+	switch err := h.uploads.Terminate(c.Request.Context(), id); {
+	case errors.Is(err, uploads.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+	case errors.Is(err, uploads.ErrBusy):
+		c.JSON(http.StatusLocked, gin.H{"error": "Another request is writing to this upload"})
+	case errors.Is(err, uploads.ErrClosed):
+		c.JSON(http.StatusGone, gin.H{"error": "Upload is no longer in progress"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to terminate upload", "details": err.Error()})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetUpload devuelve la sesión en JSON: avance, estado de la entrega al proveedor
+// (finalizing → completed | failed) y el video o trabajo de transcodificación resultante.
+// Endpoint: GET /admin/uploads/{id}
+func (h *UploadHandlers) GetUpload(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+	session, err := h.uploads.Repo().Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload"})
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"upload": session})
+}
+
+// tusResumable valida la versión TUS del cliente (412 si no es compatible).
+func (h *UploadHandlers) tusResumable(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.Header("Tus-Extension", tusExtensions)
+		c.Header("Tus-Max-Size", strconv.FormatInt(h.maxFileSizeMB*1024*1024, 10))
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Unsupported Tus-Resumable version", "supported": tusVersion})
+		return false
+	}
+	return true
+}
+
+func (h *UploadHandlers) sessionHeaders(c *gin.Context, session *uploads.Session) {
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Length, 10))
+	if session.Status == uploads.StatusUploading {
+		c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// closedStatus indica si la sesión se descartó sin entregarse.
+func closedStatus(status string) bool {
+	return status == uploads.StatusExpired || status == uploads.StatusAborted || status == uploads.StatusFailed
+}
+
+// parseUploadMetadata decodifica Upload-Metadata: pares "clave base64(valor)"
+// separados por coma (el valor puede omitirse).
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("metadata %q: %w", key, err)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
@@ -2,14 +2,15 @@ package admin
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/qenti/qenti/internal/pkg/bans"
+	"github.com/qenti/qenti/internal/pkg/pagination"
 	"github.com/qenti/qenti/internal/pkg/transactions"
 	"github.com/qenti/qenti/internal/pkg/unlocks"
 	"github.com/qenti/qenti/internal/pkg/users"
@@ -25,10 +26,10 @@ type UsersHandlers struct {
 	db               *sql.DB
 }
 
-func NewUsersHandlers(usersRepo *users.Repository, db *sql.DB) *UsersHandlers {
+func NewUsersHandlers(usersRepo *users.Repository, bansRepo *bans.Repository, db *sql.DB) *UsersHandlers {
 	return &UsersHandlers{
 		usersRepo:        usersRepo,
-		bansRepo:         bans.NewRepository(db),
+		bansRepo:         bansRepo,
 		transactionsRepo: transactions.NewRepository(db),
 		unlocksRepo:      unlocks.NewRepository(db),
 		viewsRepo:        views.NewRepository(db),
@@ -36,22 +37,17 @@ func NewUsersHandlers(usersRepo *users.Repository, db *sql.DB) *UsersHandlers {
 	}
 }
 
-// GetUsers lista usuarios con paginación
+// GetUsers lista usuarios del más nuevo al más viejo, paginados con ?limit= (default
+// 20, máx. 100) y ?cursor= (next_cursor de la página anterior)
 func (h *UsersHandlers) GetUsers(c *gin.Context) {
 	ctx := c.Request.Context()
 	
 	// Parámetros de paginación
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+	page, ok := pagination.FromQueryWithLimit(c, 20)
+	if !ok {
+		return
 	}
 	
-	offset := (page - 1) * limit
-	
 	// Contar total
 	var total int
 	countQuery := `SELECT COUNT(*) FROM users`
@@ -64,10 +60,13 @@ func (h *UsersHandlers) GetUsers(c *gin.Context) {
 	}
 	
 	// Obtener usuarios
-	query := `SELECT id, email, firebase_uid, coin_balance, is_premium, created_at, updated_at 
-	          FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+	var args []interface{}
+	query := `SELECT id, email, firebase_uid, coin_balance, is_premium, created_at
+	          FROM users WHERE TRUE` + page.Where(&args, "created_at", "id") + pagination.OrderBy("created_at", "id")
+	args = append(args, page.FetchLimit())
+	query += fmt.Sprintf(` LIMIT $%d`, len(args))
 	
-	rows, err := h.db.QueryContext(ctx, query, limit, offset)
+	rows, err := h.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch users",
@@ -77,37 +76,35 @@ func (h *UsersHandlers) GetUsers(c *gin.Context) {
 	defer rows.Close()
 	
 	type UserListItem struct {
-		ID          string `json:"id"`
-		Email       string `json:"email"`
-		CoinBalance int    `json:"coin_balance"`
-		IsPremium   bool   `json:"is_premium"`
-		CreatedAt   string `json:"created_at"`
+		ID          uuid.UUID `json:"id"`
+		Email       string    `json:"email"`
+		CoinBalance int       `json:"coin_balance"`
+		IsPremium   bool      `json:"is_premium"`
+		CreatedAt   time.Time `json:"created_at"`
 	}
 	
-	var usersList []UserListItem
+	usersList := []UserListItem{}
 	for rows.Next() {
 		var u UserListItem
-		var id uuid.UUID
-		var createdAt, updatedAt string
 		var firebaseUID string
 		
-		err := rows.Scan(&id, &u.Email, &firebaseUID, &u.CoinBalance, &u.IsPremium, &createdAt, &updatedAt)
+		err := rows.Scan(&u.ID, &u.Email, &firebaseUID, &u.CoinBalance, &u.IsPremium, &u.CreatedAt)
 		if err != nil {
 			continue
 		}
 		
-		u.ID = id.String()
-		u.CreatedAt = createdAt
 		usersList = append(usersList, u)
 	}
+	usersList, next := pagination.Slice(usersList, page, func(u UserListItem) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: u.CreatedAt, ID: u.ID}
+	})
 	
 	c.JSON(http.StatusOK, gin.H{
 		"users": usersList,
 		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-			"pages": (total + limit - 1) / limit,
+			"limit":       page.Limit,
+			"total":       total,
+			"next_cursor": next,
 		},
 	})
 }
@@ -0,0 +1,173 @@
+package admin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/videostatus"
+)
+
+// cloudflareWebhookTolerance antigüedad máxima aceptada del timestamp firmado.
+const cloudflareWebhookTolerance = 5 * time.Minute
+
+// GetVideoStatus consulta al proveedor el estado actual del video del episodio y
+// lo registra (útil para el panel mientras el proveedor codifica).
+// Endpoint: GET /admin/episodes/{id}/video-status
+func (h *Handlers) GetVideoStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	episodeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid episode ID"})
+		return
+	}
+	episode, err := h.episodesRepo.GetByID(ctx, episodeID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Episode not found"})
+		return
+	}
+
+	status, err := h.videoStatus.Sync(ctx, episode)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":        "Failed to get video status from provider",
+			"details":      err.Error(),
+			"video_status": episode.VideoStatus,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"episode_id":   episodeID,
+		"video_id":     episode.VideoIDBunny,
+		"video_status": status.Status,
+		"progress":     status.Progress,
+		"detail":       status.Detail,
+		"provider":     h.videoProvider.ProviderName(),
+	})
+}
+
+// VideoWebhookHandlers recibe los avisos de codificación de los proveedores de video.
+// El cuerpo solo identifica el video: el estado siempre se re-consulta a la API del
+// proveedor (videostatus.Service.SyncVideo), así un aviso falso no puede publicar
+// un episodio.
+type VideoWebhookHandlers struct {
+	videoStatus *videostatus.Service
+	bunny       config.BunnyConfig
+	cloudflare  config.CloudflareConfig
+}
+
+func NewVideoWebhookHandlers(videoStatus *videostatus.Service, bunny config.BunnyConfig, cloudflare config.CloudflareConfig) *VideoWebhookHandlers {
+	return &VideoWebhookHandlers{videoStatus: videoStatus, bunny: bunny, cloudflare: cloudflare}
+}
+
+// HandleBunnyWebhook procesa el webhook de encoding de Bunny Stream
+// ({"VideoLibraryId":123,"VideoGuid":"...","Status":3}).
+// Endpoint: POST /api/v1/webhooks/bunny?secret=<BUNNY_WEBHOOK_SECRET>
+func (h *VideoWebhookHandlers) HandleBunnyWebhook(c *gin.Context) {
+	if h.bunny.WebhookSecret != "" &&
+		!hmac.Equal([]byte(c.Query("secret")), []byte(h.bunny.WebhookSecret)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook secret"})
+		return
+	}
+
+	var payload struct {
+		VideoLibraryID int    `json:"VideoLibraryId"`
+		VideoGUID      string `json:"VideoGuid"`
+		Status         int    `json:"Status"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.VideoGUID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+		return
+	}
+	if strconv.Itoa(payload.VideoLibraryID) != h.bunny.StreamLibraryID {
+		// Otra biblioteca de la misma cuenta: no es nuestro
+		c.JSON(http.StatusOK, gin.H{"message": "Ignored: unknown library"})
+		return
+	}
+
+	h.syncVideo(c, payload.VideoGUID)
+}
+
+// HandleCloudflareWebhook procesa el webhook de Cloudflare Stream, firmado con
+// Webhook-Signature: time=<unix>,sig1=<hex(HMAC-SHA256(secret, time + "." + body))>.
+// Endpoint: POST /api/v1/webhooks/cloudflare
+func (h *VideoWebhookHandlers) HandleCloudflareWebhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if h.cloudflare.WebhookSecret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "CLOUDFLARE_WEBHOOK_SECRET not configured"})
+		return
+	}
+	if !verifyCloudflareSignature(c.GetHeader("Webhook-Signature"), body, h.cloudflare.WebhookSecret, time.Now()) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+		return
+	}
+
+	var payload struct {
+		UID string `json:"uid"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.UID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+		return
+	}
+
+	h.syncVideo(c, payload.UID)
+}
+
+// syncVideo re-consulta el estado del video y responde. Un video que ningún
+// episodio tiene (reemplazado o borrado) se acepta igual para que el proveedor no
+// reintente.
+func (h *VideoWebhookHandlers) syncVideo(c *gin.Context, videoID string) {
+	episode, err := h.videoStatus.SyncVideo(c.Request.Context(), videoID)
+	if err != nil {
+		log.Printf("webhook: sync video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync video status"})
+		return
+	}
+	if episode == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Ignored: video not assigned to any episode"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed successfully", "episode_id": episode.ID})
+}
+
+func verifyCloudflareSignature(header string, body []byte, secret string, now time.Time) bool {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "time":
+			ts = v
+		case "sig1":
+			sig = v
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return false
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > cloudflareWebhookTolerance || age < -cloudflareWebhookTolerance {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
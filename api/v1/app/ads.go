@@ -152,13 +152,8 @@ func (h *Handlers) RewardCoinsForAd(c *gin.Context) {
 	cooldownMinutes := h.cfg.AdReward.CooldownMinutes
 
 	// Límite diario según país: Tier-A (high eCPM) recibe más anuncios permitidos.
-	// Cloudflare rellena CF-IPCountry automáticamente; el SDK móvil puede enviar X-Country.
 	dailyLimit := h.cfg.AdReward.DailyLimit
-	country := c.GetHeader("CF-IPCountry")
-	if country == "" {
-		country = c.GetHeader("X-Country")
-	}
-	if country != "" {
+	if country := requestCountry(c); country != "" {
 		for _, tc := range h.cfg.AdTier.TierACountries {
 			if tc == country {
 				dailyLimit = h.cfg.AdTier.TierADailyLimit
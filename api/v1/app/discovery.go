@@ -1,64 +1,71 @@
 package app
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/search"
 )
 
-// GetTrending devuelve series ordenadas por score de actividad real (vistas + unlocks × 2)
-// en los últimos 7 días. Endpoint público.
+// GetTrending devuelve series ordenadas por actividad reciente (vistas,
+// finalizaciones y unlocks con decaimiento por antigüedad, ver trending.Service)
+// en los últimos días; completa con las más nuevas. Endpoint público.
 //
-// GET /api/v1/app/trending?limit=20&days=7&producer_slug=slug
+// GET /api/v1/app/trending?producer_slug=slug
 func (h *Handlers) GetTrending(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	limit := 20
-	days := 7
 
-	producerID, err := resolveProducerSlug(ctx, h.db, c.Query("producer_slug"))
+	producerID, err := h.resolveProducerSlug(ctx, c.Query("producer_slug"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve producer"})
 		return
 	}
 
-	seriesList, err := h.seriesRepo.GetTrendingFiltered(ctx, limit, days, producerID)
+	seriesList, err := h.trending.TrendingSeries(ctx, producerID, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch trending series",
 		})
 		return
 	}
-
-	if seriesList == nil {
-		seriesList = []models.Series{}
-	}
+	h.localizeSeries(c, seriesList)
 
 	c.JSON(http.StatusOK, gin.H{
 		"series":      seriesList,
-		"days_window": days,
+		"days_window": h.trending.WindowDays(),
 	})
 }
 
-// GetMostViewed devuelve series ordenadas por total de vistas histórico (all-time).
+// GetMostViewed devuelve series ordenadas por total de vistas histórico (all-time);
+// completa con las más nuevas.
 //
-// GET /api/v1/app/most-viewed?limit=30
+// GET /api/v1/app/most-viewed?producer_slug=slug
 func (h *Handlers) GetMostViewed(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	limit := 30
 
-	seriesList, err := h.seriesRepo.GetMostViewed(ctx, limit)
+	producerID, err := h.resolveProducerSlug(ctx, c.Query("producer_slug"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch most viewed"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve producer"})
 		return
 	}
 
-	if seriesList == nil {
-		seriesList = []models.Series{}
+	seriesList, err := h.trending.MostViewedSeries(ctx, producerID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch most viewed"})
+		return
 	}
+	h.localizeSeries(c, seriesList)
 
 	c.JSON(http.StatusOK, gin.H{"series": seriesList})
 }
@@ -85,18 +92,24 @@ func (h *Handlers) GetNewReleases(c *gin.Context) {
 			seriesList = []models.Series{}
 		}
 	}
+	h.localizeSeries(c, seriesList)
 
 	c.JSON(http.StatusOK, gin.H{"series": seriesList})
 }
 
-// Search busca series por título o descripción (case-insensitive, mínimo 2 chars).
+// Search busca series por título, productor, títulos de episodios o descripción
+// (mínimo 2 chars) en el idioma pedido y sus fallbacks: ignora acentos, tolera
+// errores de tipeo y ordena por relevancia y popularidad. Cada serie trae score y
+// fragmentos resaltados (highlights, coincidencias entre <mark> y </mark>).
+// La búsqueda queda registrada; search_id sirve para reportar el clic con
+// ClickSearchResult. ?genre= y ?tag= filtran como en GetSeries.
 //
-// GET /api/v1/app/search?q=drama&producer_slug=slug
+// GET /api/v1/app/search?q=drama&producer_slug=slug&genre=romance
 func (h *Handlers) Search(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	q := strings.TrimSpace(c.Query("q"))
-	if len(q) < 2 {
+	if utf8.RuneCountInString(q) < 2 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Search query must be at least 2 characters",
 		})
@@ -105,13 +118,20 @@ func (h *Handlers) Search(c *gin.Context) {
 
 	limit := 30
 
-	producerID, err := resolveProducerSlug(ctx, h.db, c.Query("producer_slug"))
+	producerID, err := h.resolveProducerSlug(ctx, c.Query("producer_slug"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve producer"})
 		return
 	}
 
-	seriesList, err := h.seriesRepo.SearchFiltered(ctx, q, limit, producerID)
+	results, err := h.search.Search(ctx, search.Query{
+		Text:       q,
+		Locales:    localesFromContext(c),
+		ProducerID: producerID,
+		Genres:     slugsFromQuery(c, "genre"),
+		Tags:       slugsFromQuery(c, "tag"),
+		Limit:      limit,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Search failed",
@@ -119,13 +139,98 @@ func (h *Handlers) Search(c *gin.Context) {
 		return
 	}
 
-	if seriesList == nil {
-		seriesList = []models.Series{}
+	if results == nil {
+		results = []search.Result{}
+	}
+	// Título y descripción en el idioma pedido (los highlights quedan en el del índice que coincidió)
+	seriesList := make([]models.Series, len(results))
+	for i := range results {
+		seriesList[i] = results[i].Series
+	}
+	h.localizeSeries(c, seriesList)
+	for i := range results {
+		results[i].Series = seriesList[i]
+	}
+
+	searchID := uuid.New()
+	entry := search.QueryLog{
+		ID:          searchID,
+		Query:       q,
+		ResultCount: len(results),
+		ProducerID:  producerID,
+	}
+	if locales := localesFromContext(c); len(locales) > 0 {
+		entry.Locale = locales[0]
+	}
+	if userID, deviceID, ok := viewerFromContext(c); ok {
+		if userID != uuid.Nil {
+			entry.UserID = &userID
+		} else {
+			entry.DeviceID = &deviceID
+		}
 	}
+	// El registro no demora la respuesta
+	go func() {
+		if err := h.search.LogQuery(context.Background(), entry); err != nil {
+			log.Printf("search: %v", err)
+		}
+	}()
 
 	c.JSON(http.StatusOK, gin.H{
-		"series": seriesList,
-		"query":  q,
-		"count":  len(seriesList),
+		"search_id": searchID,
+		"series":    results,
+		"query":     q,
+		"count":     len(results),
 	})
 }
+
+// SuggestSearch autocompleta la búsqueda mientras se escribe: títulos de series en
+// el idioma pedido y búsquedas frecuentes que empiezan con q (cualquier palabra).
+// Se sirve del índice en memoria que se reconstruye periódicamente.
+//
+// GET /api/v1/app/search/suggest?q=amo&limit=8&producer_slug=slug
+func (h *Handlers) SuggestSearch(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	q := strings.TrimSpace(c.Query("q"))
+	limit := 8
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 20 {
+		limit = l
+	}
+
+	producerID, err := h.resolveProducerSlug(ctx, c.Query("producer_slug"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve producer"})
+		return
+	}
+
+	suggestions := h.search.Suggest(q, localesFromContext(c), producerID, limit)
+	if suggestions == nil {
+		suggestions = []search.Suggestion{}
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// ClickSearchResult registra qué serie se abrió desde los resultados de una
+// búsqueda (solo el primer clic cuenta). position es la posición en la lista, desde 0.
+//
+// POST /api/v1/app/search/click
+// Body: {"search_id": "uuid", "series_id": "uuid", "position": 0}
+func (h *Handlers) ClickSearchResult(c *gin.Context) {
+	var req struct {
+		SearchID uuid.UUID `json:"search_id" binding:"required"`
+		SeriesID uuid.UUID `json:"series_id" binding:"required"`
+		Position *int      `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	logged, err := h.search.LogClick(c.Request.Context(), req.SearchID, req.SeriesID, req.Position)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log click"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"logged": logged})
+}
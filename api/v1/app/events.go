@@ -0,0 +1,126 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/events"
+)
+
+// maxEventsPerBatch límite de eventos por request para acotar el tamaño del INSERT.
+const maxEventsPerBatch = 100
+
+// PlaybackEventRequest es un evento individual dentro del lote.
+type PlaybackEventRequest struct {
+	Type            string          `json:"type"`
+	EpisodeID       string          `json:"episode_id"`
+	PositionSeconds int             `json:"position_seconds"`
+	DurationMs      int             `json:"duration_ms"`
+	Data            json.RawMessage `json:"data"`
+	ClientTS        time.Time       `json:"client_ts"`
+}
+
+// IngestEventsRequest payload de POST /app/events. El dispositivo no va en el body:
+// sale del device ID firmado por la API (middleware.DeviceID).
+type IngestEventsRequest struct {
+	SessionID string                 `json:"session_id"`
+	Events    []PlaybackEventRequest `json:"events" binding:"required"`
+}
+
+// IngestEvents recibe un lote de eventos de reproducción y los guarda en playback_events.
+// Auth opcional: si hay JWT se asocian al usuario; siempre llevan el device ID de la
+// request y el session_id del body.
+// Los eventos inválidos se descartan individualmente y se reportan en "rejected".
+//
+// POST /api/v1/app/events
+func (h *Handlers) IngestEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req IngestEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if len(req.Events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "events must not be empty"})
+		return
+	}
+	if len(req.Events) > maxEventsPerBatch {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":      "Too many events in batch",
+			"max_events": maxEventsPerBatch,
+		})
+		return
+	}
+
+	var uid *uuid.UUID
+	if userID, exists := c.Get("user_id"); exists {
+		id := userID.(uuid.UUID)
+		uid = &id
+	}
+	// El device ID firmado por la API (middleware.DeviceID, que lo emite si falta):
+	// así MergeIntoUser puede reasignar estos eventos al iniciar sesión.
+	deviceID := c.MustGet("device_id").(uuid.UUID).String()
+	if len(req.SessionID) > 128 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session_id must be at most 128 characters"})
+		return
+	}
+
+	type rejectedEvent struct {
+		Index  int    `json:"index"`
+		Reason string `json:"reason"`
+	}
+
+	now := time.Now()
+	batch := make([]events.Event, 0, len(req.Events))
+	rejected := []rejectedEvent{}
+	for i, ev := range req.Events {
+		if !events.IsValidType(ev.Type) {
+			rejected = append(rejected, rejectedEvent{Index: i, Reason: "invalid type"})
+			continue
+		}
+		episodeID, err := uuid.Parse(ev.EpisodeID)
+		if err != nil {
+			rejected = append(rejected, rejectedEvent{Index: i, Reason: "invalid episode_id"})
+			continue
+		}
+		// Relojes de cliente muy desfasados: se descartan para no ensuciar los análisis
+		if ev.ClientTS.IsZero() || ev.ClientTS.After(now.Add(time.Hour)) || ev.ClientTS.Before(now.Add(-7*24*time.Hour)) {
+			rejected = append(rejected, rejectedEvent{Index: i, Reason: "invalid client_ts"})
+			continue
+		}
+		if ev.PositionSeconds < 0 || ev.DurationMs < 0 {
+			rejected = append(rejected, rejectedEvent{Index: i, Reason: "negative position or duration"})
+			continue
+		}
+		batch = append(batch, events.Event{
+			UserID:          uid,
+			DeviceID:        deviceID,
+			SessionID:       req.SessionID,
+			EpisodeID:       episodeID,
+			Type:            ev.Type,
+			PositionSeconds: ev.PositionSeconds,
+			DurationMs:      ev.DurationMs,
+			Data:            ev.Data,
+			ClientTS:        ev.ClientTS,
+		})
+	}
+
+	eventsRepo := events.NewRepository(h.db)
+	if err := eventsRepo.InsertBatch(ctx, batch); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store events"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"accepted": len(batch),
+		"rejected": rejected,
+	})
+}
@@ -2,15 +2,18 @@ package app
 
 import (
 	"database/sql"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/pagination"
 )
 
 // ToggleFavorite agrega o elimina una serie de los favoritos del usuario.
 // Si ya era favorita la elimina; si no, la agrega. Devuelve el nuevo estado.
+// Los invitados sin sesión guardan sus favoritos contra su device ID.
 //
 // POST /api/v1/app/favorites/:series_id
 func (h *Handlers) ToggleFavorite(c *gin.Context) {
@@ -23,22 +26,30 @@ func (h *Handlers) ToggleFavorite(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+	uid, deviceID, ok := viewerFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication or device ID required"})
 		return
 	}
-	uid := userID.(uuid.UUID)
 
 	// Intentar insertar (UPSERT tipo "ignorar si ya existe")
-	var newID uuid.UUID
-	insertErr := h.db.QueryRowContext(ctx,
-		`INSERT INTO favorites (user_id, series_id)
+	insertQuery := `INSERT INTO favorites (user_id, series_id)
 		 VALUES ($1, $2)
 		 ON CONFLICT (user_id, series_id) DO NOTHING
-		 RETURNING id`,
-		uid, seriesID,
-	).Scan(&newID)
+		 RETURNING id`
+	deleteQuery := `DELETE FROM favorites WHERE user_id = $1 AND series_id = $2`
+	ownerID := uid
+	if uid == uuid.Nil {
+		insertQuery = `INSERT INTO favorites (user_id, device_id, series_id)
+		 VALUES (NULL, $1, $2)
+		 ON CONFLICT (device_id, series_id) WHERE user_id IS NULL AND device_id IS NOT NULL DO NOTHING
+		 RETURNING id`
+		deleteQuery = `DELETE FROM favorites WHERE user_id IS NULL AND device_id = $1 AND series_id = $2`
+		ownerID = deviceID
+	}
+
+	var newID uuid.UUID
+	insertErr := h.db.QueryRowContext(ctx, insertQuery, ownerID, seriesID).Scan(&newID)
 
 	if insertErr == nil {
 		// Se insertó → favorito AÑADIDO
@@ -48,10 +59,7 @@ func (h *Handlers) ToggleFavorite(c *gin.Context) {
 
 	if insertErr == sql.ErrNoRows {
 		// Ya existía → ELIMINAR (toggle off)
-		if _, err := h.db.ExecContext(ctx,
-			`DELETE FROM favorites WHERE user_id = $1 AND series_id = $2`,
-			uid, seriesID,
-		); err != nil {
+		if _, err := h.db.ExecContext(ctx, deleteQuery, ownerID, seriesID); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove favorite"})
 			return
 		}
@@ -62,47 +70,73 @@ func (h *Handlers) ToggleFavorite(c *gin.Context) {
 	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to toggle favorite"})
 }
 
-// GetFavorites devuelve las series marcadas como favoritas por el usuario.
+// GetFavorites devuelve las series marcadas como favoritas por el usuario o el invitado,
+// de la última marcada a la primera, paginadas con ?limit= y ?cursor=.
 //
 // GET /api/v1/app/favorites
 func (h *Handlers) GetFavorites(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+	uid, deviceID, ok := viewerFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication or device ID required"})
+		return
+	}
+	page, ok := pagination.FromQuery(c)
+	if !ok {
 		return
 	}
-	uid := userID.(uuid.UUID)
 
+	owner, ownerID := "f.user_id = $1", uid
+	if uid == uuid.Nil {
+		owner, ownerID = "f.user_id IS NULL AND f.device_id = $1", deviceID
+	}
+
+	args := []interface{}{ownerID}
 	query := `
-		SELECT s.id, s.title, s.description, s.horizontal_poster, s.vertical_poster,
-		       s.is_active, s.created_at, s.updated_at
+		SELECT s.id, s.title, s.description, s.horizontal_poster, s.vertical_poster, s.horizontal_poster_image_id, s.vertical_poster_image_id,
+		       s.is_active, s.created_at, s.updated_at, f.id, f.created_at
 		FROM favorites f
 		JOIN series s ON s.id = f.series_id
-		WHERE f.user_id = $1
-		  AND s.is_active = TRUE
-		ORDER BY f.created_at DESC
-	`
-	rows, err := h.db.QueryContext(ctx, query, uid)
+		WHERE ` + owner + `
+		  AND s.is_active = TRUE`
+	query += page.Where(&args, "f.created_at", "f.id") + pagination.OrderBy("f.created_at", "f.id")
+	args = append(args, page.FetchLimit())
+	query += fmt.Sprintf(` LIMIT $%d`, len(args))
+
+	rows, err := h.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch favorites"})
 		return
 	}
 	defer rows.Close()
 
-	seriesList := []models.Series{}
+	// La página avanza por la fecha en que se marcó, no por la de la serie
+	type favorite struct {
+		series models.Series
+		cursor pagination.Cursor
+	}
+	var favorites []favorite
 	for rows.Next() {
-		var s models.Series
+		var f favorite
+		s := &f.series
 		if err := rows.Scan(
 			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+			&s.VerticalPoster, &s.HorizontalPosterImageID, &s.VerticalPosterImageID, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+			&f.cursor.ID, &f.cursor.CreatedAt,
 		); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read favorites"})
 			return
 		}
-		seriesList = append(seriesList, s)
+		favorites = append(favorites, f)
+	}
+	favorites, next := pagination.Slice(favorites, page, func(f favorite) pagination.Cursor { return f.cursor })
+
+	seriesList := make([]models.Series, len(favorites))
+	for i, f := range favorites {
+		seriesList[i] = f.series
 	}
+	h.localizeSeries(c, seriesList)
 
-	c.JSON(http.StatusOK, gin.H{"series": seriesList})
+	c.JSON(http.StatusOK, gin.H{"series": seriesList, "next_cursor": next})
 }
@@ -2,19 +2,51 @@ package app
 
 import (
 	"context"
+	"log"
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/feed"
 	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/series"
+	"github.com/qenti/qenti/internal/pkg/taxonomy"
+	"github.com/qenti/qenti/internal/pkg/trending"
 	"github.com/qenti/qenti/internal/pkg/views"
 )
 
-// GetFeed retorna el feed del home con algoritmo de recomendados y trending
+// FeedSection fila armada del home. Banners trae el arte propio de las series
+// de una fila hero (por ID de serie) y ContinueWatching los items de esa fila.
+type FeedSection struct {
+	ID               *uuid.UUID                   `json:"id,omitempty"`
+	Kind             string                       `json:"kind"`
+	Title            string                       `json:"title"`
+	GenreSlug        string                       `json:"genre_slug,omitempty"`
+	Series           []models.Series              `json:"series"`
+	Banners          map[uuid.UUID]*models.Image  `json:"banners,omitempty"`
+	ContinueWatching []views.ContinueWatchingItem `json:"continue_watching,omitempty"`
+}
+
+// newReleasesDays antigüedad máxima de una serie en la fila de estrenos.
+const newReleasesDays = 30
+
+// defaultFeedRows filas del home cuando la app no tiene ninguna activa para el viewer.
+var defaultFeedRows = []feed.Row{
+	{Kind: feed.KindTrending, Title: "Trending", ItemLimit: 10},
+	{Kind: feed.KindRecommended, Title: "Recomendados para ti", ItemLimit: 15},
+}
+
+// GetFeed arma el home con las filas activas de la app (?producer_slug=slug, o la
+// de plataforma) que apuntan al viewer por país (CF-IPCountry / X-Country),
+// plataforma (X-Platform / ?platform=) y premium. Las filas vacías se omiten.
+//
+// GET /api/v1/app/feed
 func (h *Handlers) GetFeed(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	producerID, err := resolveProducerSlug(ctx, h.db, c.Query("producer_slug"))
+	producerID, err := h.resolveProducerSlug(ctx, c.Query("producer_slug"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve producer"})
 		return
@@ -28,220 +60,225 @@ func (h *Handlers) GetFeed(c *gin.Context) {
 		})
 		return
 	}
+	// Se traduce antes de armar las secciones, que copian de allSeries
+	h.localizeSeries(c, allSeries)
 
-	type FeedSection struct {
-		Title  string         `json:"title"`
-		Series []models.Series `json:"series"`
+	rows, err := h.feedRepo.ActiveRows(ctx, producerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feed layout"})
+		return
+	}
+	viewer := feed.Viewer{Country: requestCountry(c), Platform: requestPlatform(c)}
+	if uid, _, ok := viewerFromContext(c); ok && uid != uuid.Nil {
+		if user, err := h.usersRepo.GetByID(ctx, uid); err == nil && user != nil {
+			viewer.Premium = user.IsPremium
+		}
+	}
+	var layout []feed.Row
+	for i := range rows {
+		if rows[i].Targets(viewer) {
+			layout = append(layout, rows[i])
+		}
+	}
+	if len(layout) == 0 {
+		layout = defaultFeedRows
 	}
 
-	// Trending: Basado en vistas de últimas 48 horas
-	trendingSeries := h.getTrendingSeries(ctx, allSeries, 10)
-
-	// Recomendados: Personalizado si el usuario está autenticado
-	var recommendedSeries []models.Series
-	userID, exists := c.Get("user_id")
-	if exists {
-		uid := userID.(uuid.UUID)
-		recommendedSeries = h.getRecommendedSeries(ctx, uid, allSeries)
-	} else {
-		// Si no está autenticado, mostrar series más populares
-		recommendedSeries = h.getTrendingSeries(ctx, allSeries, 15)
+	seriesByID := make(map[uuid.UUID]models.Series, len(allSeries))
+	for _, s := range allSeries {
+		seriesByID[s.ID] = s
 	}
+	chain := localesFromContext(c)
 
-	feed := []FeedSection{
-		{
-			Title:  "Trending",
-			Series: trendingSeries,
-		},
-		{
-			Title:  "Recomendados para ti",
-			Series: recommendedSeries,
-		},
+	sections := make([]FeedSection, 0, len(layout))
+	for i := range layout {
+		section, err := h.buildFeedSection(c, &layout[i], chain, allSeries, seriesByID)
+		if err != nil {
+			log.Printf("feed: row %s (%s): %v", layout[i].ID, layout[i].Kind, err)
+			continue
+		}
+		if len(section.Series) == 0 && len(section.ContinueWatching) == 0 {
+			continue
+		}
+		sections = append(sections, section)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"feed": feed,
+		"feed": sections,
 	})
 }
 
-// getTrendingSeries obtiene las series más vistas en las últimas 48 horas
-func (h *Handlers) getTrendingSeries(ctx context.Context, allSeries []models.Series, limit int) []models.Series {
-	_ = views.NewRepository(h.db) // viewsRepo no usado aún
-	
-	// Obtener top episodios vistos en últimas 48 horas
-	var topEpisodes []uuid.UUID
-	topEpisodesQuery := `
-		SELECT e.id, COUNT(v.id) as view_count
-		FROM episodes e
-		JOIN views v ON v.episode_id = e.id
-		WHERE v.created_at > NOW() - INTERVAL '48 hours'
-		GROUP BY e.id
-		ORDER BY view_count DESC
-		LIMIT $1
-	`
-	
-	rows, err := h.db.QueryContext(ctx, topEpisodesQuery, limit*5)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var epID uuid.UUID
-			var viewCount int
-			if err := rows.Scan(&epID, &viewCount); err == nil {
-				topEpisodes = append(topEpisodes, epID)
+// buildFeedSection arma una fila según su tipo, con a lo sumo ItemLimit series.
+func (h *Handlers) buildFeedSection(c *gin.Context, row *feed.Row, chain []string, allSeries []models.Series, seriesByID map[uuid.UUID]models.Series) (FeedSection, error) {
+	ctx := c.Request.Context()
+	section := FeedSection{Kind: row.Kind, Title: row.LocalizedTitle(chain)}
+	if row.ID != uuid.Nil {
+		id := row.ID
+		section.ID = &id
+	}
+	limit := row.ItemLimit
+
+	switch row.Kind {
+	case feed.KindTrending:
+		section.Series = h.getTrendingSeries(ctx, allSeries, limit)
+
+	case feed.KindRecommended:
+		if uid, deviceID, ok := viewerFromContext(c); ok {
+			section.Series = h.getRecommendedSeries(ctx, uid, deviceID, allSeries, limit)
+		} else {
+			// Si no está autenticado, mostrar series más populares
+			section.Series = h.getTrendingSeries(ctx, allSeries, limit)
+		}
+
+	case feed.KindNewReleases:
+		// allSeries viene de la más nueva a la más vieja
+		since := time.Now().AddDate(0, 0, -newReleasesDays)
+		for _, s := range allSeries {
+			if s.CreatedAt.After(since) {
+				section.Series = append(section.Series, s)
 			}
 		}
-	}
-	
-	if len(topEpisodes) == 0 {
-		// Si no hay vistas recientes, retornar series más recientes
-		if len(allSeries) > limit {
-			return allSeries[:limit]
+
+	case feed.KindMostViewed:
+		ranking, err := h.trending.MostViewed(ctx, row.ProducerID)
+		if err != nil {
+			return section, err
 		}
-		return allSeries
-	}
-	
-	// Mapear episodios a series y contar vistas por serie
-	seriesViews := make(map[uuid.UUID]int)
-	for _, epID := range topEpisodes {
-		// Obtener serie del episodio
-		var seriesID uuid.UUID
-		query := `SELECT series_id FROM episodes WHERE id = $1`
-		err := h.db.QueryRowContext(ctx, query, epID).Scan(&seriesID)
-		if err == nil {
-			seriesViews[seriesID]++
+		for _, sc := range ranking {
+			if localized, ok := seriesByID[sc.SeriesID]; ok {
+				section.Series = append(section.Series, localized)
+			}
+			if len(section.Series) >= limit {
+				break
+			}
 		}
-	}
-	
-	// Ordenar series por número de vistas
-	type SeriesWithViews struct {
-		Series models.Series
-		Views  int
-	}
-	
-	var seriesList []SeriesWithViews
-	for _, s := range allSeries {
-		views := seriesViews[s.ID]
-		seriesList = append(seriesList, SeriesWithViews{
-			Series: s,
-			Views:  views,
-		})
-	}
-	
-	// Ordenar por vistas (descendente)
-	for i := 0; i < len(seriesList)-1; i++ {
-		for j := i + 1; j < len(seriesList); j++ {
-			if seriesList[i].Views < seriesList[j].Views {
-				seriesList[i], seriesList[j] = seriesList[j], seriesList[i]
+
+	case feed.KindGenre:
+		if row.GenreID == nil {
+			return section, nil
+		}
+		genre, err := h.taxonomyRepo.GetGenre(ctx, *row.GenreID)
+		if err != nil || genre == nil || !genre.IsActive {
+			return section, err
+		}
+		section.GenreSlug = genre.Slug
+		if row.Title == "" {
+			section.Title = taxonomy.LocalizedName(genre, chain)
+		}
+		list, err := h.seriesRepo.List(ctx, series.ListFilter{ProducerID: row.ProducerID, Genres: []string{genre.Slug}})
+		if err != nil {
+			return section, err
+		}
+		for _, s := range list {
+			if localized, ok := seriesByID[s.ID]; ok {
+				section.Series = append(section.Series, localized)
 			}
 		}
-	}
-	
-	// Tomar las top N
-	result := make([]models.Series, 0, limit)
-	for i := 0; i < len(seriesList) && i < limit; i++ {
-		result = append(result, seriesList[i].Series)
-	}
-	
-	// Si no hay suficientes con vistas, completar con series recientes
-	if len(result) < limit {
-		for _, s := range allSeries {
-			found := false
-			for _, r := range result {
-				if r.ID == s.ID {
-					found = true
-					break
-				}
+
+	case feed.KindCurated, feed.KindHero:
+		var imageIDs []uuid.UUID
+		for _, item := range row.Items {
+			s, ok := seriesByID[item.SeriesID]
+			if !ok {
+				continue // inactiva o de otra app
+			}
+			section.Series = append(section.Series, s)
+			if row.Kind == feed.KindHero && item.ImageID != nil {
+				imageIDs = append(imageIDs, *item.ImageID)
+			}
+		}
+		if len(imageIDs) > 0 {
+			found, err := h.imagesRepo.GetMany(ctx, imageIDs)
+			if err != nil {
+				return section, err
 			}
-			if !found {
-				result = append(result, s)
-				if len(result) >= limit {
-					break
+			section.Banners = make(map[uuid.UUID]*models.Image)
+			for _, item := range row.Items {
+				if item.ImageID != nil && found[*item.ImageID] != nil {
+					section.Banners[item.SeriesID] = found[*item.ImageID]
 				}
 			}
 		}
-	}
-	
-	return result
-}
 
-// getRecommendedSeries obtiene recomendaciones personalizadas para un usuario
-func (h *Handlers) getRecommendedSeries(ctx context.Context, userID uuid.UUID, allSeries []models.Series) []models.Series {
-	// Obtener series que el usuario ya vio
-	var watchedSeries []uuid.UUID
-	query := `SELECT DISTINCT e.series_id 
-	          FROM views v
-	          JOIN episodes e ON e.id = v.episode_id
-	          WHERE v.user_id = $1 AND v.completed = TRUE`
-	
-	rows, err := h.db.QueryContext(ctx, query, userID)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var seriesID uuid.UUID
-			if err := rows.Scan(&seriesID); err == nil {
-				watchedSeries = append(watchedSeries, seriesID)
+	case feed.KindContinueWatching:
+		uid, deviceID, ok := viewerFromContext(c)
+		if !ok {
+			return section, nil
+		}
+		viewsRepo := views.NewRepository(h.db)
+		var items []views.ContinueWatchingItem
+		var err error
+		if uid != uuid.Nil {
+			items, err = viewsRepo.GetContinueWatching(ctx, uid, limit)
+		} else {
+			items, err = viewsRepo.GetContinueWatchingForDevice(ctx, deviceID, limit)
+		}
+		if err != nil {
+			return section, err
+		}
+		for _, item := range items {
+			if _, ok := seriesByID[item.SeriesID]; ok {
+				section.ContinueWatching = append(section.ContinueWatching, item)
 			}
 		}
+		h.localizeContinueWatching(c, section.ContinueWatching)
 	}
-	
-	// Si el usuario no ha visto nada, retornar trending
-	if len(watchedSeries) == 0 {
-		return h.getTrendingSeries(ctx, allSeries, 15)
+
+	if len(section.Series) > limit {
+		section.Series = section.Series[:limit]
 	}
-	
-	// Filtrar series que el usuario NO ha visto
-	recommended := make([]models.Series, 0)
-	watchedMap := make(map[uuid.UUID]bool)
-	for _, id := range watchedSeries {
-		watchedMap[id] = true
+	return section, nil
+}
+
+// getTrendingSeries obtiene las series de allSeries con más actividad reciente
+// (ver trending.Service.Trending), completando con las más nuevas hasta limit.
+func (h *Handlers) getTrendingSeries(ctx context.Context, allSeries []models.Series, limit int) []models.Series {
+	ranking, err := h.trending.Trending(ctx, nil)
+	if err != nil {
+		log.Printf("trending: %v", err)
+	}
+	return trending.Rank(allSeries, ranking, limit)
+}
+
+// getRecommendedSeries obtiene recomendaciones personalizadas para el usuario o,
+// sin sesión, el invitado deviceID: las series más parecidas (filtrado colaborativo)
+// a las que vio, marcó como favoritas o desbloqueó, mezcladas con la popularidad
+// reciente. No repite las que ya terminó; sin historial devuelve trending.
+func (h *Handlers) getRecommendedSeries(ctx context.Context, userID, deviceID uuid.UUID, allSeries []models.Series, limit int) []models.Series {
+	profile, err := h.recommender.ForViewer(ctx, userID, deviceID)
+	if err != nil {
+		log.Printf("recommendations: viewer: %v", err)
+		return h.getTrendingSeries(ctx, allSeries, limit)
+	}
+
+	// Si el usuario no ha visto nada, retornar trending
+	if len(profile.Seeds) == 0 {
+		return h.getTrendingSeries(ctx, allSeries, limit)
 	}
-	
+
+	recommended := make([]models.Series, 0, len(allSeries))
 	for _, s := range allSeries {
-		if !watchedMap[s.ID] {
+		if !profile.Completed[s.ID] {
 			recommended = append(recommended, s)
 		}
 	}
-	
-	// Si hay menos de 10 recomendaciones, agregar algunas que ya vio (pero no completó)
-	if len(recommended) < 10 {
-		// Obtener series que vio pero no completó
-		partialQuery := `SELECT DISTINCT e.series_id 
-		                 FROM views v
-		                 JOIN episodes e ON e.id = v.episode_id
-		                 WHERE v.user_id = $1 AND v.completed = FALSE
-		                 LIMIT 5`
-		
-		rows, err := h.db.QueryContext(ctx, partialQuery, userID)
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var seriesID uuid.UUID
-				if err := rows.Scan(&seriesID); err == nil {
-					// Buscar la serie y agregarla si no está ya
-					for _, s := range allSeries {
-						if s.ID == seriesID {
-							found := false
-							for _, r := range recommended {
-								if r.ID == s.ID {
-									found = true
-									break
-								}
-							}
-							if !found {
-								recommended = append(recommended, s)
-								break
-							}
-						}
-					}
-				}
-			}
-		}
+
+	// Entre las que empatan (sin afinidad ni vistas recientes) primero las de géneros
+	// y etiquetas en común con las suyas; el orden estable deja las nuevas primero
+	taxonomyScores, err := h.taxonomyRepo.SimilarityScores(ctx, profile.Seeds)
+	if err != nil {
+		log.Printf("taxonomy: similar series: %v", err)
 	}
-	
-	// Limitar a 15
-	if len(recommended) > 15 {
-		recommended = recommended[:15]
+	sort.SliceStable(recommended, func(i, j int) bool {
+		a, b := recommended[i].ID, recommended[j].ID
+		if profile.Scores[a] != profile.Scores[b] {
+			return profile.Scores[a] > profile.Scores[b]
+		}
+		return taxonomyScores[a] > taxonomyScores[b]
+	})
+
+	if len(recommended) > limit {
+		recommended = recommended[:limit]
 	}
-	
 	return recommended
 }
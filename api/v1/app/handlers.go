@@ -1,21 +1,35 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/qenti/qenti/internal/config"
 	"github.com/qenti/qenti/internal/pkg/ads"
+	"github.com/qenti/qenti/internal/pkg/cache"
 	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/feed"
+	"github.com/qenti/qenti/internal/pkg/images"
 	"github.com/qenti/qenti/internal/pkg/models"
 	"github.com/qenti/qenti/internal/pkg/notifications"
+	"github.com/qenti/qenti/internal/pkg/pagination"
 	"github.com/qenti/qenti/internal/pkg/payment"
+	"github.com/qenti/qenti/internal/pkg/privacy"
+	"github.com/qenti/qenti/internal/pkg/producers"
+	"github.com/qenti/qenti/internal/pkg/recommendations"
+	"github.com/qenti/qenti/internal/pkg/search"
+	"github.com/qenti/qenti/internal/pkg/taxonomy"
 	"github.com/qenti/qenti/internal/pkg/series"
 	"github.com/qenti/qenti/internal/pkg/storage"
+	"github.com/qenti/qenti/internal/pkg/tracks"
 	"github.com/qenti/qenti/internal/pkg/transactions"
+	"github.com/qenti/qenti/internal/pkg/translations"
+	"github.com/qenti/qenti/internal/pkg/trending"
 	"github.com/qenti/qenti/internal/pkg/unlocks"
 	"github.com/qenti/qenti/internal/pkg/users"
 	"github.com/qenti/qenti/internal/pkg/views"
@@ -28,8 +42,18 @@ type Handlers struct {
 	unlocksRepo    *unlocks.Repository
 	videoProvider  storage.VideoProvider
 	adsValidator   *ads.Validator
+	imagesRepo     *images.Repository
+	tracks         *tracks.Service
+	translations   *translations.Service
+	search         *search.Service
+	taxonomyRepo   *taxonomy.Repository
+	feedRepo       *feed.Repository
+	producersRepo  *producers.Repository
+	recommender    *recommendations.Service
+	trending       *trending.Service
 	paymentService *payment.Service
 	notifService   *notifications.Service
+	privacy        *privacy.Service
 	db             *sql.DB // Para acceso a vistas y transacciones
 	cfg            *config.Config
 }
@@ -42,6 +66,9 @@ func NewHandlers(
 	videoProvider storage.VideoProvider,
 	paymentService *payment.Service,
 	notifService *notifications.Service,
+	searchService *search.Service,
+	privacyService *privacy.Service,
+	cacheStore *cache.Cache,
 	db *sql.DB,
 	cfg *config.Config,
 ) *Handlers {
@@ -52,34 +79,62 @@ func NewHandlers(
 		unlocksRepo:    unlocksRepo,
 		videoProvider:  videoProvider,
 		adsValidator:   ads.NewValidator(db),
+		imagesRepo:     images.NewRepository(db),
+		tracks:         tracks.NewService(db, videoProvider),
+		translations:   translations.NewService(db, cfg.Localization),
+		search:         searchService,
+		taxonomyRepo:   taxonomy.NewRepository(db),
+		feedRepo:       feed.NewRepository(db),
+		producersRepo:  producers.NewRepository(db, cacheStore),
+		recommender:    recommendations.NewService(db, cfg.Recommendations),
+		trending:       trending.NewService(db, cfg.Trending, cacheStore, seriesRepo),
 		paymentService: paymentService,
 		notifService:   notifService,
+		privacy:        privacyService,
 		db:             db,
 		cfg:            cfg,
 	}
 }
 
-// GetSeries lista las series disponibles.
-// Acepta ?producer_slug=slug para filtrar por tenant (multi-tenancy móvil).
+// GetSeries lista las series disponibles con sus géneros y etiquetas, de la más
+// nueva a la más vieja, paginadas con ?limit= y ?cursor= (next_cursor de la página anterior).
+// Acepta ?producer_slug=slug para filtrar por tenant (multi-tenancy móvil) y
+// ?genre=slug,slug y ?tag=slug,slug (alguno de los géneros y alguna de las etiquetas).
 func (h *Handlers) GetSeries(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	producerID, err := resolveProducerSlug(ctx, h.db, c.Query("producer_slug"))
+	page, ok := pagination.FromQuery(c)
+	if !ok {
+		return
+	}
+	producerID, err := h.resolveProducerSlug(ctx, c.Query("producer_slug"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve producer"})
 		return
 	}
 
-	seriesList, err := h.seriesRepo.GetAllFiltered(ctx, producerID)
+	seriesList, err := h.seriesRepo.List(ctx, series.ListFilter{
+		ProducerID: producerID,
+		Genres:     slugsFromQuery(c, "genre"),
+		Tags:       slugsFromQuery(c, "tag"),
+		Page:       page,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch series",
 		})
 		return
 	}
+	if seriesList == nil {
+		seriesList = []models.Series{}
+	}
+	seriesList, next := pagination.Slice(seriesList, page, pagination.SeriesCursor)
+	h.localizeSeries(c, seriesList)
+	h.attachTaxonomy(c, seriesList)
 
 	c.JSON(http.StatusOK, gin.H{
-		"series": seriesList,
+		"series":      seriesList,
+		"next_cursor": next,
 	})
 }
 
@@ -103,6 +158,16 @@ func (h *Handlers) GetSeriesByID(c *gin.Context) {
 		})
 		return
 	}
+	if err := h.translations.LocalizeOneSeries(ctx, localesFromContext(c), series); err != nil {
+		log.Printf("translations: localize series %s: %v", series.ID, err)
+	}
+	if err := h.taxonomyRepo.AttachOne(ctx, localesFromContext(c), series); err != nil {
+		log.Printf("taxonomy: series %s: %v", series.ID, err)
+	}
+	// URLs de cada variante de los posters (best-effort: vertical_poster/horizontal_poster ya traen la principal)
+	if err := h.imagesRepo.AttachSeries(ctx, series); err != nil {
+		log.Printf("app: series %s images: %v", series.ID, err)
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"series": series,
@@ -139,6 +204,9 @@ func (h *Handlers) GetSeriesEpisodes(c *gin.Context) {
 		})
 		return
 	}
+	if err := h.translations.LocalizeEpisodes(ctx, localesFromContext(c), episodesList); err != nil {
+		log.Printf("translations: localize episodes of series %s: %v", seriesID, err)
+	}
 	
 	// Obtener información del usuario (si está autenticado)
 	userID, _ := c.Get("user_id")
@@ -251,9 +319,23 @@ func (h *Handlers) GetEpisodeStream(c *gin.Context) {
 		return
 	}
 	
-	if episode.VideoIDBunny == "" {
+	// El proveedor todavía está recibiendo o codificando el video: error distinto para
+	// que el cliente muestre "procesando" y reintente
+	if episode.VideoStatus == storage.VideoStatusUploading || episode.VideoStatus == storage.VideoStatusProcessing {
+		c.Header("Retry-After", "30")
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Video is processing",
+			"code": "video_processing",
+			"video_status": episode.VideoStatus,
+			"message": "El video de este episodio se está procesando. Intenta de nuevo en unos minutos.",
+		})
+		return
+	}
+	
+	if episode.VideoIDBunny == "" || episode.VideoStatus == storage.VideoStatusFailed {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Video not available",
+			"video_status": episode.VideoStatus,
 			"message": "Este episodio no tiene video configurado. Por favor verifica en el panel de administración.",
 		})
 		return
@@ -278,15 +360,28 @@ func (h *Handlers) GetEpisodeStream(c *gin.Context) {
 		return
 	}
 	
-	// Registrar vista inicial (solo si el usuario está autenticado)
+	// Registrar vista inicial: contra la cuenta si hay sesión, si no contra el device ID del invitado.
+	// Se usa context.Background() porque la goroutine sobrevive a la request.
+	viewsRepo := views.NewRepository(h.db)
 	if exists {
-		viewsRepo := views.NewRepository(h.db)
-		go viewsRepo.RecordView(ctx, &uid, episodeID, 0, false)
+		go viewsRepo.RecordView(context.Background(), &uid, episodeID, 0, false)
+	} else if deviceID, ok := c.Get("device_id"); ok {
+		go viewsRepo.RecordDeviceView(context.Background(), deviceID.(uuid.UUID), episodeID)
+	}
+	
+	// Subtítulos y audios alternativos, firmados con la misma expiración que el video
+	episodeTracks, err := h.tracks.Playback(ctx, episode, 60)
+	if err != nil {
+		log.Printf("tracks: list for episode %s: %v", episodeID, err)
+	}
+	if episodeTracks == nil {
+		episodeTracks = []tracks.PlaybackTrack{}
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"video_url": signedURL,
 		"expires_in": 3600, // segundos
+		"tracks": episodeTracks,
 	})
 }
 
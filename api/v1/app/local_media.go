@@ -0,0 +1,106 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+// LocalMediaHandlers sirve y recibe los videos del proveedor local (CDN_PROVIDER=local)
+// mediante las URLs firmadas que genera storage.LocalProvider. No usan JWT: la firma
+// de la URL es la autorización.
+type LocalMediaHandlers struct {
+	provider      *storage.LocalProvider
+	maxFileSizeMB int64
+}
+
+func NewLocalMediaHandlers(provider *storage.LocalProvider, maxFileSizeMB int64) *LocalMediaHandlers {
+	return &LocalMediaHandlers{provider: provider, maxFileSizeMB: maxFileSizeMB}
+}
+
+// verify valida la firma de la URL y responde el error si no es válida.
+func (h *LocalMediaHandlers) verify(c *gin.Context, method string) bool {
+	err := h.provider.Verify(method, c.Param("id"), c.Param("expires"), c.Param("sig"))
+	switch {
+	case errors.Is(err, storage.ErrURLExpired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "URL expired"})
+		return false
+	case err != nil:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid signature"})
+		return false
+	}
+	return true
+}
+
+// Serve entrega un archivo del video con soporte de Range (seek del reproductor).
+//
+// GET /api/v1/media/local/:expires/:sig/:id/*file
+func (h *LocalMediaHandlers) Serve(c *gin.Context) {
+	if !h.verify(c, http.MethodGet) {
+		return
+	}
+
+	f, contentType, err := h.provider.Open(c.Param("id"), strings.TrimPrefix(c.Param("file"), "/"))
+	if errors.Is(err, storage.ErrVideoNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open video"})
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open video"})
+		return
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "private, max-age=3600")
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), f)
+}
+
+// Upload recibe el cuerpo crudo del video (upload directo con la URL de CreateVideo).
+//
+// PUT /api/v1/media/local/:expires/:sig/:id
+func (h *LocalMediaHandlers) Upload(c *gin.Context) {
+	if !h.verify(c, http.MethodPut) {
+		return
+	}
+
+	contentType := c.GetHeader("Content-Type")
+	if contentType == "" {
+		contentType = "video/mp4"
+	}
+	if !strings.HasPrefix(contentType, "video/") && contentType != "application/octet-stream" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported content type: %s", contentType)})
+		return
+	}
+
+	maxBytes := h.maxFileSizeMB * 1024 * 1024
+	if c.Request.ContentLength > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large", "max_mb": h.maxFileSizeMB})
+		return
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+
+	err := h.provider.UploadVideo(c.Param("id"), body, contentType, c.Request.ContentLength)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, storage.ErrVideoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large", "max_mb": h.maxFileSizeMB})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store video", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Video uploaded successfully", "video_id": c.Param("id")})
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+const testMediaBase = "http://media.test"
+
+// newLocalMedia arma el proveedor local sobre un directorio temporal y un router
+// con las mismas rutas de medios que internal/router.
+func newLocalMedia(t *testing.T) (*storage.LocalProvider, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	provider := storage.NewLocalProvider(config.LocalStorageConfig{
+		Dir:           t.TempDir(),
+		PublicBaseURL: testMediaBase,
+		SigningKey:    "test-signing-key",
+	})
+	h := NewLocalMediaHandlers(provider, 1)
+
+	r := gin.New()
+	media := r.Group(storage.LocalMediaPath)
+	media.GET("/:expires/:sig/:id/*file", h.Serve)
+	media.HEAD("/:expires/:sig/:id/*file", h.Serve)
+	media.PUT("/:expires/:sig/:id", h.Upload)
+	return provider, r
+}
+
+// doMedia hace la request contra el router con la URL firmada completa.
+func doMedia(t *testing.T, r *gin.Engine, method, url, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	if !strings.HasPrefix(url, testMediaBase) {
+		t.Fatalf("url %q is not under %s", url, testMediaBase)
+	}
+	req := httptest.NewRequest(method, strings.TrimPrefix(url, testMediaBase), strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLocalMediaUploadAndServe(t *testing.T) {
+	provider, r := newLocalMedia(t)
+
+	upload, err := provider.CreateVideo("episode 1")
+	if err != nil {
+		t.Fatalf("CreateVideo: %v", err)
+	}
+	if upload.UploadMethod != storage.UploadMethodPUT {
+		t.Fatalf("upload method = %q, want PUT", upload.UploadMethod)
+	}
+
+	const video = "fake mp4 bytes"
+	if w := doMedia(t, r, http.MethodPut, upload.UploadURL, "video/mp4", video); w.Code != http.StatusOK {
+		t.Fatalf("PUT upload: status %d, body %s", w.Code, w.Body)
+	}
+
+	status, err := provider.GetVideoStatus(upload.ExternalID)
+	if err != nil || status.Status != storage.VideoStatusReady {
+		t.Fatalf("GetVideoStatus = %+v, %v; want ready", status, err)
+	}
+
+	playback, err := provider.GetPlaybackURL(upload.ExternalID, 10)
+	if err != nil {
+		t.Fatalf("GetPlaybackURL: %v", err)
+	}
+	w := doMedia(t, r, http.MethodGet, playback, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET playback: status %d, body %s", w.Code, w.Body)
+	}
+	if w.Body.String() != video {
+		t.Errorf("GET playback body = %q, want %q", w.Body.String(), video)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "video/mp4" {
+		t.Errorf("Content-Type = %q, want video/mp4", ct)
+	}
+}
+
+func TestLocalMediaRejectsBadSignatures(t *testing.T) {
+	provider, r := newLocalMedia(t)
+
+	upload, err := provider.CreateVideo("episode 1")
+	if err != nil {
+		t.Fatalf("CreateVideo: %v", err)
+	}
+	if w := doMedia(t, r, http.MethodPut, upload.UploadURL, "video/mp4", "video"); w.Code != http.StatusOK {
+		t.Fatalf("PUT upload: status %d, body %s", w.Code, w.Body)
+	}
+	id := upload.ExternalID
+
+	playback := provider.SignedURL(http.MethodGet, id, "original", time.Minute)
+	// La firma es el segundo segmento después de LocalMediaPath.
+	parts := strings.Split(strings.TrimPrefix(playback, testMediaBase+storage.LocalMediaPath+"/"), "/")
+	sig := parts[1]
+	tampered := strings.Replace(playback, "/"+sig+"/", "/"+flipFirst(sig)+"/", 1)
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		want   string
+	}{
+		{"expired", http.MethodGet, provider.SignedURL(http.MethodGet, id, "original", -time.Minute), "URL expired"},
+		{"tampered", http.MethodGet, tampered, "Invalid signature"},
+		{"upload url used for GET", http.MethodGet, upload.UploadURL + "/original", "Invalid signature"},
+		{"playback url used for PUT", http.MethodPut, provider.SignedURL(http.MethodGet, id, "", time.Minute), "Invalid signature"},
+		{"expired upload", http.MethodPut, provider.SignedURL(http.MethodPut, id, "", -time.Minute), "URL expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doMedia(t, r, tt.method, tt.url, "video/mp4", "replaced")
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status %d, want 403 (body %s)", w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body %s, want error %q", w.Body, tt.want)
+			}
+		})
+	}
+
+	// Ninguna request rechazada tocó el original.
+	w := doMedia(t, r, http.MethodGet, playback, "", "")
+	if w.Code != http.StatusOK || w.Body.String() != "video" {
+		t.Errorf("GET after rejections: status %d, body %q", w.Code, w.Body.String())
+	}
+}
+
+// flipFirst cambia el primer carácter de la firma por otro del alfabeto base64url.
+func flipFirst(sig string) string {
+	if sig[0] == 'A' {
+		return "B" + sig[1:]
+	}
+	return "A" + sig[1:]
+}
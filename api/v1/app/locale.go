@@ -0,0 +1,60 @@
+package app
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/views"
+)
+
+// localesFromContext cadena de fallback del idioma pedido, resuelta por
+// middleware.Locale (nil sin el middleware: se devuelven los textos por defecto).
+func localesFromContext(c *gin.Context) []string {
+	if v, exists := c.Get("locales"); exists {
+		if locales, ok := v.([]string); ok {
+			return locales
+		}
+	}
+	return nil
+}
+
+// localizeSeries traduce en su lugar títulos y descripciones al idioma pedido.
+// Best-effort: si falla, las series quedan en el idioma por defecto.
+func (h *Handlers) localizeSeries(c *gin.Context, list []models.Series) {
+	if err := h.translations.LocalizeSeries(c.Request.Context(), localesFromContext(c), list); err != nil {
+		log.Printf("translations: localize series: %v", err)
+	}
+}
+
+// localizeContinueWatching traduce los títulos de serie y episodio de "continuar viendo".
+func (h *Handlers) localizeContinueWatching(c *gin.Context, items []views.ContinueWatchingItem) {
+	if len(items) == 0 {
+		return
+	}
+	ctx, locales := c.Request.Context(), localesFromContext(c)
+	seriesIDs := make([]uuid.UUID, len(items))
+	episodeIDs := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		seriesIDs[i], episodeIDs[i] = item.SeriesID, item.EpisodeID
+	}
+	seriesTexts, err := h.translations.SeriesTexts(ctx, locales, seriesIDs)
+	if err != nil {
+		log.Printf("translations: localize continue watching: %v", err)
+		return
+	}
+	episodeTitles, err := h.translations.EpisodeTitles(ctx, locales, episodeIDs)
+	if err != nil {
+		log.Printf("translations: localize continue watching: %v", err)
+		return
+	}
+	for i := range items {
+		if text := seriesTexts[items[i].SeriesID]; text.Title != "" {
+			items[i].SeriesTitle = text.Title
+		}
+		if title, ok := episodeTitles[items[i].EpisodeID]; ok {
+			items[i].EpisodeTitle = title
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/privacy"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+// RequestDataExport encola la exportación de los datos personales del usuario
+// (perfil, dispositivos, transacciones, unlocks, vistas, favoritos, telemetría de
+// reproducción, tokens, bans) en un ZIP.
+// Se genera en segundo plano: consultar el estado con GET /user/exports.
+//
+// POST /api/v1/app/user/export
+func (h *Handlers) RequestDataExport(c *gin.Context) {
+	uid := c.MustGet("user_id").(uuid.UUID)
+
+	job, err := h.privacy.RequestExport(c.Request.Context(), uid, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request data export"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"export": job})
+}
+
+// GetDataExports lista las exportaciones del usuario (más recientes primero).
+//
+// GET /api/v1/app/user/exports
+func (h *Handlers) GetDataExports(c *gin.Context) {
+	uid := c.MustGet("user_id").(uuid.UUID)
+
+	jobs, err := h.privacy.Repo().ListExports(c.Request.Context(), &uid, 20)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exports"})
+		return
+	}
+	if jobs == nil {
+		jobs = []privacy.ExportJob{}
+	}
+	c.JSON(http.StatusOK, gin.H{"exports": jobs})
+}
+
+// DownloadDataExport descarga el ZIP de una exportación lista del propio usuario.
+//
+// GET /api/v1/app/user/exports/:id/download
+func (h *Handlers) DownloadDataExport(c *gin.Context) {
+	uid := c.MustGet("user_id").(uuid.UUID)
+
+	exportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	job, err := h.privacy.Repo().GetExport(c.Request.Context(), exportID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch export"})
+		return
+	}
+	if job == nil || job.UserID != uid {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	serveExport(c, h.privacy, job)
+}
+
+// serveExport envía el ZIP si la exportación está lista y el archivo existe.
+func serveExport(c *gin.Context, svc *privacy.Service, job *privacy.ExportJob) {
+	if job.Status != privacy.ExportReady {
+		c.JSON(http.StatusConflict, gin.H{"error": "Export not ready", "status": job.Status})
+		return
+	}
+	archive, err := svc.OpenExport(job)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		c.JSON(http.StatusGone, gin.H{"error": "Export file no longer available"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open export"})
+		return
+	}
+	defer archive.Close()
+	c.DataFromReader(http.StatusOK, job.SizeBytes, "application/zip", archive, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="qenti-data-%s.zip"`, job.CreatedAt.Format("2006-01-02")),
+	})
+}
+
+// RequestAccountDeletion programa el borrado de la cuenta tras el período de gracia
+// (PRIVACY_DELETION_GRACE_DAYS) y cierra todas las sesiones. Puede cancelarse antes
+// de scheduled_for con POST /user/deletion/cancel; iniciar sesión no lo cancela.
+//
+// DELETE /api/v1/app/user
+func (h *Handlers) RequestAccountDeletion(c *gin.Context) {
+	uid := c.MustGet("user_id").(uuid.UUID)
+
+	deletion, err := h.privacy.RequestDeletion(c.Request.Context(), uid, nil, false)
+	if errors.Is(err, privacy.ErrOwnsProducer) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account owns a producer; transfer or delete it first"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request account deletion"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"deletion": deletion})
+}
+
+// GetAccountDeletion devuelve el estado de la solicitud de borrado del usuario.
+//
+// GET /api/v1/app/user/deletion
+func (h *Handlers) GetAccountDeletion(c *gin.Context) {
+	uid := c.MustGet("user_id").(uuid.UUID)
+
+	deletion, err := h.privacy.Repo().GetDeletion(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deletion status"})
+		return
+	}
+	if deletion == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No deletion requested"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deletion": deletion})
+}
+
+// CancelAccountDeletion cancela un borrado pendiente (dentro del período de gracia)
+// pedido por el propio usuario; los abiertos por un super admin solo los cancela él.
+//
+// POST /api/v1/app/user/deletion/cancel
+func (h *Handlers) CancelAccountDeletion(c *gin.Context) {
+	uid := c.MustGet("user_id").(uuid.UUID)
+
+	cancelled, err := h.privacy.Repo().CancelDeletion(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel deletion"})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending deletion"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
@@ -16,6 +16,7 @@ type UpdateWatchProgressRequest struct {
 
 // UpdateWatchProgress guarda (upsert) cuántos segundos lleva el usuario en un episodio.
 // Se llama durante reproducción (ej. cada 30 s) o al pausar/salir.
+// Los invitados sin sesión guardan el progreso contra su device ID.
 //
 // POST /api/v1/app/episodes/:id/progress
 func (h *Handlers) UpdateWatchProgress(c *gin.Context) {
@@ -28,12 +29,11 @@ func (h *Handlers) UpdateWatchProgress(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+	uid, deviceID, ok := viewerFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication or device ID required"})
 		return
 	}
-	uid := userID.(uuid.UUID)
 
 	var req UpdateWatchProgressRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -45,7 +45,12 @@ func (h *Handlers) UpdateWatchProgress(c *gin.Context) {
 	}
 
 	viewsRepo := views.NewRepository(h.db)
-	if err := viewsRepo.UpdateWatchProgress(ctx, uid, episodeID, req.WatchedSeconds, req.Completed); err != nil {
+	if uid != uuid.Nil {
+		err = viewsRepo.UpdateWatchProgress(ctx, uid, episodeID, req.WatchedSeconds, req.Completed)
+	} else {
+		err = viewsRepo.UpdateDeviceWatchProgress(ctx, deviceID, episodeID, req.WatchedSeconds, req.Completed)
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save progress"})
 		return
 	}
@@ -57,22 +62,27 @@ func (h *Handlers) UpdateWatchProgress(c *gin.Context) {
 	})
 }
 
-// GetContinueWatching devuelve las series en curso del usuario (no finalizadas).
+// GetContinueWatching devuelve las series en curso del usuario o del invitado (no finalizadas).
 // Máximo 10 items, ordenadas por última actividad DESC.
 //
 // GET /api/v1/app/continue-watching
 func (h *Handlers) GetContinueWatching(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+	uid, deviceID, ok := viewerFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication or device ID required"})
 		return
 	}
-	uid := userID.(uuid.UUID)
 
 	viewsRepo := views.NewRepository(h.db)
-	items, err := viewsRepo.GetContinueWatching(ctx, uid, 10)
+	var items []views.ContinueWatchingItem
+	var err error
+	if uid != uuid.Nil {
+		items, err = viewsRepo.GetContinueWatching(ctx, uid, 10)
+	} else {
+		items, err = viewsRepo.GetContinueWatchingForDevice(ctx, deviceID, 10)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch continue watching"})
 		return
@@ -81,6 +91,7 @@ func (h *Handlers) GetContinueWatching(c *gin.Context) {
 	if items == nil {
 		items = []views.ContinueWatchingItem{}
 	}
+	h.localizeContinueWatching(c, items)
 
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
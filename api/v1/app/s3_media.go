@@ -0,0 +1,54 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+// S3MediaHandlers sirve los playlists HLS del proveedor S3 (CDN_PROVIDER=s3) con las
+// URLs de los segmentos firmadas. Como en LocalMediaHandlers, la firma del path es
+// la autorización.
+type S3MediaHandlers struct {
+	provider *storage.S3Provider
+}
+
+func NewS3MediaHandlers(provider *storage.S3Provider) *S3MediaHandlers {
+	return &S3MediaHandlers{provider: provider}
+}
+
+// Playlist devuelve un playlist (master o de rendition) reescrito; los segmentos
+// quedan firmados hasta el mismo vencimiento que la URL del playlist.
+//
+// GET /api/v1/media/s3/:expires/:sig/:id/*file
+func (h *S3MediaHandlers) Playlist(c *gin.Context) {
+	id, expires := c.Param("id"), c.Param("expires")
+	err := h.provider.VerifyPlaylist(id, expires, c.Param("sig"))
+	switch {
+	case errors.Is(err, storage.ErrURLExpired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "URL expired"})
+		return
+	case err != nil:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	exp, _ := strconv.ParseInt(expires, 10, 64)
+	body, err := h.provider.Playlist(id, strings.TrimPrefix(c.Param("file"), "/"), time.Until(time.Unix(exp, 0)))
+	if errors.Is(err, storage.ErrVideoNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to load playlist"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, "application/vnd.apple.mpegurl", body)
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/models"
+)
+
+const (
+	defaultSimilarLimit = 12
+	maxSimilarLimit     = 50
+)
+
+// GetSimilarSeries lista las series que más ven, marcan como favoritas o
+// desbloquean quienes vieron esta (filtrado colaborativo). Si no hay suficientes
+// datos completa con las de más géneros y etiquetas en común.
+// Acepta ?producer_slug=slug para limitar al tenant y ?limit= (default 12, máx. 50).
+//
+// GET /api/v1/app/series/:id/similar
+func (h *Handlers) GetSimilarSeries(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+	limit := defaultSimilarLimit
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if limit > maxSimilarLimit {
+		limit = maxSimilarLimit
+	}
+
+	if _, err := h.seriesRepo.GetByID(ctx, seriesID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	producerID, err := h.resolveProducerSlug(ctx, c.Query("producer_slug"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve producer"})
+		return
+	}
+	allSeries, err := h.seriesRepo.GetAllFiltered(ctx, producerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+		return
+	}
+	seriesByID := make(map[uuid.UUID]models.Series, len(allSeries))
+	for _, s := range allSeries {
+		seriesByID[s.ID] = s
+	}
+
+	neighbors, err := h.recommender.Similar(ctx, seriesID, maxSimilarLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch similar series"})
+		return
+	}
+	list := make([]models.Series, 0, limit)
+	added := map[uuid.UUID]bool{seriesID: true}
+	for _, n := range neighbors {
+		if s, ok := seriesByID[n.SeriesID]; ok && !added[n.SeriesID] && len(list) < limit {
+			list = append(list, s)
+			added[n.SeriesID] = true
+		}
+	}
+
+	// Arranque en frío (serie nueva o con poca audiencia): géneros y etiquetas en común
+	if len(list) < limit {
+		scores, err := h.taxonomyRepo.SimilarityScores(ctx, []uuid.UUID{seriesID})
+		if err != nil {
+			log.Printf("taxonomy: similar series: %v", err)
+		}
+		var rest []models.Series
+		for _, s := range allSeries {
+			if !added[s.ID] && scores[s.ID] > 0 {
+				rest = append(rest, s)
+			}
+		}
+		sort.SliceStable(rest, func(i, j int) bool { return scores[rest[i].ID] > scores[rest[j].ID] })
+		for _, s := range rest {
+			if len(list) >= limit {
+				break
+			}
+			list = append(list, s)
+		}
+	}
+
+	h.localizeSeries(c, list)
+	h.attachTaxonomy(c, list)
+	c.JSON(http.StatusOK, gin.H{"series": list})
+}
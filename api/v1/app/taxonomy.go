@@ -0,0 +1,55 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/taxonomy"
+)
+
+// slugsFromQuery lee un filtro de slugs separados por coma (?genre=romance,venganza).
+func slugsFromQuery(c *gin.Context, key string) []string {
+	var slugs []string
+	for _, v := range strings.Split(c.Query(key), ",") {
+		if slug := taxonomy.Slugify(v); slug != "" {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// attachTaxonomy completa géneros (traducidos) y etiquetas de las series.
+// Best-effort: si falla, las series salen sin taxonomía.
+func (h *Handlers) attachTaxonomy(c *gin.Context, list []models.Series) {
+	if err := h.taxonomyRepo.Attach(c.Request.Context(), localesFromContext(c), list); err != nil {
+		log.Printf("taxonomy: attach series: %v", err)
+	}
+}
+
+// GetGenres lista los géneros que tienen series activas, con cuántas y el nombre en
+// el idioma pedido, en el orden definido por el admin. El slug filtra el catálogo
+// (GET /app/series?genre=slug) y la búsqueda.
+//
+// GET /api/v1/app/genres?producer_slug=slug
+func (h *Handlers) GetGenres(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	producerID, err := h.resolveProducerSlug(ctx, c.Query("producer_slug"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve producer"})
+		return
+	}
+
+	genres, err := h.taxonomyRepo.GenreCounts(ctx, localesFromContext(c), producerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch genres"})
+		return
+	}
+	if genres == nil {
+		genres = []models.Term{}
+	}
+	c.JSON(http.StatusOK, gin.H{"genres": genres})
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// viewerFromContext identifica a quien hace la request: el usuario autenticado
+// (OptionalAuth/RequireAuth) o, si no hay sesión, el device ID anónimo del invitado
+// (middleware.DeviceID). Exactamente uno de los dos es distinto de uuid.Nil cuando ok.
+func viewerFromContext(c *gin.Context) (userID, deviceID uuid.UUID, ok bool) {
+	if v, exists := c.Get("user_id"); exists {
+		if uid, isUUID := v.(uuid.UUID); isUUID {
+			return uid, uuid.Nil, true
+		}
+	}
+	if v, exists := c.Get("device_id"); exists {
+		if did, isUUID := v.(uuid.UUID); isUUID {
+			return uuid.Nil, did, true
+		}
+	}
+	return uuid.Nil, uuid.Nil, false
+}
+
+// requestCountry país de la request (ISO-3166-1 alpha-2 en mayúsculas): Cloudflare
+// rellena CF-IPCountry automáticamente; el SDK móvil puede enviar X-Country.
+func requestCountry(c *gin.Context) string {
+	country := c.GetHeader("CF-IPCountry")
+	if country == "" {
+		country = c.GetHeader("X-Country")
+	}
+	return strings.ToUpper(strings.TrimSpace(country))
+}
+
+// requestPlatform plataforma de la app que hace la request (android | ios | web),
+// del header X-Platform o de ?platform=.
+func requestPlatform(c *gin.Context) string {
+	platform := c.GetHeader("X-Platform")
+	if platform == "" {
+		platform = c.Query("platform")
+	}
+	return strings.ToLower(strings.TrimSpace(platform))
+}
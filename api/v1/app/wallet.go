@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/pagination"
 	"github.com/qenti/qenti/internal/pkg/transactions"
 )
 
@@ -36,7 +37,8 @@ func (h *Handlers) GetWallet(c *gin.Context) {
 	})
 }
 
-// GetWalletHistory obtiene el historial de transacciones del usuario
+// GetWalletHistory obtiene el historial de transacciones del usuario, de la más
+// nueva a la más vieja, paginado con ?limit= y ?cursor=.
 func (h *Handlers) GetWalletHistory(c *gin.Context) {
 	ctx := c.Request.Context()
 	
@@ -48,16 +50,23 @@ func (h *Handlers) GetWalletHistory(c *gin.Context) {
 		return
 	}
 	uid := userID.(uuid.UUID)
+	page, ok := pagination.FromQuery(c)
+	if !ok {
+		return
+	}
 	
 	// Obtener historial de transacciones
 	transactionsRepo := transactions.NewRepository(h.db)
-	txHistory, err := transactionsRepo.GetUserHistory(ctx, uid, 50) // Últimas 50 transacciones
+	txHistory, err := transactionsRepo.ListUserHistory(ctx, uid, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get wallet history",
 		})
 		return
 	}
+	txHistory, next := pagination.Slice(txHistory, page, func(tx transactions.Transaction) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: tx.CreatedAt, ID: tx.ID}
+	})
 	
 	type TransactionResponse struct {
 		ID        string    `json:"id"`
@@ -87,6 +96,7 @@ func (h *Handlers) GetWalletHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"history": history,
 		"total_transactions": len(history),
+		"next_cursor": next,
 	})
 }
 
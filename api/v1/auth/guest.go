@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/guests"
+	"github.com/qenti/qenti/internal/pkg/jwt"
+	"github.com/qenti/qenti/internal/pkg/models"
+)
+
+// LinkAccountRequest payload para vincular una cuenta invitada con una identidad Firebase.
+type LinkAccountRequest struct {
+	FirebaseToken string `json:"firebase_token" binding:"required"`
+	// OnConflict política si la identidad ya tiene cuenta: "merge" (por defecto) o "reject"
+	OnConflict guests.ConflictPolicy `json:"on_conflict"`
+}
+
+// Guest crea (o recupera) la cuenta invitada ligada al device ID de la request y
+// devuelve un JWT con rol "guest": puede hacer check-in, ver anuncios, desbloquear
+// con monedas y guardar progreso/favoritos, pero no comprar ni acceder al panel.
+// La actividad anónima previa del dispositivo se fusiona en la cuenta invitada.
+//
+// POST /api/v1/auth/guest
+func (h *Handlers) Guest(c *gin.Context) {
+	deviceID, exists := c.Get("device_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Device ID required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, created, err := h.guestsRepo.GetOrCreate(ctx, deviceID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create guest account"})
+		return
+	}
+	if created {
+		log.Printf("✅ Nueva cuenta invitada %s para device %s", user.ID, deviceID)
+	}
+
+	resp, err := h.issueSession(c, user, "guest", "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+	resp.GuestMerge = h.mergeGuestActivity(c, user.ID)
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, resp)
+}
+
+// LinkAccount vincula la cuenta invitada del JWT con una identidad Firebase.
+//   - Si la identidad no tiene cuenta, la invitada se convierte en cuenta normal
+//     (mismo user_id: wallet, unlocks y progreso se conservan) y recibe el bono de bienvenida.
+//   - Si ya tiene cuenta, según on_conflict: "merge" fusiona la invitada en ella
+//     (guests.Repository.MergeInto) y "reject" devuelve 409 sin cambios.
+//
+// Devuelve tokens nuevos de la cuenta resultante; los del invitado dejan de ser útiles.
+//
+// POST /api/v1/auth/link
+func (h *Handlers) LinkAccount(c *gin.Context) {
+	var req LinkAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.OnConflict == "" {
+		req.OnConflict = guests.PolicyMerge
+	}
+	if req.OnConflict != guests.PolicyMerge && req.OnConflict != guests.PolicyReject {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on_conflict must be 'merge' or 'reject'"})
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt.Claims)
+	if !claims.IsGuest() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only guest accounts can be linked"})
+		return
+	}
+	guestID := c.MustGet("user_id").(uuid.UUID)
+
+	identity, err := h.authService.VerifyIdentity(req.FirebaseToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Firebase token"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	accountID, found, err := h.guestsRepo.AccountByFirebaseUID(ctx, identity.FirebaseUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up account"})
+		return
+	}
+
+	var merge *guests.MergeResult
+	if !found {
+		if _, err := h.guestsRepo.Upgrade(ctx, guestID, identity.FirebaseUID, identity.Email); err != nil {
+			switch {
+			case errors.Is(err, guests.ErrEmailTaken):
+				c.JSON(http.StatusConflict, gin.H{"error": "Email already used by another account"})
+			case errors.Is(err, guests.ErrNotGuest):
+				c.JSON(http.StatusConflict, gin.H{"error": "Guest account already linked"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link account"})
+			}
+			return
+		}
+		accountID = guestID
+	} else {
+		if req.OnConflict == guests.PolicyReject {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":          "Account already exists for this identity",
+				"account_exists": true,
+			})
+			return
+		}
+		merge, err = h.guestsRepo.MergeInto(ctx, guestID, accountID)
+		if err != nil {
+			if errors.Is(err, guests.ErrNotGuest) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Guest account already linked"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge guest account"})
+			return
+		}
+	}
+
+	user, err := h.usersRepo.GetByID(ctx, accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load account"})
+		return
+	}
+	role, producerID, _ := h.authService.GetUserRole(user.FirebaseUID)
+
+	resp, err := h.issueSession(c, user, role, producerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+	resp.AccountMerge = merge
+	c.JSON(http.StatusOK, resp)
+}
+
+// issueSession genera access token (24 h) y refresh token (7 días) para el usuario.
+func (h *Handlers) issueSession(c *gin.Context, user *models.User, role, producerID string) (*LoginResponse, error) {
+	accessToken, _, err := h.jwtService.GenerateToken(user.ID, user.Email, role, producerID, 24)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := jwt.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.refreshTokenRepo.Create(c.Request.Context(), refreshToken, user.ID, time.Now().Add(7*24*time.Hour)); err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(24 * time.Hour),
+		User: UserInfo{
+			ID:          user.ID.String(),
+			Email:       user.Email,
+			IsPremium:   user.IsPremium,
+			CoinBalance: user.CoinBalance,
+			Role:        role,
+			ProducerID:  producerID,
+		},
+	}, nil
+}
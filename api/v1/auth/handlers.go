@@ -9,6 +9,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/qenti/qenti/internal/pkg/auth"
+	"github.com/qenti/qenti/internal/pkg/devices"
+	"github.com/qenti/qenti/internal/pkg/guests"
 	"github.com/qenti/qenti/internal/pkg/invitations"
 	"github.com/qenti/qenti/internal/pkg/jwt"
 	"github.com/qenti/qenti/internal/pkg/models"
@@ -20,6 +22,8 @@ type Handlers struct {
 	authService       *auth.Service
 	jwtService        *jwt.Service
 	refreshTokenRepo  *auth.RefreshTokenRepository
+	devicesRepo       *devices.Repository
+	guestsRepo        *guests.Repository
 	usersRepo         *users.Repository
 	producersRepo     *producers.Repository
 	invitationsRepo   *invitations.Repository
@@ -33,12 +37,15 @@ func NewHandlers(
 	usersRepo *users.Repository,
 	producersRepo *producers.Repository,
 	invitationsRepo *invitations.Repository,
+	guestsRepo *guests.Repository,
 	superAdminEmail string,
 ) *Handlers {
 	return &Handlers{
 		authService:     authService,
 		jwtService:      jwtService,
 		refreshTokenRepo: auth.NewRefreshTokenRepository(db),
+		devicesRepo:     devices.NewRepository(db),
+		guestsRepo:      guestsRepo,
 		usersRepo:       usersRepo,
 		producersRepo:   producersRepo,
 		invitationsRepo: invitationsRepo,
@@ -57,6 +64,10 @@ type LoginResponse struct {
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	User         UserInfo  `json:"user"`
+	// GuestMerge resume la actividad de invitado (device ID) fusionada en la cuenta al iniciar sesión.
+	GuestMerge *devices.MergeResult `json:"guest_merge,omitempty"`
+	// AccountMerge resume lo transferido desde una cuenta invitada al vincularla (POST /auth/link).
+	AccountMerge *guests.MergeResult `json:"account_merge,omitempty"`
 }
 
 // UserInfo contiene información básica del usuario
@@ -65,7 +76,7 @@ type UserInfo struct {
 	Email          string `json:"email"`
 	IsPremium      bool   `json:"is_premium"`
 	CoinBalance    int    `json:"coin_balance"`
-	Role           string `json:"role"` // "user", "admin", "super_admin", "producer"
+	Role           string `json:"role"` // "user", "admin", "super_admin", "producer", "guest"
 	ProducerID     string `json:"producer_id,omitempty"`
 	// NeedsOnboarding es true si el usuario no tiene rol de producer aún
 	// — el frontend debe mostrar el formulario de creación de productora.
@@ -185,9 +196,26 @@ func (h *Handlers) Login(c *gin.Context) {
 		RefreshToken: refreshToken,
 		ExpiresAt:    expiresAt,
 		User: userInfo,
+		GuestMerge:   h.mergeGuestActivity(c, dbUser.ID),
 	})
 }
 
+// mergeGuestActivity fusiona en la cuenta el historial, progreso y favoritos del
+// invitado si la request trae un device ID válido (middleware.DeviceID).
+// Un fallo no bloquea el login: se loguea y se devuelve nil.
+func (h *Handlers) mergeGuestActivity(c *gin.Context, userID uuid.UUID) *devices.MergeResult {
+	deviceID, exists := c.Get("device_id")
+	if !exists {
+		return nil
+	}
+	result, err := h.devicesRepo.MergeIntoUser(c.Request.Context(), deviceID.(uuid.UUID), userID)
+	if err != nil {
+		log.Printf("Warning: Failed to merge guest activity into user %s: %v", userID, err)
+		return nil
+	}
+	return result
+}
+
 // Refresh refresca un access token usando un refresh token
 func (h *Handlers) Refresh(c *gin.Context) {
 	var req RefreshRequest
@@ -306,6 +334,8 @@ func (h *Handlers) Onboarding(c *gin.Context) {
 	user, _ := h.usersRepo.GetByID(ctx, userID)
 	if err := h.authService.GrantRole(ctx, userID, "producer", userID); err != nil {
 		log.Printf("Warning: failed to grant producer role: %v", err)
+		// Aunque falle, el rol en cache no conoce la productora nueva
+		h.authService.InvalidateRoles(ctx)
 	}
 
 	// Generar nuevos tokens con el rol 'producer' ya asignado
@@ -488,6 +518,7 @@ func (h *Handlers) DevLogin(c *gin.Context) {
 			CoinBalance: dbUser.CoinBalance,
 			Role:        role,
 		},
+		GuestMerge: h.mergeGuestActivity(c, dbUser.ID),
 	})
 }
 
@@ -0,0 +1,134 @@
+// Command migrate gestiona las migraciones versionadas del esquema (schema_migrations).
+//
+// Uso:
+//
+//	go run ./cmd/migrate up            # aplica todas las pendientes
+//	go run ./cmd/migrate up -to 5      # aplica hasta la versión 5 inclusive
+//	go run ./cmd/migrate down          # revierte la última
+//	go run ./cmd/migrate down -steps 2 # revierte las dos últimas
+//	go run ./cmd/migrate status        # lista aplicadas / pendientes
+//	go run ./cmd/migrate redo          # revierte y reaplica la última
+//	go run ./cmd/migrate accept        # acepta el checksum de migraciones aplicadas editadas
+//
+// El servidor aplica las pendientes al arrancar; este CLI sirve para revertir,
+// inspeccionar o migrar antes de un despliegue.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/database"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate <up [-to N] | down [-steps N] | status | redo | accept>")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	fset := flag.NewFlagSet(command, flag.ExitOnError)
+	to := fset.Int("to", 0, "up: versión máxima a aplicar (0 = todas)")
+	steps := fset.Int("steps", 1, "down: cuántas migraciones revertir")
+	fset.Parse(args)
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+	cfg := config.Load()
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx, *to)
+		for _, m := range applied {
+			log.Printf("↑ %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("✅ %d migration(s) applied", len(applied))
+
+	case "down":
+		if *steps < 1 {
+			log.Fatalf("-steps must be >= 1")
+		}
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			log.Printf("↓ %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("✅ %d migration(s) reverted", len(reverted))
+
+	case "redo":
+		m, err := migrator.Redo(ctx)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if m == nil {
+			log.Println("Nothing to redo")
+			return
+		}
+		log.Printf("✅ %04d_%s redone", m.Version, m.Name)
+
+	case "accept":
+		accepted, err := migrator.AcceptModified(ctx)
+		for _, name := range accepted {
+			log.Printf("✓ %s", name)
+		}
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("✅ %d checksum(s) accepted", len(accepted))
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, at := "pending", ""
+			if s.Applied {
+				state = "applied"
+				at = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state += " (modified)"
+			}
+			if s.Orphan {
+				state += " (missing file)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+		}
+		w.Flush()
+
+	default:
+		usage()
+	}
+}
@@ -0,0 +1,41 @@
+// Command recommendations recalcula la similitud entre series (series_similarity)
+// que usan los recomendados y GET /api/v1/app/series/:id/similar.
+//
+// Uso:
+//
+//	go run ./cmd/recommendations
+//
+// Es lo mismo que hace el worker del servidor cada RECOMMENDATIONS_REBUILD_MINUTES;
+// sirve para el primer cálculo o si el worker está desactivado (0).
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/database"
+	"github.com/qenti/qenti/internal/pkg/recommendations"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+	cfg := config.Load()
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Now()
+	n, err := recommendations.NewService(db, cfg.Recommendations).Rebuild(context.Background())
+	if err != nil {
+		log.Fatalf("❌ recommendations: %v", err)
+	}
+	log.Printf("✅ %d similar pairs (%v)", n, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,71 @@
+// Command rollup recalcula los agregados diarios de analytics para un rango de fechas.
+//
+// Uso:
+//
+//	go run ./cmd/rollup -from 2026-01-01 -to 2026-01-31
+//
+// Sin flags reconstruye ayer y hoy (lo mismo que hace el worker del servidor).
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/database"
+	"github.com/qenti/qenti/internal/pkg/events"
+	"github.com/qenti/qenti/internal/pkg/rollups"
+)
+
+const dateLayout = "2006-01-02"
+
+func main() {
+	now := time.Now().UTC()
+	fromStr := flag.String("from", now.AddDate(0, 0, -1).Format(dateLayout), "primer día a reconstruir (YYYY-MM-DD, UTC)")
+	toStr := flag.String("to", now.Format(dateLayout), "último día a reconstruir (YYYY-MM-DD, UTC, inclusive)")
+	withEvents := flag.Bool("events", true, "recalcular también playback_daily_stats desde playback_events")
+	flag.Parse()
+
+	from, err := time.Parse(dateLayout, *fromStr)
+	if err != nil {
+		log.Fatalf("invalid -from: %v", err)
+	}
+	to, err := time.Parse(dateLayout, *toStr)
+	if err != nil {
+		log.Fatalf("invalid -to: %v", err)
+	}
+	if to.Before(from) {
+		log.Fatalf("-to (%s) is before -from (%s)", *toStr, *fromStr)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+	cfg := config.Load()
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	rollupsRepo := rollups.NewRepository(db)
+	eventsRepo := events.NewRepository(db)
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		start := time.Now()
+		if err := rollupsRepo.BuildDay(ctx, day); err != nil {
+			log.Fatalf("❌ rollup %s: %v", day.Format(dateLayout), err)
+		}
+		if *withEvents {
+			if err := eventsRepo.RollupDay(ctx, day); err != nil {
+				log.Fatalf("❌ playback rollup %s: %v", day.Format(dateLayout), err)
+			}
+		}
+		log.Printf("✅ %s (%v)", day.Format(dateLayout), time.Since(start).Round(time.Millisecond))
+	}
+}
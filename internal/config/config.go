@@ -15,23 +15,41 @@ type Config struct {
 	// Solo debe haber 1-2 cuentas. Definir en variable de entorno SUPER_ADMIN_EMAIL.
 	SuperAdminEmail string
 
-	// CDNProvider selecciona el proveedor de video: "bunny" (default) | "cloudflare"
+	// CDNProvider selecciona el proveedor de video: "bunny" (default) | "cloudflare" | "local" | "s3"
 	CDNProvider string
 
-	Database      DatabaseConfig
-	Firebase      FirebaseConfig
-	Bunny         BunnyConfig
-	Cloudflare    CloudflareConfig
-	VideoUpload   VideoUploadConfig
-	RevenueCat    RevenueCatConfig
-	AdReward      AdRewardConfig
-	AdTier        AdTierConfig
-	EpisodeCliff  EpisodeCliffConfig
-	JWT           JWTConfig
+	Database        DatabaseConfig
+	Firebase        FirebaseConfig
+	Bunny           BunnyConfig
+	Cloudflare      CloudflareConfig
+	LocalStorage    LocalStorageConfig
+	S3              S3Config
+	VideoUpload     VideoUploadConfig
+	Transcode       TranscodeConfig
+	MediaProbe      MediaProbeConfig
+	Images          ImagesConfig
+	Localization    LocalizationConfig
+	Search          SearchConfig
+	Recommendations RecommendationsConfig
+	Trending        TrendingConfig
+	HTTPCache       HTTPCacheConfig
+	Cache           CacheConfig
+	RevenueCat      RevenueCatConfig
+	AdReward        AdRewardConfig
+	AdTier          AdTierConfig
+	EpisodeCliff    EpisodeCliffConfig
+	Rollup          RollupConfig
+	Privacy         PrivacyConfig
+	JWT             JWTConfig
 }
 
 type JWTConfig struct {
 	SecretKey string
+	// DeviceSecret firma los device IDs anónimos de invitados (default: SecretKey)
+	DeviceSecret string
+	// DeviceCookieSecure emite la cookie qenti_did solo para HTTPS (default: true
+	// salvo con ENVIRONMENT=development)
+	DeviceCookieSecure bool
 }
 
 type DatabaseConfig struct {
@@ -55,12 +73,71 @@ type BunnyConfig struct {
 	StorageZone     string
 	CDNHostname     string
 	SecurityKey     string
+	// WebhookSecret si se configura, el webhook de encoding debe llegar con ?secret=<valor>
+	// (Bunny no firma sus webhooks; igual el estado se re-consulta a la API)
+	WebhookSecret string
 }
 
 // CloudflareConfig es para Cloudflare Stream (proveedor alternativo).
 type CloudflareConfig struct {
 	AccountID string
 	APIToken  string
+	// CustomerCode subdominio de reproducción (customer-<code>.cloudflarestream.com); vacío usa videodelivery.net
+	CustomerCode string
+	// SigningKeyID y SigningKeyPEM clave de firma de Stream (POST /stream/keys, PEM tal cual o en base64).
+	// Si están configuradas, los videos exigen URL firmada y la reproducción lleva token
+	SigningKeyID  string
+	SigningKeyPEM string
+	// APIBaseURL base de la API (default https://api.cloudflare.com/client/v4); configurable para tests
+	APIBaseURL string
+	// WebhookSecret secreto de firma de los webhooks de Stream (PUT /stream/webhook)
+	WebhookSecret string
+}
+
+// LocalStorageConfig es para el proveedor "local": guarda los videos en disco y los
+// sirve la propia API con URLs firmadas (desarrollo sin red y self-hosting).
+type LocalStorageConfig struct {
+	// Dir directorio raíz de los videos (default ./data/videos)
+	Dir string
+	// PublicBaseURL URL pública de esta API con la que se arman las URLs firmadas (default http://localhost:<SERVER_PORT>)
+	PublicBaseURL string
+	// SigningKey clave HMAC de las URLs de upload/reproducción (default: JWT_SECRET)
+	SigningKey string
+}
+
+// S3Config es para el proveedor "s3": cualquier bucket compatible con S3 (AWS, MinIO,
+// R2, ...). Guarda originales y renditions HLS bajo <Prefix><video_id>/.
+type S3Config struct {
+	// Endpoint URL del servicio (ej. http://localhost:9000 para MinIO); vacío = AWS (https://s3.<region>.amazonaws.com)
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// ForcePathStyle usa endpoint/bucket/key en lugar de bucket.endpoint/key (necesario en MinIO)
+	ForcePathStyle bool
+	// Prefix prefijo de las claves de video dentro del bucket (default "videos/")
+	Prefix string
+
+	// PlaybackMode cómo se firman las URLs de reproducción: "presigned" (default, SigV4),
+	// "cloudfront" (URLs firmadas de CloudFront) o "public" (bucket/CDN público, sin firma)
+	PlaybackMode string
+	// PublicBaseURL URL del CDN o bucket público delante de las claves (requerido en cloudfront/public)
+	PublicBaseURL string
+	// CloudFrontKeyPairID y CloudFrontPrivateKey par de claves de CloudFront (PEM tal cual o en base64)
+	CloudFrontKeyPairID  string
+	CloudFrontPrivateKey string
+
+	// PlaylistBaseURL URL pública de esta API, que sirve los playlists HLS con segmentos firmados
+	// (default http://localhost:<SERVER_PORT>)
+	PlaylistBaseURL string
+	// PlaylistSigningKey clave HMAC de las URLs de playlist (default: JWT_SECRET)
+	PlaylistSigningKey string
+
+	// PackageHLS empaqueta el original en HLS (remux sin recodificar, vía ffmpeg) al completar el upload
+	PackageHLS bool
+	// FFmpegPath binario de ffmpeg (default "ffmpeg")
+	FFmpegPath string
 }
 
 // VideoUploadConfig define los límites de validación en el upload de videos.
@@ -71,6 +148,135 @@ type VideoUploadConfig struct {
 	MaxDurationSeconds int
 	// WarnFileSizeMB aviso suave si supera este tamaño (default 50 MB - objetivo 5-8 MB producción)
 	WarnFileSizeMB int64
+	// StatusPollSeconds cada cuánto se consulta al proveedor el estado de los videos en
+	// proceso, como respaldo de los webhooks (default 60; 0 deshabilita)
+	StatusPollSeconds int
+	// ResumableDir directorio donde se arman los uploads reanudables (TUS) (default ./data/uploads)
+	ResumableDir string
+	// ResumableExpiryHours horas sin actividad tras las que se descarta un upload
+	// reanudable incompleto (default 24)
+	ResumableExpiryHours int
+}
+
+// TranscodeConfig controla la transcodificación propia con ffmpeg (opcional). Con
+// Enabled, los uploads se guardan en disco y un worker genera una escalera HLS
+// vertical (9:16) + póster que luego entrega al VideoProvider configurado.
+type TranscodeConfig struct {
+	Enabled bool
+	// WorkDir directorio de originales y salidas en proceso (default ./data/transcode)
+	WorkDir     string
+	FFmpegPath  string
+	FFprobePath string
+	// Ladder anchos de la escalera vertical (default 360,540,720,1080 → 360x640 ... 1080x1920)
+	Ladder []int
+	// Workers trabajos simultáneos (default 1)
+	Workers int
+}
+
+// MediaProbeConfig controla el análisis (ffprobe) de los videos que se suben por la
+// API: la duración y los datos técnicos quedan en el episodio, los videos
+// horizontales o demasiado largos se rechazan y se extraen portadas candidatas.
+type MediaProbeConfig struct {
+	Enabled     bool
+	FFmpegPath  string
+	FFprobePath string
+	// RequireVertical rechaza videos más anchos que altos (default true)
+	RequireVertical bool
+	// ThumbnailCount portadas candidatas por video (default 4; 0 no genera)
+	ThumbnailCount int
+	// ThumbnailWidth ancho de las portadas en px (default 540)
+	ThumbnailWidth int
+	// ThumbnailDir directorio de las portadas, servidas en /api/v1/media/thumbnails (default ./data/thumbnails)
+	ThumbnailDir string
+	// ThumbnailBaseURL URL pública de la API con la que se arman las URLs de las portadas
+	ThumbnailBaseURL string
+}
+
+// ImagesConfig controla las imágenes subidas por POST /admin/images (posters,
+// banners, logos): se validan, se les quita el EXIF y se guardan en variantes.
+type ImagesConfig struct {
+	// Storage backend de las variantes: "local" (default) o "s3" (usa el bucket de S3Config)
+	Storage string
+	// Dir directorio del backend local, servido en /api/v1/media/images (default ./data/images)
+	Dir string
+	// BaseURL URL pública con la que se arman las URLs de las variantes: la de esta API en
+	// el backend local, el CDN o bucket público en s3
+	BaseURL string
+	// MaxFileSizeMB tamaño máximo de la imagen subida (default 10)
+	MaxFileSizeMB int64
+	// JPEGQuality calidad de las variantes JPEG, 1-100 (default 85)
+	JPEGQuality int
+	// WebPEnabled genera además variantes WebP y acepta WebP de entrada (requiere ffmpeg con libwebp)
+	WebPEnabled bool
+	FFmpegPath  string
+}
+
+// LocalizationConfig controla el idioma de los títulos y descripciones que devuelve
+// la app (Accept-Language o ?lang=).
+type LocalizationConfig struct {
+	// DefaultLocale idioma en que se cargan series y episodios; es el último eslabón de
+	// toda cadena de fallback (default es)
+	DefaultLocale string
+	// Fallbacks cadenas de fallback: "pt-BR:pt:es" hace que pt-BR sin traducción caiga
+	// en pt y luego en es (LOCALE_FALLBACKS, separadas por coma)
+	Fallbacks []string
+}
+
+// SearchConfig controla el índice de búsqueda de series (series_search).
+type SearchConfig struct {
+	// IndexIntervalSeconds cada cuánto se reindexan las series modificadas (default 15)
+	IndexIntervalSeconds int
+	// SuggestRebuildMinutes cada cuánto se reconstruye el índice de autocompletado (default 10)
+	SuggestRebuildMinutes int
+}
+
+// RecommendationsConfig controla el job que calcula la similitud entre series
+// (series_similarity) y la mezcla con popularidad de los recomendados.
+type RecommendationsConfig struct {
+	// RebuildMinutes cada cuánto se recalcula la similitud (default 360; 0 = solo con cmd/recommendations)
+	RebuildMinutes int
+	// WindowDays días de vistas, favoritos y unlocks que se consideran (default 90)
+	WindowDays int
+	// MinCoViewers espectadores en común mínimos para que un par de series cuente (default 2)
+	MinCoViewers int
+	// NeighborsPerSeries series similares que se guardan por serie (default 30)
+	NeighborsPerSeries int
+	// PopularityPercent peso de la popularidad reciente frente a la similitud, 0-100 (default 20)
+	PopularityPercent int
+}
+
+// TrendingConfig controla el ranking de trending (vistas, finalizaciones y unlocks
+// con decaimiento por antigüedad) y su cache por tenant.
+type TrendingConfig struct {
+	// HalfLifeHours cada cuántas horas la actividad de un día pasa a valer la mitad (default 48)
+	HalfLifeHours int
+	// WindowDays días de actividad que se consideran (default 14)
+	WindowDays int
+	// CacheSeconds cuánto se reutiliza un ranking calculado (default 300; 0 = sin cache)
+	CacheSeconds int
+}
+
+// HTTPCacheConfig controla el cache de respuestas de los endpoints públicos del
+// catálogo (feed, series, trending, estrenos).
+type HTTPCacheConfig struct {
+	// TTLSeconds cuánto se reutiliza una respuesta en memoria (default 60; 0 = solo ETag).
+	// Los cambios del admin en el catálogo la descartan antes.
+	TTLSeconds int
+	// MaxAgeSeconds max-age de Cache-Control para clientes y CDN (default 30)
+	MaxAgeSeconds int
+	// MaxEntries respuestas guardadas como máximo (default 1000)
+	MaxEntries int
+}
+
+// CacheConfig cache compartido de datos calientes (slugs de productores, roles,
+// bans, rankings).
+type CacheConfig struct {
+	// Backend "memory" (default, por proceso) o "redis" (compartido entre instancias)
+	Backend string
+	// RedisURL redis://[:password@]host:6379/db (rediss:// con TLS); requerido con backend redis
+	RedisURL string
+	// MaxEntries entradas como máximo del backend memory (default 10000)
+	MaxEntries int
 }
 
 type RevenueCatConfig struct {
@@ -106,6 +312,24 @@ type EpisodeCliffConfig struct {
 	CliffPrice int
 }
 
+// RollupConfig controla el worker que mantiene los agregados diarios de analytics.
+type RollupConfig struct {
+	// IntervalMinutes cada cuántos minutos se recalculan los rollups de hoy y ayer (default 15)
+	IntervalMinutes int
+}
+
+// PrivacyConfig configura la exportación y el borrado de datos personales (GDPR/LGPD).
+type PrivacyConfig struct {
+	// ExportStorage backend de los ZIP de exportación: "local" o "s3" (default IMAGES_STORAGE)
+	ExportStorage string
+	// ExportDir directorio donde se guardan los ZIP con ExportStorage=local (default ./data/exports)
+	ExportDir string
+	// ExportTTLHours horas que un ZIP queda disponible para descarga (default 72)
+	ExportTTLHours int
+	// DeletionGraceDays días entre la solicitud de borrado y el borrado real (default 30)
+	DeletionGraceDays int
+}
+
 func Load() *Config {
 	return &Config{
 		Environment:     getEnv("ENVIRONMENT", "development"),
@@ -136,17 +360,120 @@ func Load() *Config {
 			StorageZone:     getEnv("BUNNY_STORAGE_ZONE", ""),
 			CDNHostname:     getEnv("BUNNY_CDN_HOSTNAME", ""),
 			SecurityKey:     getEnv("BUNNY_SECURITY_KEY", ""),
+			WebhookSecret:   getEnv("BUNNY_WEBHOOK_SECRET", ""),
 		},
 
 		Cloudflare: CloudflareConfig{
 			AccountID: getEnv("CLOUDFLARE_ACCOUNT_ID", ""),
 			APIToken:  getEnv("CLOUDFLARE_API_TOKEN", ""),
+
+			CustomerCode:  getEnv("CLOUDFLARE_CUSTOMER_CODE", ""),
+			SigningKeyID:  getEnv("CLOUDFLARE_STREAM_SIGNING_KEY_ID", ""),
+			SigningKeyPEM: getEnv("CLOUDFLARE_STREAM_SIGNING_KEY_PEM", ""),
+			APIBaseURL:    getEnv("CLOUDFLARE_API_BASE_URL", "https://api.cloudflare.com/client/v4"),
+			WebhookSecret: getEnv("CLOUDFLARE_WEBHOOK_SECRET", ""),
+		},
+
+		LocalStorage: LocalStorageConfig{
+			Dir:           getEnv("LOCAL_STORAGE_DIR", "./data/videos"),
+			PublicBaseURL: getEnv("LOCAL_STORAGE_BASE_URL", "http://localhost:"+getEnv("SERVER_PORT", "8080")),
+			SigningKey:    getEnv("LOCAL_STORAGE_SIGNING_KEY", getEnv("JWT_SECRET", "change-this-secret-key-in-production")),
+		},
+
+		S3: S3Config{
+			Endpoint:        getEnv("S3_ENDPOINT", ""),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          getEnv("S3_BUCKET", ""),
+			AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+			ForcePathStyle:  getEnvBool("S3_FORCE_PATH_STYLE", false),
+			Prefix:          getEnv("S3_PREFIX", "videos/"),
+
+			PlaybackMode:         getEnv("S3_PLAYBACK_MODE", "presigned"),
+			PublicBaseURL:        getEnv("S3_PUBLIC_BASE_URL", ""),
+			CloudFrontKeyPairID:  getEnv("CLOUDFRONT_KEY_PAIR_ID", ""),
+			CloudFrontPrivateKey: getEnv("CLOUDFRONT_PRIVATE_KEY", ""),
+
+			PlaylistBaseURL:    getEnv("S3_PLAYLIST_BASE_URL", "http://localhost:"+getEnv("SERVER_PORT", "8080")),
+			PlaylistSigningKey: getEnv("S3_PLAYLIST_SIGNING_KEY", getEnv("JWT_SECRET", "change-this-secret-key-in-production")),
+
+			PackageHLS: getEnvBool("S3_PACKAGE_HLS", true),
+			FFmpegPath: getEnv("FFMPEG_PATH", "ffmpeg"),
 		},
 
 		VideoUpload: VideoUploadConfig{
-			MaxFileSizeMB:      getEnvInt64("VIDEO_MAX_FILE_SIZE_MB", 150),
-			MaxDurationSeconds: getEnvInt("VIDEO_MAX_DURATION_SECONDS", 180),
-			WarnFileSizeMB:     getEnvInt64("VIDEO_WARN_FILE_SIZE_MB", 50),
+			MaxFileSizeMB:        getEnvInt64("VIDEO_MAX_FILE_SIZE_MB", 150),
+			MaxDurationSeconds:   getEnvInt("VIDEO_MAX_DURATION_SECONDS", 180),
+			WarnFileSizeMB:       getEnvInt64("VIDEO_WARN_FILE_SIZE_MB", 50),
+			StatusPollSeconds:    getEnvInt("VIDEO_STATUS_POLL_SECONDS", 60),
+			ResumableDir:         getEnv("UPLOAD_SESSIONS_DIR", "./data/uploads"),
+			ResumableExpiryHours: getEnvInt("UPLOAD_SESSIONS_EXPIRY_HOURS", 24),
+		},
+
+		Transcode: TranscodeConfig{
+			Enabled:     getEnvBool("TRANSCODE_ENABLED", false),
+			WorkDir:     getEnv("TRANSCODE_WORK_DIR", "./data/transcode"),
+			FFmpegPath:  getEnv("FFMPEG_PATH", "ffmpeg"),
+			FFprobePath: getEnv("FFPROBE_PATH", "ffprobe"),
+			Ladder:      getEnvIntSlice("TRANSCODE_LADDER", "360,540,720,1080"),
+			Workers:     getEnvInt("TRANSCODE_WORKERS", 1),
+		},
+
+		MediaProbe: MediaProbeConfig{
+			Enabled:          getEnvBool("MEDIA_PROBE_ENABLED", false),
+			FFmpegPath:       getEnv("FFMPEG_PATH", "ffmpeg"),
+			FFprobePath:      getEnv("FFPROBE_PATH", "ffprobe"),
+			RequireVertical:  getEnvBool("MEDIA_REQUIRE_VERTICAL", true),
+			ThumbnailCount:   getEnvInt("MEDIA_THUMBNAIL_COUNT", 4),
+			ThumbnailWidth:   getEnvInt("MEDIA_THUMBNAIL_WIDTH", 540),
+			ThumbnailDir:     getEnv("MEDIA_THUMBNAIL_DIR", "./data/thumbnails"),
+			ThumbnailBaseURL: getEnv("MEDIA_THUMBNAIL_BASE_URL", "http://localhost:"+getEnv("SERVER_PORT", "8080")),
+		},
+
+		Images: ImagesConfig{
+			Storage:       getEnv("IMAGES_STORAGE", "local"),
+			Dir:           getEnv("IMAGES_DIR", "./data/images"),
+			BaseURL:       getEnv("IMAGES_BASE_URL", "http://localhost:"+getEnv("SERVER_PORT", "8080")),
+			MaxFileSizeMB: getEnvInt64("IMAGES_MAX_FILE_SIZE_MB", 10),
+			JPEGQuality:   getEnvInt("IMAGES_JPEG_QUALITY", 85),
+			WebPEnabled:   getEnvBool("IMAGES_WEBP_ENABLED", false),
+			FFmpegPath:    getEnv("FFMPEG_PATH", "ffmpeg"),
+		},
+
+		Localization: LocalizationConfig{
+			DefaultLocale: getEnv("DEFAULT_LOCALE", "es"),
+			Fallbacks:     getEnvStringSlice("LOCALE_FALLBACKS", "pt-BR:pt:es,es-419:es"),
+		},
+
+		Search: SearchConfig{
+			IndexIntervalSeconds:  getEnvInt("SEARCH_INDEX_INTERVAL_SECONDS", 15),
+			SuggestRebuildMinutes: getEnvInt("SEARCH_SUGGEST_REBUILD_MINUTES", 10),
+		},
+
+		Recommendations: RecommendationsConfig{
+			RebuildMinutes:     getEnvInt("RECOMMENDATIONS_REBUILD_MINUTES", 360),
+			WindowDays:         getEnvInt("RECOMMENDATIONS_WINDOW_DAYS", 90),
+			MinCoViewers:       getEnvInt("RECOMMENDATIONS_MIN_CO_VIEWERS", 2),
+			NeighborsPerSeries: getEnvInt("RECOMMENDATIONS_NEIGHBORS_PER_SERIES", 30),
+			PopularityPercent:  getEnvInt("RECOMMENDATIONS_POPULARITY_PERCENT", 20),
+		},
+
+		Trending: TrendingConfig{
+			HalfLifeHours: getEnvInt("TRENDING_HALF_LIFE_HOURS", 48),
+			WindowDays:    getEnvInt("TRENDING_WINDOW_DAYS", 14),
+			CacheSeconds:  getEnvInt("TRENDING_CACHE_SECONDS", 300),
+		},
+
+		HTTPCache: HTTPCacheConfig{
+			TTLSeconds:    getEnvInt("HTTP_CACHE_TTL_SECONDS", 60),
+			MaxAgeSeconds: getEnvInt("HTTP_CACHE_MAX_AGE_SECONDS", 30),
+			MaxEntries:    getEnvInt("HTTP_CACHE_MAX_ENTRIES", 1000),
+		},
+
+		Cache: CacheConfig{
+			Backend:    getEnv("CACHE_BACKEND", "memory"),
+			RedisURL:   getEnv("REDIS_URL", ""),
+			MaxEntries: getEnvInt("CACHE_MAX_ENTRIES", 10000),
 		},
 
 		RevenueCat: RevenueCatConfig{
@@ -173,8 +500,21 @@ func Load() *Config {
 			CliffPrice: getEnvInt("EPISODE_CLIFF_PRICE", 20),
 		},
 
+		Rollup: RollupConfig{
+			IntervalMinutes: getEnvInt("ROLLUP_INTERVAL_MINUTES", 15),
+		},
+
+		Privacy: PrivacyConfig{
+			ExportStorage:     getEnv("PRIVACY_EXPORT_STORAGE", getEnv("IMAGES_STORAGE", "local")),
+			ExportDir:         getEnv("PRIVACY_EXPORT_DIR", "./data/exports"),
+			ExportTTLHours:    getEnvInt("PRIVACY_EXPORT_TTL_HOURS", 72),
+			DeletionGraceDays: getEnvInt("PRIVACY_DELETION_GRACE_DAYS", 30),
+		},
+
 		JWT: JWTConfig{
-			SecretKey: getEnv("JWT_SECRET", "change-this-secret-key-in-production"),
+			SecretKey:          getEnv("JWT_SECRET", "change-this-secret-key-in-production"),
+			DeviceSecret:       getEnv("DEVICE_ID_SECRET", getEnv("JWT_SECRET", "change-this-secret-key-in-production")),
+			DeviceCookieSecure: getEnvBool("DEVICE_COOKIE_SECURE", getEnv("ENVIRONMENT", "development") != "development"),
 		},
 	}
 }
@@ -206,6 +546,28 @@ func getEnvInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	return defaultValue
+}
+
+// getEnvIntSlice lee una lista de enteros separados por coma (ignora los inválidos)
+func getEnvIntSlice(key, defaultValue string) []int {
+	var result []int
+	for _, s := range getEnvStringSlice(key, defaultValue) {
+		var n int
+		if _, err := fmt.Sscanf(s, "%d", &n); err == nil {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
 // getEnvStringSlice lee una variable de entorno y la convierte en []string
 // dividiendo por comas. Si la variable no está definida, parsea defaultValue.
 func getEnvStringSlice(key, defaultValue string) []string {
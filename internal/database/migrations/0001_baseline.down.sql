@@ -0,0 +1,17 @@
+-- Elimina todo el esquema base. Destructivo: solo para entornos de desarrollo.
+DROP TABLE IF EXISTS producer_members CASCADE;
+DROP TABLE IF EXISTS invitations CASCADE;
+ALTER TABLE IF EXISTS series DROP COLUMN IF EXISTS producer_id;
+DROP TABLE IF EXISTS producers CASCADE;
+DROP TABLE IF EXISTS device_tokens CASCADE;
+DROP TABLE IF EXISTS favorites CASCADE;
+DROP TABLE IF EXISTS ad_validations CASCADE;
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
+DROP TABLE IF EXISTS user_roles CASCADE;
+DROP TABLE IF EXISTS bans CASCADE;
+DROP TABLE IF EXISTS views CASCADE;
+DROP TABLE IF EXISTS transactions CASCADE;
+DROP TABLE IF EXISTS unlocks CASCADE;
+DROP TABLE IF EXISTS episodes CASCADE;
+DROP TABLE IF EXISTS series CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS playback_daily_stats;
+-- Borra también todas las particiones mensuales
+DROP TABLE IF EXISTS playback_events CASCADE;
@@ -0,0 +1,38 @@
+-- Crea la tabla append-only de eventos de reproducción.
+-- Está particionada por mes sobre received_at (hora del servidor); las particiones
+-- mensuales las crea events.Repository.EnsurePartition. La partición DEFAULT evita
+-- perder eventos si el worker aún no creó la del mes en curso.
+CREATE TABLE IF NOT EXISTS playback_events (
+    id               BIGSERIAL,
+    user_id          UUID,
+    device_id        VARCHAR(128),
+    session_id       VARCHAR(128),
+    episode_id       UUID NOT NULL,
+    event_type       VARCHAR(20) NOT NULL CHECK (event_type IN
+        ('start', 'heartbeat', 'seek', 'pause', 'complete', 'buffer_stall', 'quality_change')),
+    position_seconds INTEGER DEFAULT 0,
+    duration_ms      INTEGER DEFAULT 0,
+    data             JSONB,
+    client_ts        TIMESTAMP NOT NULL,
+    received_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id, received_at)
+) PARTITION BY RANGE (received_at);
+CREATE TABLE IF NOT EXISTS playback_events_default PARTITION OF playback_events DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_playback_events_episode_received ON playback_events(episode_id, received_at);
+CREATE INDEX IF NOT EXISTS idx_playback_events_user_id ON playback_events(user_id);
+
+-- Guarda los agregados diarios por episodio calculados
+-- a partir de playback_events, para que los dashboards no escaneen eventos crudos.
+CREATE TABLE IF NOT EXISTS playback_daily_stats (
+    day             DATE NOT NULL,
+    episode_id      UUID NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
+    starts          INTEGER NOT NULL DEFAULT 0,
+    unique_viewers  INTEGER NOT NULL DEFAULT 0,
+    completions     INTEGER NOT NULL DEFAULT 0,
+    watch_seconds   BIGINT NOT NULL DEFAULT 0,
+    buffer_stalls   INTEGER NOT NULL DEFAULT 0,
+    buffer_stall_ms BIGINT NOT NULL DEFAULT 0,
+    updated_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (day, episode_id)
+);
+CREATE INDEX IF NOT EXISTS idx_playback_daily_stats_episode_id ON playback_daily_stats(episode_id);
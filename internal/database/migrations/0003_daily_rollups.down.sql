@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rollup_runs;
+DROP TABLE IF EXISTS series_daily_rollups;
+DROP TABLE IF EXISTS episode_daily_rollups;
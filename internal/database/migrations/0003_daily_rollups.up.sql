@@ -0,0 +1,41 @@
+-- Crea los agregados diarios por episodio y por serie que
+-- mantiene el paquete rollups (worker en background + CLI cmd/rollup para backfill).
+-- rollup_runs registra el último build de cada día.
+CREATE TABLE IF NOT EXISTS episode_daily_rollups (
+    day            DATE NOT NULL,
+    episode_id     UUID NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
+    series_id      UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    views          INTEGER NOT NULL DEFAULT 0,
+    unique_viewers INTEGER NOT NULL DEFAULT 0,
+    completions    INTEGER NOT NULL DEFAULT 0,
+    watch_seconds  BIGINT NOT NULL DEFAULT 0,
+    unlocks_coin   INTEGER NOT NULL DEFAULT 0,
+    unlocks_ad     INTEGER NOT NULL DEFAULT 0,
+    unlocks_sub    INTEGER NOT NULL DEFAULT 0,
+    coins_spent    INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, episode_id)
+);
+CREATE INDEX IF NOT EXISTS idx_episode_daily_rollups_episode_id ON episode_daily_rollups(episode_id);
+CREATE INDEX IF NOT EXISTS idx_episode_daily_rollups_series_day ON episode_daily_rollups(series_id, day);
+
+CREATE TABLE IF NOT EXISTS series_daily_rollups (
+    day            DATE NOT NULL,
+    series_id      UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    producer_id    UUID REFERENCES producers(id) ON DELETE SET NULL,
+    views          INTEGER NOT NULL DEFAULT 0,
+    unique_viewers INTEGER NOT NULL DEFAULT 0,
+    completions    INTEGER NOT NULL DEFAULT 0,
+    watch_seconds  BIGINT NOT NULL DEFAULT 0,
+    unlocks_coin   INTEGER NOT NULL DEFAULT 0,
+    unlocks_ad     INTEGER NOT NULL DEFAULT 0,
+    unlocks_sub    INTEGER NOT NULL DEFAULT 0,
+    coins_spent    INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, series_id)
+);
+CREATE INDEX IF NOT EXISTS idx_series_daily_rollups_series_day ON series_daily_rollups(series_id, day);
+CREATE INDEX IF NOT EXISTS idx_series_daily_rollups_producer_day ON series_daily_rollups(producer_id, day);
+
+CREATE TABLE IF NOT EXISTS rollup_runs (
+    day      DATE PRIMARY KEY,
+    built_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
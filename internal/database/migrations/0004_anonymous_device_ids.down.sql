@@ -0,0 +1,9 @@
+-- La actividad de invitados sin cuenta no puede conservarse sin device_id
+DROP INDEX IF EXISTS idx_favorites_device_series;
+DELETE FROM favorites WHERE user_id IS NULL;
+ALTER TABLE favorites DROP CONSTRAINT IF EXISTS favorites_owner_check;
+ALTER TABLE favorites DROP COLUMN IF EXISTS device_id;
+ALTER TABLE favorites ALTER COLUMN user_id SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_views_device_episode;
+ALTER TABLE views DROP COLUMN IF EXISTS device_id;
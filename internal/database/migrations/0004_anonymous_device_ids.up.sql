@@ -0,0 +1,16 @@
+-- Permite registrar vistas, progreso y favoritos de
+-- invitados identificados por device ID (user_id NULL). Al iniciar sesión se
+-- fusionan en la cuenta (devices.Repository.MergeIntoUser).
+ALTER TABLE views ADD COLUMN IF NOT EXISTS device_id UUID;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_views_device_episode
+    ON views (device_id, episode_id)
+    WHERE user_id IS NULL AND device_id IS NOT NULL;
+
+ALTER TABLE favorites ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE favorites ADD COLUMN IF NOT EXISTS device_id UUID;
+ALTER TABLE favorites DROP CONSTRAINT IF EXISTS favorites_owner_check;
+ALTER TABLE favorites ADD CONSTRAINT favorites_owner_check
+    CHECK (user_id IS NOT NULL OR device_id IS NOT NULL);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_favorites_device_series
+    ON favorites (device_id, series_id)
+    WHERE user_id IS NULL AND device_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- Las cuentas invitadas no vinculadas se eliminan (no tienen identidad real)
+DELETE FROM users WHERE is_guest = TRUE;
+DROP INDEX IF EXISTS idx_users_guest_device;
+ALTER TABLE users DROP COLUMN IF EXISTS guest_device_id;
+ALTER TABLE users DROP COLUMN IF EXISTS is_guest;
@@ -0,0 +1,8 @@
+-- Agrega las cuentas invitadas: usuarios ligados a un device ID
+-- sin identidad Firebase. firebase_uid/email reciben valores sintéticos ("guest:<device>")
+-- hasta que el invitado vincula una cuenta real (guests.Repository.Upgrade / MergeInto).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_guest BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS guest_device_id UUID;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_guest_device
+    ON users (guest_device_id)
+    WHERE is_guest = TRUE;
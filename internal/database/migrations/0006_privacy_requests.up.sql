@@ -0,0 +1,31 @@
+-- Soporta las solicitudes de titulares de datos (GDPR/LGPD).
+-- data_exports: archivo ZIP con los datos personales, generado en segundo plano.
+-- account_deletions: borrado con período de gracia; sin FK a users para que el
+-- registro de auditoría sobreviva al borrado del usuario.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    status       VARCHAR(16) NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'processing', 'ready', 'failed', 'expired')),
+    file_path    TEXT,
+    size_bytes   BIGINT,
+    error        TEXT,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at   TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports(status);
+
+CREATE TABLE IF NOT EXISTS account_deletions (
+    user_id       UUID PRIMARY KEY,
+    requested_by  UUID,
+    status        VARCHAR(16) NOT NULL DEFAULT 'pending'
+                  CHECK (status IN ('pending', 'cancelled', 'completed', 'failed')),
+    requested_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    scheduled_for TIMESTAMP NOT NULL,
+    completed_at  TIMESTAMP,
+    error         TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_account_deletions_due ON account_deletions(scheduled_for) WHERE status = 'pending';
@@ -0,0 +1,26 @@
+-- Trabajos de transcodificación propia (TRANSCODE_ENABLED): el upload se guarda en
+-- disco y un worker lo convierte con ffmpeg en una escalera HLS vertical + póster
+-- que luego entrega al VideoProvider configurado. updated_at hace de heartbeat
+-- para re-encolar trabajos huérfanos tras un reinicio.
+CREATE TABLE IF NOT EXISTS transcode_jobs (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    episode_id       UUID NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
+    created_by       UUID REFERENCES users(id) ON DELETE SET NULL,
+    source_path      TEXT NOT NULL,
+    status           VARCHAR(16) NOT NULL DEFAULT 'queued'
+                     CHECK (status IN ('queued', 'transcoding', 'publishing', 'completed', 'failed')),
+    progress         REAL NOT NULL DEFAULT 0,
+    duration_seconds REAL,
+    width            INTEGER,
+    height           INTEGER,
+    renditions       TEXT[],
+    external_id      VARCHAR(255),
+    error            TEXT,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at       TIMESTAMP,
+    completed_at     TIMESTAMP,
+    updated_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_transcode_jobs_episode ON transcode_jobs(episode_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_transcode_jobs_queued ON transcode_jobs(created_at) WHERE status = 'queued';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_episodes_video_pending;
+ALTER TABLE episodes DROP CONSTRAINT IF EXISTS episodes_video_status_check;
+ALTER TABLE episodes DROP COLUMN IF EXISTS ready_at;
+ALTER TABLE episodes DROP COLUMN IF EXISTS video_status_updated_at;
+ALTER TABLE episodes DROP COLUMN IF EXISTS video_error;
+ALTER TABLE episodes DROP COLUMN IF EXISTS video_status;
@@ -0,0 +1,19 @@
+-- Ciclo de vida del video de cada episodio: el proveedor puede seguir codificando
+-- después del upload. Lo actualizan los webhooks de los proveedores y un poller
+-- (videostatus.Service). ready_at guarda la primera vez que el episodio quedó
+-- reproducible: la notificación a los fans se envía una sola vez.
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS video_status VARCHAR(16) NOT NULL DEFAULT 'created';
+ALTER TABLE episodes DROP CONSTRAINT IF EXISTS episodes_video_status_check;
+ALTER TABLE episodes ADD CONSTRAINT episodes_video_status_check
+    CHECK (video_status IN ('created', 'uploading', 'processing', 'ready', 'failed'));
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS video_error TEXT;
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS video_status_updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS ready_at TIMESTAMP;
+
+-- Los episodios que ya tenían video se consideran listos (y ya notificados)
+UPDATE episodes SET video_status = 'ready', ready_at = created_at
+WHERE COALESCE(video_id_bunny, '') <> '' AND video_status = 'created';
+
+CREATE INDEX IF NOT EXISTS idx_episodes_video_pending
+    ON episodes (video_status_updated_at)
+    WHERE video_status IN ('uploading', 'processing');
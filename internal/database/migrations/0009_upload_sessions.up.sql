@@ -0,0 +1,29 @@
+-- Uploads reanudables (protocolo TUS) de videos desde el admin: el archivo se arma
+-- en disco (UPLOAD_SESSIONS_DIR) y upload_offset registra los bytes confirmados para
+-- retomar tras un corte. Con proveedores que aceptan partes (S3, Cloudflare) cada
+-- parte completa se reenvía en el momento: provider_state es el estado opaco de ese
+-- upload y provider_offset los bytes ya entregados. Al completarse el archivo la
+-- sesión pasa a 'finalizing' y un worker lo entrega al proveedor (o a transcode).
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    episode_id       UUID NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
+    created_by       UUID REFERENCES users(id) ON DELETE SET NULL,
+    upload_length    BIGINT NOT NULL CHECK (upload_length > 0),
+    upload_offset    BIGINT NOT NULL DEFAULT 0,
+    filename         VARCHAR(255),
+    content_type     VARCHAR(100) NOT NULL DEFAULT 'video/mp4',
+    status           VARCHAR(16) NOT NULL DEFAULT 'uploading'
+                     CHECK (status IN ('uploading', 'finalizing', 'completed', 'failed', 'expired', 'aborted')),
+    video_id         VARCHAR(255),
+    provider_state   TEXT,
+    provider_offset  BIGINT NOT NULL DEFAULT 0,
+    stream_error     TEXT,
+    transcode_job_id UUID REFERENCES transcode_jobs(id) ON DELETE SET NULL,
+    error            TEXT,
+    created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at       TIMESTAMP NOT NULL,
+    CHECK (upload_offset BETWEEN 0 AND upload_length)
+);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_episode ON upload_sessions(episode_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_open ON upload_sessions(expires_at) WHERE status IN ('uploading', 'finalizing');
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS episode_thumbnails;
+ALTER TABLE episodes DROP COLUMN IF EXISTS cover_url;
+ALTER TABLE episodes DROP COLUMN IF EXISTS media_probed_at;
+ALTER TABLE episodes DROP COLUMN IF EXISTS bitrate;
+ALTER TABLE episodes DROP COLUMN IF EXISTS audio_codec;
+ALTER TABLE episodes DROP COLUMN IF EXISTS video_codec;
+ALTER TABLE episodes DROP COLUMN IF EXISTS aspect_ratio;
+ALTER TABLE episodes DROP COLUMN IF EXISTS height;
+ALTER TABLE episodes DROP COLUMN IF EXISTS width;
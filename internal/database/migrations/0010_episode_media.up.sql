@@ -0,0 +1,23 @@
+-- Datos técnicos del video de cada episodio, leídos con ffprobe cuando el upload
+-- pasa por la API (MEDIA_PROBE_ENABLED), y portadas candidatas extraídas del video
+-- entre las que el productor elige la portada del episodio (cover_url).
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS width INTEGER;
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS height INTEGER;
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS aspect_ratio VARCHAR(16);
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS video_codec VARCHAR(32);
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS audio_codec VARCHAR(32);
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS bitrate BIGINT;
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS media_probed_at TIMESTAMP;
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS cover_url TEXT;
+
+CREATE TABLE IF NOT EXISTS episode_thumbnails (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    episode_id UUID NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    at_seconds REAL NOT NULL,
+    url        TEXT NOT NULL,
+    width      INTEGER NOT NULL,
+    height     INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_episode_thumbnails_episode ON episode_thumbnails(episode_id, position);
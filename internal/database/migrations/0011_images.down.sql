@@ -0,0 +1,4 @@
+ALTER TABLE producers DROP COLUMN IF EXISTS logo_image_id;
+ALTER TABLE series DROP COLUMN IF EXISTS horizontal_poster_image_id;
+ALTER TABLE series DROP COLUMN IF EXISTS vertical_poster_image_id;
+DROP TABLE IF EXISTS images;
@@ -0,0 +1,21 @@
+-- Imágenes subidas por POST /admin/images (posters, banners, logos). Se guardan una
+-- sola vez por contenido (content_hash = SHA-256 de los bytes subidos) y se sirven en
+-- variantes redimensionadas; variants guarda { nombre: {width, height, url, webp_url} }.
+CREATE TABLE IF NOT EXISTS images (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    content_hash VARCHAR(64) NOT NULL UNIQUE,
+    format       VARCHAR(16) NOT NULL,
+    width        INTEGER NOT NULL,
+    height       INTEGER NOT NULL,
+    has_alpha    BOOLEAN NOT NULL DEFAULT FALSE,
+    size_bytes   BIGINT NOT NULL,
+    variants     JSONB NOT NULL DEFAULT '{}',
+    uploaded_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Series y productores referencian imágenes por ID; las columnas de URL existentes
+-- (vertical_poster, horizontal_poster, logo_url) siguen guardando la variante principal.
+ALTER TABLE series ADD COLUMN IF NOT EXISTS vertical_poster_image_id UUID REFERENCES images(id) ON DELETE SET NULL;
+ALTER TABLE series ADD COLUMN IF NOT EXISTS horizontal_poster_image_id UUID REFERENCES images(id) ON DELETE SET NULL;
+ALTER TABLE producers ADD COLUMN IF NOT EXISTS logo_image_id UUID REFERENCES images(id) ON DELETE SET NULL;
@@ -0,0 +1,21 @@
+-- Pistas adicionales de cada episodio: subtítulos (WebVTT, los SRT se convierten al
+-- subirlos) y audio alternativo en otros idiomas. Una pista por tipo e idioma; el
+-- archivo vive en el proveedor junto al video video_id, y solo se ofrece mientras
+-- ese siga siendo el video del episodio (un video nuevo puede tener otro timing).
+CREATE TABLE IF NOT EXISTS episode_tracks (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    episode_id   UUID NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
+    kind         VARCHAR(16) NOT NULL CHECK (kind IN ('subtitles', 'audio')),
+    language     VARCHAR(35) NOT NULL,
+    label        VARCHAR(100) NOT NULL,
+    is_default   BOOLEAN NOT NULL DEFAULT FALSE,
+    video_id     VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    ext          VARCHAR(8) NOT NULL,
+    size_bytes   BIGINT NOT NULL,
+    cue_count    INTEGER,
+    created_by   UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (episode_id, kind, language)
+);
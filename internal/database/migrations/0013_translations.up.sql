@@ -0,0 +1,27 @@
+-- Traducciones de los textos de series y episodios por locale (BCP 47: pt-BR, en,
+-- es-419). Las columnas de series/episodes quedan en el idioma por defecto
+-- (DEFAULT_LOCALE); un campo NULL en la traducción cae al siguiente locale de la
+-- cadena de fallback.
+CREATE TABLE IF NOT EXISTS series_translations (
+    series_id   UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    locale      VARCHAR(35) NOT NULL,
+    title       VARCHAR(255),
+    description TEXT,
+    updated_by  UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (series_id, locale)
+);
+
+CREATE TABLE IF NOT EXISTS episode_translations (
+    episode_id UUID NOT NULL REFERENCES episodes(id) ON DELETE CASCADE,
+    locale     VARCHAR(35) NOT NULL,
+    title      VARCHAR(255),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (episode_id, locale)
+);
+
+-- La búsqueda localizada filtra por locale antes que por título
+CREATE INDEX IF NOT EXISTS idx_series_translations_locale ON series_translations(locale);
@@ -0,0 +1,15 @@
+DROP TRIGGER IF EXISTS trg_search_producers ON producers;
+DROP TRIGGER IF EXISTS trg_search_episode_translations ON episode_translations;
+DROP TRIGGER IF EXISTS trg_search_series_translations ON series_translations;
+DROP TRIGGER IF EXISTS trg_search_episodes ON episodes;
+DROP TRIGGER IF EXISTS trg_search_series ON series;
+DROP FUNCTION IF EXISTS search_enqueue_by_producer();
+DROP FUNCTION IF EXISTS search_enqueue_by_episode();
+DROP FUNCTION IF EXISTS search_enqueue_by_series_id();
+DROP FUNCTION IF EXISTS search_enqueue_series();
+DROP TABLE IF EXISTS search_queue;
+DROP TABLE IF EXISTS series_search;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS qenti_simple;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS qenti_en;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS qenti_pt;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS qenti_es;
@@ -0,0 +1,124 @@
+-- Búsqueda de series: índice full-text por idioma (sin acentos, con stemming de
+-- español, portugués o inglés) más similitud de trigramas para tolerar errores de
+-- tipeo. El índice lo mantiene internal/pkg/search: una fila por serie y locale
+-- (el idioma por defecto y cada idioma con traducciones) con título, productor,
+-- títulos de episodios y descripción ponderados en ese orden.
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Configuraciones de texto que ignoran los acentos ("accion" encuentra "acción")
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'qenti_es') THEN
+        CREATE TEXT SEARCH CONFIGURATION qenti_es (COPY = spanish);
+        ALTER TEXT SEARCH CONFIGURATION qenti_es
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'qenti_pt') THEN
+        CREATE TEXT SEARCH CONFIGURATION qenti_pt (COPY = portuguese);
+        ALTER TEXT SEARCH CONFIGURATION qenti_pt
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, portuguese_stem;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'qenti_en') THEN
+        CREATE TEXT SEARCH CONFIGURATION qenti_en (COPY = english);
+        ALTER TEXT SEARCH CONFIGURATION qenti_en
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, english_stem;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'qenti_simple') THEN
+        CREATE TEXT SEARCH CONFIGURATION qenti_simple (COPY = simple);
+        ALTER TEXT SEARCH CONFIGURATION qenti_simple
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, simple;
+    END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS series_search (
+    series_id      UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    locale         VARCHAR(35) NOT NULL,
+    config         REGCONFIG NOT NULL,
+    -- Textos resueltos para el locale (con fallback), para los fragmentos resaltados
+    title          TEXT NOT NULL,
+    description    TEXT NOT NULL DEFAULT '',
+    producer_name  TEXT NOT NULL DEFAULT '',
+    episode_titles TEXT NOT NULL DEFAULT '',
+    -- search_text título y productor en minúsculas y sin acentos, para los trigramas
+    search_text    TEXT NOT NULL,
+    document       TSVECTOR NOT NULL,
+    updated_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (series_id, locale)
+);
+CREATE INDEX IF NOT EXISTS idx_series_search_document ON series_search USING GIN (document);
+CREATE INDEX IF NOT EXISTS idx_series_search_trgm ON series_search USING GIN (search_text gin_trgm_ops);
+
+-- Cola de series a reindexar: la llenan los triggers de los textos que entran al
+-- índice y la vacía el worker de internal/pkg/search.
+CREATE TABLE IF NOT EXISTS search_queue (
+    series_id UUID PRIMARY KEY,
+    queued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE OR REPLACE FUNCTION search_enqueue_series() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO search_queue (series_id) VALUES (NEW.id) ON CONFLICT DO NOTHING;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION search_enqueue_by_series_id() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        INSERT INTO search_queue (series_id) VALUES (OLD.series_id) ON CONFLICT DO NOTHING;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        INSERT INTO search_queue (series_id) VALUES (NEW.series_id) ON CONFLICT DO NOTHING;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION search_enqueue_by_episode() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO search_queue (series_id)
+    SELECT e.series_id FROM episodes e
+    WHERE e.id = CASE WHEN TG_OP = 'DELETE' THEN OLD.episode_id ELSE NEW.episode_id END
+    ON CONFLICT DO NOTHING;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION search_enqueue_by_producer() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO search_queue (series_id)
+    SELECT s.id FROM series s WHERE s.producer_id = NEW.id
+    ON CONFLICT DO NOTHING;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_search_series ON series;
+CREATE TRIGGER trg_search_series
+    AFTER INSERT OR UPDATE OF title, description, producer_id ON series
+    FOR EACH ROW EXECUTE FUNCTION search_enqueue_series();
+
+DROP TRIGGER IF EXISTS trg_search_episodes ON episodes;
+CREATE TRIGGER trg_search_episodes
+    AFTER INSERT OR UPDATE OF title, series_id OR DELETE ON episodes
+    FOR EACH ROW EXECUTE FUNCTION search_enqueue_by_series_id();
+
+DROP TRIGGER IF EXISTS trg_search_series_translations ON series_translations;
+CREATE TRIGGER trg_search_series_translations
+    AFTER INSERT OR UPDATE OR DELETE ON series_translations
+    FOR EACH ROW EXECUTE FUNCTION search_enqueue_by_series_id();
+
+DROP TRIGGER IF EXISTS trg_search_episode_translations ON episode_translations;
+CREATE TRIGGER trg_search_episode_translations
+    AFTER INSERT OR UPDATE OR DELETE ON episode_translations
+    FOR EACH ROW EXECUTE FUNCTION search_enqueue_by_episode();
+
+DROP TRIGGER IF EXISTS trg_search_producers ON producers;
+CREATE TRIGGER trg_search_producers
+    AFTER UPDATE OF name ON producers
+    FOR EACH ROW EXECUTE FUNCTION search_enqueue_by_producer();
+
+-- Indexar el catálogo existente en la primera pasada del worker
+INSERT INTO search_queue (series_id) SELECT id FROM series ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+-- Registro de búsquedas de la app: qué se buscó, cuántos resultados hubo y qué serie
+-- se abrió desde los resultados. Alimenta las sugerencias (búsquedas populares) y el
+-- reporte de búsquedas del admin. normalized_query va en minúsculas y sin acentos.
+CREATE TABLE IF NOT EXISTS search_queries (
+    id                UUID PRIMARY KEY,
+    query             VARCHAR(200) NOT NULL,
+    normalized_query  VARCHAR(200) NOT NULL,
+    locale            VARCHAR(35) NOT NULL,
+    result_count      INTEGER NOT NULL,
+    producer_id       UUID REFERENCES producers(id) ON DELETE SET NULL,
+    user_id           UUID REFERENCES users(id) ON DELETE SET NULL,
+    device_id         UUID,
+    clicked_series_id UUID REFERENCES series(id) ON DELETE SET NULL,
+    clicked_position  INTEGER,
+    clicked_at        TIMESTAMP,
+    created_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_search_queries_created_at ON search_queries(created_at);
+CREATE INDEX IF NOT EXISTS idx_search_queries_normalized ON search_queries(normalized_query, created_at);
@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS trg_search_tags ON tags;
+DROP TRIGGER IF EXISTS trg_search_genres ON genres;
+DROP TRIGGER IF EXISTS trg_search_series_tags ON series_tags;
+DROP TRIGGER IF EXISTS trg_search_series_genres ON series_genres;
+DROP FUNCTION IF EXISTS search_enqueue_by_tag();
+DROP FUNCTION IF EXISTS search_enqueue_by_genre();
+ALTER TABLE series_search DROP COLUMN IF EXISTS taxonomy;
+DROP TABLE IF EXISTS series_tags;
+DROP TABLE IF EXISTS series_genres;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS genres;
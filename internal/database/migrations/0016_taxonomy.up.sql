@@ -0,0 +1,77 @@
+-- Taxonomía de contenido: géneros curados por el super_admin (Romance, Venganza,
+-- CEO...) y etiquetas libres que cada productor asigna a sus series. La app los
+-- identifica por slug (?genre=romance&tag=segunda-oportunidad) y ambos entran al
+-- índice de búsqueda junto al nombre del productor.
+CREATE TABLE IF NOT EXISTS genres (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug         VARCHAR(60) NOT NULL UNIQUE,
+    name         VARCHAR(100) NOT NULL,
+    -- translations nombre en otros idiomas: {"pt-BR": "Vingança", "en": "Revenge"}
+    translations JSONB NOT NULL DEFAULT '{}',
+    position     INTEGER NOT NULL DEFAULT 0,
+    is_active    BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug       VARCHAR(60) NOT NULL UNIQUE,
+    name       VARCHAR(60) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS series_genres (
+    series_id UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    genre_id  UUID NOT NULL REFERENCES genres(id) ON DELETE CASCADE,
+    PRIMARY KEY (series_id, genre_id)
+);
+CREATE INDEX IF NOT EXISTS idx_series_genres_genre ON series_genres(genre_id);
+
+CREATE TABLE IF NOT EXISTS series_tags (
+    series_id UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    tag_id    UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (series_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_series_tags_tag ON series_tags(tag_id);
+
+-- Géneros y etiquetas en el índice de búsqueda (peso B, como el productor)
+ALTER TABLE series_search ADD COLUMN IF NOT EXISTS taxonomy TEXT NOT NULL DEFAULT '';
+
+CREATE OR REPLACE FUNCTION search_enqueue_by_genre() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO search_queue (series_id)
+    SELECT sg.series_id FROM series_genres sg WHERE sg.genre_id = NEW.id
+    ON CONFLICT DO NOTHING;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION search_enqueue_by_tag() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO search_queue (series_id)
+    SELECT st.series_id FROM series_tags st WHERE st.tag_id = NEW.id
+    ON CONFLICT DO NOTHING;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_search_series_genres ON series_genres;
+CREATE TRIGGER trg_search_series_genres
+    AFTER INSERT OR DELETE ON series_genres
+    FOR EACH ROW EXECUTE FUNCTION search_enqueue_by_series_id();
+
+DROP TRIGGER IF EXISTS trg_search_series_tags ON series_tags;
+CREATE TRIGGER trg_search_series_tags
+    AFTER INSERT OR DELETE ON series_tags
+    FOR EACH ROW EXECUTE FUNCTION search_enqueue_by_series_id();
+
+DROP TRIGGER IF EXISTS trg_search_genres ON genres;
+CREATE TRIGGER trg_search_genres
+    AFTER UPDATE OF name, translations, is_active ON genres
+    FOR EACH ROW EXECUTE FUNCTION search_enqueue_by_genre();
+
+DROP TRIGGER IF EXISTS trg_search_tags ON tags;
+CREATE TRIGGER trg_search_tags
+    AFTER UPDATE OF name ON tags
+    FOR EACH ROW EXECUTE FUNCTION search_enqueue_by_tag();
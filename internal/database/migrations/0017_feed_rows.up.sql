@@ -0,0 +1,39 @@
+-- Composición del home de la app: filas ordenadas que arma el admin (el de cada
+-- productor para su app, el super_admin para la de plataforma). Cada fila es una
+-- lista curada, un banner hero, un género o una fila algorítmica (trending,
+-- estrenos, más vistas, continuar viendo, recomendados), con título traducible,
+-- segmentación (país, plataforma, premium o no) y ventana de publicación.
+CREATE TABLE IF NOT EXISTS feed_rows (
+    id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- producer_id app del productor; NULL = app de plataforma
+    producer_id        UUID REFERENCES producers(id) ON DELETE CASCADE,
+    position           INTEGER NOT NULL DEFAULT 0,
+    kind               VARCHAR(30) NOT NULL
+                       CHECK (kind IN ('hero', 'curated', 'genre', 'trending', 'new_releases',
+                                       'most_viewed', 'continue_watching', 'recommended')),
+    title              VARCHAR(100) NOT NULL DEFAULT '',
+    title_translations JSONB NOT NULL DEFAULT '{}',
+    genre_id           UUID REFERENCES genres(id) ON DELETE CASCADE,
+    item_limit         INTEGER NOT NULL DEFAULT 20,
+    -- Segmentación: listas vacías = todos
+    countries          TEXT[] NOT NULL DEFAULT '{}',
+    platforms          TEXT[] NOT NULL DEFAULT '{}',
+    audience           VARCHAR(10) NOT NULL DEFAULT 'all' CHECK (audience IN ('all', 'premium', 'free')),
+    starts_at          TIMESTAMP,
+    ends_at            TIMESTAMP,
+    is_active          BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by         UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_feed_rows_producer ON feed_rows(producer_id, position);
+
+-- Series de las filas curadas y hero, en orden. image_id arte propio del banner
+-- (si no, se usa el poster horizontal de la serie).
+CREATE TABLE IF NOT EXISTS feed_row_items (
+    row_id    UUID NOT NULL REFERENCES feed_rows(id) ON DELETE CASCADE,
+    series_id UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    position  INTEGER NOT NULL,
+    image_id  UUID REFERENCES images(id) ON DELETE SET NULL,
+    PRIMARY KEY (row_id, series_id)
+);
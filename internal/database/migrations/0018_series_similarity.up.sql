@@ -0,0 +1,16 @@
+-- Similitud entre series por filtrado colaborativo ítem a ítem: dos series se
+-- parecen cuando las mismas personas (usuarios o invitados) las ven, las marcan
+-- como favoritas o desbloquean sus episodios. La recalcula periódicamente
+-- recommendations.Service.Rebuild; solo se guardan los vecinos más parecidos.
+CREATE TABLE IF NOT EXISTS series_similarity (
+    series_id         UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    similar_series_id UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    -- score similitud coseno entre los vectores de interacción (0-1)
+    score             DOUBLE PRECISION NOT NULL,
+    -- co_viewers personas que interactuaron con ambas series
+    co_viewers        INTEGER NOT NULL,
+    computed_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (series_id, similar_series_id),
+    CHECK (series_id <> similar_series_id)
+);
+CREATE INDEX IF NOT EXISTS idx_series_similarity_series_score ON series_similarity(series_id, score DESC);
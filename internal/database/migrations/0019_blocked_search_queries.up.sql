@@ -0,0 +1,8 @@
+-- Búsquedas que nunca se sugieren en el autocompletado aunque sean populares
+-- (spam, insultos, nombres de terceros). Las carga el super_admin; normalized_query
+-- va normalizada igual que search_queries.normalized_query.
+CREATE TABLE IF NOT EXISTS blocked_search_queries (
+    normalized_query VARCHAR(200) PRIMARY KEY,
+    blocked_by       UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS hls_package_jobs;
@@ -0,0 +1,15 @@
+-- Empaquetado HLS de los originales subidos a S3 (S3_PACKAGE_HLS): cada video
+-- completo encola un trabajo que un worker remuxa con ffmpeg y publica. Si falla se
+-- reintenta desde run_after; updated_at hace de heartbeat para retomar los trabajos
+-- de instancias caídas.
+CREATE TABLE IF NOT EXISTS hls_package_jobs (
+    external_id VARCHAR(255) PRIMARY KEY,
+    status      VARCHAR(16) NOT NULL DEFAULT 'queued'
+                CHECK (status IN ('queued', 'packaging', 'completed', 'failed')),
+    attempts    INTEGER NOT NULL DEFAULT 0,
+    error       TEXT,
+    run_after   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_hls_package_jobs_queued ON hls_package_jobs(run_after) WHERE status = 'queued';
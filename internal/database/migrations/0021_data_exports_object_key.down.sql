@@ -0,0 +1,2 @@
+UPDATE data_exports SET status = 'expired', object_key = NULL WHERE object_key IS NOT NULL;
+ALTER TABLE data_exports RENAME COLUMN object_key TO file_path;
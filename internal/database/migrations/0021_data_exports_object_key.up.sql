@@ -0,0 +1,7 @@
+-- Los ZIP de exportación pasan de un directorio local de la instancia que los
+-- generó a un ObjectStore compartido (PRIVACY_EXPORT_STORAGE): data_exports guarda
+-- la clave en vez de la ruta. Las rutas viejas no tienen clave equivalente, así
+-- que esas exportaciones se dan por vencidas (los ZIP que queden en
+-- PRIVACY_EXPORT_DIR/<user_id>/ se pueden borrar a mano).
+ALTER TABLE data_exports RENAME COLUMN file_path TO object_key;
+UPDATE data_exports SET status = 'expired', object_key = NULL WHERE object_key IS NOT NULL;
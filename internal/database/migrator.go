@@ -0,0 +1,333 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationLockKey clave del advisory lock de Postgres que serializa las migraciones
+// entre réplicas que arrancan a la vez (valor arbitrario, fijo para toda la app).
+const migrationLockKey int64 = 0x71656e7469 // "qenti"
+
+// migrationFileRe reconoce "0001_nombre.up.sql" / "0001_nombre.down.sql".
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration es una migración numerada con su SQL de subida y (opcional) de bajada.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// checksum identifica el contenido del SQL de subida, para detectar archivos ya
+// aplicados que se editaron después.
+func (m Migration) checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrChecksumMismatch una migración ya aplicada se editó después (ver
+// `migrate status`). Se resuelve revirtiendo el archivo y creando una migración
+// nueva, o con `migrate accept` si el cambio no afecta al esquema.
+var ErrChecksumMismatch = errors.New("applied migrations were modified")
+
+// MigrationStatus estado de una migración frente a schema_migrations.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified el archivo cambió después de aplicarse (checksum distinto)
+	Modified bool
+	// Orphan está registrada en la DB pero ya no existe el archivo
+	Orphan bool
+}
+
+// Migrator aplica y revierte migraciones versionadas registrándolas en schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator crea un Migrator con las migraciones embebidas en el binario.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// LoadMigrations lee los archivos NNNN_nombre.{up,down}.sql de dir, ordenados por versión.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations dir: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := migrationFileRe.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", e.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// withLock ejecuta fn en una conexión dedicada que tiene el advisory lock tomado y
+// la tabla schema_migrations creada.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	// Con context.Background(): el unlock debe ejecutarse aunque ctx ya esté cancelado
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       VARCHAR(255) NOT NULL,
+			checksum   VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	result := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		result[version] = a
+	}
+	return result, rows.Err()
+}
+
+// runInTx ejecuta el SQL de una migración y su registro en schema_migrations en
+// una misma transacción: o queda todo aplicado o nada.
+func runInTx(ctx context.Context, conn *sql.Conn, body, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// modified devuelve "NNNN_nombre" de las migraciones aplicadas cuyo archivo cambió
+// después de aplicarse.
+func (m *Migrator) modified(applied map[int]appliedMigration) []string {
+	var names []string
+	for _, mig := range m.migrations {
+		if a, ok := applied[mig.Version]; ok && a.checksum != mig.checksum() {
+			names = append(names, fmt.Sprintf("%04d_%s", mig.Version, mig.Name))
+		}
+	}
+	return names
+}
+
+// Up aplica las migraciones pendientes hasta target inclusive (0 = todas) y
+// devuelve las que aplicó. Falla con ErrChecksumMismatch, sin aplicar nada, si una
+// migración ya aplicada se editó: el cambio nunca llegaría a la base.
+func (m *Migrator) Up(ctx context.Context, target int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if names := m.modified(applied); len(names) > 0 {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(names, ", "))
+		}
+		for _, mig := range m.migrations {
+			if target > 0 && mig.Version > target {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := runInTx(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, mig.checksum(),
+			); err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down revierte las últimas `steps` migraciones aplicadas (en orden inverso) y
+// devuelve las que revirtió.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %04d_%s has no down file", mig.Version, mig.Name)
+			}
+			if err := runInTx(ctx, conn, mig.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, mig.Version,
+			); err != nil {
+				return fmt.Errorf("rollback of %04d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// AcceptModified registra el checksum actual de las migraciones aplicadas que se
+// editaron (para cambios que no alteran el esquema, como comentarios) y devuelve
+// cuáles actualizó. Los cambios de esquema van en una migración nueva.
+func (m *Migrator) AcceptModified(ctx context.Context) ([]string, error) {
+	var names []string
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if a, ok := applied[mig.Version]; !ok || a.checksum == mig.checksum() {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx,
+				`UPDATE schema_migrations SET checksum = $2 WHERE version = $1`, mig.Version, mig.checksum(),
+			); err != nil {
+				return fmt.Errorf("failed to update checksum of %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			names = append(names, fmt.Sprintf("%04d_%s", mig.Version, mig.Name))
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Redo revierte y vuelve a aplicar la última migración aplicada.
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	reverted, err := m.Down(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(reverted) == 0 {
+		return nil, nil
+	}
+	if _, err := m.Up(ctx, reverted[0].Version); err != nil {
+		return nil, err
+	}
+	return &reverted[0], nil
+}
+
+// Status devuelve el estado de cada migración conocida, más las registradas en la
+// DB cuyo archivo ya no existe.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var result []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+			if a, ok := applied[mig.Version]; ok {
+				at := a.appliedAt
+				s.Applied = true
+				s.AppliedAt = &at
+				s.Modified = a.checksum != mig.checksum()
+				delete(applied, mig.Version)
+			}
+			result = append(result, s)
+		}
+		for version, a := range applied {
+			at := a.appliedAt
+			result = append(result, MigrationStatus{Version: version, Name: a.name, Applied: true, AppliedAt: &at, Orphan: true})
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+		return nil
+	})
+	return result, err
+}
@@ -89,6 +89,37 @@ func OptionalAuth(jwtService *jwt.Service) gin.HandlerFunc {
 	}
 }
 
+// AuthIfPresent es OptionalAuth para rutas que aceptan un usuario o un dispositivo
+// anónimo (progreso, favoritos): sin header Authorization sigue sin datos de usuario,
+// pero un token inválido o vencido responde 401 igual que RequireAuth, en vez de
+// caer en silencio al device ID y escribir en la cuenta equivocada.
+func AuthIfPresent(jwtService *jwt.Service) gin.HandlerFunc {
+	requireAuth := RequireAuth(jwtService)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		requireAuth(c)
+	}
+}
+
+// RejectGuest bloquea a las cuentas invitadas (rol "guest") en rutas que requieren una
+// identidad real (pagos, onboarding, invitaciones). Debe ir después de RequireAuth.
+func RejectGuest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") == "guest" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":         "Guest accounts must link an account first",
+				"link_required": true,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // RequireAdmin verifica que el usuario tenga acceso al panel:
 // acepta roles "admin", "super_admin" y "producer".
 // Establece producer_id en el contexto cuando el rol es "producer".
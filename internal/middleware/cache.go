@@ -0,0 +1,352 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/cache"
+	"github.com/qenti/qenti/internal/pkg/devices"
+)
+
+// purgeKey clave del backend compartido con el último Purge de cualquier réplica.
+const purgeKey = "http_cache:purge"
+
+// ResponseCache cache en memoria de las respuestas de los endpoints públicos del
+// catálogo, por ruta, query (incluido ?producer_slug=, el tenant), idioma y los
+// headers de los que dependa la respuesta. Además agrega ETag y Cache-Control a
+// las respuestas y contesta 304 a If-None-Match. Purge (o PurgeOnMutation en las
+// rutas del admin) lo vacía cuando cambia el catálogo; con SharePurges también
+// vacía el de las demás réplicas.
+type ResponseCache struct {
+	mu         sync.Mutex
+	entries    map[string]*cachedResponse
+	ttl        time.Duration
+	maxAge     int
+	maxEntries int
+	// generation cambia con cada Purge: una respuesta que empezó a armarse antes
+	// puede tener datos viejos y no se guarda.
+	generation uint64
+
+	shared    cache.Backend
+	lastPurge string // último purgeKey visto o publicado
+}
+
+type cachedResponse struct {
+	body        []byte
+	contentType string
+	etag        string
+	expires     time.Time
+}
+
+// NewResponseCache crea el cache: ttl es cuánto se reutiliza una respuesta en
+// memoria (0 = solo ETag, sin cache), maxAge los segundos de Cache-Control para
+// los clientes y el CDN, y maxEntries el máximo de respuestas guardadas.
+func NewResponseCache(ttl time.Duration, maxAge, maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		entries:    make(map[string]*cachedResponse),
+		ttl:        ttl,
+		maxAge:     maxAge,
+		maxEntries: maxEntries,
+	}
+}
+
+// Cache cachea la respuesta de la ruta. vary son los headers (además del idioma)
+// de los que depende la respuesta.
+func (rc *ResponseCache) Cache(vary ...string) gin.HandlerFunc {
+	return rc.handler(false, vary)
+}
+
+// CachePersonalized como Cache para los pedidos anónimos. Los que traen sesión o
+// device ID (header o cookie qenti_did) arman una respuesta propia: solo reciben
+// ETag y Cache-Control private.
+func (rc *ResponseCache) CachePersonalized(vary ...string) gin.HandlerFunc {
+	return rc.handler(true, vary)
+}
+
+// Purge descarta todas las respuestas guardadas y, con SharePurges, lo publica
+// para las demás réplicas.
+func (rc *ResponseCache) Purge() {
+	rc.purgeLocal()
+
+	rc.mu.Lock()
+	shared := rc.shared
+	token := uuid.NewString()
+	rc.lastPurge = token
+	rc.mu.Unlock()
+	if shared == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := shared.Set(ctx, purgeKey, []byte(token), 0, nil); err != nil {
+		log.Printf("middleware: publish response cache purge: %v", err)
+	}
+}
+
+func (rc *ResponseCache) purgeLocal() {
+	rc.mu.Lock()
+	rc.entries = make(map[string]*cachedResponse)
+	rc.generation++
+	rc.mu.Unlock()
+}
+
+// SharePurges publica los Purge en backend (el cache compartido, p. ej. Redis) y
+// cada interval aplica los publicados por otras réplicas, así un cambio del
+// catálogo se ve en todas en a lo sumo interval y no el TTL.
+func (rc *ResponseCache) SharePurges(ctx context.Context, backend cache.Backend, interval time.Duration) {
+	rc.mu.Lock()
+	rc.shared = backend
+	rc.mu.Unlock()
+
+	// El valor actual es el punto de partida: no es un Purge nuevo
+	if token, ok, err := backend.Get(ctx, purgeKey); err == nil && ok {
+		rc.mu.Lock()
+		rc.lastPurge = string(token)
+		rc.mu.Unlock()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				token, ok, err := backend.Get(ctx, purgeKey)
+				if err != nil {
+					log.Printf("middleware: read response cache purge: %v", err)
+					continue
+				}
+				if !ok {
+					continue
+				}
+				rc.mu.Lock()
+				changed := string(token) != rc.lastPurge
+				rc.lastPurge = string(token)
+				rc.mu.Unlock()
+				if changed {
+					rc.purgeLocal()
+				}
+			}
+		}
+	}()
+}
+
+// PurgeOnMutation vacía el cache después de cada pedido que modifica datos
+// (POST, PUT, PATCH, DELETE) y termina bien. Va en los grupos de rutas del admin.
+func (rc *ResponseCache) PurgeOnMutation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if c.Writer.Status() < http.StatusBadRequest {
+			rc.Purge()
+		}
+	}
+}
+
+func (rc *ResponseCache) handler(personalized bool, vary []string) gin.HandlerFunc {
+	varyHeader := strings.Join(vary, ", ")
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		// El idioma va en la clave; el Vary: Accept-Language lo agrega Locale
+		if varyHeader != "" {
+			c.Writer.Header().Add("Vary", varyHeader)
+		}
+		private := false
+		if personalized {
+			c.Writer.Header().Add("Vary", "Authorization, "+devices.HeaderName+", Cookie")
+			private = c.GetHeader("Authorization") != "" || c.GetHeader(devices.HeaderName) != ""
+			if cookie, err := c.Cookie(devices.CookieName); err == nil && cookie != "" {
+				private = true
+			}
+		}
+		shared := !private && rc.ttl > 0
+		key := rc.key(c, vary)
+
+		var generation uint64
+		if shared {
+			var cached *cachedResponse
+			if cached, generation = rc.get(key); cached != nil {
+				c.Header("X-Cache", "HIT")
+				rc.respond(c, http.StatusOK, cached.contentType, cached.etag, cached.body, private)
+				c.Abort()
+				return
+			}
+		}
+
+		original := c.Writer
+		buffered := &bufferedWriter{ResponseWriter: original}
+		func() {
+			// Restaurado también si el handler entra en pánico, para que Recovery responda
+			defer func() { c.Writer = original }()
+			c.Writer = buffered
+			c.Next()
+		}()
+
+		status := buffered.Status()
+		body := buffered.body.Bytes()
+		if status != http.StatusOK {
+			c.Writer.WriteHeader(status)
+			c.Writer.Write(body)
+			return
+		}
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		contentType := c.Writer.Header().Get("Content-Type")
+		if shared {
+			c.Header("X-Cache", "MISS")
+			rc.set(key, generation, &cachedResponse{
+				body:        append([]byte(nil), body...),
+				contentType: contentType,
+				etag:        etag,
+				expires:     time.Now().Add(rc.ttl),
+			})
+		}
+		rc.respond(c, status, contentType, etag, body, private)
+	}
+}
+
+// respond escribe la respuesta (o 304 si el cliente ya tiene esa versión).
+func (rc *ResponseCache) respond(c *gin.Context, status int, contentType, etag string, body []byte, private bool) {
+	c.Header("ETag", etag)
+	if private {
+		c.Header("Cache-Control", "private, no-cache")
+	} else {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", rc.maxAge))
+	}
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	if contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	c.Writer.WriteHeader(status)
+	c.Writer.Write(body)
+}
+
+// key identifica la respuesta: ruta, query ordenada, cadena de idiomas y headers vary.
+func (rc *ResponseCache) key(c *gin.Context, vary []string) string {
+	var b strings.Builder
+	b.WriteString(c.Request.URL.Path)
+	b.WriteByte('?')
+	b.WriteString(c.Request.URL.Query().Encode())
+	if v, ok := c.Get("locales"); ok {
+		if locales, ok := v.([]string); ok {
+			b.WriteString("|" + strings.Join(locales, ","))
+		}
+	}
+	for _, h := range vary {
+		b.WriteString("|" + c.GetHeader(h))
+	}
+	return b.String()
+}
+
+// get devuelve la respuesta guardada en key, o nil, y la generación actual para
+// guardar la respuesta que se arme si no estaba.
+func (rc *ResponseCache) get(key string) (*cachedResponse, uint64) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok {
+		return nil, rc.generation
+	}
+	if time.Now().After(entry.expires) {
+		delete(rc.entries, key)
+		return nil, rc.generation
+	}
+	return entry, rc.generation
+}
+
+// set guarda la respuesta salvo que haya habido un Purge desde generation.
+func (rc *ResponseCache) set(key string, generation uint64, entry *cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if generation != rc.generation {
+		return
+	}
+	if len(rc.entries) >= rc.maxEntries {
+		// Primero las vencidas; si no alcanza, cualquiera
+		now := time.Now()
+		for k, e := range rc.entries {
+			if now.After(e.expires) {
+				delete(rc.entries, k)
+			}
+		}
+		for k := range rc.entries {
+			if len(rc.entries) < rc.maxEntries {
+				break
+			}
+			delete(rc.entries, k)
+		}
+	}
+	rc.entries[key] = entry
+}
+
+// etagMatches indica si If-None-Match incluye etag (o es "*").
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter retiene status y cuerpo de la respuesta hasta calcular el ETag.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.status != 0 || w.body.Len() > 0
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qenti/qenti/internal/pkg/cache"
+)
+
+// cachedRouter sirve GET /catalog con rc; cada respuesta lleva el valor de version
+// al momento de armarla y, si se pasa, llama a during en medio del handler.
+func cachedRouter(rc *ResponseCache, version *int, during func()) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/catalog", rc.Cache(), func(c *gin.Context) {
+		v := *version
+		if during != nil {
+			during()
+		}
+		c.JSON(http.StatusOK, gin.H{"version": v})
+	})
+	return r
+}
+
+func get(r *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/catalog", nil))
+	return w
+}
+
+func TestResponseCacheDropsFillsStartedBeforePurge(t *testing.T) {
+	rc := NewResponseCache(time.Minute, 60, 100)
+	version := 1
+	purging := true
+	r := cachedRouter(rc, &version, func() {
+		// El catálogo cambia y se purga mientras se arma la respuesta
+		if purging {
+			purging = false
+			version = 2
+			rc.Purge()
+		}
+	})
+
+	if w := get(r); w.Header().Get("X-Cache") != "MISS" || w.Body.String() != `{"version":1}` {
+		t.Fatalf("first GET: X-Cache=%q body=%s", w.Header().Get("X-Cache"), w.Body)
+	}
+	// La respuesta vieja no quedó guardada
+	if w := get(r); w.Header().Get("X-Cache") != "MISS" || w.Body.String() != `{"version":2}` {
+		t.Fatalf("second GET: X-Cache=%q body=%s, want a fresh MISS", w.Header().Get("X-Cache"), w.Body)
+	}
+	if w := get(r); w.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("third GET: X-Cache=%q, want HIT", w.Header().Get("X-Cache"))
+	}
+}
+
+func TestResponseCacheSharePurges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := cache.NewMemory(100)
+
+	version := 1
+	a := NewResponseCache(time.Minute, 60, 100)
+	b := NewResponseCache(time.Minute, 60, 100)
+	a.SharePurges(ctx, backend, 10*time.Millisecond)
+	b.SharePurges(ctx, backend, 10*time.Millisecond)
+	ra, rb := cachedRouter(a, &version, nil), cachedRouter(b, &version, nil)
+
+	get(rb)
+	if w := get(rb); w.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("replica b: X-Cache=%q, want HIT", w.Header().Get("X-Cache"))
+	}
+
+	version = 2
+	a.Purge()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		w := get(rb)
+		if w.Body.String() == `{"version":2}` {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replica b still serves %s after the purge on a", w.Body)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if w := get(ra); w.Body.String() != `{"version":2}` {
+		t.Errorf("replica a: body %s after its own purge", w.Body)
+	}
+}
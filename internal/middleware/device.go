@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qenti/qenti/internal/pkg/devices"
+)
+
+// deviceCookieMaxAge duración de la cookie de device ID (1 año).
+const deviceCookieMaxAge = 365 * 24 * 60 * 60
+
+// DeviceID resuelve el device ID anónimo firmado desde el header X-Device-ID o la
+// cookie qenti_did y lo guarda en el contexto como "device_id" (uuid.UUID).
+// Si issue es true y no hay un token válido, emite uno nuevo y lo devuelve en el
+// header X-Device-ID y en la cookie, para que el cliente lo reenvíe en adelante.
+// secureCookie marca la cookie como Secure (solo HTTPS); se desactiva en desarrollo.
+func DeviceID(secret string, secureCookie, issue bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(devices.HeaderName)
+		if token == "" {
+			token, _ = c.Cookie(devices.CookieName)
+		}
+
+		if token != "" {
+			if id, err := devices.ParseToken(secret, token); err == nil {
+				c.Set("device_id", id)
+				c.Next()
+				return
+			}
+		}
+
+		if issue {
+			id, newToken := devices.NewToken(secret)
+			c.Set("device_id", id)
+			c.Header(devices.HeaderName, newToken)
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(devices.CookieName, newToken, deviceCookieMaxAge, "/", "", secureCookie, true)
+		}
+
+		c.Next()
+	}
+}
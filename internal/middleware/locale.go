@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/qenti/qenti/internal/pkg/translations"
+)
+
+// Locale resuelve el idioma del pedido desde ?lang= (tiene prioridad) o el header
+// Accept-Language y guarda en el contexto como "locales" ([]string) la cadena de
+// fallback a aplicar a títulos y descripciones.
+func Locale(resolver *translations.Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var requested []string
+		if lang, ok := translations.NormalizeLocale(c.Query("lang")); ok {
+			requested = []string{lang}
+		} else {
+			requested = translations.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+			// La respuesta depende del header: los caches deben distinguirla por idioma
+			c.Header("Vary", "Accept-Language")
+		}
+		c.Set("locales", resolver.Chain(requested))
+		c.Next()
+	}
+}
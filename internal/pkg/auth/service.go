@@ -8,20 +8,30 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/cache"
 	"github.com/qenti/qenti/internal/pkg/models"
 )
 
+// rolesCacheTag tag de los roles en cache; cualquier cambio de roles o equipos lo invalida.
+const rolesCacheTag = "roles"
+
+// roleCacheTTL cuánto se recuerda el rol de un usuario.
+const roleCacheTTL = time.Minute
+
 type Service struct {
 	db              *sql.DB
 	firebaseService *FirebaseService
+	cache           *cache.Cache
 }
 
-func NewService(db *sql.DB, firebaseService *FirebaseService) *Service {
+func NewService(db *sql.DB, firebaseService *FirebaseService, cacheStore *cache.Cache) *Service {
 	return &Service{
 		db:              db,
 		firebaseService: firebaseService,
+		cache:           cacheStore,
 	}
 }
 
@@ -37,6 +47,29 @@ type UserInfo struct {
 func (s *Service) VerifyToken(token string) (*UserInfo, error) {
 	ctx := context.Background()
 
+	identity, err := s.VerifyIdentity(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Obtener o crear usuario en DB
+	user, err := s.GetOrCreateUser(ctx, identity.FirebaseUID, identity.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get or create user: %w", err)
+	}
+	
+	return &UserInfo{
+		ID:          user.ID,
+		FirebaseUID: user.FirebaseUID,
+		Email:       user.Email,
+		IsPremium:   user.IsPremium,
+	}, nil
+}
+
+// VerifyIdentity verifica un token de Firebase y devuelve su UID y email SIN tocar la DB.
+// Se usa al vincular una cuenta invitada, donde hay que saber si la identidad ya
+// tiene cuenta antes de crear nada. El ID del resultado no es significativo.
+func (s *Service) VerifyIdentity(token string) (*UserInfo, error) {
 	// Si no hay Firebase Admin SDK configurado, decodificar el JWT sin verificar firma.
 	// Esto permite desarrollo local con Google Auth real en el frontend.
 	// NUNCA usar en producción sin Firebase Admin SDK configurado.
@@ -49,36 +82,15 @@ func (s *Service) VerifyToken(token string) (*UserInfo, error) {
 			firebaseUID = "mock-firebase-uid-" + uuid.New().String()[:8]
 			email = "dev-" + firebaseUID[:16] + "@mock.local"
 		}
-		user, err := s.GetOrCreateUser(ctx, firebaseUID, email)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get or create user: %w", err)
-		}
-		return &UserInfo{
-			ID:          user.ID,
-			FirebaseUID: user.FirebaseUID,
-			Email:       user.Email,
-			IsPremium:   user.IsPremium,
-		}, nil
+		return &UserInfo{FirebaseUID: firebaseUID, Email: email}, nil
 	}
 	
 	// Verificar token con Firebase
-	firebaseUser, err := s.firebaseService.GetUserInfo(ctx, token)
+	firebaseUser, err := s.firebaseService.GetUserInfo(context.Background(), token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify Firebase token: %w", err)
 	}
-	
-	// Obtener o crear usuario en DB
-	user, err := s.GetOrCreateUser(ctx, firebaseUser.FirebaseUID, firebaseUser.Email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get or create user: %w", err)
-	}
-	
-	return &UserInfo{
-		ID:          user.ID,
-		FirebaseUID: user.FirebaseUID,
-		Email:       user.Email,
-		IsPremium:   user.IsPremium,
-	}, nil
+	return firebaseUser, nil
 }
 
 // GetOrCreateUser obtiene un usuario por Firebase UID o lo crea si no existe
@@ -142,7 +154,27 @@ type RoleInfo struct {
 // Prioridad: super_admin > admin > producer > user
 // Para producers: busca primero si es dueño de una productora, luego si es miembro invitado.
 func (s *Service) GetUserRole(firebaseUID string) (role, producerID string, err error) {
-	ctx := context.Background()
+	info, err := cache.GetOrLoad(context.Background(), s.cache, "user_role:"+firebaseUID, roleCacheTTL, []string{rolesCacheTag},
+		func(ctx context.Context) (RoleInfo, error) {
+			role, producerID, err := s.loadUserRole(ctx, firebaseUID)
+			return RoleInfo{Role: role, ProducerID: producerID}, err
+		})
+	if err != nil {
+		return "user", "", err
+	}
+	return info.Role, info.ProducerID, nil
+}
+
+// InvalidateRoles descarta los roles en cache. Llamarlo después de cambiar
+// user_roles o producer_members por fuera de este servicio.
+func (s *Service) InvalidateRoles(ctx context.Context) {
+	if err := s.cache.Invalidate(ctx, rolesCacheTag); err != nil {
+		log.Printf("auth: invalidate roles cache: %v", err)
+	}
+}
+
+// loadUserRole lee de la DB el rol de GetUserRole.
+func (s *Service) loadUserRole(ctx context.Context, firebaseUID string) (role, producerID string, err error) {
 
 	// Busca el rol más prioritario del usuario.
 	// El producer_id se toma de producers (dueño) o producer_members (invitado).
@@ -166,6 +198,13 @@ func (s *Service) GetUserRole(firebaseUID string) (role, producerID string, err
 
 	err = s.db.QueryRowContext(ctx, query, firebaseUID).Scan(&role, &producerID)
 	if err == sql.ErrNoRows {
+		// Sin roles asignados: "guest" para cuentas invitadas, "user" para el resto
+		var isGuest bool
+		if err := s.db.QueryRowContext(ctx,
+			`SELECT is_guest FROM users WHERE firebase_uid = $1`, firebaseUID,
+		).Scan(&isGuest); err == nil && isGuest {
+			return "guest", "", nil
+		}
 		return "user", "", nil
 	}
 	if err != nil {
@@ -179,6 +218,9 @@ func (s *Service) GrantAdminRole(ctx context.Context, userID uuid.UUID, grantedB
 	query := `INSERT INTO user_roles (user_id, role, granted_by) VALUES ($1, 'admin', $2) 
 	          ON CONFLICT (user_id, role) DO NOTHING`
 	_, err := s.db.ExecContext(ctx, query, userID, grantedBy)
+	if err == nil {
+		s.InvalidateRoles(ctx)
+	}
 	return err
 }
 
@@ -187,6 +229,9 @@ func (s *Service) GrantRole(ctx context.Context, userID uuid.UUID, roleName stri
 	query := `INSERT INTO user_roles (user_id, role, granted_by) VALUES ($1, $2, $3) 
 	          ON CONFLICT (user_id, role) DO NOTHING`
 	_, err := s.db.ExecContext(ctx, query, userID, roleName, grantedBy)
+	if err == nil {
+		s.InvalidateRoles(ctx)
+	}
 	return err
 }
 
@@ -215,6 +260,7 @@ func (s *Service) AddMemberToProducer(ctx context.Context, producerID, userID uu
 	if err != nil {
 		return fmt.Errorf("AddMemberToProducer roles: %w", err)
 	}
+	s.InvalidateRoles(ctx)
 	return nil
 }
 
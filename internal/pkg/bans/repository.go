@@ -4,17 +4,26 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/cache"
 )
 
+// cacheTag tag de los bans activos en cache.
+const cacheTag = "bans"
+
+// banCacheTTL cuánto se recuerda si un usuario está baneado.
+const banCacheTTL = 30 * time.Second
+
 type Repository struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *cache.Cache
 }
 
-func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db: db}
+func NewRepository(db *sql.DB, cacheStore *cache.Cache) *Repository {
+	return &Repository{db: db, cache: cacheStore}
 }
 
 // Ban representa un ban de usuario
@@ -55,12 +64,29 @@ func (r *Repository) Create(ctx context.Context, ban *Ban) error {
 	if err != nil {
 		return fmt.Errorf("failed to create ban: %w", err)
 	}
+	if err := r.cache.Delete(ctx, "ban:"+ban.UserID.String()); err != nil {
+		log.Printf("bans: invalidate cache: %v", err)
+	}
 	
 	return nil
 }
 
-// IsBanned verifica si un usuario está baneado actualmente
+// IsBanned verifica si un usuario está baneado actualmente (vía cache)
 func (r *Repository) IsBanned(ctx context.Context, userID uuid.UUID) (bool, *Ban, error) {
+	ban, err := cache.GetOrLoad(ctx, r.cache, "ban:"+userID.String(), banCacheTTL, []string{cacheTag},
+		func(ctx context.Context) (*Ban, error) { return r.activeBan(ctx, userID) })
+	if err != nil {
+		return false, nil, err
+	}
+	// Un ban en cache puede haber vencido desde que se guardó
+	if ban == nil || (ban.ExpiresAt != nil && !ban.ExpiresAt.After(time.Now())) {
+		return false, nil, nil
+	}
+	return true, ban, nil
+}
+
+// activeBan lee de la DB el ban activo más reciente del usuario, o nil si no tiene.
+func (r *Repository) activeBan(ctx context.Context, userID uuid.UUID) (*Ban, error) {
 	query := `SELECT id, user_id, reason, banned_by, expires_at, is_active, created_at 
 	          FROM bans 
 	          WHERE user_id = $1 AND is_active = TRUE 
@@ -77,10 +103,10 @@ func (r *Repository) IsBanned(ctx context.Context, userID uuid.UUID) (bool, *Ban
 	)
 	
 	if err == sql.ErrNoRows {
-		return false, nil, nil
+		return nil, nil
 	}
 	if err != nil {
-		return false, nil, fmt.Errorf("failed to check ban: %w", err)
+		return nil, fmt.Errorf("failed to check ban: %w", err)
 	}
 	
 	if bannedBy.Valid {
@@ -92,7 +118,7 @@ func (r *Repository) IsBanned(ctx context.Context, userID uuid.UUID) (bool, *Ban
 		ban.ExpiresAt = &expiresAt.Time
 	}
 	
-	return true, &ban, nil
+	return &ban, nil
 }
 
 // Revoke revoca un ban (lo marca como inactivo)
@@ -111,6 +137,9 @@ func (r *Repository) Revoke(ctx context.Context, banID uuid.UUID) error {
 	if rowsAffected == 0 {
 		return fmt.Errorf("ban not found")
 	}
+	if err := r.cache.Invalidate(ctx, cacheTag); err != nil {
+		log.Printf("bans: invalidate cache: %v", err)
+	}
 	
 	return nil
 }
@@ -0,0 +1,216 @@
+// Package cache guarda datos calientes que hoy se leen de la DB en cada request
+// (slugs de productores, roles, bans, rankings). Los valores se guardan en un
+// Backend intercambiable: Memory (LRU por proceso, sin red; el que usan los tests
+// y desarrollo) o Redis (compartido entre instancias).
+//
+// Cada entrada puede llevar tags: Invalidate(tag) descarta todas las entradas que
+// lo llevan, así una escritura no necesita conocer las claves que la leen.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/qenti/qenti/internal/config"
+)
+
+// Backend almacenamiento de las entradas. Los valores son bytes opacos; ttl 0 =
+// sin vencimiento.
+type Backend interface {
+	// Get devuelve el valor de key, o ok=false si no está o venció.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set guarda value en key durante ttl, asociado a tags.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	// Delete descarta las claves.
+	Delete(ctx context.Context, keys ...string) error
+	// Invalidate descarta todas las entradas con alguno de los tags.
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// Cache Backend más GetOrLoad, que carga cada clave una sola vez aunque la pidan
+// varias requests a la vez.
+type Cache struct {
+	Backend
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// call carga en curso de una clave; las requests que llegan mientras tanto esperan
+// su resultado. stale indica que la clave se borró o invalidó durante la carga: lo
+// leído puede ser anterior a la escritura, así que no se guarda.
+type call struct {
+	done  chan struct{}
+	tags  []string
+	stale bool
+	value []byte
+	err   error
+}
+
+// New construye el cache según cfg.Backend. Con "redis" verifica la conexión.
+func New(cfg config.CacheConfig) (*Cache, error) {
+	switch cfg.Backend {
+	case "memory", "":
+		return NewCache(NewMemory(cfg.MaxEntries)), nil
+	case "redis":
+		redis, err := NewRedis(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := redis.Ping(ctx); err != nil {
+			return nil, fmt.Errorf("cache: redis ping: %w", err)
+		}
+		return NewCache(redis), nil
+	default:
+		return nil, fmt.Errorf("cache: unknown CACHE_BACKEND=%q (valid: memory, redis)", cfg.Backend)
+	}
+}
+
+// NewCache envuelve un backend.
+func NewCache(backend Backend) *Cache {
+	return &Cache{Backend: backend, calls: make(map[string]*call)}
+}
+
+// Delete descarta las claves, incluidas las cargas en curso de GetOrLoad.
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	c.markStale(func(key string, _ []string) bool {
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+		return false
+	})
+	return c.Backend.Delete(ctx, keys...)
+}
+
+// Invalidate descarta las entradas con alguno de los tags, incluidas las cargas en
+// curso de GetOrLoad.
+func (c *Cache) Invalidate(ctx context.Context, tags ...string) error {
+	c.markStale(func(_ string, callTags []string) bool {
+		for _, t := range callTags {
+			for _, tag := range tags {
+				if t == tag {
+					return true
+				}
+			}
+		}
+		return false
+	})
+	return c.Backend.Invalidate(ctx, tags...)
+}
+
+// markStale marca las cargas en curso que cumplen match y las saca del mapa, así
+// las próximas llamadas vuelven a leer la DB en vez de esperar un valor viejo.
+// Se llama antes de borrar del backend: una carga que guarde después lo verá.
+func (c *Cache) markStale(match func(key string, tags []string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, inflight := range c.calls {
+		if match(key, inflight.tags) {
+			inflight.stale = true
+			delete(c.calls, key)
+		}
+	}
+}
+
+// GetOrLoad devuelve el valor de key (JSON) o, si no está, lo carga con load y lo
+// guarda durante ttl con tags. Las llamadas concurrentes para la misma clave
+// comparten una sola carga, que corre con un contexto propio: si la request que la
+// inició se cancela, las demás siguen esperando. Si el backend falla se loguea y
+// se usa load directo: el cache nunca debería tirar una request.
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, tags []string, load func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	data, ok, err := c.Get(ctx, key)
+	if err != nil {
+		log.Printf("cache: get %s: %v", key, err)
+	}
+	if ok {
+		if err := json.Unmarshal(data, &value); err == nil {
+			return value, nil
+		}
+		// Entrada de otra versión del tipo: se recarga
+	}
+
+	data, err = c.load(ctx, key, ttl, tags, func(ctx context.Context) ([]byte, error) {
+		loaded, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(loaded)
+		if err != nil {
+			return nil, fmt.Errorf("cache: encode %s: %w", key, err)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return value, err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("cache: decode %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// load ejecuta fn para key (y guarda el resultado) salvo que ya haya una carga en
+// curso, en cuyo caso espera su resultado. Vuelve antes si ctx se cancela.
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, tags []string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	inflight, ok := c.calls[key]
+	if !ok {
+		inflight = &call{done: make(chan struct{}), tags: tags}
+		c.calls[key] = inflight
+		go c.run(context.WithoutCancel(ctx), key, ttl, inflight, fn)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-inflight.done:
+		return inflight.value, inflight.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run hace la carga de call y la guarda, salvo que la clave se haya invalidado
+// mientras tanto.
+func (c *Cache) run(ctx context.Context, key string, ttl time.Duration, inflight *call, fn func(ctx context.Context) ([]byte, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			inflight.value, inflight.err = nil, fmt.Errorf("cache: load %s panicked: %v", key, r)
+		}
+		c.mu.Lock()
+		if c.calls[key] == inflight {
+			delete(c.calls, key)
+		}
+		c.mu.Unlock()
+		close(inflight.done)
+	}()
+
+	inflight.value, inflight.err = fn(ctx)
+	if inflight.err != nil || c.isStale(inflight) {
+		return
+	}
+	if err := c.Backend.Set(ctx, key, inflight.value, ttl, inflight.tags); err != nil {
+		log.Printf("cache: set %s: %v", key, err)
+		return
+	}
+	// Una invalidación entre el chequeo y el Set pudo no ver la entrada nueva
+	if c.isStale(inflight) {
+		if err := c.Backend.Delete(ctx, key); err != nil {
+			log.Printf("cache: delete %s: %v", key, err)
+		}
+	}
+}
+
+func (c *Cache) isStale(inflight *call) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return inflight.stale
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadCachesValue(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache(NewMemory(10))
+	var loads int32
+	load := func(ctx context.Context) ([]string, error) {
+		atomic.AddInt32(&loads, 1)
+		return []string{"a", "b"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		got, err := GetOrLoad(ctx, c, "k", time.Minute, nil, load)
+		if err != nil || len(got) != 2 || got[1] != "b" {
+			t.Fatalf("GetOrLoad = %v, %v", got, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+}
+
+func TestGetOrLoadDeduplicatesConcurrentLoads(t *testing.T) {
+	c := NewCache(NewMemory(10))
+	var loads int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := GetOrLoad(context.Background(), c, "k", time.Minute, nil, load); err != nil || got != 42 {
+				t.Errorf("GetOrLoad = %v, %v", got, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+}
+
+func TestGetOrLoadDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache(NewMemory(10))
+	boom := errors.New("boom")
+
+	if _, err := GetOrLoad(ctx, c, "k", time.Minute, nil, func(ctx context.Context) (int, error) { return 0, boom }); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	got, err := GetOrLoad(ctx, c, "k", time.Minute, nil, func(ctx context.Context) (int, error) { return 7, nil })
+	if err != nil || got != 7 {
+		t.Errorf("GetOrLoad after error = %v, %v", got, err)
+	}
+}
+
+func TestGetOrLoadSkipsSetAfterInvalidate(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache(NewMemory(10))
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	done := make(chan bool)
+	go func() {
+		banned, _ := GetOrLoad(ctx, c, "ban:u", time.Minute, []string{"bans"}, func(ctx context.Context) (bool, error) {
+			close(started)
+			<-release
+			return false, nil // leído antes del ban
+		})
+		done <- banned
+	}()
+	<-started
+	c.Delete(ctx, "ban:u") // el ban se crea mientras la carga está en curso
+	close(release)
+	<-done
+
+	if _, ok, _ := c.Get(ctx, "ban:u"); ok {
+		t.Error("a load that raced with Delete should not be cached")
+	}
+	got, _ := GetOrLoad(ctx, c, "ban:u", time.Minute, []string{"bans"}, func(ctx context.Context) (bool, error) { return true, nil })
+	if !got {
+		t.Error("next load should read the new value")
+	}
+}
+
+func TestGetOrLoadWaitersSurviveCancelledCaller(t *testing.T) {
+	c := NewCache(NewMemory(10))
+	release := make(chan struct{})
+	load := func(ctx context.Context) (int, error) {
+		<-release
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := GetOrLoad(first, c, "k", time.Minute, nil, load)
+		firstErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	waiter := make(chan int)
+	go func() {
+		got, _ := GetOrLoad(context.Background(), c, "k", time.Minute, nil, load)
+		waiter <- got
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller err = %v", err)
+	}
+	close(release)
+	if got := <-waiter; got != 1 {
+		t.Errorf("waiter got %d, want 1", got)
+	}
+}
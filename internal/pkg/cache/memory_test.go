@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2)
+	m.Set(ctx, "a", []byte("1"), 0, nil)
+	m.Set(ctx, "b", []byte("2"), 0, nil)
+	m.Get(ctx, "a") // "b" pasa a ser la menos usada
+	m.Set(ctx, "c", []byte("3"), 0, nil)
+
+	if _, ok, _ := m.Get(ctx, "b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := m.Get(ctx, key); !ok {
+			t.Errorf("%s should still be cached", key)
+		}
+	}
+}
+
+func TestMemoryExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10)
+	m.Set(ctx, "short", []byte("1"), 10*time.Millisecond, []string{"t"})
+	m.Set(ctx, "forever", []byte("2"), 0, nil)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok, _ := m.Get(ctx, "short"); ok {
+		t.Error("short should have expired")
+	}
+	if _, ok, _ := m.Get(ctx, "forever"); !ok {
+		t.Error("entries without ttl should not expire")
+	}
+	if len(m.tags) != 0 {
+		t.Errorf("expired entry left tags behind: %v", m.tags)
+	}
+}
+
+func TestMemoryInvalidatesTags(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10)
+	m.Set(ctx, "a", []byte("1"), 0, []string{"producers"})
+	m.Set(ctx, "b", []byte("2"), 0, []string{"producers", "roles"})
+	m.Set(ctx, "c", []byte("3"), 0, []string{"roles"})
+
+	if err := m.Invalidate(ctx, "producers"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"a": false, "b": false, "c": true} {
+		if _, ok, _ := m.Get(ctx, key); ok != want {
+			t.Errorf("%s cached = %v, want %v", key, ok, want)
+		}
+	}
+	if _, ok := m.tags["roles"]["b"]; ok {
+		t.Error("b should have been removed from its other tags")
+	}
+}
+
+func TestMemoryDelete(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10)
+	m.Set(ctx, "a", []byte("1"), 0, []string{"t"})
+	m.Delete(ctx, "a", "missing")
+
+	if _, ok, _ := m.Get(ctx, "a"); ok {
+		t.Error("a should have been deleted")
+	}
+	if len(m.entries) != 0 || len(m.tags) != 0 {
+		t.Errorf("delete left state behind: %d entries, tags %v", len(m.entries), m.tags)
+	}
+}
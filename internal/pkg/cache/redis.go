@@ -0,0 +1,335 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// redisPrefix espacio de nombres de las claves, por si el Redis es compartido
+	redisPrefix = "qenti:cache:"
+	// redisPoolSize conexiones ociosas que se conservan
+	redisPoolSize = 16
+	// redisTimeout plazo de cada comando si el contexto no trae uno
+	redisTimeout = 2 * time.Second
+)
+
+// errRedisNil respuesta nula de Redis (clave inexistente).
+var errRedisNil = errors.New("redis: nil")
+
+// Redis backend sobre Redis (o cualquier servidor que hable RESP, como Valkey o
+// KeyDB), compartido entre instancias. Cada tag es un SET con las claves que lo
+// llevan. Cliente mínimo: solo los comandos que necesita el cache.
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      *tls.Config
+	pool     chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// NewRedis crea el backend para rawURL (redis://[user:password@]host:port/db, o
+// rediss:// con TLS). No abre conexiones hasta el primer comando.
+func NewRedis(rawURL string) (*Redis, error) {
+	if rawURL == "" {
+		return nil, errors.New("cache: REDIS_URL is required with CACHE_BACKEND=redis")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("cache: invalid REDIS_URL: %w", err)
+	}
+	r := &Redis{addr: u.Host, pool: make(chan *redisConn, redisPoolSize)}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		r.tls = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("cache: invalid REDIS_URL scheme %q (valid: redis, rediss)", u.Scheme)
+	}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.username = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("cache: invalid REDIS_URL database %q", db)
+		}
+	}
+	return r, nil
+}
+
+// Ping verifica la conexión.
+func (r *Redis) Ping(ctx context.Context) error {
+	_, err := r.do(ctx, "PING")
+	return err
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", redisPrefix+key)
+	if err == errRedisNil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+	return value, true, nil
+}
+
+// redisSetScript guarda la entrada y la agrega a sus tags en una sola operación
+// atómica, para que un Invalidate no pueda colarse entre el SET y el SADD y dejar
+// la entrada fuera del tag. KEYS[1] es la entrada y KEYS[2..] los SETs de sus tags;
+// ARGV[1] el valor y ARGV[2] el TTL en ms (0 = sin vencimiento). Cada SET de tag
+// vive al menos tanto como su entrada más larga.
+const redisSetScript = `
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+for i = 2, #KEYS do
+	local existed = redis.call('EXISTS', KEYS[i]) == 1
+	redis.call('SADD', KEYS[i], KEYS[1])
+	if ttl <= 0 then
+		redis.call('PERSIST', KEYS[i])
+	else
+		local remaining = redis.call('PTTL', KEYS[i])
+		if not existed or (remaining >= 0 and remaining < ttl) then
+			redis.call('PEXPIRE', KEYS[i], ttl)
+		end
+	end
+end
+return 1`
+
+// redisInvalidateScript borra los SETs de los tags (KEYS) y todas sus entradas
+// de forma atómica: ningún Set concurrente puede quedar con la entrada guardada
+// y fuera del tag. Las entradas no van en KEYS, así que no sirve con Redis Cluster.
+const redisInvalidateScript = `
+local n = 0
+for _, tag in ipairs(KEYS) do
+	local members = redis.call('SMEMBERS', tag)
+	for i = 1, #members, 500 do
+		redis.call('DEL', unpack(members, i, math.min(i + 499, #members)))
+	end
+	redis.call('DEL', tag)
+	n = n + #members
+end
+return n`
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	args := []string{"EVAL", redisSetScript, strconv.Itoa(1 + len(tags)), redisPrefix + key}
+	for _, tag := range tags {
+		args = append(args, redisPrefix+"tag:"+tag)
+	}
+	ms := int64(0)
+	if ttl > 0 {
+		// Un TTL menor a 1 ms no puede quedar en 0, que sería "sin vencimiento"
+		ms = max(ttl.Milliseconds(), 1)
+	}
+	args = append(args, string(value), strconv.FormatInt(ms, 10))
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []string{"DEL"}
+	for _, key := range keys {
+		args = append(args, redisPrefix+key)
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+func (r *Redis) Invalidate(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	args := []string{"EVAL", redisInvalidateScript, strconv.Itoa(len(tags))}
+	for _, tag := range tags {
+		args = append(args, redisPrefix+"tag:"+tag)
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// do ejecuta un comando y devuelve la respuesta: string (simple), int64, []byte
+// (bulk) o []interface{} (array). errRedisNil si la respuesta es nula.
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	conn.conn.SetDeadline(deadline)
+
+	reply, err := conn.command(args...)
+	var replyErr redisError
+	if err != nil && err != errRedisNil && !errors.As(err, &replyErr) {
+		// Error de red o de protocolo: la conexión queda en un estado desconocido
+		conn.conn.Close()
+		return nil, fmt.Errorf("redis %s: %w", args[0], err)
+	}
+	r.put(conn)
+	if replyErr != "" {
+		return nil, fmt.Errorf("redis %s: %w", args[0], replyErr)
+	}
+	return reply, err
+}
+
+// get toma una conexión ociosa del pool o abre una nueva (AUTH y SELECT incluidos).
+func (r *Redis) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if r.tls != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: r.tls}).DialContext(ctx, "tcp", r.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", r.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: dial %s: %w", r.addr, err)
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	conn.SetDeadline(time.Now().Add(redisTimeout))
+	if r.password != "" {
+		args := []string{"AUTH", r.password}
+		if r.username != "" {
+			args = []string{"AUTH", r.username, r.password}
+		}
+		if _, err := c.command(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis: auth: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := c.command("SELECT", strconv.Itoa(r.db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis: select %d: %w", r.db, err)
+		}
+	}
+	return c, nil
+}
+
+// put devuelve la conexión al pool, o la cierra si está lleno.
+func (r *Redis) put(conn *redisConn) {
+	select {
+	case r.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// redisError respuesta de error del servidor (-ERR ...); la conexión sigue usable.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// command escribe el comando como array de bulk strings y lee la respuesta.
+func (c *redisConn) command(args ...string) (interface{}, error) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length %q", line)
+		}
+		if n < 0 {
+			return nil, errRedisNil
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := c.readReply()
+			var replyErr redisError
+			if errors.As(err, &replyErr) {
+				// Hay que leer el resto del array para no dejar la conexión a medias
+				item = replyErr
+			} else if err != nil && err != errRedisNil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
+
+// readLine lee una línea terminada en \r\n, sin el terminador.
+func (c *redisConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
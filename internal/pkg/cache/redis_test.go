@@ -0,0 +1,345 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis servidor RESP en memoria con los comandos que usa el backend Redis,
+// para probar el cliente sin red externa.
+type fakeRedis struct {
+	mu       sync.Mutex
+	strings  map[string]string
+	sets     map[string]map[string]bool
+	ttls     map[string]int64 // ms; ausente = sin vencimiento
+	commands []string
+	password string
+}
+
+func newFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeRedis{
+		strings:  make(map[string]string),
+		sets:     make(map[string]map[string]bool),
+		ttls:     make(map[string]int64),
+		password: password,
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+		var reply string
+		if !authed && args[0] != "AUTH" {
+			reply = "-NOAUTH Authentication required\r\n"
+		} else {
+			reply = f.exec(args, &authed)
+		}
+		f.mu.Unlock()
+		conn.Write([]byte(reply))
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func bulk(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+func (f *fakeRedis) exec(args []string, authed *bool) string {
+	switch args[0] {
+	case "AUTH":
+		if args[len(args)-1] != f.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		*authed = true
+		return "+OK\r\n"
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "SET":
+		f.strings[args[1]] = args[2]
+		delete(f.ttls, args[1])
+		if len(args) == 5 && args[3] == "PX" {
+			ms, _ := strconv.ParseInt(args[4], 10, 64)
+			f.ttls[args[1]] = ms
+		}
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SADD":
+		if f.sets[args[1]] == nil {
+			f.sets[args[1]] = make(map[string]bool)
+		}
+		f.sets[args[1]][args[2]] = true
+		return ":1\r\n"
+	case "SMEMBERS":
+		out := fmt.Sprintf("*%d\r\n", len(f.sets[args[1]]))
+		for member := range f.sets[args[1]] {
+			out += bulk(member)
+		}
+		return out
+	case "PTTL":
+		if _, ok := f.sets[args[1]]; !ok {
+			if _, ok := f.strings[args[1]]; !ok {
+				return ":-2\r\n"
+			}
+		}
+		ms, ok := f.ttls[args[1]]
+		if !ok {
+			return ":-1\r\n"
+		}
+		return fmt.Sprintf(":%d\r\n", ms)
+	case "PEXPIRE":
+		ms, _ := strconv.ParseInt(args[2], 10, 64)
+		f.ttls[args[1]] = ms
+		return ":1\r\n"
+	case "PERSIST":
+		delete(f.ttls, args[1])
+		return ":1\r\n"
+	case "EVAL":
+		return f.eval(args[1], args[3:], authed)
+	case "EXISTS":
+		_, isSet := f.sets[args[1]]
+		_, isString := f.strings[args[1]]
+		if isSet || isString {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "DEL":
+		for _, key := range args[1:] {
+			delete(f.strings, key)
+			delete(f.sets, key)
+			delete(f.ttls, key)
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-1)
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+// eval emula los scripts del backend con los mismos comandos, atómicamente (el
+// llamador tiene f.mu), en vez de interpretar Lua.
+func (f *fakeRedis) eval(script string, rest []string, authed *bool) string {
+	switch script {
+	case redisSetScript:
+		keys, argv := rest[:len(rest)-2], rest[len(rest)-2:]
+		ttl, _ := strconv.ParseInt(argv[1], 10, 64)
+		if ttl > 0 {
+			f.exec([]string{"SET", keys[0], argv[0], "PX", argv[1]}, authed)
+		} else {
+			f.exec([]string{"SET", keys[0], argv[0]}, authed)
+		}
+		for _, tag := range keys[1:] {
+			existed := f.exec([]string{"EXISTS", tag}, authed) == ":1\r\n"
+			f.exec([]string{"SADD", tag, keys[0]}, authed)
+			if ttl <= 0 {
+				f.exec([]string{"PERSIST", tag}, authed)
+				continue
+			}
+			remaining, _ := strconv.ParseInt(strings.TrimSpace(f.exec([]string{"PTTL", tag}, authed)[1:]), 10, 64)
+			if !existed || (remaining >= 0 && remaining < ttl) {
+				f.exec([]string{"PEXPIRE", tag, argv[1]}, authed)
+			}
+		}
+		return ":1\r\n"
+	case redisInvalidateScript:
+		n := 0
+		for _, tag := range rest {
+			members := []string{"DEL", tag}
+			for member := range f.sets[tag] {
+				members = append(members, member)
+			}
+			n += len(members) - 2
+			f.exec(members, authed)
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	default:
+		return "-NOSCRIPT unknown script\r\n"
+	}
+}
+
+func TestRedisSetGetDelete(t *testing.T) {
+	f, addr := newFakeRedis(t, "secret")
+	r, err := NewRedis("redis://:secret@" + addr + "/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := r.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Valores binarios con \r\n: el cliente manda bulk strings con longitud
+	value := []byte("line1\r\nline2\x00")
+	if err := r.Set(ctx, "k", value, time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := r.Get(ctx, "k")
+	if err != nil || !ok || string(got) != string(value) {
+		t.Fatalf("Get = %q, %v, %v", got, ok, err)
+	}
+	if f.ttls[redisPrefix+"k"] != 60000 {
+		t.Errorf("ttl = %d, want 60000", f.ttls[redisPrefix+"k"])
+	}
+	if err := r.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := r.Get(ctx, "k"); ok || err != nil {
+		t.Errorf("Get after Delete = %v, %v", ok, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.commands[0] != "AUTH secret" || f.commands[1] != "SELECT 2" {
+		t.Errorf("connection setup = %v", f.commands[:2])
+	}
+}
+
+func TestRedisTagsExpireAndInvalidate(t *testing.T) {
+	f, addr := newFakeRedis(t, "")
+	r, _ := NewRedis("redis://" + addr)
+	ctx := context.Background()
+
+	r.Set(ctx, "a", []byte("1"), time.Minute, []string{"bans"})
+	r.Set(ctx, "b", []byte("2"), 2*time.Minute, []string{"bans"})
+	r.Set(ctx, "c", []byte("3"), 30*time.Second, []string{"bans"})
+
+	f.mu.Lock()
+	tagTTL := f.ttls[redisPrefix+"tag:bans"]
+	f.mu.Unlock()
+	if tagTTL != 120000 {
+		t.Errorf("tag set ttl = %d, want the longest entry ttl (120000)", tagTTL)
+	}
+
+	if err := r.Invalidate(ctx, "bans"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if _, ok, _ := r.Get(ctx, key); ok {
+			t.Errorf("%s should have been invalidated", key)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.sets[redisPrefix+"tag:bans"]; ok {
+		t.Error("tag set should have been deleted")
+	}
+	// Cada Set e Invalidate es un único EVAL: nada puede intercalarse entre el
+	// SET de la entrada y su SADD, ni entre el SMEMBERS y el DEL
+	for _, cmd := range f.commands[:4] {
+		if !strings.HasPrefix(cmd, "EVAL ") {
+			t.Errorf("unexpected command outside a script: %.40q", cmd)
+		}
+	}
+}
+
+func TestRedisServerErrorKeepsConnection(t *testing.T) {
+	_, addr := newFakeRedis(t, "")
+	r, _ := NewRedis("redis://" + addr)
+	ctx := context.Background()
+
+	if _, err := r.do(ctx, "BOGUS"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(r.pool) != 1 {
+		t.Errorf("pool = %d, the connection should be reused after a server error", len(r.pool))
+	}
+	if err := r.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisWithGetOrLoad(t *testing.T) {
+	_, addr := newFakeRedis(t, "")
+	r, _ := NewRedis("redis://" + addr)
+	c := NewCache(r)
+	ctx := context.Background()
+
+	loads := 0
+	load := func(ctx context.Context) (map[string]int, error) {
+		loads++
+		return map[string]int{"views": 3}, nil
+	}
+	for i := 0; i < 2; i++ {
+		got, err := GetOrLoad(ctx, c, "stats", time.Minute, []string{"t"}, load)
+		if err != nil || got["views"] != 3 {
+			t.Fatalf("GetOrLoad = %v, %v", got, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+}
+
+func TestNewRedisValidatesURL(t *testing.T) {
+	for _, raw := range []string{"", "http://localhost", "redis://localhost/abc"} {
+		if _, err := NewRedis(raw); err == nil {
+			t.Errorf("NewRedis(%q) should fail", raw)
+		}
+	}
+	r, err := NewRedis("rediss://user:pw@cache.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.addr != "cache.example.com:6379" || r.tls == nil || r.username != "user" || r.password != "pw" {
+		t.Errorf("parsed = %+v", r)
+	}
+}
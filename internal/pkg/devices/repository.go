@@ -0,0 +1,100 @@
+package devices
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MergeResult resume qué se transfirió del invitado a la cuenta.
+type MergeResult struct {
+	Views     int64 `json:"views"`
+	Favorites int64 `json:"favorites"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// MergeIntoUser transfiere el historial, progreso y favoritos del device ID
+// invitado a la cuenta userID, en una única transacción.
+// Si la cuenta ya tenía progreso en el mismo episodio se conserva el mayor
+// watched_seconds y completed = OR de ambos.
+func (r *Repository) MergeIntoUser(ctx context.Context, deviceID, userID uuid.UUID) (*MergeResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin merge tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Episodios que la cuenta ya tenía: fusionar progreso en la fila del usuario
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE views u
+		SET watched_seconds = GREATEST(u.watched_seconds, g.watched_seconds),
+		    completed       = u.completed OR g.completed,
+		    updated_at      = GREATEST(u.updated_at, g.updated_at)
+		FROM views g
+		WHERE g.device_id = $1 AND g.user_id IS NULL
+		  AND u.user_id = $2 AND u.episode_id = g.episode_id`,
+		deviceID, userID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to merge guest progress: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM views g
+		WHERE g.device_id = $1 AND g.user_id IS NULL
+		  AND EXISTS (SELECT 1 FROM views u WHERE u.user_id = $2 AND u.episode_id = g.episode_id)`,
+		deviceID, userID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to drop merged guest views: %w", err)
+	}
+
+	// 2. Resto de vistas del invitado: reasignar a la cuenta
+	res, err := tx.ExecContext(ctx,
+		`UPDATE views SET user_id = $2 WHERE device_id = $1 AND user_id IS NULL`,
+		deviceID, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign guest views: %w", err)
+	}
+	viewsMoved, _ := res.RowsAffected()
+
+	// 3. Favoritos
+	res, err = tx.ExecContext(ctx, `
+		INSERT INTO favorites (user_id, series_id, created_at)
+		SELECT $2, series_id, created_at FROM favorites
+		WHERE device_id = $1 AND user_id IS NULL
+		ON CONFLICT (user_id, series_id) DO NOTHING`,
+		deviceID, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge guest favorites: %w", err)
+	}
+	favoritesMoved, _ := res.RowsAffected()
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM favorites WHERE device_id = $1 AND user_id IS NULL`, deviceID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to drop guest favorites: %w", err)
+	}
+
+	// 4. Telemetría reciente del dispositivo
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE playback_events SET user_id = $2
+		WHERE device_id = $1 AND user_id IS NULL
+		  AND received_at > NOW() - INTERVAL '30 days'`,
+		deviceID.String(), userID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to reassign guest events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit guest merge: %w", err)
+	}
+	return &MergeResult{Views: viewsMoved, Favorites: favoritesMoved}, nil
+}
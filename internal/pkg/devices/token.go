@@ -0,0 +1,49 @@
+// Package devices identifica a los espectadores invitados (sin login) mediante un
+// device ID anónimo firmado por la API, y fusiona su actividad en la cuenta del
+// usuario cuando inicia sesión.
+package devices
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// HeaderName es el header con el que el cliente móvil reenvía su device ID.
+const HeaderName = "X-Device-ID"
+
+// CookieName es la cookie equivalente para clientes web.
+const CookieName = "qenti_did"
+
+// NewToken genera un device ID nuevo y su token firmado "<uuid>.<firma>".
+func NewToken(secret string) (uuid.UUID, string) {
+	id := uuid.New()
+	return id, id.String() + "." + sign(secret, id)
+}
+
+// ParseToken verifica la firma de un token y devuelve el device ID.
+func ParseToken(secret, token string) (uuid.UUID, error) {
+	idStr, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, fmt.Errorf("devices: malformed token")
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("devices: invalid id: %w", err)
+	}
+	if !hmac.Equal([]byte(sig), []byte(sign(secret, id))) {
+		return uuid.Nil, fmt.Errorf("devices: invalid signature")
+	}
+	return id, nil
+}
+
+// sign calcula Base64Url_NoPadding(HMAC-SHA256(secret, id)).
+func sign(secret string, id uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("device:" + id.String()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
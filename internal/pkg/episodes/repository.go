@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/pagination"
 )
 
 type Repository struct {
@@ -19,8 +22,9 @@ func NewRepository(db *sql.DB) *Repository {
 
 // GetBySeriesID retorna todos los episodios de una serie ordenados por número
 func (r *Repository) GetBySeriesID(ctx context.Context, seriesID uuid.UUID) ([]models.Episode, error) {
-	query := `SELECT id, series_id, episode_number, title, video_id_bunny, duration, 
-	          is_free, price_coins, created_at, updated_at 
+	query := `SELECT id, series_id, episode_number, title, video_id_bunny, video_status, COALESCE(video_error, ''), duration, 
+	          is_free, price_coins, COALESCE(cover_url, ''), COALESCE(width, 0), COALESCE(height, 0), COALESCE(aspect_ratio, ''),
+	          COALESCE(video_codec, ''), COALESCE(audio_codec, ''), COALESCE(bitrate, 0), created_at, updated_at 
 	          FROM episodes WHERE series_id = $1 ORDER BY episode_number ASC`
 	
 	rows, err := r.db.QueryContext(ctx, query, seriesID)
@@ -34,7 +38,8 @@ func (r *Repository) GetBySeriesID(ctx context.Context, seriesID uuid.UUID) ([]m
 		var e models.Episode
 		err := rows.Scan(
 			&e.ID, &e.SeriesID, &e.EpisodeNumber, &e.Title,
-			&e.VideoIDBunny, &e.Duration, &e.IsFree, &e.PriceCoins,
+			&e.VideoIDBunny, &e.VideoStatus, &e.VideoError, &e.Duration, &e.IsFree, &e.PriceCoins,
+			&e.CoverURL, &e.Width, &e.Height, &e.AspectRatio, &e.VideoCodec, &e.AudioCodec, &e.BitRate,
 			&e.CreatedAt, &e.UpdatedAt,
 		)
 		if err != nil {
@@ -49,13 +54,15 @@ func (r *Repository) GetBySeriesID(ctx context.Context, seriesID uuid.UUID) ([]m
 // GetByID retorna un episodio por ID
 func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Episode, error) {
 	var e models.Episode
-	query := `SELECT id, series_id, episode_number, title, video_id_bunny, duration, 
-	          is_free, price_coins, created_at, updated_at 
+	query := `SELECT id, series_id, episode_number, title, video_id_bunny, video_status, COALESCE(video_error, ''), duration, 
+	          is_free, price_coins, COALESCE(cover_url, ''), COALESCE(width, 0), COALESCE(height, 0), COALESCE(aspect_ratio, ''),
+	          COALESCE(video_codec, ''), COALESCE(audio_codec, ''), COALESCE(bitrate, 0), created_at, updated_at 
 	          FROM episodes WHERE id = $1`
 	
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&e.ID, &e.SeriesID, &e.EpisodeNumber, &e.Title,
-		&e.VideoIDBunny, &e.Duration, &e.IsFree, &e.PriceCoins,
+		&e.VideoIDBunny, &e.VideoStatus, &e.VideoError, &e.Duration, &e.IsFree, &e.PriceCoins,
+		&e.CoverURL, &e.Width, &e.Height, &e.AspectRatio, &e.VideoCodec, &e.AudioCodec, &e.BitRate,
 		&e.CreatedAt, &e.UpdatedAt,
 	)
 	
@@ -72,14 +79,17 @@ func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Episode
 // Create crea un nuevo episodio
 func (r *Repository) Create(ctx context.Context, episode *models.Episode) error {
 	episode.ID = uuid.New()
+	// Con un video ya asignado queda 'processing' hasta que el proveedor confirme
 	query := `INSERT INTO episodes (id, series_id, episode_number, title, video_id_bunny, 
-	          duration, is_free, price_coins) 
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at, updated_at`
+	          duration, is_free, price_coins, video_status) 
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8,
+	                  CASE WHEN $5 = '' THEN 'created' ELSE 'processing' END)
+	          RETURNING video_status, created_at, updated_at`
 	
 	err := r.db.QueryRowContext(ctx, query,
 		episode.ID, episode.SeriesID, episode.EpisodeNumber, episode.Title,
 		episode.VideoIDBunny, episode.Duration, episode.IsFree, episode.PriceCoins,
-	).Scan(&episode.CreatedAt, &episode.UpdatedAt)
+	).Scan(&episode.VideoStatus, &episode.CreatedAt, &episode.UpdatedAt)
 	
 	if err != nil {
 		return fmt.Errorf("failed to create episode: %w", err)
@@ -92,13 +102,14 @@ func (r *Repository) Create(ctx context.Context, episode *models.Episode) error
 func (r *Repository) Update(ctx context.Context, episode *models.Episode) error {
 	query := `UPDATE episodes 
 	          SET title = $1, video_id_bunny = $2, duration = $3, 
-	              is_free = $4, price_coins = $5, updated_at = CURRENT_TIMESTAMP 
-	          WHERE id = $6 RETURNING updated_at`
+	              is_free = $4, price_coins = $5, updated_at = CURRENT_TIMESTAMP, 
+	              ` + videoStatusOnChange + `
+	          WHERE id = $6 RETURNING video_status, updated_at`
 	
 	err := r.db.QueryRowContext(ctx, query,
 		episode.Title, episode.VideoIDBunny, episode.Duration,
 		episode.IsFree, episode.PriceCoins, episode.ID,
-	).Scan(&episode.UpdatedAt)
+	).Scan(&episode.VideoStatus, &episode.UpdatedAt)
 	
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("episode not found")
@@ -110,9 +121,22 @@ func (r *Repository) Update(ctx context.Context, episode *models.Episode) error
 	return nil
 }
 
-// UpdateVideoID actualiza el video_id_bunny de un episodio después de la subida
+// videoStatusOnChange reinicia el estado del video cuando cambia video_id_bunny ($2):
+// un video nuevo queda 'processing' hasta que el proveedor confirme que está listo.
+const videoStatusOnChange = `video_status = CASE
+	                  WHEN COALESCE(video_id_bunny, '') = $2 THEN video_status
+	                  WHEN $2 = '' THEN 'created' ELSE 'processing' END,
+	              video_error = CASE WHEN COALESCE(video_id_bunny, '') = $2 THEN video_error END,
+	              video_status_updated_at = CASE
+	                  WHEN COALESCE(video_id_bunny, '') = $2 THEN video_status_updated_at
+	                  ELSE CURRENT_TIMESTAMP END`
+
+// UpdateVideoID actualiza el video_id_bunny de un episodio después de la subida.
+// Si el video cambia, el estado vuelve a 'processing' (ver videostatus.Service).
 func (r *Repository) UpdateVideoID(ctx context.Context, episodeID uuid.UUID, videoID string) error {
-	query := `UPDATE episodes SET video_id_bunny = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	query := `UPDATE episodes SET video_id_bunny = $1, updated_at = CURRENT_TIMESTAMP, 
+	          ` + strings.ReplaceAll(videoStatusOnChange, "$2", "$1") + `
+	          WHERE id = $2`
 	result, err := r.db.ExecContext(ctx, query, videoID, episodeID)
 	if err != nil {
 		return fmt.Errorf("failed to update video_id: %w", err)
@@ -130,35 +154,35 @@ func (r *Repository) UpdateVideoID(ctx context.Context, episodeID uuid.UUID, vid
 	return nil
 }
 
-// GetAll retorna todos los episodios con filtros opcionales
-func (r *Repository) GetAll(ctx context.Context, seriesID *uuid.UUID) ([]models.Episode, error) {
-	var query string
+// List retorna una página de episodios, del más nuevo al más viejo (trae
+// page.FetchLimit filas, ver pagination.Slice). Con seriesID solo los de esa serie.
+func (r *Repository) List(ctx context.Context, seriesID *uuid.UUID, page pagination.Page) ([]models.Episode, error) {
+	query := `SELECT id, series_id, episode_number, title, video_id_bunny, video_status, COALESCE(video_error, ''), duration,
+	          is_free, price_coins, COALESCE(cover_url, ''), COALESCE(width, 0), COALESCE(height, 0), COALESCE(aspect_ratio, ''),
+	          COALESCE(video_codec, ''), COALESCE(audio_codec, ''), COALESCE(bitrate, 0), created_at, updated_at
+	          FROM episodes WHERE TRUE`
 	var args []interface{}
-	
 	if seriesID != nil {
-		query = `SELECT id, series_id, episode_number, title, video_id_bunny, duration, 
-		         is_free, price_coins, created_at, updated_at 
-		         FROM episodes WHERE series_id = $1 ORDER BY episode_number ASC`
-		args = []interface{}{*seriesID}
-	} else {
-		query = `SELECT id, series_id, episode_number, title, video_id_bunny, duration, 
-		         is_free, price_coins, created_at, updated_at 
-		         FROM episodes ORDER BY created_at DESC`
-		args = []interface{}{}
+		args = append(args, *seriesID)
+		query += fmt.Sprintf(` AND series_id = $%d`, len(args))
 	}
-	
+	query += page.Where(&args, "created_at", "id") + pagination.OrderBy("created_at", "id")
+	args = append(args, page.FetchLimit())
+	query += fmt.Sprintf(` LIMIT $%d`, len(args))
+
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query episodes: %w", err)
 	}
 	defer rows.Close()
-	
+
 	var episodes []models.Episode
 	for rows.Next() {
 		var e models.Episode
 		err := rows.Scan(
 			&e.ID, &e.SeriesID, &e.EpisodeNumber, &e.Title,
-			&e.VideoIDBunny, &e.Duration, &e.IsFree, &e.PriceCoins,
+			&e.VideoIDBunny, &e.VideoStatus, &e.VideoError, &e.Duration, &e.IsFree, &e.PriceCoins,
+			&e.CoverURL, &e.Width, &e.Height, &e.AspectRatio, &e.VideoCodec, &e.AudioCodec, &e.BitRate,
 			&e.CreatedAt, &e.UpdatedAt,
 		)
 		if err != nil {
@@ -166,7 +190,7 @@ func (r *Repository) GetAll(ctx context.Context, seriesID *uuid.UUID) ([]models.
 		}
 		episodes = append(episodes, e)
 	}
-	
+
 	return episodes, nil
 }
 
@@ -190,3 +214,125 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+
+// GetByVideoID retorna el episodio que tiene asignado el video externo, o nil si
+// ninguno lo tiene (p.ej. un webhook de un video ya reemplazado).
+func (r *Repository) GetByVideoID(ctx context.Context, videoID string) (*models.Episode, error) {
+	var e models.Episode
+	query := `SELECT id, series_id, episode_number, title, video_id_bunny, video_status, COALESCE(video_error, ''), duration, 
+	          is_free, price_coins, COALESCE(cover_url, ''), COALESCE(width, 0), COALESCE(height, 0), COALESCE(aspect_ratio, ''),
+	          COALESCE(video_codec, ''), COALESCE(audio_codec, ''), COALESCE(bitrate, 0), created_at, updated_at 
+	          FROM episodes WHERE video_id_bunny = $1 LIMIT 1`
+
+	err := r.db.QueryRowContext(ctx, query, videoID).Scan(
+		&e.ID, &e.SeriesID, &e.EpisodeNumber, &e.Title,
+		&e.VideoIDBunny, &e.VideoStatus, &e.VideoError, &e.Duration, &e.IsFree, &e.PriceCoins,
+		&e.CoverURL, &e.Width, &e.Height, &e.AspectRatio, &e.VideoCodec, &e.AudioCodec, &e.BitRate,
+		&e.CreatedAt, &e.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get episode by video: %w", err)
+	}
+	return &e, nil
+}
+
+// SetVideoStatus registra el estado del video videoID del episodio. Solo aplica si
+// el episodio sigue teniendo ese video (los avisos de un video reemplazado se
+// ignoran) y si el estado o el detalle cambian. firstReady indica que el episodio
+// quedó reproducible por primera vez.
+func (r *Repository) SetVideoStatus(ctx context.Context, episodeID uuid.UUID, videoID, status, detail string) (updated, firstReady bool, err error) {
+	query := `WITH prev AS (
+	              SELECT id, ready_at FROM episodes WHERE id = $1 FOR UPDATE
+	          )
+	          UPDATE episodes e
+	          SET video_status = $3, video_error = NULLIF($4, ''), video_status_updated_at = CURRENT_TIMESTAMP,
+	              ready_at = CASE WHEN $3 = 'ready' THEN COALESCE(e.ready_at, CURRENT_TIMESTAMP) ELSE e.ready_at END
+	          FROM prev
+	          WHERE e.id = prev.id AND COALESCE(e.video_id_bunny, '') = $2
+	            AND (e.video_status <> $3 OR COALESCE(e.video_error, '') <> $4)
+	          RETURNING prev.ready_at IS NULL AND e.ready_at IS NOT NULL`
+
+	err = r.db.QueryRowContext(ctx, query, episodeID, videoID, status, detail).Scan(&firstReady)
+	if err == sql.ErrNoRows {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to set video status: %w", err)
+	}
+	return true, firstReady, nil
+}
+
+// TouchVideoStatus renueva video_status_updated_at sin cambiar el estado, para
+// que el poller no vuelva a consultar el mismo video en cada pasada.
+func (r *Repository) TouchVideoStatus(ctx context.Context, episodeID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE episodes SET video_status_updated_at = CURRENT_TIMESTAMP WHERE id = $1`, episodeID)
+	if err != nil {
+		return fmt.Errorf("failed to touch video status: %w", err)
+	}
+	return nil
+}
+
+// ListPendingVideos retorna episodios con video subiendo o en proceso cuyo estado
+// no se actualiza desde hace más de olderThan, el más antiguo primero.
+func (r *Repository) ListPendingVideos(ctx context.Context, olderThan time.Duration, limit int) ([]models.Episode, error) {
+	query := `SELECT id, series_id, episode_number, title, video_id_bunny, video_status, COALESCE(video_error, ''), duration, 
+	          is_free, price_coins, COALESCE(cover_url, ''), COALESCE(width, 0), COALESCE(height, 0), COALESCE(aspect_ratio, ''),
+	          COALESCE(video_codec, ''), COALESCE(audio_codec, ''), COALESCE(bitrate, 0), created_at, updated_at 
+	          FROM episodes
+	          WHERE video_status IN ('uploading', 'processing') AND COALESCE(video_id_bunny, '') <> ''
+	            AND video_status_updated_at < NOW() - make_interval(secs => $1)
+	          ORDER BY video_status_updated_at ASC LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, olderThan.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending videos: %w", err)
+	}
+	defer rows.Close()
+
+	var episodes []models.Episode
+	for rows.Next() {
+		var e models.Episode
+		err := rows.Scan(
+			&e.ID, &e.SeriesID, &e.EpisodeNumber, &e.Title,
+			&e.VideoIDBunny, &e.VideoStatus, &e.VideoError, &e.Duration, &e.IsFree, &e.PriceCoins,
+			&e.CoverURL, &e.Width, &e.Height, &e.AspectRatio, &e.VideoCodec, &e.AudioCodec, &e.BitRate,
+			&e.CreatedAt, &e.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan episode: %w", err)
+		}
+		episodes = append(episodes, e)
+	}
+	return episodes, rows.Err()
+}
+
+// SetMedia registra la duración y los datos técnicos leídos del video.
+func (r *Repository) SetMedia(ctx context.Context, episodeID uuid.UUID, duration int, media models.EpisodeMedia) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE episodes
+		SET duration = $2, width = $3, height = $4, aspect_ratio = $5, video_codec = $6,
+		    audio_codec = NULLIF($7, ''), bitrate = NULLIF($8, 0), media_probed_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		episodeID, duration, media.Width, media.Height, media.AspectRatio, media.VideoCodec,
+		media.AudioCodec, media.BitRate)
+	if err != nil {
+		return fmt.Errorf("failed to set episode media: %w", err)
+	}
+	return nil
+}
+
+// SetCover asigna la portada del episodio.
+func (r *Repository) SetCover(ctx context.Context, episodeID uuid.UUID, coverURL string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE episodes SET cover_url = NULLIF($2, ''), updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		episodeID, coverURL)
+	if err != nil {
+		return fmt.Errorf("failed to set episode cover: %w", err)
+	}
+	return nil
+}
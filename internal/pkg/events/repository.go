@@ -0,0 +1,274 @@
+// Package events almacena la telemetría de reproducción a nivel de evento
+// (start, heartbeat, seek, pause, complete, buffer_stall, quality_change).
+// La tabla playback_events es append-only y está particionada por mes;
+// los agregados diarios se consolidan en playback_daily_stats.
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de evento aceptados por POST /app/events.
+const (
+	TypeStart         = "start"
+	TypeHeartbeat     = "heartbeat"
+	TypeSeek          = "seek"
+	TypePause         = "pause"
+	TypeComplete      = "complete"
+	TypeBufferStall   = "buffer_stall"
+	TypeQualityChange = "quality_change"
+)
+
+// validTypes se usa para validar el campo type de cada evento.
+var validTypes = map[string]bool{
+	TypeStart:         true,
+	TypeHeartbeat:     true,
+	TypeSeek:          true,
+	TypePause:         true,
+	TypeComplete:      true,
+	TypeBufferStall:   true,
+	TypeQualityChange: true,
+}
+
+// IsValidType indica si t es un tipo de evento soportado.
+func IsValidType(t string) bool {
+	return validTypes[t]
+}
+
+// Event representa un evento de reproducción enviado por el cliente.
+type Event struct {
+	UserID          *uuid.UUID
+	DeviceID        string
+	SessionID       string
+	EpisodeID       uuid.UUID
+	Type            string
+	PositionSeconds int
+	// DurationMs duración asociada al evento (ej. tiempo de un buffer stall)
+	DurationMs int
+	// Data payload libre (ej. {"from": 10, "to": 42} en un seek, o la calidad en quality_change)
+	Data     json.RawMessage
+	ClientTS time.Time
+}
+
+// DailyStat es una fila de playback_daily_stats.
+type DailyStat struct {
+	Day           time.Time `json:"day"`
+	EpisodeID     uuid.UUID `json:"episode_id"`
+	Starts        int       `json:"starts"`
+	UniqueViewers int       `json:"unique_viewers"`
+	Completions   int       `json:"completions"`
+	WatchSeconds  int       `json:"watch_seconds"`
+	BufferStalls  int       `json:"buffer_stalls"`
+	BufferStallMs int64     `json:"buffer_stall_ms"`
+}
+
+// HeartbeatIntervalSeconds es el intervalo con el que el cliente envía heartbeats.
+// Cada heartbeat cuenta como este número de segundos vistos en el rollup diario.
+const HeartbeatIntervalSeconds = 10
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// InsertBatch inserta un lote de eventos en una única transacción con un INSERT multi-fila.
+// received_at lo asigna el servidor (es la clave de partición); client_ts se guarda tal cual.
+func (r *Repository) InsertBatch(ctx context.Context, batch []Event) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	const cols = 10
+	var sb strings.Builder
+	sb.WriteString(`INSERT INTO playback_events
+		(user_id, device_id, session_id, episode_id, event_type,
+		 position_seconds, duration_ms, data, client_ts, received_at) VALUES `)
+
+	now := time.Now().UTC()
+	args := make([]interface{}, 0, len(batch)*cols)
+	for i, e := range batch {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		base := i * cols
+		fmt.Fprintf(&sb, "($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10)
+
+		var userIDValue interface{}
+		if e.UserID != nil {
+			userIDValue = *e.UserID
+		}
+		var dataValue interface{}
+		if len(e.Data) > 0 {
+			dataValue = string(e.Data)
+		}
+		args = append(args,
+			userIDValue, nullIfEmpty(e.DeviceID), nullIfEmpty(e.SessionID), e.EpisodeID, e.Type,
+			e.PositionSeconds, e.DurationMs, dataValue, e.ClientTS.UTC(), now,
+		)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin events tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, sb.String(), args...); err != nil {
+		return fmt.Errorf("failed to insert playback events: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit playback events: %w", err)
+	}
+	return nil
+}
+
+// EnsurePartition crea (si no existe) la partición mensual que contiene t.
+// Se llama al arrancar y periódicamente para el mes siguiente, de modo que las
+// inserciones nunca caigan en la partición por defecto.
+//
+// Si la partición por defecto ya tiene eventos del mes (el worker no corrió a
+// tiempo), Postgres no deja crear la partición encima: la tabla se arma aparte,
+// se le mueven esas filas y recién entonces se adjunta.
+func (r *Repository) EnsurePartition(ctx context.Context, t time.Time) error {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	name := fmt.Sprintf("playback_events_%04d%02d", start.Year(), int(start.Month()))
+
+	exists, err := r.partitionExists(ctx, r.db, name)
+	if err != nil || exists {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin partition tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// El lock frena las inserciones en la partición por defecto mientras se mueven
+	// las filas, y serializa a otras instancias creando la misma partición
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE playback_events_default IN ACCESS EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("failed to lock default partition: %w", err)
+	}
+	if exists, err := r.partitionExists(ctx, tx, name); err != nil || exists {
+		return err
+	}
+
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE %s (LIKE playback_events INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, name),
+		fmt.Sprintf(`WITH moved AS (
+			DELETE FROM playback_events_default
+			WHERE received_at >= '%s' AND received_at < '%s'
+			RETURNING *
+		 )
+		 INSERT INTO %s SELECT * FROM moved`, from, to, name),
+		fmt.Sprintf(`ALTER TABLE playback_events ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`, name, from, to),
+	}
+	for _, query := range statements {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit partition %s: %w", name, err)
+	}
+	return nil
+}
+
+// rowQuerier lo cumplen *sql.DB y *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// partitionExists indica si ya existe la tabla name.
+func (r *Repository) partitionExists(ctx context.Context, q rowQuerier, name string) (bool, error) {
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check partition %s: %w", name, err)
+	}
+	return exists, nil
+}
+
+// RollupDay recalcula los agregados de un día (UTC) en playback_daily_stats.
+// Es idempotente: se puede re-ejecutar sobre el mismo día sin duplicar.
+func (r *Repository) RollupDay(ctx context.Context, day time.Time) error {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+
+	query := `
+		INSERT INTO playback_daily_stats
+			(day, episode_id, starts, unique_viewers, completions,
+			 watch_seconds, buffer_stalls, buffer_stall_ms, updated_at)
+		SELECT $1::date, pe.episode_id,
+		       COUNT(*) FILTER (WHERE pe.event_type = 'start'),
+		       COUNT(DISTINCT COALESCE(pe.user_id::text, pe.device_id)),
+		       COUNT(*) FILTER (WHERE pe.event_type = 'complete'),
+		       COUNT(*) FILTER (WHERE pe.event_type = 'heartbeat') * $4,
+		       COUNT(*) FILTER (WHERE pe.event_type = 'buffer_stall'),
+		       COALESCE(SUM(pe.duration_ms) FILTER (WHERE pe.event_type = 'buffer_stall'), 0)
+		FROM playback_events pe
+		JOIN episodes e ON e.id = pe.episode_id -- descarta eventos de episodios borrados
+		WHERE pe.received_at >= $2 AND pe.received_at < $3
+		GROUP BY pe.episode_id
+		ON CONFLICT (day, episode_id) DO UPDATE SET
+			starts          = EXCLUDED.starts,
+			unique_viewers  = EXCLUDED.unique_viewers,
+			completions     = EXCLUDED.completions,
+			watch_seconds   = EXCLUDED.watch_seconds,
+			buffer_stalls   = EXCLUDED.buffer_stalls,
+			buffer_stall_ms = EXCLUDED.buffer_stall_ms,
+			updated_at      = NOW()
+	`
+	if _, err := r.db.ExecContext(ctx, query, start, start, end, HeartbeatIntervalSeconds); err != nil {
+		return fmt.Errorf("failed to rollup playback events for %s: %w", start.Format("2006-01-02"), err)
+	}
+	return nil
+}
+
+// GetDailyStats devuelve los agregados diarios de un episodio entre from y to (inclusive).
+func (r *Repository) GetDailyStats(ctx context.Context, episodeID uuid.UUID, from, to time.Time) ([]DailyStat, error) {
+	query := `
+		SELECT day, episode_id, starts, unique_viewers, completions,
+		       watch_seconds, buffer_stalls, buffer_stall_ms
+		FROM playback_daily_stats
+		WHERE episode_id = $1 AND day BETWEEN $2::date AND $3::date
+		ORDER BY day ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, episodeID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query playback daily stats: %w", err)
+	}
+	defer rows.Close()
+
+	var result []DailyStat
+	for rows.Next() {
+		var s DailyStat
+		if err := rows.Scan(
+			&s.Day, &s.EpisodeID, &s.Starts, &s.UniqueViewers, &s.Completions,
+			&s.WatchSeconds, &s.BufferStalls, &s.BufferStallMs,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan playback daily stat: %w", err)
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// RunMaintenance asegura las particiones del mes actual y del siguiente y
+// recalcula los agregados de hoy y de ayer (los eventos de ayer pueden llegar tarde).
+// Un error en un paso no frena los demás: se devuelven todos juntos.
+func (r *Repository) RunMaintenance(ctx context.Context) error {
+	now := time.Now().UTC()
+	// Desde el día 1: el 31 de enero + 1 mes sería marzo y se saltearía febrero
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var errs []error
+	for _, t := range []time.Time{month, month.AddDate(0, 1, 0)} {
+		if err := r.EnsurePartition(ctx, t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		if err := r.RollupDay(ctx, day); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StartWorker ejecuta RunMaintenance al arrancar y luego cada `interval`
+// en una goroutine, hasta que ctx se cancele. Los errores solo se loguean.
+func (r *Repository) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		if err := r.RunMaintenance(ctx); err != nil {
+			log.Printf("events: maintenance failed: %v", err)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.RunMaintenance(ctx); err != nil {
+					log.Printf("events: maintenance failed: %v", err)
+				}
+			}
+		}
+	}()
+}
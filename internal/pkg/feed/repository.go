@@ -0,0 +1,250 @@
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const rowColumns = `id, producer_id, position, kind, title, title_translations, genre_id, item_limit,
+	countries, platforms, audience, starts_at, ends_at, is_active, created_by, created_at, updated_at`
+
+func scanRow(row interface{ Scan(...interface{}) error }) (*Row, error) {
+	var r Row
+	var translations []byte
+	if err := row.Scan(&r.ID, &r.ProducerID, &r.Position, &r.Kind, &r.Title, &translations, &r.GenreID, &r.ItemLimit,
+		pq.Array(&r.Countries), pq.Array(&r.Platforms), &r.Audience, &r.StartsAt, &r.EndsAt, &r.IsActive,
+		&r.CreatedBy, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(translations, &r.TitleTranslations); err != nil {
+		return nil, fmt.Errorf("failed to decode row title translations: %w", err)
+	}
+	if r.TitleTranslations == nil {
+		r.TitleTranslations = map[string]string{}
+	}
+	if r.Countries == nil {
+		r.Countries = []string{}
+	}
+	if r.Platforms == nil {
+		r.Platforms = []string{}
+	}
+	r.Items = []Item{}
+	return &r, nil
+}
+
+// producerClause filtra por la app del productor (NULL = app de plataforma).
+func producerClause(producerID *uuid.UUID) (string, []interface{}) {
+	if producerID == nil {
+		return `producer_id IS NULL`, nil
+	}
+	return `producer_id = $1`, []interface{}{*producerID}
+}
+
+// List lista todas las filas de la app del productor (nil = plataforma) en orden,
+// incluidas las inactivas y las fuera de su ventana.
+func (r *Repository) List(ctx context.Context, producerID *uuid.UUID) ([]Row, error) {
+	where, args := producerClause(producerID)
+	return r.find(ctx, `WHERE `+where+` ORDER BY position, created_at`, args...)
+}
+
+// ActiveRows lista en orden las filas activas y dentro de su ventana de la app del
+// productor (nil = plataforma). La segmentación se aplica después con Row.Targets.
+func (r *Repository) ActiveRows(ctx context.Context, producerID *uuid.UUID) ([]Row, error) {
+	where, args := producerClause(producerID)
+	return r.find(ctx, `WHERE `+where+` AND is_active = TRUE
+		AND (starts_at IS NULL OR starts_at <= NOW()) AND (ends_at IS NULL OR ends_at > NOW())
+		ORDER BY position, created_at`, args...)
+}
+
+// Get devuelve la fila con sus series, o nil si no existe.
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*Row, error) {
+	rows, err := r.find(ctx, `WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+func (r *Repository) find(ctx context.Context, where string, args ...interface{}) ([]Row, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+rowColumns+` FROM feed_rows `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feed rows: %w", err)
+	}
+	var list []Row
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		row, err := scanRow(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan feed row: %w", err)
+		}
+		index[row.ID] = len(list)
+		list = append(list, *row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list feed rows: %w", err)
+	}
+	if len(list) == 0 {
+		return list, nil
+	}
+
+	ids := make([]string, len(list))
+	for i := range list {
+		ids[i] = list[i].ID.String()
+	}
+	items, err := r.db.QueryContext(ctx, `
+		SELECT row_id, series_id, image_id FROM feed_row_items
+		WHERE row_id = ANY($1::uuid[]) ORDER BY row_id, position`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feed row items: %w", err)
+	}
+	defer items.Close()
+	for items.Next() {
+		var rowID uuid.UUID
+		var item Item
+		if err := items.Scan(&rowID, &item.SeriesID, &item.ImageID); err != nil {
+			return nil, fmt.Errorf("failed to scan feed row item: %w", err)
+		}
+		i := index[rowID]
+		list[i].Items = append(list[i].Items, item)
+	}
+	return list, items.Err()
+}
+
+// Create crea la fila (al final de la app si Position es 0) con sus series, y
+// completa ID, posición y fechas.
+func (r *Repository) Create(ctx context.Context, row *Row) error {
+	translations, err := json.Marshal(row.TitleTranslations)
+	if err != nil {
+		return fmt.Errorf("failed to encode row title translations: %w", err)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if row.Position == 0 {
+		where, args := producerClause(row.ProducerID)
+		if err := tx.QueryRowContext(ctx,
+			`SELECT COALESCE(MAX(position), 0) + 1 FROM feed_rows WHERE `+where, args...,
+		).Scan(&row.Position); err != nil {
+			return fmt.Errorf("failed to get next row position: %w", err)
+		}
+	}
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO feed_rows (producer_id, position, kind, title, title_translations, genre_id, item_limit,
+		                       countries, platforms, audience, starts_at, ends_at, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at, updated_at`,
+		row.ProducerID, row.Position, row.Kind, row.Title, translations, row.GenreID, row.ItemLimit,
+		pq.Array(row.Countries), pq.Array(row.Platforms), row.Audience, row.StartsAt, row.EndsAt,
+		row.IsActive, row.CreatedBy,
+	).Scan(&row.ID, &row.CreatedAt, &row.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create feed row: %w", err)
+	}
+	if err := replaceItems(ctx, tx, row.ID, row.Items); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit feed row: %w", err)
+	}
+	return nil
+}
+
+// Update guarda todos los campos editables de la fila y reemplaza sus series.
+func (r *Repository) Update(ctx context.Context, row *Row) error {
+	translations, err := json.Marshal(row.TitleTranslations)
+	if err != nil {
+		return fmt.Errorf("failed to encode row title translations: %w", err)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE feed_rows
+		SET position = $2, kind = $3, title = $4, title_translations = $5, genre_id = $6, item_limit = $7,
+		    countries = $8, platforms = $9, audience = $10, starts_at = $11, ends_at = $12, is_active = $13,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`,
+		row.ID, row.Position, row.Kind, row.Title, translations, row.GenreID, row.ItemLimit,
+		pq.Array(row.Countries), pq.Array(row.Platforms), row.Audience, row.StartsAt, row.EndsAt, row.IsActive,
+	).Scan(&row.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update feed row: %w", err)
+	}
+	if err := replaceItems(ctx, tx, row.ID, row.Items); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit feed row: %w", err)
+	}
+	return nil
+}
+
+func replaceItems(ctx context.Context, tx *sql.Tx, rowID uuid.UUID, items []Item) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM feed_row_items WHERE row_id = $1`, rowID); err != nil {
+		return fmt.Errorf("failed to clear feed row items: %w", err)
+	}
+	for i, item := range items {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO feed_row_items (row_id, series_id, position, image_id) VALUES ($1, $2, $3, $4)`,
+			rowID, item.SeriesID, i, item.ImageID); err != nil {
+			return fmt.Errorf("failed to add feed row item: %w", err)
+		}
+	}
+	return nil
+}
+
+// Delete borra la fila. Devuelve false si no existía.
+func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM feed_rows WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete feed row: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Reorder asigna las posiciones 1..n a las filas de la app del productor en el
+// orden de ids. Las filas de la app que no estén en ids van después, en su orden.
+func (r *Repository) Reorder(ctx context.Context, producerID *uuid.UUID, ids []uuid.UUID) error {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+	where, args := producerClause(producerID)
+	n := len(args) + 1
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE feed_rows f SET position = o.position, updated_at = CURRENT_TIMESTAMP
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (
+				ORDER BY COALESCE(array_position($%d::uuid[], id), %d + position), created_at) AS position
+			FROM feed_rows WHERE %s
+		) o
+		WHERE f.id = o.id`, n, len(ids), where), append(args, pq.Array(keys))...)
+	if err != nil {
+		return fmt.Errorf("failed to reorder feed rows: %w", err)
+	}
+	return nil
+}
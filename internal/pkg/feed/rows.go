@@ -0,0 +1,125 @@
+// Package feed guarda la composición del home de la app: las filas que define el
+// admin de cada app (curadas, hero, por género o algorítmicas), con su segmentación
+// y ventana de publicación. El armado de cada fila lo hace el handler del feed.
+package feed
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de fila.
+const (
+	KindHero             = "hero"
+	KindCurated          = "curated"
+	KindGenre            = "genre"
+	KindTrending         = "trending"
+	KindNewReleases      = "new_releases"
+	KindMostViewed       = "most_viewed"
+	KindContinueWatching = "continue_watching"
+	KindRecommended      = "recommended"
+)
+
+// Audiencias de una fila.
+const (
+	AudienceAll     = "all"
+	AudiencePremium = "premium"
+	AudienceFree    = "free"
+)
+
+// Platforms plataformas que se pueden segmentar (header X-Platform de la app).
+var Platforms = []string{"android", "ios", "web"}
+
+// ValidKind indica si kind es un tipo de fila conocido.
+func ValidKind(kind string) bool {
+	switch kind {
+	case KindHero, KindCurated, KindGenre, KindTrending, KindNewReleases,
+		KindMostViewed, KindContinueWatching, KindRecommended:
+		return true
+	}
+	return false
+}
+
+// HasItems indica si las series de la fila las elige el admin (curadas y hero).
+func HasItems(kind string) bool {
+	return kind == KindHero || kind == KindCurated
+}
+
+// Item serie de una fila curada o hero. ImageID arte propio del banner.
+type Item struct {
+	SeriesID uuid.UUID  `json:"series_id"`
+	ImageID  *uuid.UUID `json:"image_id,omitempty"`
+}
+
+// Row fila del home.
+type Row struct {
+	ID                uuid.UUID         `json:"id"`
+	ProducerID        *uuid.UUID        `json:"producer_id,omitempty"`
+	Position          int               `json:"position"`
+	Kind              string            `json:"kind"`
+	Title             string            `json:"title"`
+	TitleTranslations map[string]string `json:"title_translations"`
+	GenreID           *uuid.UUID        `json:"genre_id,omitempty"`
+	ItemLimit         int               `json:"item_limit"`
+	Countries         []string          `json:"countries"`
+	Platforms         []string          `json:"platforms"`
+	Audience          string            `json:"audience"`
+	StartsAt          *time.Time        `json:"starts_at,omitempty"`
+	EndsAt            *time.Time        `json:"ends_at,omitempty"`
+	IsActive          bool              `json:"is_active"`
+	Items             []Item            `json:"items"`
+	CreatedBy         *uuid.UUID        `json:"created_by,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
+
+// Viewer datos del pedido con los que se segmentan las filas. Los vacíos solo
+// coinciden con las filas que no segmentan por ese dato.
+type Viewer struct {
+	Country  string // ISO-3166-1 alpha-2 en mayúsculas
+	Platform string
+	Premium  bool
+}
+
+// Targets indica si la segmentación de la fila (país, plataforma y audiencia)
+// incluye al viewer. El estado y la ventana de publicación los filtra ActiveRows.
+func (r *Row) Targets(v Viewer) bool {
+	if len(r.Countries) > 0 && !contains(r.Countries, v.Country) {
+		return false
+	}
+	if len(r.Platforms) > 0 && !contains(r.Platforms, v.Platform) {
+		return false
+	}
+	switch r.Audience {
+	case AudiencePremium:
+		return v.Premium
+	case AudienceFree:
+		return !v.Premium
+	}
+	return true
+}
+
+// LocalizedTitle devuelve el título en el primer idioma de la cadena que tenga
+// traducción, o el del idioma por defecto.
+func (r *Row) LocalizedTitle(chain []string) string {
+	for _, l := range chain {
+		if t := r.TitleTranslations[l]; t != "" {
+			return t
+		}
+	}
+	return r.Title
+}
+
+func contains(list []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
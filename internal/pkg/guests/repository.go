@@ -0,0 +1,318 @@
+// Package guests gestiona las cuentas invitadas: usuarios ligados a un device ID que
+// pueden ganar monedas (check-in, anuncios) y desbloquear episodios sin identidad
+// Firebase, y que más tarde se vinculan a una cuenta real.
+package guests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/cache"
+	"github.com/qenti/qenti/internal/pkg/jwt"
+	"github.com/qenti/qenti/internal/pkg/models"
+)
+
+// WelcomeBonus monedas de bienvenida. El invitado no las recibe al crearse sino al
+// vincular una identidad nueva, igual que un usuario que entra directo con Firebase.
+const WelcomeBonus = 50
+
+// tokenCheckTimeout tiempo máximo de la consulta de TokenRevoked.
+const tokenCheckTimeout = 2 * time.Second
+
+// guestCacheTTL cuánto se recuerda si una cuenta sigue siendo invitada. MergeInto y
+// Upgrade borran la entrada; un borrado de cuenta (privacy) tarda a lo sumo esto.
+const guestCacheTTL = 30 * time.Second
+
+// ConflictPolicy define qué hacer al vincular un invitado con una identidad Firebase
+// que ya tiene cuenta.
+type ConflictPolicy string
+
+const (
+	// PolicyMerge fusiona el invitado en la cuenta existente (por defecto).
+	PolicyMerge ConflictPolicy = "merge"
+	// PolicyReject no toca nada y devuelve 409 para que el cliente pregunte al usuario.
+	PolicyReject ConflictPolicy = "reject"
+)
+
+var (
+	// ErrNotGuest el usuario no existe o ya no es invitado (p. ej. ya se vinculó).
+	ErrNotGuest = errors.New("guests: user is not a guest account")
+	// ErrEmailTaken el email de la identidad pertenece a otra cuenta con distinto firebase_uid.
+	ErrEmailTaken = errors.New("guests: email already used by another account")
+)
+
+// MergeResult resume qué se transfirió de la cuenta invitada a la cuenta destino.
+type MergeResult struct {
+	Coins     int   `json:"coins"`
+	Unlocks   int64 `json:"unlocks"`
+	Views     int64 `json:"views"`
+	Favorites int64 `json:"favorites"`
+}
+
+type Repository struct {
+	db    *sql.DB
+	cache *cache.Cache
+}
+
+func NewRepository(db *sql.DB, cacheStore *cache.Cache) *Repository {
+	return &Repository{db: db, cache: cacheStore}
+}
+
+func guestCacheKey(guestID uuid.UUID) string {
+	return "guest:" + guestID.String()
+}
+
+// forget descarta del cache el estado de la cuenta invitada tras fusionarla o
+// convertirla, para que sus tokens dejen de valer en todas las instancias.
+func (r *Repository) forget(ctx context.Context, guestID uuid.UUID) {
+	if err := r.cache.Delete(ctx, guestCacheKey(guestID)); err != nil {
+		log.Printf("guests: invalidate cache: %v", err)
+	}
+}
+
+// syntheticIdentity firebase_uid y email sintéticos de un invitado (ambos son NOT NULL UNIQUE).
+func syntheticIdentity(deviceID uuid.UUID) (firebaseUID, email string) {
+	return "guest:" + deviceID.String(), "guest-" + deviceID.String() + "@guest.qenti.local"
+}
+
+// GetOrCreate devuelve la cuenta invitada del dispositivo, creándola si no existe.
+// created indica si se acaba de crear.
+func (r *Repository) GetOrCreate(ctx context.Context, deviceID uuid.UUID) (user *models.User, created bool, err error) {
+	firebaseUID, email := syntheticIdentity(deviceID)
+
+	var u models.User
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO users (id, email, firebase_uid, coin_balance, is_premium, is_guest, guest_device_id)
+		VALUES ($1, $2, $3, 0, FALSE, TRUE, $4)
+		ON CONFLICT (firebase_uid) DO NOTHING
+		RETURNING id, email, firebase_uid, coin_balance, is_premium, created_at, updated_at`,
+		uuid.New(), email, firebaseUID, deviceID,
+	).Scan(&u.ID, &u.Email, &u.FirebaseUID, &u.CoinBalance, &u.IsPremium, &u.CreatedAt, &u.UpdatedAt)
+	if err == nil {
+		return &u, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to create guest user: %w", err)
+	}
+
+	// Ya existía: devolverla. Tras Upgrade/MergeInto el uid sintético queda libre y
+	// el mismo dispositivo obtendría una cuenta invitada nueva.
+	err = r.db.QueryRowContext(ctx, `
+		SELECT id, email, firebase_uid, coin_balance, is_premium, created_at, updated_at
+		FROM users WHERE firebase_uid = $1`,
+		firebaseUID,
+	).Scan(&u.ID, &u.Email, &u.FirebaseUID, &u.CoinBalance, &u.IsPremium, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get guest user: %w", err)
+	}
+	return &u, false, nil
+}
+
+// IsGuest indica si guestID sigue siendo una cuenta invitada (vía cache): MergeInto
+// la borra, Upgrade la convierte en una cuenta normal y el borrado de cuenta la elimina.
+func (r *Repository) IsGuest(ctx context.Context, guestID uuid.UUID) (bool, error) {
+	return cache.GetOrLoad(ctx, r.cache, guestCacheKey(guestID), guestCacheTTL, nil,
+		func(ctx context.Context) (bool, error) {
+			var guest bool
+			err := r.db.QueryRowContext(ctx,
+				`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND is_guest = TRUE)`, guestID,
+			).Scan(&guest)
+			if err != nil {
+				return false, fmt.Errorf("failed to check guest user: %w", err)
+			}
+			return guest, nil
+		})
+}
+
+// TokenRevoked es la verificación de revocación de jwt.Service: rechaza los tokens
+// de invitado cuya cuenta ya no es invitada (fusionada, vinculada o borrada), que
+// siguen firmados hasta vencer. Solo consulta para tokens de invitado.
+//
+// Si la consulta falla el token se rechaza: aceptarlo dejaría entrar con la cuenta
+// de otro. Con la base caída la request tampoco podría atenderse, y el cliente
+// recupera la misma cuenta invitada con POST /auth/guest (va ligada al dispositivo).
+func (r *Repository) TokenRevoked(claims *jwt.Claims) bool {
+	if !claims.IsGuest() {
+		return false
+	}
+	guestID, err := claims.GetUserID()
+	if err != nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCheckTimeout)
+	defer cancel()
+	guest, err := r.IsGuest(ctx, guestID)
+	if err != nil {
+		log.Printf("guests: %v", err)
+		return true
+	}
+	return !guest
+}
+
+// AccountByFirebaseUID devuelve el ID de la cuenta (no invitada) con esa identidad Firebase.
+// found es false si la identidad todavía no tiene cuenta.
+func (r *Repository) AccountByFirebaseUID(ctx context.Context, firebaseUID string) (id uuid.UUID, found bool, err error) {
+	err = r.db.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE firebase_uid = $1 AND is_guest = FALSE`, firebaseUID,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to look up account: %w", err)
+	}
+	return id, true, nil
+}
+
+// Upgrade convierte la cuenta invitada en una cuenta normal con la identidad Firebase
+// dada. Como el user_id no cambia, wallet, unlocks, progreso y favoritos se conservan.
+// Suma WelcomeBonus al balance.
+func (r *Repository) Upgrade(ctx context.Context, guestID uuid.UUID, firebaseUID, email string) (*models.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin upgrade tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var taken bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND id <> $2)`, email, guestID,
+	).Scan(&taken); err != nil {
+		return nil, fmt.Errorf("failed to check email: %w", err)
+	}
+	if taken {
+		return nil, ErrEmailTaken
+	}
+
+	var u models.User
+	err = tx.QueryRowContext(ctx, `
+		UPDATE users
+		SET firebase_uid = $2, email = $3,
+		    is_guest = FALSE, guest_device_id = NULL,
+		    coin_balance = coin_balance + $4,
+		    updated_at = NOW()
+		WHERE id = $1 AND is_guest = TRUE
+		RETURNING id, email, firebase_uid, coin_balance, is_premium, created_at, updated_at`,
+		guestID, firebaseUID, email, WelcomeBonus,
+	).Scan(&u.ID, &u.Email, &u.FirebaseUID, &u.CoinBalance, &u.IsPremium, &u.CreatedAt, &u.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotGuest
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upgrade guest: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit guest upgrade: %w", err)
+	}
+	r.forget(ctx, guestID)
+	return &u, nil
+}
+
+// MergeInto fusiona la cuenta invitada en la cuenta accountID y borra la invitada,
+// todo en una transacción. Política de conflictos:
+//   - monedas: se suman
+//   - unlocks y favoritos: unión (los duplicados se descartan)
+//   - progreso del mismo episodio: mayor watched_seconds, completed = OR
+//   - transacciones, anuncios validados, tokens FCM y telemetría: se reasignan
+//   - is_premium y roles: se conservan los de la cuenta destino
+func (r *Repository) MergeInto(ctx context.Context, guestID, accountID uuid.UUID) (*MergeResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin merge tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &MergeResult{}
+
+	// Bloquear la fila del invitado: evita gastar monedas mientras se fusiona
+	err = tx.QueryRowContext(ctx,
+		`SELECT coin_balance FROM users WHERE id = $1 AND is_guest = TRUE FOR UPDATE`, guestID,
+	).Scan(&result.Coins)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotGuest
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock guest user: %w", err)
+	}
+
+	// 1. Progreso: fusionar episodios en común y reasignar el resto
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE views u
+		SET watched_seconds = GREATEST(u.watched_seconds, g.watched_seconds),
+		    completed       = u.completed OR g.completed,
+		    updated_at      = GREATEST(u.updated_at, g.updated_at)
+		FROM views g
+		WHERE g.user_id = $1 AND u.user_id = $2 AND u.episode_id = g.episode_id`,
+		guestID, accountID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to merge guest progress: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM views g
+		WHERE g.user_id = $1
+		  AND EXISTS (SELECT 1 FROM views u WHERE u.user_id = $2 AND u.episode_id = g.episode_id)`,
+		guestID, accountID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to drop merged guest views: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE views SET user_id = $2 WHERE user_id = $1`, guestID, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign guest views: %w", err)
+	}
+	result.Views, _ = res.RowsAffected()
+
+	// 2. Unlocks y favoritos: unión (las filas del invitado se borran en cascada al final)
+	res, err = tx.ExecContext(ctx, `
+		INSERT INTO unlocks (user_id, episode_id, method, unlocked_at)
+		SELECT $2, episode_id, method, unlocked_at FROM unlocks WHERE user_id = $1
+		ON CONFLICT (user_id, episode_id) DO NOTHING`,
+		guestID, accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge guest unlocks: %w", err)
+	}
+	result.Unlocks, _ = res.RowsAffected()
+
+	res, err = tx.ExecContext(ctx, `
+		INSERT INTO favorites (user_id, series_id, created_at)
+		SELECT $2, series_id, created_at FROM favorites WHERE user_id = $1
+		ON CONFLICT (user_id, series_id) DO NOTHING`,
+		guestID, accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge guest favorites: %w", err)
+	}
+	result.Favorites, _ = res.RowsAffected()
+
+	// 3. Historial que se conserva tal cual
+	for _, table := range []string{"transactions", "ad_validations", "device_tokens", "playback_events"} {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE `+table+` SET user_id = $2 WHERE user_id = $1`, guestID, accountID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to reassign guest %s: %w", table, err)
+		}
+	}
+
+	// 4. Monedas y borrado del invitado
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET coin_balance = coin_balance + $2, updated_at = NOW() WHERE id = $1`,
+		accountID, result.Coins,
+	); err != nil {
+		return nil, fmt.Errorf("failed to transfer guest coins: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, guestID); err != nil {
+		return nil, fmt.Errorf("failed to delete guest user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit guest merge: %w", err)
+	}
+	r.forget(ctx, guestID)
+	return result, nil
+}
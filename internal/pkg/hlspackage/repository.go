@@ -0,0 +1,101 @@
+// Package hlspackage corre el empaquetado HLS de los originales que se suben a un
+// proveedor storage.HLSPackager (S3 con S3_PACKAGE_HLS) como trabajos persistentes
+// en hls_package_jobs: si ffmpeg o el bucket fallan se reintenta con espera
+// creciente, y los trabajos de una instancia caída los retoma otra.
+package hlspackage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/qenti/qenti/internal/pkg/jobqueue"
+)
+
+// Estados de hls_package_jobs.
+const (
+	StatusQueued    = "queued"
+	StatusPackaging = "packaging"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Job es el empaquetado HLS de un video.
+type Job struct {
+	ExternalID string
+	Status     string
+	Attempts   int
+	Error      string
+}
+
+type Repository struct {
+	db    *sql.DB
+	queue *jobqueue.Queue
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db, queue: jobqueue.New(db, jobqueue.Table{
+		Name:     "hls_package_jobs",
+		Key:      "external_id",
+		Noun:     "HLS package job",
+		Running:  []string{StatusPackaging},
+		RunAfter: true,
+	})}
+}
+
+// Enqueue encola el empaquetado del video. Un video ya encolado o terminado vuelve
+// a empezar; uno en curso se deja como está.
+func (r *Repository) Enqueue(ctx context.Context, externalID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO hls_package_jobs (external_id) VALUES ($1)
+		ON CONFLICT (external_id) DO UPDATE SET
+			status = 'queued', attempts = 0, error = NULL, run_after = NOW(), updated_at = NOW()
+		WHERE hls_package_jobs.status <> 'packaging'`, externalID)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue HLS package job: %w", err)
+	}
+	return nil
+}
+
+// Claim toma el trabajo pendiente más antiguo y lo marca 'packaging'. SKIP LOCKED
+// permite varias instancias. Devuelve nil si no hay trabajo.
+func (r *Repository) Claim(ctx context.Context) (*Job, error) {
+	var job Job
+	ok, err := r.queue.Claim(ctx, `external_id, status, attempts, COALESCE(error, '')`, func(row jobqueue.Row) error {
+		return row.Scan(&job.ExternalID, &job.Status, &job.Attempts, &job.Error)
+	})
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Touch renueva el heartbeat de un trabajo en curso (ver RequeueStale).
+func (r *Repository) Touch(ctx context.Context, externalID string) error {
+	return r.queue.Touch(ctx, externalID)
+}
+
+// Complete marca el trabajo como terminado.
+func (r *Repository) Complete(ctx context.Context, externalID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE hls_package_jobs SET status = 'completed', error = NULL, updated_at = NOW()
+		WHERE external_id = $1`, externalID)
+	if err != nil {
+		return fmt.Errorf("failed to complete HLS package job: %w", err)
+	}
+	return nil
+}
+
+// Retry vuelve a encolar el trabajo después de retryAfter, o lo marca como fallido
+// si ya no quedan intentos (retryAfter <= 0).
+func (r *Repository) Retry(ctx context.Context, externalID string, cause error, retryAfter time.Duration) error {
+	return r.queue.Retry(ctx, externalID, cause, retryAfter)
+}
+
+// RequeueStale re-encola los trabajos en curso sin heartbeat desde hace más de
+// staleAfter (la instancia que los procesaba murió). Tras maxAttempts intentos se
+// marcan como fallidos. Devuelve cuántos re-encoló.
+func (r *Repository) RequeueStale(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int64, error) {
+	return r.queue.RequeueStale(ctx, staleAfter, maxAttempts)
+}
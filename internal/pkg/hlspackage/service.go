@@ -0,0 +1,135 @@
+package hlspackage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+const (
+	// staleAfter sin heartbeat durante este tiempo un trabajo en curso se re-encola.
+	staleAfter = 10 * time.Minute
+	// heartbeatInterval cada cuánto se renueva el heartbeat de un trabajo en curso.
+	heartbeatInterval = time.Minute
+	// maxAttempts intentos antes de dar el empaquetado por fallido.
+	maxAttempts = 5
+	// retryDelay espera antes del segundo intento; se duplica en cada uno.
+	retryDelay = time.Minute
+)
+
+// Service encola y procesa los empaquetados HLS del proveedor.
+type Service struct {
+	repo     *Repository
+	packager storage.HLSPackager
+	wake     chan struct{}
+}
+
+// NewService crea el servicio y lo registra como la cola de empaquetado del proveedor.
+func NewService(db *sql.DB, packager storage.HLSPackager) *Service {
+	s := &Service{
+		repo:     NewRepository(db),
+		packager: packager,
+		wake:     make(chan struct{}, 1),
+	}
+	packager.SetPackageQueue(s.Enqueue)
+	return s
+}
+
+// Enqueue encola el empaquetado del video y despierta al worker.
+func (s *Service) Enqueue(ctx context.Context, externalID string) error {
+	if err := s.repo.Enqueue(ctx, externalID); err != nil {
+		return err
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// StartWorker procesa la cola al arrancar, con cada Enqueue y cada `interval`, y
+// re-encola los trabajos huérfanos de instancias caídas, hasta que ctx se cancele.
+func (s *Service) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := s.repo.RequeueStale(ctx, staleAfter, maxAttempts); err != nil {
+				log.Printf("hlspackage: %v", err)
+			} else if n > 0 {
+				log.Printf("hlspackage: requeued %d stale jobs", n)
+			}
+			s.ProcessQueue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// ProcessQueue procesa los trabajos pendientes hasta vaciar la cola.
+func (s *Service) ProcessQueue(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := s.repo.Claim(ctx)
+		if err != nil {
+			log.Printf("hlspackage: %v", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		stop := s.heartbeat(ctx, job.ExternalID)
+		err = s.packager.PackageHLS(ctx, job.ExternalID)
+		stop()
+		if err != nil && ctx.Err() != nil {
+			return // apagado: RequeueStale lo retoma
+		}
+		if err == nil {
+			log.Printf("✅ hlspackage: HLS listo para %s", job.ExternalID)
+			if err := s.repo.Complete(ctx, job.ExternalID); err != nil {
+				log.Printf("hlspackage: %v", err)
+			}
+			continue
+		}
+
+		// Un video borrado no tiene nada que reintentar
+		retryAfter := retryDelay << (job.Attempts - 1)
+		if job.Attempts >= maxAttempts || errors.Is(err, storage.ErrVideoNotFound) {
+			retryAfter = 0
+		}
+		log.Printf("hlspackage: video %s attempt %d failed: %v", job.ExternalID, job.Attempts, err)
+		if err := s.repo.Retry(ctx, job.ExternalID, err, retryAfter); err != nil {
+			log.Printf("hlspackage: %v", err)
+		}
+	}
+}
+
+// heartbeat renueva updated_at del trabajo mientras se empaqueta.
+func (s *Service) heartbeat(ctx context.Context, externalID string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.repo.Touch(ctx, externalID); err != nil {
+					log.Printf("hlspackage: %v", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,159 @@
+// Package images procesa las imágenes que se suben por la API (posters, banners,
+// logos): detecta el formato por los magic bytes, aplica la orientación EXIF y la
+// descarta junto con el resto de metadatos al recodificar, genera las variantes
+// redimensionadas y las guarda en un storage.ObjectStore. Cada imagen se guarda
+// una sola vez por contenido (SHA-256 de los bytes subidos).
+package images
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/qenti/qenti/internal/pkg/models"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const imageColumns = `id, content_hash, format, width, height, has_alpha, size_bytes, variants, uploaded_by, created_at`
+
+func scanImage(row interface{ Scan(...interface{}) error }) (*models.Image, error) {
+	var img models.Image
+	var variants []byte
+	if err := row.Scan(&img.ID, &img.ContentHash, &img.Format, &img.Width, &img.Height, &img.HasAlpha,
+		&img.SizeBytes, &variants, &img.UploadedBy, &img.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(variants, &img.Variants); err != nil {
+		return nil, fmt.Errorf("invalid variants: %w", err)
+	}
+	return &img, nil
+}
+
+// Create registra la imagen. Si otra con el mismo content_hash se guardó antes
+// (uploads concurrentes del mismo archivo), devuelve esa y false.
+func (r *Repository) Create(ctx context.Context, img *models.Image) (*models.Image, bool, error) {
+	variants, err := json.Marshal(img.Variants)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode variants: %w", err)
+	}
+	created, err := scanImage(r.db.QueryRowContext(ctx, `
+		INSERT INTO images (id, content_hash, format, width, height, has_alpha, size_bytes, variants, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (content_hash) DO NOTHING
+		RETURNING `+imageColumns,
+		img.ID, img.ContentHash, img.Format, img.Width, img.Height, img.HasAlpha, img.SizeBytes, variants, img.UploadedBy))
+	if err == nil {
+		return created, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to create image: %w", err)
+	}
+	existing, err := r.GetByHash(ctx, img.ContentHash)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing == nil {
+		return nil, false, fmt.Errorf("failed to create image: conflicting row disappeared")
+	}
+	return existing, false, nil
+}
+
+// Get devuelve una imagen por ID, o nil si no existe.
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*models.Image, error) {
+	img, err := scanImage(r.db.QueryRowContext(ctx,
+		`SELECT `+imageColumns+` FROM images WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+	return img, nil
+}
+
+// GetByHash devuelve la imagen con ese contenido, o nil si no existe.
+func (r *Repository) GetByHash(ctx context.Context, contentHash string) (*models.Image, error) {
+	img, err := scanImage(r.db.QueryRowContext(ctx,
+		`SELECT `+imageColumns+` FROM images WHERE content_hash = $1`, contentHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+	return img, nil
+}
+
+// GetMany devuelve las imágenes pedidas indexadas por ID (las que no existen se omiten).
+func (r *Repository) GetMany(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.Image, error) {
+	result := make(map[uuid.UUID]*models.Image, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+imageColumns+` FROM images WHERE id = ANY($1::uuid[])`, pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get images: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan image: %w", err)
+		}
+		result[img.ID] = img
+	}
+	return result, rows.Err()
+}
+
+// AttachSeries completa las URLs de las variantes de los posters de la serie.
+func (r *Repository) AttachSeries(ctx context.Context, s *models.Series) error {
+	found, err := r.GetMany(ctx, presentIDs(s.HorizontalPosterImageID, s.VerticalPosterImageID))
+	if err != nil {
+		return err
+	}
+	s.HorizontalPosterImage = lookup(found, s.HorizontalPosterImageID)
+	s.VerticalPosterImage = lookup(found, s.VerticalPosterImageID)
+	return nil
+}
+
+// AttachProducer completa las URLs de las variantes del logo del productor.
+func (r *Repository) AttachProducer(ctx context.Context, p *models.Producer) error {
+	found, err := r.GetMany(ctx, presentIDs(p.LogoImageID))
+	if err != nil {
+		return err
+	}
+	p.LogoImage = lookup(found, p.LogoImageID)
+	return nil
+}
+
+func presentIDs(ids ...*uuid.UUID) []uuid.UUID {
+	var present []uuid.UUID
+	for _, id := range ids {
+		if id != nil {
+			present = append(present, *id)
+		}
+	}
+	return present
+}
+
+func lookup(found map[uuid.UUID]*models.Image, id *uuid.UUID) *models.Image {
+	if id == nil {
+		return nil
+	}
+	return found[*id]
+}
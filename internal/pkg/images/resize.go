@@ -0,0 +1,134 @@
+package images
+
+import (
+	"image"
+	"math"
+)
+
+// Variant tamaño de salida de una variante.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	// Crop recorta al aspecto Width:Height (centrado) antes de escalar; si es false
+	// la imagen entra completa dentro de Width×Height
+	Crop bool
+}
+
+// Variants variantes que se generan de cada imagen. Nunca se agranda: si el
+// original es más chico que la variante, sale del tamaño del original (con el
+// mismo aspecto).
+var Variants = []Variant{
+	{Name: "original", Width: 2048, Height: 2048},
+	{Name: "poster", Width: 720, Height: 1080, Crop: true},   // vertical 2:3
+	{Name: "banner", Width: 1280, Height: 720, Crop: true},   // horizontal 16:9
+	{Name: "thumbnail", Width: 320, Height: 320, Crop: true}, // cuadrada
+}
+
+// render recorta y escala src a la variante.
+func render(src *image.NRGBA, v Variant) *image.NRGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	crop := src.Rect
+	var dw, dh int
+	if v.Crop {
+		aspect := float64(v.Width) / float64(v.Height)
+		cw, ch := w, h
+		if float64(w)/float64(h) > aspect {
+			cw = max(1, int(math.Round(float64(h)*aspect)))
+		} else {
+			ch = max(1, int(math.Round(float64(w)/aspect)))
+		}
+		crop = image.Rect((w-cw)/2, (h-ch)/2, (w-cw)/2+cw, (h-ch)/2+ch)
+		dw, dh = v.Width, v.Height
+		if cw < v.Width {
+			dw, dh = cw, ch
+		}
+	} else {
+		scale := math.Min(1, math.Min(float64(v.Width)/float64(w), float64(v.Height)/float64(h)))
+		dw, dh = max(1, int(math.Round(float64(w)*scale))), max(1, int(math.Round(float64(h)*scale)))
+	}
+	return resize(src.SubImage(crop).(*image.NRGBA), dw, dh)
+}
+
+// resize escala con un filtro de área (promedio de los píxeles que cubre cada
+// píxel de salida), adecuado para reducir. Los canales se promedian
+// premultiplicados por alfa para no oscurecer los bordes transparentes.
+func resize(src *image.NRGBA, dw, dh int) *image.NRGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == dw && sh == dh {
+		return toNRGBA(src)
+	}
+	xw, yw := areaWeights(sw, dw), areaWeights(sh, dh)
+
+	// Pasada horizontal: sh filas × dw columnas, RGBA premultiplicado
+	tmp := make([]float64, sh*dw*4)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y):]
+		for x, ws := range xw {
+			var r, g, b, a float64
+			for _, w := range ws {
+				p := row[w.index*4 : w.index*4+4]
+				pa := float64(p[3]) * w.weight
+				r += float64(p[0]) * pa
+				g += float64(p[1]) * pa
+				b += float64(p[2]) * pa
+				a += pa
+			}
+			t := tmp[(y*dw+x)*4:]
+			t[0], t[1], t[2], t[3] = r, g, b, a
+		}
+	}
+
+	// Pasada vertical y vuelta a NRGBA
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y, ws := range yw {
+		for x := 0; x < dw; x++ {
+			var r, g, b, a float64
+			for _, w := range ws {
+				t := tmp[(w.index*dw+x)*4:]
+				r += t[0] * w.weight
+				g += t[1] * w.weight
+				b += t[2] * w.weight
+				a += t[3] * w.weight
+			}
+			p := dst.Pix[dst.PixOffset(x, y):]
+			if a > 0 {
+				p[0], p[1], p[2] = clamp8(r/a), clamp8(g/a), clamp8(b/a)
+			}
+			p[3] = clamp8(a)
+		}
+	}
+	return dst
+}
+
+type areaWeight struct {
+	index  int
+	weight float64
+}
+
+// areaWeights reparte cada píxel de salida sobre el tramo [i·s, (i+1)·s) de la
+// entrada (s = src/dst), con peso proporcional a la cobertura. Los pesos suman 1.
+func areaWeights(src, dst int) [][]areaWeight {
+	scale := float64(src) / float64(dst)
+	weights := make([][]areaWeight, dst)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < src && float64(j) < end; j++ {
+			cover := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if cover > 0 {
+				weights[i] = append(weights[i], areaWeight{index: j, weight: cover / scale})
+			}
+		}
+	}
+	return weights
+}
+
+func clamp8(v float64) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,206 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+// maxPixels tope de píxeles de la imagen de entrada (50 MP): un PNG chico puede
+// declarar dimensiones enormes y agotar la memoria al decodificarse.
+const maxPixels = 50_000_000
+
+var (
+	// ErrUnsupportedFormat el archivo no es JPEG, PNG, GIF ni WebP (o es WebP sin IMAGES_WEBP_ENABLED).
+	ErrUnsupportedFormat = errors.New("images: unsupported image format")
+	// ErrInvalidImage el archivo tiene la firma de un formato aceptado pero no se puede decodificar.
+	ErrInvalidImage = errors.New("images: invalid image")
+	// ErrTooLarge la imagen supera maxPixels.
+	ErrTooLarge = errors.New("images: image dimensions too large")
+)
+
+type Service struct {
+	repo  *Repository
+	store storage.ObjectStore
+	cfg   config.ImagesConfig
+}
+
+func NewService(db *sql.DB, store storage.ObjectStore, cfg config.ImagesConfig) *Service {
+	if cfg.JPEGQuality < 1 || cfg.JPEGQuality > 100 {
+		cfg.JPEGQuality = 85
+	}
+	return &Service{repo: NewRepository(db), store: store, cfg: cfg}
+}
+
+// Repo expone el repositorio para los handlers de consulta.
+func (s *Service) Repo() *Repository {
+	return s.repo
+}
+
+// MaxBytes tamaño máximo de la imagen subida (IMAGES_MAX_FILE_SIZE_MB).
+func (s *Service) MaxBytes() int64 {
+	return s.cfg.MaxFileSizeMB * 1024 * 1024
+}
+
+// Upload valida la imagen, genera sus variantes y la registra. Si ya se había
+// subido el mismo contenido devuelve esa imagen y false sin volver a procesarla.
+// Falla con ErrUnsupportedFormat, ErrInvalidImage o ErrTooLarge.
+func (s *Service) Upload(ctx context.Context, data []byte, uploadedBy *uuid.UUID) (*models.Image, bool, error) {
+	format := Detect(data)
+	if format == "" || (format == FormatWebP && !s.cfg.WebPEnabled) {
+		return nil, false, ErrUnsupportedFormat
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	existing, err := s.repo.GetByHash(ctx, hash)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	src, err := s.decode(ctx, format, data)
+	if err != nil {
+		return nil, false, err
+	}
+	img := &models.Image{
+		ID:          uuid.New(),
+		ContentHash: hash,
+		Format:      format,
+		Width:       src.Rect.Dx(),
+		Height:      src.Rect.Dy(),
+		HasAlpha:    !src.Opaque(),
+		SizeBytes:   int64(len(data)),
+		Variants:    make(map[string]models.ImageVariant, len(Variants)),
+		UploadedBy:  uploadedBy,
+	}
+
+	var keys []string
+	for _, v := range Variants {
+		variant, written, err := s.put(ctx, hash, v, render(src, v), img.HasAlpha)
+		keys = append(keys, written...)
+		if err != nil {
+			s.remove(keys)
+			return nil, false, err
+		}
+		img.Variants[v.Name] = variant
+	}
+
+	// Las claves dependen solo del contenido: si otro upload del mismo archivo ganó
+	// la carrera, sus variantes son estas mismas y no hay nada que borrar
+	saved, created, err := s.repo.Create(ctx, img)
+	if err != nil {
+		s.remove(keys)
+		return nil, false, err
+	}
+	return saved, created, nil
+}
+
+// decode decodifica la imagen a NRGBA con la orientación EXIF ya aplicada.
+func (s *Service) decode(ctx context.Context, format string, data []byte) (*image.NRGBA, error) {
+	if format == FormatWebP {
+		width, height, err := webpSize(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		if width*height > maxPixels {
+			return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, width, height)
+		}
+		converted, err := webpToPNG(ctx, s.cfg.FFmpegPath, data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		format, data = FormatPNG, converted
+	}
+
+	cfg, err := decodeConfig(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("%w: empty image", ErrInvalidImage)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+
+	src, err := decode(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if format == FormatJPEG {
+		src = orient(src, jpegOrientation(data))
+	}
+	return src, nil
+}
+
+// put codifica y guarda una variante: JPEG (PNG si tiene transparencia) y, con
+// WebP habilitado, también WebP. Devuelve las claves escritas.
+func (s *Service) put(ctx context.Context, hash string, v Variant, out *image.NRGBA, hasAlpha bool) (models.ImageVariant, []string, error) {
+	variant := models.ImageVariant{Width: out.Rect.Dx(), Height: out.Rect.Dy()}
+	var buf bytes.Buffer
+	var lossless []byte
+	ext, contentType := "jpg", "image/jpeg"
+	if hasAlpha {
+		ext, contentType = "png", "image/png"
+		if err := png.Encode(&buf, out); err != nil {
+			return variant, nil, fmt.Errorf("images: encode %s: %w", v.Name, err)
+		}
+		lossless = buf.Bytes()
+	} else if err := jpeg.Encode(&buf, out, &jpeg.Options{Quality: s.cfg.JPEGQuality}); err != nil {
+		return variant, nil, fmt.Errorf("images: encode %s: %w", v.Name, err)
+	}
+
+	var keys []string
+	key := fmt.Sprintf("images/%s/%s.%s", hash, v.Name, ext)
+	if err := s.store.Put(key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), contentType); err != nil {
+		return variant, keys, err
+	}
+	keys = append(keys, key)
+	variant.URL = s.store.URL(key)
+
+	if !s.cfg.WebPEnabled {
+		return variant, keys, nil
+	}
+	if lossless == nil {
+		var pngBuf bytes.Buffer
+		if err := png.Encode(&pngBuf, out); err != nil {
+			return variant, keys, fmt.Errorf("images: encode %s: %w", v.Name, err)
+		}
+		lossless = pngBuf.Bytes()
+	}
+	webp, err := pngToWebP(ctx, s.cfg.FFmpegPath, lossless)
+	if err != nil {
+		return variant, keys, fmt.Errorf("images: encode %s webp: %w", v.Name, err)
+	}
+	key = fmt.Sprintf("images/%s/%s.webp", hash, v.Name)
+	if err := s.store.Put(key, bytes.NewReader(webp), int64(len(webp)), "image/webp"); err != nil {
+		return variant, keys, err
+	}
+	keys = append(keys, key)
+	variant.WebPURL = s.store.URL(key)
+	return variant, keys, nil
+}
+
+// remove borra las variantes de un upload que falló (best-effort).
+func (s *Service) remove(keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil {
+			log.Printf("images: cleanup %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package images
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// webpQuality calidad de libwebp (0-100) de las variantes WebP.
+const webpQuality = 80
+
+// La librería estándar no lee ni escribe WebP: las conversiones pasan por ffmpeg
+// (compilado con libwebp), siempre a través de PNG para no perder calidad dos veces.
+
+// webpToPNG convierte un WebP (primer cuadro) a PNG.
+func webpToPNG(ctx context.Context, ffmpegPath string, data []byte) ([]byte, error) {
+	return convert(ctx, ffmpegPath, data, "in.webp", "out.png", "-frames:v", "1")
+}
+
+// webpSize lee las dimensiones del encabezado WebP (VP8, VP8L o VP8X) sin
+// decodificar, para rechazar imágenes enormes antes de pasarlas a ffmpeg.
+func webpSize(data []byte) (width, height int, err error) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, errors.New("webp: invalid header")
+	}
+	payload := data[20:]
+	switch string(data[12:16]) {
+	case "VP8 ":
+		// Lossy: frame tag (3 bytes), start code 9d 01 2a, ancho y alto de 14 bits
+		if payload[3] != 0x9d || payload[4] != 0x01 || payload[5] != 0x2a {
+			return 0, 0, errors.New("webp: invalid VP8 start code")
+		}
+		width = int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3fff)
+	case "VP8L":
+		// Lossless: firma 0x2f y ancho-1 / alto-1 de 14 bits
+		if payload[0] != 0x2f {
+			return 0, 0, errors.New("webp: invalid VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(payload[1:5])
+		width = int(bits&0x3fff) + 1
+		height = int((bits>>14)&0x3fff) + 1
+	case "VP8X":
+		// Extendido: flags (4 bytes) y lienzo ancho-1 / alto-1 de 24 bits
+		width = int(uint32(payload[4])|uint32(payload[5])<<8|uint32(payload[6])<<16) + 1
+		height = int(uint32(payload[7])|uint32(payload[8])<<8|uint32(payload[9])<<16) + 1
+	default:
+		return 0, 0, fmt.Errorf("webp: unknown chunk %q", data[12:16])
+	}
+	return width, height, nil
+}
+
+// pngToWebP convierte un PNG a WebP (conserva la transparencia).
+func pngToWebP(ctx context.Context, ffmpegPath string, data []byte) ([]byte, error) {
+	return convert(ctx, ffmpegPath, data, "in.png", "out.webp",
+		"-c:v", "libwebp", "-quality", strconv.Itoa(webpQuality))
+}
+
+func convert(ctx context.Context, ffmpegPath string, data []byte, inName, outName string, args ...string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "qenti-image-*")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg: create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, inName), filepath.Join(dir, outName)
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, fmt.Errorf("ffmpeg: write input: %w", err)
+	}
+	cmdArgs := append([]string{"-hide_banner", "-loglevel", "error", "-y", "-i", in}, args...)
+	cmd := exec.CommandContext(ctx, ffmpegPath, append(cmdArgs, out)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: convert %s: %w: %s", outName, err, strings.TrimSpace(string(output)))
+	}
+	return os.ReadFile(out)
+}
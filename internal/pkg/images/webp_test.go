@@ -0,0 +1,65 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qenti/qenti/internal/config"
+)
+
+// webpFile arma un WebP con un único chunk.
+func webpFile(fourCC string, payload []byte) []byte {
+	size := 4 + 8 + len(payload)
+	out := []byte("RIFF")
+	out = append(out, byte(size), byte(size>>8), byte(size>>16), byte(size>>24))
+	out = append(out, "WEBP"+fourCC...)
+	out = append(out, byte(len(payload)), byte(len(payload)>>8), byte(len(payload)>>16), byte(len(payload)>>24))
+	return append(out, payload...)
+}
+
+func TestWebPSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		width, height int
+	}{
+		{
+			name:  "lossy",
+			data:  webpFile("VP8 ", []byte{0x30, 0x01, 0x00, 0x9d, 0x01, 0x2a, 0x80, 0x02, 0xe0, 0x01, 0, 0}),
+			width: 640, height: 480,
+		},
+		{
+			// 1x1 lossless (ancho-1 y alto-1 en cero)
+			name:  "lossless",
+			data:  webpFile("VP8L", []byte{0x2f, 0x00, 0x00, 0x00, 0x10, 0x07, 0x10, 0x11, 0x11, 0x88}),
+			width: 1, height: 1,
+		},
+		{
+			name:  "extended",
+			data:  webpFile("VP8X", []byte{0x10, 0, 0, 0, 0x1f, 0x4e, 0x00, 0x1f, 0x4e, 0x00}),
+			width: 20000, height: 20000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, err := webpSize(tt.data)
+			if err != nil || w != tt.width || h != tt.height {
+				t.Errorf("webpSize = %dx%d, %v; want %dx%d", w, h, err, tt.width, tt.height)
+			}
+		})
+	}
+
+	if _, _, err := webpSize([]byte("RIFF....WEBPVP8 ")); err == nil {
+		t.Error("truncated file should fail")
+	}
+}
+
+func TestDecodeRejectsHugeWebPBeforeFFmpeg(t *testing.T) {
+	// FFmpegPath inexistente: si el chequeo no corta antes, el error sería de ffmpeg
+	s := &Service{cfg: config.ImagesConfig{FFmpegPath: "/nonexistent/ffmpeg"}}
+	huge := webpFile("VP8X", []byte{0x10, 0, 0, 0, 0x1f, 0x4e, 0x00, 0x1f, 0x4e, 0x00})
+	if _, err := s.decode(context.Background(), FormatWebP, huge); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+}
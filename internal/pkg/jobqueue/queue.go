@@ -0,0 +1,152 @@
+// Package jobqueue implementa las transiciones comunes de las colas de trabajos
+// persistentes en Postgres (transcode_jobs, hls_package_jobs): Claim con SKIP LOCKED
+// para varios workers e instancias, updated_at como heartbeat (Touch) y
+// RequeueStale para retomar los trabajos de una instancia caída.
+//
+// La tabla necesita las columnas status, attempts, error, created_at y updated_at.
+package jobqueue
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Table describe la tabla de una cola.
+type Table struct {
+	// Name nombre de la tabla.
+	Name string
+	// Key columna que identifica un trabajo.
+	Key string
+	// Noun nombre de un trabajo en los mensajes de error (ej. "transcode job").
+	Noun string
+	// Running estados en curso: el primero es el que pone Claim y RequeueStale
+	// revisa todos.
+	Running []string
+	// RunAfter la tabla tiene run_after: Claim toma por run_after solo los trabajos
+	// ya vencidos (si no, por created_at) y Retry lo usa para la espera.
+	RunAfter bool
+	// OnClaim, OnFail y OnRequeue asignaciones SQL extra de cada transición
+	// (ej. "progress = 0"), vacías si no hay.
+	OnClaim   string
+	OnFail    string
+	OnRequeue string
+}
+
+// Queue opera sobre la tabla de una cola.
+type Queue struct {
+	db *sql.DB
+	t  Table
+}
+
+func New(db *sql.DB, t Table) *Queue {
+	return &Queue{db: db, t: t}
+}
+
+// extra antepone la coma a una asignación opcional.
+func extra(set string) string {
+	if set == "" {
+		return ""
+	}
+	return ", " + set
+}
+
+// Row fila que devuelve Claim (*sql.Row).
+type Row interface {
+	Scan(dest ...interface{}) error
+}
+
+// Claim toma el trabajo encolado más antiguo, lo pasa al primer estado de Running
+// y lee con scan las columnas returning. Devuelve false si no hay trabajo.
+func (q *Queue) Claim(ctx context.Context, returning string, scan func(Row) error) (bool, error) {
+	ready, order := "", "created_at"
+	if q.t.RunAfter {
+		ready, order = " AND run_after <= NOW()", "run_after"
+	}
+	err := scan(q.db.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE %[1]s
+		SET status = $1, attempts = attempts + 1, updated_at = NOW()%[3]s
+		WHERE %[2]s = (
+			SELECT %[2]s FROM %[1]s
+			WHERE status = 'queued'%[4]s
+			ORDER BY %[5]s LIMIT 1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %[6]s`, q.t.Name, q.t.Key, extra(q.t.OnClaim), ready, order, returning),
+		q.t.Running[0]))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim %s: %w", q.t.Noun, err)
+	}
+	return true, nil
+}
+
+// Touch renueva el heartbeat de un trabajo en curso (ver RequeueStale).
+func (q *Queue) Touch(ctx context.Context, key interface{}) error {
+	_, err := q.db.ExecContext(ctx,
+		fmt.Sprintf(`UPDATE %s SET updated_at = NOW() WHERE %s = $1`, q.t.Name, q.t.Key), key)
+	if err != nil {
+		return fmt.Errorf("failed to touch %s: %w", q.t.Noun, err)
+	}
+	return nil
+}
+
+// Fail marca el trabajo como fallido.
+func (q *Queue) Fail(ctx context.Context, key interface{}, cause error) error {
+	_, err := q.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s SET status = 'failed', error = $2, updated_at = NOW()%s
+		WHERE %s = $1`, q.t.Name, extra(q.t.OnFail), q.t.Key), key, cause.Error())
+	if err != nil {
+		return fmt.Errorf("failed to mark %s failed: %w", q.t.Noun, err)
+	}
+	return nil
+}
+
+// Retry vuelve a encolar el trabajo después de retryAfter, o lo marca como fallido
+// si ya no quedan intentos (retryAfter <= 0). Requiere RunAfter.
+func (q *Queue) Retry(ctx context.Context, key interface{}, cause error, retryAfter time.Duration) error {
+	if retryAfter <= 0 {
+		return q.Fail(ctx, key, cause)
+	}
+	_, err := q.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET status = 'queued', error = $2, run_after = NOW() + make_interval(secs => $3), updated_at = NOW()
+		WHERE %s = $1`, q.t.Name, q.t.Key), key, cause.Error(), retryAfter.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to retry %s: %w", q.t.Noun, err)
+	}
+	return nil
+}
+
+// RequeueStale re-encola los trabajos en curso sin heartbeat desde hace más de
+// staleAfter (la instancia que los procesaba murió). Tras maxAttempts intentos se
+// marcan como fallidos. Devuelve cuántos re-encoló.
+func (q *Queue) RequeueStale(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int64, error) {
+	running := pq.Array(q.t.Running)
+	_, err := q.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET status = 'failed', error = 'worker lost too many times', updated_at = NOW()%s
+		WHERE status = ANY($3) AND attempts >= $2
+			AND updated_at < NOW() - make_interval(secs => $1)`, q.t.Name, extra(q.t.OnFail)),
+		staleAfter.Seconds(), maxAttempts, running)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail stale %ss: %w", q.t.Noun, err)
+	}
+
+	requeue := q.t.OnRequeue
+	if q.t.RunAfter {
+		requeue = "run_after = NOW()" + extra(requeue)
+	}
+	res, err := q.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s SET status = 'queued', updated_at = NOW()%s
+		WHERE status = ANY($2) AND updated_at < NOW() - make_interval(secs => $1)`, q.t.Name, extra(requeue)),
+		staleAfter.Seconds(), running)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale %ss: %w", q.t.Noun, err)
+	}
+	return res.RowsAffected()
+}
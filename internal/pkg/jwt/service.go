@@ -10,8 +10,12 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrTokenRevoked el token tiene firma válida pero fue revocado (ver SetRevocationCheck).
+var ErrTokenRevoked = errors.New("token revoked")
+
 type Service struct {
 	secretKey []byte
+	revoked   func(claims *Claims) bool
 }
 
 func NewService(secretKey string) *Service {
@@ -23,9 +27,9 @@ func NewService(secretKey string) *Service {
 // Claims representa los claims del JWT según la estructura propuesta
 type Claims struct {
 	// Subject: identificador único del usuario con prefijo
-	Sub string `json:"sub"` // Formato: "usr_<uuid>", "adm_<uuid>", "prd_<uuid>", "sad_<uuid>"
+	Sub string `json:"sub"` // Formato: "usr_<uuid>", "adm_<uuid>", "prd_<uuid>", "sad_<uuid>", "gst_<uuid>"
 
-	// Role: define permisos ("user", "admin", "producer", "super_admin")
+	// Role: define permisos ("user", "admin", "producer", "super_admin", "guest")
 	Role string `json:"role"`
 
 	// Email: opcional, útil para auditoría
@@ -66,6 +70,8 @@ func subPrefixByRole(role string) string {
 		return "sad_"
 	case "producer":
 		return "prd_"
+	case "guest":
+		return "gst_"
 	default:
 		return "usr_"
 	}
@@ -113,6 +119,12 @@ func GenerateRefreshToken() (string, error) {
 	return GenerateJTI()
 }
 
+// SetRevocationCheck registra una verificación que ValidateToken aplica a los tokens
+// con firma válida: si devuelve true, el token se rechaza con ErrTokenRevoked.
+func (s *Service) SetRevocationCheck(revoked func(claims *Claims) bool) {
+	s.revoked = revoked
+}
+
 // ValidateToken valida y parsea un token JWT
 func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
@@ -132,6 +144,10 @@ func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	if s.revoked != nil && s.revoked(claims) {
+		return nil, ErrTokenRevoked
+	}
+
 	return claims, nil
 }
 
@@ -152,6 +168,12 @@ func (c *Claims) IsProducer() bool {
 	return c.Role == "producer"
 }
 
+// IsGuest verifica si es una cuenta invitada (ligada a un dispositivo, sin identidad Firebase).
+// Puede ganar y gastar monedas, pero no comprar ni acceder al panel.
+func (c *Claims) IsGuest() bool {
+	return c.Role == "guest"
+}
+
 // IsProducerOrAdmin verifica si puede acceder al panel (tenant admin o super_admin)
 func (c *Claims) IsProducerOrAdmin() bool {
 	return c.IsProducer() || c.IsAdmin()
@@ -159,7 +181,7 @@ func (c *Claims) IsProducerOrAdmin() bool {
 
 // GetUserID extrae el UUID del usuario desde el campo 'sub'
 func (c *Claims) GetUserID() (uuid.UUID, error) {
-	// Remover prefijo de 4 caracteres (usr_, adm_, prd_, sad_)
+	// Remover prefijo de 4 caracteres (usr_, adm_, prd_, sad_, gst_)
 	sub := c.Sub
 	if len(sub) > 4 {
 		sub = sub[4:]
@@ -0,0 +1,121 @@
+// Package media analiza los videos que se suben por la API: lee con ffprobe la
+// duración, resolución, relación de aspecto, codecs y bitrate, los valida contra
+// la configuración y extrae con ffmpeg portadas candidatas para el episodio.
+package media
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Thumbnail portada candidata extraída del video de un episodio.
+type Thumbnail struct {
+	ID        uuid.UUID `json:"id"`
+	EpisodeID uuid.UUID `json:"episode_id"`
+	Position  int       `json:"position"`
+	AtSeconds float64   `json:"at_seconds"`
+	URL       string    `json:"url"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const thumbnailColumns = `id, episode_id, position, at_seconds, url, width, height, created_at`
+
+func scanThumbnail(row interface{ Scan(...interface{}) error }) (*Thumbnail, error) {
+	var t Thumbnail
+	if err := row.Scan(&t.ID, &t.EpisodeID, &t.Position, &t.AtSeconds, &t.URL, &t.Width, &t.Height, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListByEpisode lista las portadas candidatas del episodio en orden.
+func (r *Repository) ListByEpisode(ctx context.Context, episodeID uuid.UUID) ([]Thumbnail, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+thumbnailColumns+` FROM episode_thumbnails
+		WHERE episode_id = $1 ORDER BY position`, episodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list thumbnails: %w", err)
+	}
+	defer rows.Close()
+
+	var thumbs []Thumbnail
+	for rows.Next() {
+		t, err := scanThumbnail(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan thumbnail: %w", err)
+		}
+		thumbs = append(thumbs, *t)
+	}
+	return thumbs, rows.Err()
+}
+
+// Get devuelve una portada candidata por ID, o nil si no existe.
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*Thumbnail, error) {
+	t, err := scanThumbnail(r.db.QueryRowContext(ctx,
+		`SELECT `+thumbnailColumns+` FROM episode_thumbnails WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thumbnail: %w", err)
+	}
+	return t, nil
+}
+
+// Replace reemplaza las candidatas del episodio por thumbs y devuelve las
+// anteriores (para borrar sus archivos).
+func (r *Repository) Replace(ctx context.Context, episodeID uuid.UUID, thumbs []Thumbnail) ([]Thumbnail, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM episode_thumbnails WHERE episode_id = $1
+		RETURNING `+thumbnailColumns, episodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete thumbnails: %w", err)
+	}
+	var old []Thumbnail
+	for rows.Next() {
+		t, err := scanThumbnail(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan thumbnail: %w", err)
+		}
+		old = append(old, *t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete thumbnails: %w", err)
+	}
+
+	for _, t := range thumbs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO episode_thumbnails (id, episode_id, position, at_seconds, url, width, height)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			t.ID, episodeID, t.Position, t.AtSeconds, t.URL, t.Width, t.Height)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert thumbnail: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit thumbnails: %w", err)
+	}
+	return old, nil
+}
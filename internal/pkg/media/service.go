@@ -0,0 +1,253 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/storage"
+	"github.com/qenti/qenti/internal/pkg/transcode"
+)
+
+// ThumbnailsPath ruta pública que sirve las portadas candidatas (ThumbnailDir).
+const ThumbnailsPath = "/api/v1/media/thumbnails"
+
+var (
+	// ErrTooLong el video supera VideoUploadConfig.MaxDurationSeconds.
+	ErrTooLong = errors.New("media: video exceeds max duration")
+	// ErrNotVertical el video es horizontal y MediaProbeConfig.RequireVertical está activo.
+	ErrNotVertical = errors.New("media: video is not vertical")
+)
+
+// applyTimeout tiempo máximo para registrar los datos y extraer las portadas de un video.
+const applyTimeout = 5 * time.Minute
+
+const (
+	// probeTimeout tiempo máximo para inspeccionar un original remoto (CheckUploaded).
+	probeTimeout = 2 * time.Minute
+	// sourceURLTTL vigencia de la URL firmada con la que ffprobe lee el original.
+	sourceURLTTL = 15 * time.Minute
+)
+
+// Staged video subido guardado en un archivo temporal y ya inspeccionado.
+type Staged struct {
+	Path string
+	Info *transcode.MediaInfo
+}
+
+type Service struct {
+	repo         *Repository
+	episodesRepo *episodes.Repository
+	cfg          config.MediaProbeConfig
+	maxDuration  int
+}
+
+func NewService(db *sql.DB, cfg config.MediaProbeConfig, maxDurationSeconds int) *Service {
+	cfg.ThumbnailBaseURL = strings.TrimRight(cfg.ThumbnailBaseURL, "/")
+	if cfg.ThumbnailWidth <= 0 {
+		cfg.ThumbnailWidth = 540
+	}
+	return &Service{
+		repo:         NewRepository(db),
+		episodesRepo: episodes.NewRepository(db),
+		cfg:          cfg,
+		maxDuration:  maxDurationSeconds,
+	}
+}
+
+// Repo expone el repositorio para los handlers de consulta.
+func (s *Service) Repo() *Repository {
+	return s.repo
+}
+
+// Inspect lee el video con ffprobe y aplica las reglas de la configuración. Falla
+// con transcode.ErrNotVideo, ErrTooLong o ErrNotVertical.
+func (s *Service) Inspect(ctx context.Context, file string) (*transcode.MediaInfo, error) {
+	info, err := transcode.Probe(ctx, s.cfg.FFprobePath, file)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.check(info.DurationSeconds, info.Width, info.Height); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// CheckUploaded aplica las reglas de la configuración a un video que el cliente
+// subió directo al proveedor (sin pasar por Stage), cuando el proveedor lo da por
+// listo. Usa los datos que informa el proveedor (status.Media) o inspecciona el
+// original con ffprobe (storage.SourceProvider). Solo falla con ErrTooLong o
+// ErrNotVertical: si el video no se puede inspeccionar se registra y se acepta.
+func (s *Service) CheckUploaded(ctx context.Context, provider storage.VideoProvider, videoID string, status *storage.VideoStatus) error {
+	if status.Media != nil {
+		return s.check(status.Media.DurationSeconds, status.Media.Width, status.Media.Height)
+	}
+	sp, ok := provider.(storage.SourceProvider)
+	if !ok {
+		return nil
+	}
+	src, err := sp.SourceURL(videoID, sourceURLTTL)
+	if err != nil {
+		log.Printf("media: video %s: %v", videoID, err)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	info, err := transcode.Probe(ctx, s.cfg.FFprobePath, src)
+	if err != nil {
+		log.Printf("media: video %s: %v", videoID, err)
+		return nil
+	}
+	return s.check(info.DurationSeconds, info.Width, info.Height)
+}
+
+// check valida duración y orientación; los datos desconocidos (cero) no se validan.
+func (s *Service) check(durationSeconds float64, width, height int) error {
+	if s.maxDuration > 0 && durationSeconds > float64(s.maxDuration) {
+		return fmt.Errorf("%w: %.0fs > %ds", ErrTooLong, durationSeconds, s.maxDuration)
+	}
+	if s.cfg.RequireVertical && width > 0 && height > 0 && width > height {
+		return fmt.Errorf("%w: %dx%d", ErrNotVertical, width, height)
+	}
+	return nil
+}
+
+// Stage guarda src en un archivo temporal y lo inspecciona. Si el video no pasa
+// la validación el archivo se borra; si no, el llamador lo libera con Done.
+func (s *Service) Stage(ctx context.Context, src io.Reader) (*Staged, error) {
+	f, err := os.CreateTemp("", "qenti-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("media: create temp file: %w", err)
+	}
+	_, err = io.Copy(f, src)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("media: store upload: %w", err)
+	}
+
+	info, err := s.Inspect(ctx, f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &Staged{Path: f.Name(), Info: info}, nil
+}
+
+// Done libera un video de Stage: si se entregó, registra sus datos en el episodio y
+// extrae las portadas en segundo plano antes de borrar el archivo.
+func (s *Service) Done(staged *Staged, episodeID uuid.UUID, delivered bool) {
+	if !delivered {
+		os.Remove(staged.Path)
+		return
+	}
+	go func() {
+		defer os.Remove(staged.Path)
+		ctx, cancel := context.WithTimeout(context.Background(), applyTimeout)
+		defer cancel()
+		if err := s.Apply(ctx, episodeID, staged.Path, staged.Info); err != nil {
+			log.Printf("media: episode %s: %v", episodeID, err)
+		}
+	}()
+}
+
+// Apply registra la duración y los datos técnicos del video en el episodio y
+// reemplaza sus portadas candidatas. Si el episodio no tenía portada o usaba una
+// candidata del video anterior, se asigna la candidata del medio.
+func (s *Service) Apply(ctx context.Context, episodeID uuid.UUID, file string, info *transcode.MediaInfo) error {
+	err := s.episodesRepo.SetMedia(ctx, episodeID, int(math.Round(info.DurationSeconds)), models.EpisodeMedia{
+		Width:       info.Width,
+		Height:      info.Height,
+		AspectRatio: info.AspectRatio(),
+		VideoCodec:  info.VideoCodec,
+		AudioCodec:  info.AudioCodec,
+		BitRate:     info.BitRate,
+	})
+	if err != nil {
+		return err
+	}
+	if s.cfg.ThumbnailCount <= 0 {
+		return nil
+	}
+
+	thumbs := s.extract(ctx, episodeID, file, info)
+	if len(thumbs) == 0 {
+		return fmt.Errorf("no thumbnails could be extracted")
+	}
+	old, err := s.repo.Replace(ctx, episodeID, thumbs)
+	if err != nil {
+		for _, t := range thumbs {
+			os.Remove(s.thumbnailFile(t))
+		}
+		return err
+	}
+
+	episode, err := s.episodesRepo.GetByID(ctx, episodeID)
+	if err != nil {
+		return err
+	}
+	replaceCover := episode.CoverURL == ""
+	for _, t := range old {
+		if t.URL == episode.CoverURL {
+			replaceCover = true
+		}
+		os.Remove(s.thumbnailFile(t))
+	}
+	if replaceCover {
+		return s.episodesRepo.SetCover(ctx, episodeID, thumbs[len(thumbs)/2].URL)
+	}
+	return nil
+}
+
+// extract toma ThumbnailCount cuadros repartidos a lo largo del video (sin el
+// primero ni el último, que suelen ser fundidos o placas).
+func (s *Service) extract(ctx context.Context, episodeID uuid.UUID, file string, info *transcode.MediaInfo) []Thumbnail {
+	dir := filepath.Join(s.cfg.ThumbnailDir, episodeID.String())
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Printf("media: create thumbnail dir: %v", err)
+		return nil
+	}
+	width := min(s.cfg.ThumbnailWidth, info.Width)
+	height := 0
+	if info.Width > 0 {
+		height = int(math.Round(float64(width)*float64(info.Height)/float64(info.Width)/2)) * 2
+	}
+
+	n := s.cfg.ThumbnailCount
+	var thumbs []Thumbnail
+	for i := 0; i < n; i++ {
+		t := Thumbnail{
+			ID:        uuid.New(),
+			EpisodeID: episodeID,
+			Position:  len(thumbs),
+			AtSeconds: math.Round(info.DurationSeconds*float64(i+1)/float64(n+1)*100) / 100,
+			Width:     width &^ 1,
+			Height:    height,
+		}
+		t.URL = fmt.Sprintf("%s%s/%s/%s.jpg", s.cfg.ThumbnailBaseURL, ThumbnailsPath, episodeID, t.ID)
+		if err := transcode.Frame(ctx, s.cfg.FFmpegPath, file, s.thumbnailFile(t), t.AtSeconds, width); err != nil {
+			log.Printf("media: episode %s: thumbnail at %.2fs: %v", episodeID, t.AtSeconds, err)
+			continue
+		}
+		thumbs = append(thumbs, t)
+	}
+	return thumbs
+}
+
+func (s *Service) thumbnailFile(t Thumbnail) string {
+	return filepath.Join(s.cfg.ThumbnailDir, t.EpisodeID.String(), t.ID.String()+".jpg")
+}
@@ -26,10 +26,75 @@ type Series struct {
 	VerticalPoster  string     `json:"vertical_poster" db:"vertical_poster"`
 	IsActive        bool       `json:"is_active" db:"is_active"`
 	ProducerID      *uuid.UUID `json:"producer_id,omitempty" db:"producer_id"` // nil = contenido de plataforma
+	// HorizontalPosterImageID y VerticalPosterImageID imágenes subidas (POST /admin/images) de
+	// las que salen los posters; nil si el poster es una URL externa
+	HorizontalPosterImageID *uuid.UUID `json:"horizontal_poster_image_id,omitempty" db:"horizontal_poster_image_id"`
+	VerticalPosterImageID   *uuid.UUID `json:"vertical_poster_image_id,omitempty" db:"vertical_poster_image_id"`
+	// HorizontalPosterImage y VerticalPosterImage URLs de cada variante (solo en el detalle)
+	HorizontalPosterImage *Image `json:"horizontal_poster_image,omitempty" db:"-"`
+	VerticalPosterImage   *Image `json:"vertical_poster_image,omitempty" db:"-"`
+	// Genres y Tags taxonomía de la serie (en el listado y el detalle de la app)
+	Genres []Term `json:"genres,omitempty" db:"-"`
+	Tags   []Term `json:"tags,omitempty" db:"-"`
 	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// Genre género de contenido (Romance, Venganza, CEO...) curado por el super_admin.
+// Name está en el idioma por defecto; Translations lo trae en otros idiomas.
+type Genre struct {
+	ID           uuid.UUID         `json:"id"`
+	Slug         string            `json:"slug"`
+	Name         string            `json:"name"`
+	Translations map[string]string `json:"translations"`
+	Position     int               `json:"position"`
+	IsActive     bool              `json:"is_active"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// Tag etiqueta libre que los productores asignan a sus series.
+type Tag struct {
+	ID        uuid.UUID `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Term género o etiqueta tal como aparece en una serie o en los listados de la app,
+// con el nombre ya traducido. SeriesCount solo viene en los listados.
+type Term struct {
+	ID          uuid.UUID `json:"id"`
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	SeriesCount int       `json:"series_count,omitempty"`
+}
+
+// Image imagen subida por POST /admin/images. Se guarda una vez por contenido
+// (ContentHash) y se sirve en variantes redimensionadas: original, poster, banner
+// y thumbnail.
+type Image struct {
+	ID          uuid.UUID               `json:"id"`
+	ContentHash string                  `json:"content_hash"`
+	Format      string                  `json:"format"` // formato de entrada: jpeg | png | gif | webp
+	Width       int                     `json:"width"`
+	Height      int                     `json:"height"`
+	HasAlpha    bool                    `json:"has_alpha"`
+	SizeBytes   int64                   `json:"size_bytes"`
+	Variants    map[string]ImageVariant `json:"variants"`
+	UploadedBy  *uuid.UUID              `json:"uploaded_by,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+}
+
+// ImageVariant versión redimensionada de una imagen, en JPEG (PNG si tiene
+// transparencia) y opcionalmente en WebP.
+type ImageVariant struct {
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	URL     string `json:"url"`
+	WebPURL string `json:"webp_url,omitempty"`
+}
+
 // Producer representa un productor de contenido (empresa o individuo)
 // que puede subir y gestionar sus propias series.
 type Producer struct {
@@ -42,6 +107,10 @@ type Producer struct {
 	IsActive    bool      `json:"is_active" db:"is_active"`
 	// Status controla el flujo de aprobación: pending → active (o suspended)
 	Status      string    `json:"status" db:"status"` // pending | active | suspended
+	// LogoImageID imagen subida (POST /admin/images) de la que sale el logo; nil si es una URL externa
+	LogoImageID *uuid.UUID `json:"logo_image_id,omitempty" db:"logo_image_id"`
+	// LogoImage URLs de cada variante del logo (solo en el detalle)
+	LogoImage   *Image    `json:"logo_image,omitempty" db:"-"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -75,13 +144,29 @@ type Episode struct {
 	EpisodeNumber int      `json:"episode_number" db:"episode_number"`
 	Title        string    `json:"title" db:"title"`
 	VideoIDBunny string    `json:"video_id_bunny" db:"video_id_bunny"`
+	VideoStatus  string    `json:"video_status" db:"video_status"` // created|uploading|processing|ready|failed
+	VideoError   string    `json:"video_error,omitempty" db:"video_error"`
 	Duration     int       `json:"duration" db:"duration"` // en segundos
 	IsFree       bool      `json:"is_free" db:"is_free"`
 	PriceCoins   int       `json:"price_coins" db:"price_coins"`
+	// CoverURL portada elegida entre las candidatas generadas al subir el video
+	CoverURL     string    `json:"cover_url,omitempty" db:"cover_url"`
+	EpisodeMedia
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// EpisodeMedia datos técnicos del video del episodio, leídos con ffprobe al subirlo
+// por la API (MEDIA_PROBE_ENABLED).
+type EpisodeMedia struct {
+	Width       int    `json:"width,omitempty" db:"width"`
+	Height      int    `json:"height,omitempty" db:"height"`
+	AspectRatio string `json:"aspect_ratio,omitempty" db:"aspect_ratio"` // ej. "9:16"
+	VideoCodec  string `json:"video_codec,omitempty" db:"video_codec"`
+	AudioCodec  string `json:"audio_codec,omitempty" db:"audio_codec"`
+	BitRate     int64  `json:"bitrate,omitempty" db:"bitrate"` // bits por segundo
+}
+
 // Unlock representa el desbloqueo de un episodio por un usuario
 type Unlock struct {
 	ID         uuid.UUID `json:"id" db:"id"`
@@ -0,0 +1,119 @@
+// Package pagination implementa la paginación por cursor (keyset) de los listados:
+// las filas se ordenan por (created_at, id) descendente y cada página continúa
+// después de la última fila de la anterior, así los inserts no corren ni repiten
+// resultados y el costo no crece con el número de página como con OFFSET.
+//
+// El cursor es opaco para los clientes: ?cursor= recibe el next_cursor de la
+// respuesta anterior; sin él se pide la primera página.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Límites por defecto de ?limit=.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor el cursor no es uno emitido por la API.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor posición en un listado: la última fila devuelta.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Page página pedida: a lo sumo Limit filas después de After (nil = la primera).
+type Page struct {
+	Limit int
+	After *Cursor
+}
+
+// Encode codifica el cursor para next_cursor. created_at va en microsegundos, la
+// precisión de Postgres.
+func Encode(c Cursor) string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode decodifica un cursor de Encode. ErrInvalidCursor si no es válido.
+func Decode(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := Cursor{CreatedAt: time.UnixMicro(n).UTC()}
+	if c.ID, err = uuid.Parse(id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Parse arma la página de los parámetros ?limit= y ?cursor=. Un limit vacío,
+// inválido o menor que 1 usa defaultLimit; uno mayor que maxLimit se recorta.
+func Parse(limit, cursor string, defaultLimit, maxLimit int) (Page, error) {
+	p := Page{Limit: defaultLimit}
+	if n, err := strconv.Atoi(limit); err == nil && n > 0 {
+		p.Limit = n
+	}
+	if p.Limit > maxLimit {
+		p.Limit = maxLimit
+	}
+	if cursor != "" {
+		after, err := Decode(cursor)
+		if err != nil {
+			return p, err
+		}
+		p.After = after
+	}
+	return p, nil
+}
+
+// Where condición keyset para las columnas createdAt e id (vacía en la primera
+// página); agrega sus parámetros a args. Va con OrderBy y LIMIT FetchLimit.
+func (p Page) Where(args *[]interface{}, createdAt, id string) string {
+	if p.After == nil {
+		return ""
+	}
+	*args = append(*args, p.After.CreatedAt, p.After.ID)
+	return fmt.Sprintf(` AND (%s, %s) < ($%d, $%d)`, createdAt, id, len(*args)-1, len(*args))
+}
+
+// OrderBy orden del listado paginado.
+func OrderBy(createdAt, id string) string {
+	return fmt.Sprintf(` ORDER BY %s DESC, %s DESC`, createdAt, id)
+}
+
+// FetchLimit filas a pedir: una más que la página, para saber si hay otra.
+func (p Page) FetchLimit() int {
+	return p.Limit + 1
+}
+
+// Slice recorta las filas traídas con FetchLimit a la página y devuelve el cursor
+// de la siguiente, o nil si no hay más. key da la posición de una fila.
+func Slice[T any](rows []T, p Page, key func(T) Cursor) ([]T, *string) {
+	if len(rows) <= p.Limit {
+		return rows, nil
+	}
+	rows = rows[:p.Limit]
+	next := Encode(key(rows[len(rows)-1]))
+	return rows, &next
+}
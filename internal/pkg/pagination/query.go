@@ -0,0 +1,34 @@
+package pagination
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qenti/qenti/internal/pkg/models"
+)
+
+// FromQuery lee ?limit= (default DefaultLimit, máx. MaxLimit) y ?cursor= de la
+// request. Si el cursor no es válido responde 400 y devuelve false.
+func FromQuery(c *gin.Context) (Page, bool) {
+	return FromQueryWithLimit(c, DefaultLimit)
+}
+
+// FromQueryWithLimit como FromQuery con otro limit por defecto.
+func FromQueryWithLimit(c *gin.Context, defaultLimit int) (Page, bool) {
+	page, err := Parse(c.Query("limit"), c.Query("cursor"), defaultLimit, MaxLimit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return page, false
+	}
+	return page, true
+}
+
+// SeriesCursor posición de una serie en los listados de series.
+func SeriesCursor(s models.Series) Cursor {
+	return Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+}
+
+// EpisodeCursor posición de un episodio en los listados de episodios.
+func EpisodeCursor(e models.Episode) Cursor {
+	return Cursor{CreatedAt: e.CreatedAt, ID: e.ID}
+}
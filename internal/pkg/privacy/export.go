@@ -0,0 +1,173 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// userDevicesCTE device IDs ligados al usuario ($1): el de su cuenta invitada y los
+// de la actividad de invitado fusionada en la cuenta (views y playback_events
+// conservan el device_id al reasignarse; la cuenta invitada fusionada se borra).
+const userDevicesCTE = `WITH user_devices AS (
+		SELECT guest_device_id::text AS device_id FROM users WHERE id = $1 AND guest_device_id IS NOT NULL
+		UNION SELECT device_id::text FROM views WHERE user_id = $1 AND device_id IS NOT NULL
+		UNION SELECT device_id::text FROM favorites WHERE user_id = $1 AND device_id IS NOT NULL
+		UNION SELECT device_id FROM playback_events WHERE user_id = $1 AND device_id IS NOT NULL
+	)`
+
+// exportSections define qué entra en el ZIP: un archivo JSON por sección, cada uno
+// con las filas del usuario ($1). Al agregar tablas con datos personales, sumarlas aquí.
+var exportSections = []struct {
+	name  string
+	query string
+}{
+	{"profile", `SELECT id, email, firebase_uid, coin_balance, is_premium, is_guest, guest_device_id, created_at, updated_at
+		FROM users WHERE id = $1`},
+	{"devices", userDevicesCTE + ` SELECT device_id FROM user_devices ORDER BY device_id`},
+	{"transactions", `SELECT id, type, amount, episode_id, method, created_at
+		FROM transactions WHERE user_id = $1 ORDER BY created_at`},
+	{"unlocks", `SELECT u.episode_id, e.title AS episode_title, u.method, u.unlocked_at
+		FROM unlocks u LEFT JOIN episodes e ON e.id = u.episode_id
+		WHERE u.user_id = $1 ORDER BY u.unlocked_at`},
+	{"views", `SELECT v.episode_id, e.title AS episode_title, v.device_id, v.watched_seconds, v.completed, v.created_at, v.updated_at
+		FROM views v LEFT JOIN episodes e ON e.id = v.episode_id
+		WHERE v.user_id = $1 ORDER BY v.created_at`},
+	{"favorites", `SELECT f.series_id, s.title AS series_title, f.created_at
+		FROM favorites f LEFT JOIN series s ON s.id = f.series_id
+		WHERE f.user_id = $1 ORDER BY f.created_at`},
+	// Actividad anónima de sus dispositivos que todavía no se fusionó en la cuenta
+	{"device_views", userDevicesCTE + ` SELECT v.device_id, v.episode_id, e.title AS episode_title, v.watched_seconds, v.completed, v.created_at, v.updated_at
+		FROM views v LEFT JOIN episodes e ON e.id = v.episode_id
+		WHERE v.user_id IS NULL AND v.device_id::text IN (SELECT device_id FROM user_devices)
+		ORDER BY v.created_at`},
+	{"device_favorites", userDevicesCTE + ` SELECT f.device_id, f.series_id, s.title AS series_title, f.created_at
+		FROM favorites f LEFT JOIN series s ON s.id = f.series_id
+		WHERE f.user_id IS NULL AND f.device_id::text IN (SELECT device_id FROM user_devices)
+		ORDER BY f.created_at`},
+	{"playback_events", userDevicesCTE + ` SELECT pe.episode_id, pe.event_type, pe.position_seconds, pe.duration_ms, pe.data,
+		       pe.device_id, pe.session_id, pe.client_ts, pe.received_at
+		FROM playback_events pe
+		WHERE pe.user_id = $1
+		   OR (pe.user_id IS NULL AND pe.device_id IN (SELECT device_id FROM user_devices))
+		ORDER BY pe.received_at`},
+	{"search_queries", `SELECT q.query, q.locale, q.result_count, q.clicked_series_id, s.title AS clicked_series_title, q.created_at
+		FROM search_queries q LEFT JOIN series s ON s.id = q.clicked_series_id
+		WHERE q.user_id = $1 ORDER BY q.created_at`},
+	{"device_tokens", `SELECT token, platform, created_at
+		FROM device_tokens WHERE user_id = $1 ORDER BY created_at`},
+	{"bans", `SELECT reason, expires_at, is_active, created_at
+		FROM bans WHERE user_id = $1 ORDER BY created_at`},
+	{"roles", `SELECT role, created_at FROM user_roles WHERE user_id = $1`},
+	{"ad_rewards", `SELECT ad_id, episode_id, created_at
+		FROM ad_validations WHERE user_id = $1 ORDER BY created_at`},
+}
+
+// buildArchive arma el ZIP con una sección JSON por tabla y un manifest.json en un
+// temporal y lo sube al ObjectStore como exports/<userID>/<jobID>.zip, para que
+// cualquier instancia pueda servir la descarga. Devuelve la clave y el tamaño.
+func (s *Service) buildArchive(ctx context.Context, job *ExportJob) (string, int64, error) {
+	f, err := os.CreateTemp("", "qenti-export-*.zip")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	sections := []string{}
+
+	for _, section := range exportSections {
+		rows, err := queryRows(ctx, s.repo.db, section.query, job.UserID)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to export %s: %w", section.name, err)
+		}
+		if err := writeJSON(zw, section.name+".json", rows); err != nil {
+			return "", 0, err
+		}
+		sections = append(sections, section.name)
+	}
+	manifest := map[string]interface{}{
+		"user_id":      job.UserID,
+		"export_id":    job.ID,
+		"generated_at": time.Now().UTC(),
+		"sections":     sections,
+	}
+	if err := writeJSON(zw, "manifest.json", manifest); err != nil {
+		return "", 0, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to finalize export zip: %w", err)
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to size export file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", 0, fmt.Errorf("failed to rewind export file: %w", err)
+	}
+
+	key := fmt.Sprintf("exports/%s/%s.zip", job.UserID, job.ID)
+	if err := s.store.Put(key, f, size, "application/zip"); err != nil {
+		return "", 0, fmt.Errorf("failed to store export: %w", err)
+	}
+	return key, size, nil
+}
+
+// queryRows ejecuta la consulta y devuelve cada fila como mapa columna → valor.
+func queryRows(ctx context.Context, db *sql.DB, query string, userID uuid.UUID) ([]map[string]interface{}, error) {
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			// lib/pq devuelve texto/uuid como []byte: convertir para que el JSON sea legible
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export: %w", name, err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return nil
+}
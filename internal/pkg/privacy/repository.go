@@ -0,0 +1,419 @@
+// Package privacy atiende las solicitudes de titulares de datos (GDPR/LGPD):
+// exportación de datos personales y borrado de cuenta con período de gracia.
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Estados de data_exports.
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportReady      = "ready"
+	ExportFailed     = "failed"
+	ExportExpired    = "expired"
+)
+
+// Estados de account_deletions.
+const (
+	DeletionPending   = "pending"
+	DeletionCancelled = "cancelled"
+	DeletionCompleted = "completed"
+	DeletionFailed    = "failed"
+)
+
+// ErrOwnsProducer el usuario es dueño de una productora: hay que transferirla o
+// eliminarla antes de borrar la cuenta (el borrado en cascada arrastraría su catálogo).
+var ErrOwnsProducer = errors.New("privacy: user owns a producer")
+
+// ExportJob es una solicitud de exportación de datos.
+type ExportJob struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	RequestedBy *uuid.UUID `json:"requested_by,omitempty"`
+	Status      string     `json:"status"`
+	ObjectKey   string     `json:"-"`
+	SizeBytes   int64      `json:"size_bytes,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// DeletionRequest es una solicitud de borrado de cuenta.
+type DeletionRequest struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	RequestedBy  *uuid.UUID `json:"requested_by,omitempty"`
+	Status       string     `json:"status"`
+	RequestedAt  time.Time  `json:"requested_at"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const exportColumns = `id, user_id, requested_by, status, COALESCE(object_key, ''), COALESCE(size_bytes, 0),
+	COALESCE(error, ''), created_at, completed_at, expires_at`
+
+func scanExport(row interface{ Scan(...interface{}) error }) (*ExportJob, error) {
+	var j ExportJob
+	err := row.Scan(&j.ID, &j.UserID, &j.RequestedBy, &j.Status, &j.ObjectKey, &j.SizeBytes,
+		&j.Error, &j.CreatedAt, &j.CompletedAt, &j.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// CreateExport encola una exportación. Si el usuario ya tiene una pendiente o en
+// curso, devuelve esa en lugar de crear otra.
+func (r *Repository) CreateExport(ctx context.Context, userID uuid.UUID, requestedBy *uuid.UUID) (*ExportJob, error) {
+	job, err := scanExport(r.db.QueryRowContext(ctx, `
+		SELECT `+exportColumns+` FROM data_exports
+		WHERE user_id = $1 AND status IN ('pending', 'processing')
+		ORDER BY created_at DESC LIMIT 1`, userID))
+	if err == nil {
+		return job, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check pending export: %w", err)
+	}
+
+	job, err = scanExport(r.db.QueryRowContext(ctx, `
+		INSERT INTO data_exports (user_id, requested_by) VALUES ($1, $2)
+		RETURNING `+exportColumns, userID, requestedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+	return job, nil
+}
+
+// GetExport devuelve una exportación por ID, o nil si no existe.
+func (r *Repository) GetExport(ctx context.Context, id uuid.UUID) (*ExportJob, error) {
+	job, err := scanExport(r.db.QueryRowContext(ctx,
+		`SELECT `+exportColumns+` FROM data_exports WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	return job, nil
+}
+
+// ListExports lista las exportaciones más recientes; userID nil = todas.
+func (r *Repository) ListExports(ctx context.Context, userID *uuid.UUID, limit int) ([]ExportJob, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+exportColumns+` FROM data_exports
+		WHERE ($1::uuid IS NULL OR user_id = $1)
+		ORDER BY created_at DESC LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exports: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []ExportJob
+	for rows.Next() {
+		job, err := scanExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimExport toma la exportación pendiente más antigua y la marca 'processing'.
+// SKIP LOCKED permite varias instancias del worker. Devuelve nil si no hay trabajo.
+func (r *Repository) ClaimExport(ctx context.Context) (*ExportJob, error) {
+	job, err := scanExport(r.db.QueryRowContext(ctx, `
+		UPDATE data_exports SET status = 'processing'
+		WHERE id = (
+			SELECT id FROM data_exports WHERE status = 'pending'
+			ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+exportColumns))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim export: %w", err)
+	}
+	return job, nil
+}
+
+// CompleteExport marca la exportación como lista para descargar hasta expiresAt.
+func (r *Repository) CompleteExport(ctx context.Context, id uuid.UUID, objectKey string, size int64, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = 'ready', object_key = $2, size_bytes = $3, completed_at = NOW(), expires_at = $4
+		WHERE id = $1`, id, objectKey, size, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to complete export: %w", err)
+	}
+	return nil
+}
+
+// FailExport marca la exportación como fallida.
+func (r *Repository) FailExport(ctx context.Context, id uuid.UUID, cause error) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE data_exports SET status = 'failed', error = $2, completed_at = NOW() WHERE id = $1`,
+		id, cause.Error())
+	if err != nil {
+		return fmt.Errorf("failed to mark export failed: %w", err)
+	}
+	return nil
+}
+
+// ExpireExports marca como 'expired' las exportaciones vencidas y devuelve sus
+// claves para que el llamador borre los archivos del ObjectStore.
+func (r *Repository) ExpireExports(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE data_exports SET status = 'expired'
+		WHERE status = 'ready' AND expires_at < NOW()
+		RETURNING COALESCE(object_key, '')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire exports: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, fmt.Errorf("failed to scan expired export: %w", err)
+		}
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// UserExportFiles devuelve las claves de todos los ZIP de un usuario (para borrarlos con la cuenta).
+func (r *Repository) UserExportFiles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT object_key FROM data_exports WHERE user_id = $1 AND object_key IS NOT NULL`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export files: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, fmt.Errorf("failed to scan export file: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+const deletionColumns = `user_id, requested_by, status, requested_at, scheduled_for, completed_at, COALESCE(error, '')`
+
+func scanDeletion(row interface{ Scan(...interface{}) error }) (*DeletionRequest, error) {
+	var d DeletionRequest
+	err := row.Scan(&d.UserID, &d.RequestedBy, &d.Status, &d.RequestedAt, &d.ScheduledFor, &d.CompletedAt, &d.Error)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ScheduleDeletion programa el borrado de la cuenta para scheduledFor. Si ya había
+// una solicitud (cancelada o fallida) se reactiva con la nueva fecha.
+func (r *Repository) ScheduleDeletion(ctx context.Context, userID uuid.UUID, requestedBy *uuid.UUID, scheduledFor time.Time) (*DeletionRequest, error) {
+	d, err := scanDeletion(r.db.QueryRowContext(ctx, `
+		INSERT INTO account_deletions (user_id, requested_by, status, requested_at, scheduled_for)
+		VALUES ($1, $2, 'pending', NOW(), $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			requested_by  = EXCLUDED.requested_by,
+			status        = 'pending',
+			requested_at  = NOW(),
+			scheduled_for = EXCLUDED.scheduled_for,
+			completed_at  = NULL,
+			error         = NULL
+		WHERE account_deletions.status <> 'completed'
+		RETURNING `+deletionColumns, userID, requestedBy, scheduledFor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule deletion: %w", err)
+	}
+	return d, nil
+}
+
+// CancelDeletion cancela un borrado pendiente pedido por el propio usuario. Los que
+// abrió un super admin (requested_by de otra persona) no se tocan: para el usuario
+// es como si no hubiera ninguno y devuelve false.
+func (r *Repository) CancelDeletion(ctx context.Context, userID uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE account_deletions SET status = 'cancelled'
+		WHERE user_id = $1 AND status = 'pending'
+		  AND (requested_by IS NULL OR requested_by = user_id)`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel deletion: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// CancelDeletionAdmin cancela un borrado pendiente sin importar quién lo pidió.
+// Devuelve false si no había ninguno.
+func (r *Repository) CancelDeletionAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE account_deletions SET status = 'cancelled' WHERE user_id = $1 AND status = 'pending'`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel deletion: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetDeletion devuelve la solicitud de borrado del usuario, o nil si no existe.
+func (r *Repository) GetDeletion(ctx context.Context, userID uuid.UUID) (*DeletionRequest, error) {
+	d, err := scanDeletion(r.db.QueryRowContext(ctx,
+		`SELECT `+deletionColumns+` FROM account_deletions WHERE user_id = $1`, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deletion: %w", err)
+	}
+	return d, nil
+}
+
+// ListDeletions lista solicitudes de borrado; status vacío = todas.
+func (r *Repository) ListDeletions(ctx context.Context, status string, limit int) ([]DeletionRequest, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+deletionColumns+` FROM account_deletions
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY requested_at DESC LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var list []DeletionRequest
+	for rows.Next() {
+		d, err := scanDeletion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deletion: %w", err)
+		}
+		list = append(list, *d)
+	}
+	return list, rows.Err()
+}
+
+// DueDeletions devuelve los usuarios cuyo período de gracia ya terminó.
+func (r *Repository) DueDeletions(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id FROM account_deletions
+		WHERE status = 'pending' AND scheduled_for <= NOW()
+		ORDER BY scheduled_for LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan due deletion: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// FailDeletion registra el motivo por el que no se pudo borrar la cuenta.
+func (r *Repository) FailDeletion(ctx context.Context, userID uuid.UUID, cause error) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE account_deletions SET status = 'failed', error = $2 WHERE user_id = $1`, userID, cause.Error())
+	if err != nil {
+		return fmt.Errorf("failed to mark deletion failed: %w", err)
+	}
+	return nil
+}
+
+// OwnsProducer indica si el usuario es dueño de alguna productora.
+func (r *Repository) OwnsProducer(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return ownsProducer(ctx, r.db, userID)
+}
+
+func ownsProducer(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}, userID uuid.UUID) (bool, error) {
+	var owns bool
+	if err := q.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM producers WHERE user_id = $1)`, userID,
+	).Scan(&owns); err != nil {
+		return false, fmt.Errorf("failed to check producers: %w", err)
+	}
+	return owns, nil
+}
+
+// DeleteUser borra la cuenta y anonimiza lo que debe conservarse, en una transacción:
+//   - users y todo lo que cuelga con ON DELETE CASCADE (unlocks, transactions, favorites,
+//     device_tokens, bans, roles, refresh_tokens, ad_validations, exportaciones)
+//   - views: user_id/device_id a NULL (se conservan para las métricas agregadas)
+//   - playback_events: user_id/device_id/session_id a NULL
+//   - search_queries: user_id/device_id a NULL (se conservan para los reportes de búsqueda)
+//   - invitations: se desvinculan created_by/used_by (no tienen ON DELETE)
+//
+// Falla con ErrOwnsProducer si el usuario es dueño de una productora.
+func (r *Repository) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin delete tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if owns, err := ownsProducer(ctx, tx, userID); err != nil {
+		return err
+	} else if owns {
+		return ErrOwnsProducer
+	}
+
+	anonymize := []string{
+		`UPDATE views SET user_id = NULL, device_id = NULL WHERE user_id = $1`,
+		`UPDATE playback_events SET user_id = NULL, device_id = NULL, session_id = NULL WHERE user_id = $1`,
+		`UPDATE search_queries SET user_id = NULL, device_id = NULL WHERE user_id = $1`,
+		`UPDATE invitations SET created_by = NULL WHERE created_by = $1`,
+		`UPDATE invitations SET used_by = NULL WHERE used_by = $1`,
+	}
+	for _, q := range anonymize {
+		if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+			return fmt.Errorf("failed to anonymize user data: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE account_deletions SET status = 'completed', completed_at = NOW(), error = NULL
+		WHERE user_id = $1`, userID,
+	); err != nil {
+		return fmt.Errorf("failed to record deletion: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user deletion: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/auth"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+// Service coordina exportaciones y borrados: los handlers encolan solicitudes y el
+// worker (StartWorker) genera los ZIP y ejecuta los borrados vencidos.
+type Service struct {
+	repo         *Repository
+	refreshRepo  *auth.RefreshTokenRepository
+	store        storage.ObjectStore
+	exportTTL    time.Duration
+	deletionWait time.Duration
+}
+
+// NewService crea el servicio; store guarda los ZIP (storage.NewExportStore).
+func NewService(db *sql.DB, cfg config.PrivacyConfig, store storage.ObjectStore) *Service {
+	return &Service{
+		repo:         NewRepository(db),
+		refreshRepo:  auth.NewRefreshTokenRepository(db),
+		store:        store,
+		exportTTL:    time.Duration(cfg.ExportTTLHours) * time.Hour,
+		deletionWait: time.Duration(cfg.DeletionGraceDays) * 24 * time.Hour,
+	}
+}
+
+// Repo expone el repositorio para consultas de solo lectura desde los handlers.
+func (s *Service) Repo() *Repository {
+	return s.repo
+}
+
+// OpenExport abre el ZIP de una exportación lista. Devuelve
+// storage.ErrObjectNotFound si el archivo ya no está.
+func (s *Service) OpenExport(job *ExportJob) (io.ReadCloser, error) {
+	return s.store.Get(job.ObjectKey)
+}
+
+// RequestExport encola la exportación de datos del usuario y dispara su
+// procesamiento en segundo plano. requestedBy es nil si la pide el propio usuario.
+func (s *Service) RequestExport(ctx context.Context, userID uuid.UUID, requestedBy *uuid.UUID) (*ExportJob, error) {
+	job, err := s.repo.CreateExport(ctx, userID, requestedBy)
+	if err != nil {
+		return nil, err
+	}
+	go s.ProcessExports(context.Background())
+	return job, nil
+}
+
+// RequestDeletion programa el borrado tras el período de gracia y cierra todas las
+// sesiones del usuario (revoca sus refresh tokens). Con immediate (herramienta de
+// super admin) el borrado se ejecuta en el acto.
+// Falla con ErrOwnsProducer si el usuario es dueño de una productora.
+func (s *Service) RequestDeletion(ctx context.Context, userID uuid.UUID, requestedBy *uuid.UUID, immediate bool) (*DeletionRequest, error) {
+	if owns, err := s.repo.OwnsProducer(ctx, userID); err != nil {
+		return nil, err
+	} else if owns {
+		return nil, ErrOwnsProducer
+	}
+
+	when := time.Now().Add(s.deletionWait)
+	if immediate {
+		when = time.Now()
+	}
+	d, err := s.repo.ScheduleDeletion(ctx, userID, requestedBy, when)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshRepo.RevokeAllUserTokens(ctx, userID); err != nil {
+		log.Printf("privacy: failed to revoke tokens for %s: %v", userID, err)
+	}
+	if immediate {
+		if err := s.deleteUser(ctx, userID); err != nil {
+			return nil, err
+		}
+		return s.repo.GetDeletion(ctx, userID)
+	}
+	return d, nil
+}
+
+// ProcessExports genera todas las exportaciones pendientes.
+func (s *Service) ProcessExports(ctx context.Context) {
+	for {
+		job, err := s.repo.ClaimExport(ctx)
+		if err != nil {
+			log.Printf("privacy: %v", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		key, size, err := s.buildArchive(ctx, job)
+		if err != nil {
+			log.Printf("privacy: export %s failed: %v", job.ID, err)
+			if err := s.repo.FailExport(ctx, job.ID, err); err != nil {
+				log.Printf("privacy: %v", err)
+			}
+			continue
+		}
+		if err := s.repo.CompleteExport(ctx, job.ID, key, size, time.Now().Add(s.exportTTL)); err != nil {
+			log.Printf("privacy: %v", err)
+		}
+	}
+}
+
+// ProcessDeletions ejecuta los borrados cuyo período de gracia terminó y borra los
+// ZIP de exportación vencidos.
+func (s *Service) ProcessDeletions(ctx context.Context) {
+	ids, err := s.repo.DueDeletions(ctx, 100)
+	if err != nil {
+		log.Printf("privacy: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if err := s.deleteUser(ctx, id); err != nil {
+			log.Printf("privacy: deletion of %s failed: %v", id, err)
+		}
+	}
+
+	keys, err := s.repo.ExpireExports(ctx)
+	if err != nil {
+		log.Printf("privacy: %v", err)
+		return
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil {
+			log.Printf("privacy: failed to remove expired export %s: %v", key, err)
+		}
+	}
+}
+
+// deleteUser borra los ZIP del usuario y luego la cuenta. Si falla, deja la
+// solicitud en 'failed' con el motivo para que un super admin la revise.
+func (s *Service) deleteUser(ctx context.Context, userID uuid.UUID) error {
+	keys, err := s.repo.UserExportFiles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteUser(ctx, userID); err != nil {
+		if ferr := s.repo.FailDeletion(ctx, userID, err); ferr != nil {
+			log.Printf("privacy: %v", ferr)
+		}
+		return err
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil {
+			log.Printf("privacy: failed to remove export %s: %v", key, err)
+		}
+	}
+	log.Printf("✅ privacy: cuenta %s eliminada", userID)
+	return nil
+}
+
+// StartWorker procesa exportaciones y borrados vencidos cada `interval` en una
+// goroutine, hasta que ctx se cancele.
+func (s *Service) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.ProcessExports(ctx)
+			s.ProcessDeletions(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
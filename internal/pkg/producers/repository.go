@@ -4,25 +4,62 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/cache"
 	"github.com/qenti/qenti/internal/pkg/models"
 )
 
+// cacheTag tag de las entradas del cache que dependen del slug o el status de
+// algún productor.
+const cacheTag = "producers"
+
+// slugCacheTTL cuánto se recuerda la resolución de un slug.
+const slugCacheTTL = 5 * time.Minute
+
 type Repository struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *cache.Cache
 }
 
-func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db: db}
+func NewRepository(db *sql.DB, cacheStore *cache.Cache) *Repository {
+	return &Repository{db: db, cache: cacheStore}
+}
+
+// IDBySlug devuelve el ID del productor activo con ese slug, o nil si no hay
+// ninguno. Pasa por el cache: las apps lo resuelven en casi cada request.
+func (r *Repository) IDBySlug(ctx context.Context, slug string) (*uuid.UUID, error) {
+	return cache.GetOrLoad(ctx, r.cache, "producer_slug:"+slug, slugCacheTTL, []string{cacheTag},
+		func(ctx context.Context) (*uuid.UUID, error) {
+			var id uuid.UUID
+			err := r.db.QueryRowContext(ctx,
+				`SELECT id FROM producers WHERE slug = $1 AND status = 'active' LIMIT 1`, slug,
+			).Scan(&id)
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve producer slug: %w", err)
+			}
+			return &id, nil
+		})
+}
+
+// invalidate descarta del cache lo que depende de los slugs y status.
+func (r *Repository) invalidate(ctx context.Context) {
+	if err := r.cache.Invalidate(ctx, cacheTag); err != nil {
+		log.Printf("producers: invalidate cache: %v", err)
+	}
 }
 
 // GetAll retorna todos los productores con el email de su usuario vinculado y métricas básicas.
 func (r *Repository) GetAll(ctx context.Context) ([]models.ProducerWithEmail, error) {
 	query := `
 		SELECT p.id, p.user_id, p.name, p.slug, p.logo_url, p.description,
-		       p.is_active, p.status, p.created_at, p.updated_at, u.email,
+		       p.is_active, p.status, p.logo_image_id, p.created_at, p.updated_at, u.email,
 		       COALESCE((SELECT COUNT(*) FROM series s WHERE s.producer_id = p.id), 0) AS series_count,
 		       COALESCE((SELECT COUNT(*) FROM producer_members pm WHERE pm.producer_id = p.id), 0) AS members_count
 		FROM producers p
@@ -40,7 +77,7 @@ func (r *Repository) GetAll(ctx context.Context) ([]models.ProducerWithEmail, er
 		var p models.ProducerWithEmail
 		if err := rows.Scan(
 			&p.ID, &p.UserID, &p.Name, &p.Slug, &p.LogoURL, &p.Description,
-			&p.IsActive, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.Email,
+			&p.IsActive, &p.Status, &p.LogoImageID, &p.CreatedAt, &p.UpdatedAt, &p.Email,
 			&p.SeriesCount, &p.MembersCount,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan producer: %w", err)
@@ -54,7 +91,7 @@ func (r *Repository) GetAll(ctx context.Context) ([]models.ProducerWithEmail, er
 func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*models.ProducerWithEmail, error) {
 	query := `
 		SELECT p.id, p.user_id, p.name, p.slug, p.logo_url, p.description,
-		       p.is_active, p.status, p.created_at, p.updated_at, u.email
+		       p.is_active, p.status, p.logo_image_id, p.created_at, p.updated_at, u.email
 		FROM producers p
 		JOIN users u ON u.id = p.user_id
 		WHERE p.id = $1`
@@ -62,7 +99,7 @@ func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Produce
 	var p models.ProducerWithEmail
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&p.ID, &p.UserID, &p.Name, &p.Slug, &p.LogoURL, &p.Description,
-		&p.IsActive, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.Email,
+		&p.IsActive, &p.Status, &p.LogoImageID, &p.CreatedAt, &p.UpdatedAt, &p.Email,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("producer not found")
@@ -75,12 +112,12 @@ func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Produce
 
 // GetByUserID retorna el productor vinculado a un usuario.
 func (r *Repository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.Producer, error) {
-	query := `SELECT id, user_id, name, slug, logo_url, description, is_active, status, created_at, updated_at
+	query := `SELECT id, user_id, name, slug, logo_url, description, is_active, status, logo_image_id, created_at, updated_at
 	          FROM producers WHERE user_id = $1 LIMIT 1`
 	var p models.Producer
 	err := r.db.QueryRowContext(ctx, query, userID).Scan(
 		&p.ID, &p.UserID, &p.Name, &p.Slug, &p.LogoURL, &p.Description,
-		&p.IsActive, &p.Status, &p.CreatedAt, &p.UpdatedAt,
+		&p.IsActive, &p.Status, &p.LogoImageID, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -102,12 +139,17 @@ func (r *Repository) Create(ctx context.Context, p *models.Producer) error {
 	if p.Status == "" {
 		p.Status = "pending"
 	}
-	query := `INSERT INTO producers (id, user_id, name, slug, logo_url, description, is_active, status)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	query := `INSERT INTO producers (id, user_id, name, slug, logo_url, description, is_active, status, logo_image_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	          RETURNING created_at, updated_at`
-	return r.db.QueryRowContext(ctx, query,
-		p.ID, p.UserID, p.Name, p.Slug, p.LogoURL, p.Description, p.IsActive, p.Status,
+	err := r.db.QueryRowContext(ctx, query,
+		p.ID, p.UserID, p.Name, p.Slug, p.LogoURL, p.Description, p.IsActive, p.Status, p.LogoImageID,
 	).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	r.invalidate(ctx)
+	return nil
 }
 
 // SetStatus actualiza el status de un productor (approve/reject/suspend).
@@ -118,16 +160,21 @@ func (r *Repository) SetStatus(ctx context.Context, id uuid.UUID, status string)
 		`UPDATE producers SET status = $1, is_active = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`,
 		status, isActive, id,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	r.invalidate(ctx)
+	return nil
 }
 
 // Update actualiza un productor existente.
 func (r *Repository) Update(ctx context.Context, p *models.Producer) error {
 	query := `UPDATE producers
-	          SET name = $1, logo_url = $2, description = $3, is_active = $4, updated_at = CURRENT_TIMESTAMP
-	          WHERE id = $5 RETURNING updated_at`
+	          SET name = $1, logo_url = $2, description = $3, is_active = $4, logo_image_id = $5,
+	              updated_at = CURRENT_TIMESTAMP
+	          WHERE id = $6 RETURNING updated_at`
 	err := r.db.QueryRowContext(ctx, query,
-		p.Name, p.LogoURL, p.Description, p.IsActive, p.ID,
+		p.Name, p.LogoURL, p.Description, p.IsActive, p.LogoImageID, p.ID,
 	).Scan(&p.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("producer not found")
@@ -145,6 +192,7 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
 	if rows == 0 {
 		return fmt.Errorf("producer not found")
 	}
+	r.invalidate(ctx)
 	return nil
 }
 
@@ -0,0 +1,365 @@
+// Package recommendations implementa las recomendaciones personalizadas por
+// filtrado colaborativo ítem a ítem: un job periódico calcula la similitud coseno
+// entre series a partir de quién las ve, las marca como favoritas y desbloquea sus
+// episodios (series_similarity), y cada pedido suma la similitud de las series del
+// viewer con las demás y la mezcla con la popularidad reciente.
+package recommendations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+)
+
+const (
+	// Pesos de cada señal por persona y serie; se suman (máximo 5).
+	viewWeight      = 1 // vio algún episodio
+	completedWeight = 2 // terminó algún episodio (en lugar de viewWeight)
+	favoriteWeight  = 2
+	unlockWeight    = 1
+	// maxSeriesPerViewer deja fuera del cálculo a quien interactuó con más series
+	// (cuentas de prueba o bots), que no aportan señal y encarecen el self-join.
+	maxSeriesPerViewer = 200
+	// popularityDays días de series_daily_rollups que cuentan para la popularidad.
+	popularityDays = 14
+	// rebuildLockKey clave del advisory lock que evita que varias réplicas
+	// recalculen series_similarity a la vez (valor arbitrario, fijo).
+	rebuildLockKey int64 = 0x71656e7472 // "qentr"
+)
+
+// ErrRebuildInProgress otra réplica (o cmd/recommendations) está recalculando.
+var ErrRebuildInProgress = errors.New("recommendations: rebuild already in progress")
+
+// Neighbor serie similar a otra.
+type Neighbor struct {
+	SeriesID  uuid.UUID
+	Score     float64
+	CoViewers int
+}
+
+// Profile recomendaciones de un viewer. Scores mezcla similitud y popularidad
+// (0-1) de las series candidatas; Seeds son las series con las que interactuó y
+// Completed las que terminó (no se recomiendan).
+type Profile struct {
+	Scores    map[uuid.UUID]float64
+	Seeds     []uuid.UUID
+	Completed map[uuid.UUID]bool
+}
+
+type Service struct {
+	db  *sql.DB
+	cfg config.RecommendationsConfig
+}
+
+func NewService(db *sql.DB, cfg config.RecommendationsConfig) *Service {
+	return &Service{db: db, cfg: cfg}
+}
+
+// interactionsSQL CTE "interactions" (owner, series_id, weight) con las señales de
+// los últimos $1 días. forViewer limita a las del usuario $2 o el invitado $3.
+func interactionsSQL(forViewer bool) string {
+	owner := func(alias string) string {
+		if !forViewer {
+			return ""
+		}
+		return fmt.Sprintf(` AND (%[1]s.user_id = $2 OR (%[1]s.user_id IS NULL AND %[1]s.device_id = $3))`, alias)
+	}
+	unlockOwner := ""
+	if forViewer {
+		unlockOwner = ` AND u.user_id = $2`
+	}
+	return fmt.Sprintf(`
+		interactions AS (
+			SELECT owner, series_id, SUM(weight)::float8 AS weight
+			FROM (
+				SELECT COALESCE(v.user_id, v.device_id) AS owner, e.series_id,
+				       MAX(CASE WHEN v.completed THEN %[1]d ELSE %[2]d END) AS weight
+				FROM views v
+				JOIN episodes e ON e.id = v.episode_id
+				WHERE COALESCE(v.user_id, v.device_id) IS NOT NULL
+				  AND v.created_at > NOW() - make_interval(days => $1)%[5]s
+				GROUP BY 1, 2
+				UNION ALL
+				SELECT COALESCE(f.user_id, f.device_id), f.series_id, %[3]d
+				FROM favorites f
+				WHERE f.created_at > NOW() - make_interval(days => $1)%[6]s
+				UNION ALL
+				SELECT u.user_id, e.series_id, %[4]d
+				FROM unlocks u
+				JOIN episodes e ON e.id = u.episode_id
+				WHERE u.unlocked_at > NOW() - make_interval(days => $1)%[7]s
+				GROUP BY 1, 2
+			) signals
+			GROUP BY owner, series_id
+		)`, completedWeight, viewWeight, favoriteWeight, unlockWeight, owner("v"), owner("f"), unlockOwner)
+}
+
+// Rebuild recalcula series_similarity: por cada serie guarda las
+// NeighborsPerSeries más parecidas con al menos MinCoViewers personas en común.
+// Devuelve la cantidad de pares guardados, o ErrRebuildInProgress si otro proceso
+// ya está recalculando.
+func (s *Service) Rebuild(ctx context.Context) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// El lock se libera solo al terminar la transacción
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, rebuildLockKey).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock series similarity: %w", err)
+	}
+	if !locked {
+		return 0, ErrRebuildInProgress
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM series_similarity`); err != nil {
+		return 0, fmt.Errorf("failed to clear series similarity: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `
+		WITH`+interactionsSQL(false)+`,
+		active AS (
+			SELECT i.* FROM interactions i
+			WHERE i.owner IN (SELECT owner FROM interactions GROUP BY owner HAVING COUNT(*) BETWEEN 2 AND $4)
+		),
+		norms AS (
+			SELECT series_id, SQRT(SUM(weight * weight)) AS norm FROM active GROUP BY series_id
+		),
+		pairs AS (
+			SELECT a.series_id, b.series_id AS similar_series_id,
+			       SUM(a.weight * b.weight) AS dot, COUNT(*) AS co_viewers
+			FROM active a
+			JOIN active b ON b.owner = a.owner AND b.series_id <> a.series_id
+			GROUP BY a.series_id, b.series_id
+			HAVING COUNT(*) >= $2
+		),
+		scored AS (
+			SELECT p.series_id, p.similar_series_id, p.dot / (na.norm * nb.norm) AS score, p.co_viewers
+			FROM pairs p
+			JOIN norms na ON na.series_id = p.series_id
+			JOIN norms nb ON nb.series_id = p.similar_series_id
+		),
+		ranked AS (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY series_id ORDER BY score DESC, co_viewers DESC) AS rank
+			FROM scored
+		)
+		INSERT INTO series_similarity (series_id, similar_series_id, score, co_viewers)
+		SELECT series_id, similar_series_id, score, co_viewers FROM ranked WHERE rank <= $3`,
+		s.cfg.WindowDays, s.cfg.MinCoViewers, s.cfg.NeighborsPerSeries, maxSeriesPerViewer)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute series similarity: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit series similarity: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// StartWorker ejecuta Rebuild al arrancar y luego cada `interval` en una goroutine,
+// hasta que ctx se cancele. Con varias réplicas solo una recalcula: se saltea si otra
+// lo está haciendo o lo hizo hace menos de medio intervalo. Los errores solo se loguean.
+func (s *Service) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		rebuild := func() {
+			var recent bool
+			if err := s.db.QueryRowContext(ctx,
+				`SELECT COALESCE(MAX(computed_at) > NOW() - make_interval(secs => $1), FALSE) FROM series_similarity`,
+				(interval / 2).Seconds(),
+			).Scan(&recent); err != nil {
+				log.Printf("recommendations: last rebuild: %v", err)
+			} else if recent {
+				return
+			}
+			start := time.Now()
+			n, err := s.Rebuild(ctx)
+			if errors.Is(err, ErrRebuildInProgress) {
+				log.Printf("recommendations: rebuild skipped, another instance is running it")
+				return
+			}
+			if err != nil {
+				log.Printf("recommendations: rebuild failed: %v", err)
+				return
+			}
+			log.Printf("recommendations: %d similar pairs (%v)", n, time.Since(start).Round(time.Millisecond))
+		}
+		rebuild()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rebuild()
+			}
+		}
+	}()
+}
+
+// Similar devuelve las series más parecidas a seriesID, de la más a la menos.
+func (s *Service) Similar(ctx context.Context, seriesID uuid.UUID, limit int) ([]Neighbor, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT similar_series_id, score, co_viewers FROM series_similarity
+		WHERE series_id = $1
+		ORDER BY score DESC, co_viewers DESC
+		LIMIT $2`, seriesID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get similar series: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Neighbor
+	for rows.Next() {
+		var n Neighbor
+		if err := rows.Scan(&n.SeriesID, &n.Score, &n.CoViewers); err != nil {
+			return nil, fmt.Errorf("failed to scan similar series: %w", err)
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+// ForViewer arma las recomendaciones del usuario userID o, sin sesión, del
+// invitado deviceID (el otro en uuid.Nil).
+func (s *Service) ForViewer(ctx context.Context, userID, deviceID uuid.UUID) (*Profile, error) {
+	var user, device interface{}
+	if userID != uuid.Nil {
+		user = userID
+	} else {
+		device = deviceID
+	}
+	p := &Profile{Completed: make(map[uuid.UUID]bool)}
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH`+interactionsSQL(true)+`
+		SELECT i.series_id,
+		       EXISTS (
+		           SELECT 1 FROM views v JOIN episodes e ON e.id = v.episode_id
+		           WHERE e.series_id = i.series_id AND v.completed = TRUE
+		             AND (v.user_id = $2 OR (v.user_id IS NULL AND v.device_id = $3))
+		       )
+		FROM interactions i`, s.cfg.WindowDays, user, device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get viewer interactions: %w", err)
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var completed bool
+		if err := rows.Scan(&id, &completed); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan viewer interaction: %w", err)
+		}
+		p.Seeds = append(p.Seeds, id)
+		if completed {
+			p.Completed[id] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get viewer interactions: %w", err)
+	}
+	if len(p.Seeds) == 0 {
+		p.Scores = map[uuid.UUID]float64{}
+		return p, nil
+	}
+
+	// Puntaje de cada candidata: similitud con las series del viewer ponderada
+	// por cuánto interactuó con cada una
+	similarity := make(map[uuid.UUID]float64)
+	rows, err = s.db.QueryContext(ctx, `
+		WITH`+interactionsSQL(true)+`
+		SELECT s.similar_series_id, SUM(i.weight * s.score)
+		FROM interactions i
+		JOIN series_similarity s ON s.series_id = i.series_id
+		GROUP BY s.similar_series_id`, s.cfg.WindowDays, user, device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to score recommendations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var score float64
+		if err := rows.Scan(&id, &score); err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation: %w", err)
+		}
+		similarity[id] = score
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to score recommendations: %w", err)
+	}
+
+	popularity, err := s.Popularity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.Scores = Blend(similarity, popularity, s.cfg.PopularityPercent)
+	return p, nil
+}
+
+// Popularity vistas de los últimos popularityDays días de cada serie, escaladas a
+// 0-1 respecto de la más vista.
+func (s *Service) Popularity(ctx context.Context) (map[uuid.UUID]float64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT series_id, SUM(views) FROM series_daily_rollups
+		WHERE day > CURRENT_DATE - $1::int
+		GROUP BY series_id`, popularityDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series popularity: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[uuid.UUID]float64)
+	for rows.Next() {
+		var id uuid.UUID
+		var views float64
+		if err := rows.Scan(&id, &views); err != nil {
+			return nil, fmt.Errorf("failed to scan series popularity: %w", err)
+		}
+		result[id] = views
+	}
+	return normalize(result), rows.Err()
+}
+
+// Blend mezcla similitud y popularidad (ambas llevadas a 0-1): popularityPercent
+// es el peso de la popularidad sobre 100. Las series sin similitud quedan solo con
+// su parte de popularidad, por debajo de las que el viewer tiene afinidad.
+func Blend(similarity, popularity map[uuid.UUID]float64, popularityPercent int) map[uuid.UUID]float64 {
+	if popularityPercent < 0 {
+		popularityPercent = 0
+	} else if popularityPercent > 100 {
+		popularityPercent = 100
+	}
+	w := float64(popularityPercent) / 100
+	result := make(map[uuid.UUID]float64, len(similarity)+len(popularity))
+	for id, score := range normalize(similarity) {
+		result[id] += (1 - w) * score
+	}
+	for id, score := range popularity {
+		result[id] += w * score
+	}
+	return result
+}
+
+// normalize divide cada valor por el máximo (en su lugar).
+func normalize(m map[uuid.UUID]float64) map[uuid.UUID]float64 {
+	max := 0.0
+	for _, v := range m {
+		if v > max {
+			max = v
+		}
+	}
+	if max > 0 {
+		for id, v := range m {
+			m[id] = v / max
+		}
+	}
+	return m
+}
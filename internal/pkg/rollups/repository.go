@@ -0,0 +1,340 @@
+// Package rollups mantiene agregados diarios por episodio y por serie
+// (vistas, viewers únicos, completados, segundos vistos, unlocks por método y
+// monedas gastadas) para que dashboard y discovery no escaneen views/unlocks crudos.
+package rollups
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/events"
+)
+
+const (
+	// buildLockKey clave del advisory lock que serializa BuildDay entre réplicas
+	// y cmd/rollup (valor arbitrario, fijo).
+	buildLockKey int64 = 0x71656e7462 // "qentb"
+	// refreshLockKey clave del advisory lock que deja a una sola réplica
+	// corriendo Refresh (y el backfill del primer arranque).
+	refreshLockKey int64 = 0x71656e7466 // "qentf"
+)
+
+// SeriesTotal es el agregado de una serie sobre una ventana de días.
+type SeriesTotal struct {
+	SeriesID     uuid.UUID `json:"series_id"`
+	Title        string    `json:"title"`
+	Views        int       `json:"views"`
+	Completions  int       `json:"completions"`
+	WatchSeconds int64     `json:"watch_seconds"`
+	Unlocks      int       `json:"unlocks"`
+	CoinsSpent   int       `json:"coins_spent"`
+}
+
+// EpisodeCompletion es la tasa de completado histórica de un episodio.
+type EpisodeCompletion struct {
+	SeriesID       uuid.UUID `json:"series_id"`
+	EpisodeNumber  int       `json:"episode_number"`
+	CompletionRate float64   `json:"completion_rate"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// dayBounds devuelve [inicio, fin) del día UTC que contiene t.
+func dayBounds(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// BuildDay recalcula por completo los rollups de un día (UTC).
+// Borra e inserta dentro de una transacción, por lo que es idempotente y
+// seguro de re-ejecutar (backfill o refresco incremental del día en curso).
+func (r *Repository) BuildDay(ctx context.Context, day time.Time) error {
+	start, end := dayBounds(day)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin rollup tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serializa los builds de todas las réplicas y del CLI: dos DELETE+INSERT
+	// del mismo día a la vez chocan en la clave primaria
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, buildLockKey); err != nil {
+		return fmt.Errorf("failed to lock rollups: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM episode_daily_rollups WHERE day = $1::date`, start); err != nil {
+		return fmt.Errorf("failed to clear episode rollups: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM series_daily_rollups WHERE day = $1::date`, start); err != nil {
+		return fmt.Errorf("failed to clear series rollups: %w", err)
+	}
+
+	episodeQuery := `
+		WITH v AS (
+			-- Las vistas anónimas solo llevan device_id: el viewer es el usuario o,
+			-- si no hay, el dispositivo.
+			SELECT episode_id, COUNT(*) AS views,
+			       COUNT(DISTINCT COALESCE(user_id::text, device_id::text)) AS unique_viewers
+			FROM views
+			WHERE created_at >= $1 AND created_at < $2
+			GROUP BY episode_id
+		), p AS (
+			-- Sale de playback_events y no de views, que guarda el progreso acumulado
+			-- por viewer: cada heartbeat es un delta del día.
+			SELECT episode_id, COUNT(*) FILTER (WHERE event_type = 'heartbeat') * $3 AS watch_seconds
+			FROM playback_events
+			WHERE received_at >= $1 AND received_at < $2
+			GROUP BY episode_id
+		), c AS (
+			-- Un completado cuenta solo el día del primer complete del viewer, para no
+			-- superar a las vistas. El primero sale de una sola pasada agrupada sobre
+			-- los completes previos de los pares (episodio, viewer) que completaron hoy.
+			SELECT episode_id, COUNT(*) AS completions
+			FROM (
+				SELECT episode_id, MIN(received_at) AS first_at
+				FROM playback_events
+				WHERE event_type = 'complete' AND received_at < $2
+				  AND (episode_id, COALESCE(user_id::text, device_id)) IN (
+				      SELECT episode_id, COALESCE(user_id::text, device_id)
+				      FROM playback_events
+				      WHERE event_type = 'complete' AND received_at >= $1 AND received_at < $2
+				  )
+				GROUP BY episode_id, COALESCE(user_id::text, device_id)
+			) first_completes
+			WHERE first_at >= $1
+			GROUP BY episode_id
+		), u AS (
+			SELECT episode_id,
+			       COUNT(*) FILTER (WHERE method = 'COIN') AS unlocks_coin,
+			       COUNT(*) FILTER (WHERE method = 'AD')   AS unlocks_ad,
+			       COUNT(*) FILTER (WHERE method = 'SUB')  AS unlocks_sub
+			FROM unlocks
+			WHERE unlocked_at >= $1 AND unlocked_at < $2
+			GROUP BY episode_id
+		), t AS (
+			SELECT episode_id, -SUM(amount) AS coins_spent
+			FROM transactions
+			WHERE type = 'unlock' AND episode_id IS NOT NULL
+			  AND created_at >= $1 AND created_at < $2
+			GROUP BY episode_id
+		), ids AS (
+			SELECT episode_id FROM v
+			UNION SELECT episode_id FROM p
+			UNION SELECT episode_id FROM u
+			UNION SELECT episode_id FROM t
+		)
+		INSERT INTO episode_daily_rollups
+			(day, episode_id, series_id, views, unique_viewers, completions, watch_seconds,
+			 unlocks_coin, unlocks_ad, unlocks_sub, coins_spent)
+		SELECT $1::date, e.id, e.series_id,
+		       COALESCE(v.views, 0), COALESCE(v.unique_viewers, 0),
+		       COALESCE(c.completions, 0), COALESCE(p.watch_seconds, 0),
+		       COALESCE(u.unlocks_coin, 0), COALESCE(u.unlocks_ad, 0), COALESCE(u.unlocks_sub, 0),
+		       COALESCE(t.coins_spent, 0)
+		FROM ids
+		JOIN episodes e ON e.id = ids.episode_id
+		LEFT JOIN v ON v.episode_id = ids.episode_id
+		LEFT JOIN p ON p.episode_id = ids.episode_id
+		LEFT JOIN c ON c.episode_id = ids.episode_id
+		LEFT JOIN u ON u.episode_id = ids.episode_id
+		LEFT JOIN t ON t.episode_id = ids.episode_id
+	`
+	if _, err := tx.ExecContext(ctx, episodeQuery, start, end, events.HeartbeatIntervalSeconds); err != nil {
+		return fmt.Errorf("failed to build episode rollups: %w", err)
+	}
+
+	// Los viewers únicos de la serie no son la suma de los de sus episodios:
+	// se recalculan contra views para el día.
+	seriesQuery := `
+		INSERT INTO series_daily_rollups
+			(day, series_id, producer_id, views, unique_viewers, completions, watch_seconds,
+			 unlocks_coin, unlocks_ad, unlocks_sub, coins_spent)
+		SELECT $1::date, s.id, s.producer_id,
+		       SUM(r.views), COALESCE(MAX(uv.unique_viewers), 0),
+		       SUM(r.completions), SUM(r.watch_seconds),
+		       SUM(r.unlocks_coin), SUM(r.unlocks_ad), SUM(r.unlocks_sub),
+		       SUM(r.coins_spent)
+		FROM episode_daily_rollups r
+		JOIN series s ON s.id = r.series_id
+		LEFT JOIN (
+			SELECT e.series_id, COUNT(DISTINCT COALESCE(v.user_id::text, v.device_id::text)) AS unique_viewers
+			FROM views v
+			JOIN episodes e ON e.id = v.episode_id
+			WHERE v.created_at >= $1 AND v.created_at < $2
+			GROUP BY e.series_id
+		) uv ON uv.series_id = s.id
+		WHERE r.day = $1::date
+		GROUP BY s.id, s.producer_id
+	`
+	if _, err := tx.ExecContext(ctx, seriesQuery, start, end); err != nil {
+		return fmt.Errorf("failed to build series rollups: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO rollup_runs (day, built_at) VALUES ($1::date, NOW())
+		ON CONFLICT (day) DO UPDATE SET built_at = NOW()`, start,
+	); err != nil {
+		return fmt.Errorf("failed to record rollup run: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rollups for %s: %w", start.Format("2006-01-02"), err)
+	}
+	return nil
+}
+
+// BuildRange recalcula los rollups de cada día en [from, to] (ambos inclusive).
+// Se detiene en el primer error devolviendo el día que falló.
+func (r *Repository) BuildRange(ctx context.Context, from, to time.Time) error {
+	from, _ = dayBounds(from)
+	to, _ = dayBounds(to)
+	if to.Before(from) {
+		return fmt.Errorf("invalid range: %s is before %s", to.Format("2006-01-02"), from.Format("2006-01-02"))
+	}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if err := r.BuildDay(ctx, day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LastBuiltDay devuelve el último día con rollup construido (nil si nunca se construyó).
+func (r *Repository) LastBuiltDay(ctx context.Context) (*time.Time, error) {
+	var day sql.NullTime
+	if err := r.db.QueryRowContext(ctx, `SELECT MAX(day) FROM rollup_runs`).Scan(&day); err != nil {
+		return nil, fmt.Errorf("failed to get last rollup day: %w", err)
+	}
+	if !day.Valid {
+		return nil, nil
+	}
+	return &day.Time, nil
+}
+
+// FirstActivityDay devuelve el primer día con vistas, eventos de reproducción,
+// unlocks o gasto de monedas
+// (nil si no hay actividad).
+func (r *Repository) FirstActivityDay(ctx context.Context) (*time.Time, error) {
+	var first sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT LEAST(
+			(SELECT MIN(created_at) FROM views),
+			(SELECT MIN(unlocked_at) FROM unlocks),
+			(SELECT MIN(created_at) FROM transactions WHERE type = 'unlock' AND episode_id IS NOT NULL),
+			(SELECT MIN(received_at) FROM playback_events)
+		)`).Scan(&first)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get first activity day: %w", err)
+	}
+	if !first.Valid {
+		return nil, nil
+	}
+	day, _ := dayBounds(first.Time.UTC())
+	return &day, nil
+}
+
+// TopSeries devuelve las series con más vistas en los últimos `days` días.
+// Si producerID != nil filtra por tenant.
+func (r *Repository) TopSeries(ctx context.Context, producerID *uuid.UUID, days, limit int) ([]SeriesTotal, error) {
+	query := `
+		SELECT s.id, s.title,
+		       COALESCE(SUM(r.views), 0), COALESCE(SUM(r.completions), 0),
+		       COALESCE(SUM(r.watch_seconds), 0),
+		       COALESCE(SUM(r.unlocks_coin + r.unlocks_ad + r.unlocks_sub), 0),
+		       COALESCE(SUM(r.coins_spent), 0)
+		FROM series s
+		LEFT JOIN series_daily_rollups r
+		       ON r.series_id = s.id AND r.day > CURRENT_DATE - $1::int
+		WHERE s.is_active = TRUE
+		  AND ($3::uuid IS NULL OR s.producer_id = $3)
+		GROUP BY s.id, s.title
+		ORDER BY 3 DESC
+		LIMIT $2
+	`
+	var pid interface{}
+	if producerID != nil {
+		pid = *producerID
+	}
+	rows, err := r.db.QueryContext(ctx, query, days, limit, pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top series rollups: %w", err)
+	}
+	defer rows.Close()
+
+	var result []SeriesTotal
+	for rows.Next() {
+		var t SeriesTotal
+		if err := rows.Scan(
+			&t.SeriesID, &t.Title, &t.Views, &t.Completions,
+			&t.WatchSeconds, &t.Unlocks, &t.CoinsSpent,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan top series rollup: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// CompletionByEpisode devuelve la tasa de completado por episodio (histórico).
+// Si producerID != nil filtra por tenant.
+func (r *Repository) CompletionByEpisode(ctx context.Context, producerID *uuid.UUID, limit int) ([]EpisodeCompletion, error) {
+	query := `
+		SELECT e.series_id, e.episode_number,
+		       COALESCE(SUM(r.completions)::float / NULLIF(SUM(r.views), 0), 0)
+		FROM episodes e
+		JOIN series s ON s.id = e.series_id
+		LEFT JOIN episode_daily_rollups r ON r.episode_id = e.id
+		WHERE ($2::uuid IS NULL OR s.producer_id = $2)
+		GROUP BY e.series_id, e.episode_number
+		ORDER BY e.series_id, e.episode_number
+		LIMIT $1
+	`
+	var pid interface{}
+	if producerID != nil {
+		pid = *producerID
+	}
+	rows, err := r.db.QueryContext(ctx, query, limit, pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query episode completion rollups: %w", err)
+	}
+	defer rows.Close()
+
+	var result []EpisodeCompletion
+	for rows.Next() {
+		var ec EpisodeCompletion
+		if err := rows.Scan(&ec.SeriesID, &ec.EpisodeNumber, &ec.CompletionRate); err != nil {
+			return nil, fmt.Errorf("failed to scan episode completion: %w", err)
+		}
+		result = append(result, ec)
+	}
+	return result, rows.Err()
+}
+
+// CoinsSpent devuelve las monedas gastadas en unlocks en los últimos `days` días.
+func (r *Repository) CoinsSpent(ctx context.Context, producerID *uuid.UUID, days int) (int, error) {
+	var pid interface{}
+	if producerID != nil {
+		pid = *producerID
+	}
+	var total int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(coins_spent), 0)
+		FROM series_daily_rollups
+		WHERE day > CURRENT_DATE - $1::int
+		  AND ($2::uuid IS NULL OR producer_id = $2)`, days, pid,
+	).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum coins spent: %w", err)
+	}
+	return total, nil
+}
@@ -0,0 +1,138 @@
+package rollups
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/qenti/qenti/internal/database"
+)
+
+// testDB abre la base de QENTI_TEST_DATABASE_URL con las migraciones aplicadas.
+// Sin la variable el test se salta: necesita un Postgres real.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("QENTI_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("QENTI_TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	return db
+}
+
+// testEpisode crea una serie con un episodio y la borra (con sus rollups y
+// eventos) al terminar el test, junto con los rollup_runs de day.
+func testEpisode(t *testing.T, db *sql.DB, day time.Time) (uuid.UUID, uuid.UUID) {
+	t.Helper()
+	seriesID, episodeID := uuid.New(), uuid.New()
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM playback_events WHERE episode_id = $1`, episodeID)
+		db.Exec(`DELETE FROM series WHERE id = $1`, seriesID)
+		db.Exec(`DELETE FROM rollup_runs WHERE day = $1::date`, day)
+	})
+	if _, err := db.Exec(`INSERT INTO series (id, title) VALUES ($1, 'rollups test')`, seriesID); err != nil {
+		t.Fatalf("insert series: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO episodes (id, series_id, episode_number, title) VALUES ($1, $2, 1, 'ep')`,
+		episodeID, seriesID); err != nil {
+		t.Fatalf("insert episode: %v", err)
+	}
+	return seriesID, episodeID
+}
+
+func TestBuildDayCountsDeviceOnlyViewers(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	// Un día fijo lejos del presente para no chocar con datos reales.
+	day := time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)
+	at := day.Add(10 * time.Hour)
+
+	seriesID, episodeID := testEpisode(t, db, day)
+
+	// Dos vistas anónimas del mismo dispositivo y una de otro: dos viewers.
+	deviceA, deviceB := uuid.New(), uuid.New()
+	for _, device := range []uuid.UUID{deviceA, deviceA, deviceB} {
+		if _, err := db.Exec(`INSERT INTO views (episode_id, device_id, created_at) VALUES ($1, $2, $3)`,
+			episodeID, device, at); err != nil {
+			t.Fatalf("insert view: %v", err)
+		}
+	}
+
+	if err := NewRepository(db).BuildDay(ctx, day); err != nil {
+		t.Fatalf("BuildDay: %v", err)
+	}
+
+	var views, viewers int
+	if err := db.QueryRow(`SELECT views, unique_viewers FROM episode_daily_rollups WHERE day = $1::date AND episode_id = $2`,
+		day, episodeID).Scan(&views, &viewers); err != nil {
+		t.Fatalf("episode rollup: %v", err)
+	}
+	if views != 3 || viewers != 2 {
+		t.Errorf("episode rollup: views=%d unique_viewers=%d, want 3 and 2", views, viewers)
+	}
+
+	if err := db.QueryRow(`SELECT views, unique_viewers FROM series_daily_rollups WHERE day = $1::date AND series_id = $2`,
+		day, seriesID).Scan(&views, &viewers); err != nil {
+		t.Fatalf("series rollup: %v", err)
+	}
+	if views != 3 || viewers != 2 {
+		t.Errorf("series rollup: views=%d unique_viewers=%d, want 3 and 2", views, viewers)
+	}
+}
+
+func TestBuildDayCountsFirstCompletionOnly(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	prev := time.Date(2001, 2, 4, 0, 0, 0, 0, time.UTC)
+	day := prev.AddDate(0, 0, 1)
+	_, episodeID := testEpisode(t, db, day)
+	t.Cleanup(func() { db.Exec(`DELETE FROM rollup_runs WHERE day = $1::date`, prev) })
+
+	// El dispositivo A ya completó el día anterior; B completa dos veces en el día.
+	completes := []struct {
+		device string
+		at     time.Time
+	}{
+		{"device-a", prev.Add(time.Hour)},
+		{"device-a", day.Add(time.Hour)},
+		{"device-b", day.Add(2 * time.Hour)},
+		{"device-b", day.Add(3 * time.Hour)},
+	}
+	for _, e := range completes {
+		if _, err := db.Exec(`INSERT INTO playback_events (device_id, episode_id, event_type, client_ts, received_at)
+		                      VALUES ($1, $2, 'complete', $3, $3)`, e.device, episodeID, e.at); err != nil {
+			t.Fatalf("insert event: %v", err)
+		}
+	}
+
+	repo := NewRepository(db)
+	for _, d := range []time.Time{prev, day} {
+		if err := repo.BuildDay(ctx, d); err != nil {
+			t.Fatalf("BuildDay %s: %v", d.Format("2006-01-02"), err)
+		}
+	}
+
+	for d, want := range map[time.Time]int{prev: 1, day: 1} {
+		var completions int
+		if err := db.QueryRow(`SELECT completions FROM episode_daily_rollups WHERE day = $1::date AND episode_id = $2`,
+			d, episodeID).Scan(&completions); err != nil {
+			t.Fatalf("episode rollup %s: %v", d.Format("2006-01-02"), err)
+		}
+		if completions != want {
+			t.Errorf("completions on %s = %d, want %d", d.Format("2006-01-02"), completions, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package rollups
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Refresh reconstruye ayer y hoy. Ayer se incluye porque el progreso y los
+// unlocks pueden seguir llegando después de medianoche UTC. Si quedaron días sin
+// construir (el servidor estuvo caído) los rellena desde el último build; si nunca
+// se construyó ninguno, desde la primera actividad registrada, para que discovery
+// y el dashboard no arranquen vacíos sobre una base con historial.
+//
+// Solo una réplica refresca a la vez: si otra tiene el lock, no hace nada.
+func (r *Repository) Refresh(ctx context.Context) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get rollup lock connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, refreshLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to lock rollup refresh: %w", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, refreshLockKey); err != nil {
+			log.Printf("rollups: unlock refresh: %v", err)
+			// La conexión no vuelve al pool con el lock tomado: se descarta
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+
+	now := time.Now().UTC()
+	from := now.AddDate(0, 0, -1)
+
+	last, err := r.LastBuiltDay(ctx)
+	if err != nil {
+		return err
+	}
+	if last == nil {
+		first, err := r.FirstActivityDay(ctx)
+		if err != nil {
+			return err
+		}
+		if first != nil && first.Before(from) {
+			log.Printf("rollups: no rollups built yet, backfilling from %s", first.Format("2006-01-02"))
+			from = *first
+		}
+	} else if last.Before(from) {
+		from = *last
+	}
+	return r.BuildRange(ctx, from, now)
+}
+
+// StartWorker ejecuta Refresh al arrancar y luego cada `interval` en una goroutine,
+// hasta que ctx se cancele. Los errores solo se loguean. Para recalcular rangos
+// históricos ya construidos usar el CLI cmd/rollup.
+func (r *Repository) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		if err := r.Refresh(ctx); err != nil {
+			log.Printf("rollups: refresh failed: %v", err)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Refresh(ctx); err != nil {
+					log.Printf("rollups: refresh failed: %v", err)
+				}
+			}
+		}
+	}()
+}
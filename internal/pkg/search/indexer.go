@@ -0,0 +1,328 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// queueBatch series que se reindexan por pasada del worker.
+const queueBatch = 100
+
+// episodeSeparator separa los títulos de episodios en el texto indexado.
+const episodeSeparator = " · "
+
+// seriesSource textos de una serie en el idioma por defecto y sus traducciones.
+type seriesSource struct {
+	title, description, producerName string
+	episodes                         []uuid.UUID
+	episodeTitles                    map[uuid.UUID]string
+	// géneros (nombre y traducciones por locale) y etiquetas
+	genres []genreSource
+	tags   []string
+	// traducciones por locale; los campos vacíos no están traducidos
+	titles, descriptions map[string]string
+	episodeTranslations  map[uuid.UUID]map[string]string
+}
+
+type genreSource struct {
+	name         string
+	translations map[string]string
+}
+
+// Reindex reconstruye las filas de series_search de la serie: una por el idioma por
+// defecto y otra por cada locale con traducciones, con los textos resueltos por su
+// cadena de fallback. Si la serie ya no existe, borra sus filas.
+func (s *Service) Reindex(ctx context.Context, seriesID uuid.UUID) error {
+	src, err := s.loadSource(ctx, seriesID)
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM series_search WHERE series_id = $1`, seriesID); err != nil {
+			return fmt.Errorf("failed to delete search rows: %w", err)
+		}
+		return nil
+	}
+
+	locales := []string{s.resolver.DefaultLocale()}
+	seen := map[string]bool{locales[0]: true}
+	addLocale := func(l string) {
+		if !seen[l] {
+			seen[l] = true
+			locales = append(locales, l)
+		}
+	}
+	for l := range src.titles {
+		addLocale(l)
+	}
+	for l := range src.descriptions {
+		addLocale(l)
+	}
+	for _, byLocale := range src.episodeTranslations {
+		for l := range byLocale {
+			addLocale(l)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM series_search WHERE series_id = $1 AND locale <> ALL($2)`, seriesID, pq.Array(locales)); err != nil {
+		return fmt.Errorf("failed to delete stale search rows: %w", err)
+	}
+	for _, locale := range locales {
+		chain := s.resolver.Chain([]string{locale})
+		title := resolveText(chain, src.titles, src.title)
+		description := resolveText(chain, src.descriptions, src.description)
+		episodeTitles := make([]string, 0, len(src.episodes))
+		for _, id := range src.episodes {
+			if t := resolveText(chain, src.episodeTranslations[id], src.episodeTitles[id]); t != "" {
+				episodeTitles = append(episodeTitles, t)
+			}
+		}
+		terms := make([]string, 0, len(src.genres)+len(src.tags))
+		for _, g := range src.genres {
+			terms = append(terms, resolveText(chain, g.translations, g.name))
+		}
+		terms = append(terms, src.tags...)
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO series_search (series_id, locale, config, title, description, producer_name,
+			                           episode_titles, taxonomy, search_text, document, updated_at)
+			VALUES ($1, $2, $3::regconfig, $4, $5, $6, $7, $8,
+			        lower(unaccent($4 || ' ' || $6)),
+			        setweight(to_tsvector($3::regconfig, $4), 'A') ||
+			        setweight(to_tsvector($3::regconfig, $6 || ' ' || $8), 'B') ||
+			        setweight(to_tsvector($3::regconfig, $7), 'C') ||
+			        setweight(to_tsvector($3::regconfig, $5), 'D'),
+			        CURRENT_TIMESTAMP)
+			ON CONFLICT (series_id, locale) DO UPDATE SET
+				config = EXCLUDED.config, title = EXCLUDED.title, description = EXCLUDED.description,
+				producer_name = EXCLUDED.producer_name, episode_titles = EXCLUDED.episode_titles, taxonomy = EXCLUDED.taxonomy,
+				search_text = EXCLUDED.search_text, document = EXCLUDED.document, updated_at = EXCLUDED.updated_at`,
+			seriesID, locale, textSearchConfig(locale), title, description, src.producerName,
+			strings.Join(episodeTitles, episodeSeparator), strings.Join(terms, ", ")); err != nil {
+			return fmt.Errorf("failed to index series: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit search index: %w", err)
+	}
+	return nil
+}
+
+// resolveText devuelve la primera traducción no vacía de la cadena, o def (el texto
+// del idioma por defecto).
+func resolveText(chain []string, translated map[string]string, def string) string {
+	for _, l := range chain {
+		if t := translated[l]; t != "" {
+			return t
+		}
+	}
+	return def
+}
+
+// loadSource lee los textos de la serie, sus episodios, géneros, etiquetas y
+// traducciones. Devuelve nil si la serie no existe.
+func (s *Service) loadSource(ctx context.Context, seriesID uuid.UUID) (*seriesSource, error) {
+	src := &seriesSource{
+		episodeTitles:       make(map[uuid.UUID]string),
+		titles:              make(map[string]string),
+		descriptions:        make(map[string]string),
+		episodeTranslations: make(map[uuid.UUID]map[string]string),
+	}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT s.title, COALESCE(s.description, ''), COALESCE(p.name, '')
+		FROM series s LEFT JOIN producers p ON p.id = s.producer_id
+		WHERE s.id = $1`, seriesID).Scan(&src.title, &src.description, &src.producerName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get series: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, title FROM episodes WHERE series_id = $1 ORDER BY episode_number`, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list episodes: %w", err)
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan episode: %w", err)
+		}
+		src.episodes = append(src.episodes, id)
+		src.episodeTitles[id] = title
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list episodes: %w", err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT locale, COALESCE(title, ''), COALESCE(description, '')
+		FROM series_translations WHERE series_id = $1`, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series translations: %w", err)
+	}
+	for rows.Next() {
+		var locale, title, description string
+		if err := rows.Scan(&locale, &title, &description); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan series translation: %w", err)
+		}
+		src.titles[locale], src.descriptions[locale] = title, description
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list series translations: %w", err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT g.name, g.translations FROM series_genres sg JOIN genres g ON g.id = sg.genre_id
+		WHERE sg.series_id = $1 AND g.is_active = TRUE ORDER BY g.position, g.name`, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series genres: %w", err)
+	}
+	for rows.Next() {
+		var g genreSource
+		var translations []byte
+		if err := rows.Scan(&g.name, &translations); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan series genre: %w", err)
+		}
+		if err := json.Unmarshal(translations, &g.translations); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to decode genre translations: %w", err)
+		}
+		src.genres = append(src.genres, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list series genres: %w", err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT t.name FROM series_tags st JOIN tags t ON t.id = st.tag_id
+		WHERE st.series_id = $1 ORDER BY t.name`, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series tags: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan series tag: %w", err)
+		}
+		src.tags = append(src.tags, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list series tags: %w", err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT et.episode_id, et.locale, COALESCE(et.title, '')
+		FROM episode_translations et JOIN episodes e ON e.id = et.episode_id
+		WHERE e.series_id = $1`, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list episode translations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var locale, title string
+		if err := rows.Scan(&id, &locale, &title); err != nil {
+			return nil, fmt.Errorf("failed to scan episode translation: %w", err)
+		}
+		if src.episodeTranslations[id] == nil {
+			src.episodeTranslations[id] = make(map[string]string)
+		}
+		src.episodeTranslations[id][locale] = title
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list episode translations: %w", err)
+	}
+	return src, nil
+}
+
+// ProcessQueue reindexa hasta limit series de search_queue (encoladas por los
+// triggers). Las que fallan vuelven a la cola. Devuelve cuántas se reindexaron.
+func (s *Service) ProcessQueue(ctx context.Context, limit int) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		DELETE FROM search_queue
+		WHERE series_id IN (
+			SELECT series_id FROM search_queue ORDER BY queued_at
+			LIMIT $1 FOR UPDATE SKIP LOCKED)
+		RETURNING series_id`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to dequeue series: %w", err)
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan queued series: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to dequeue series: %w", err)
+	}
+
+	indexed := 0
+	for _, id := range ids {
+		if err := s.Reindex(ctx, id); err == nil {
+			indexed++
+		} else {
+			log.Printf("search: reindex series %s: %v", id, err)
+			if _, err := s.db.ExecContext(ctx,
+				`INSERT INTO search_queue (series_id) VALUES ($1) ON CONFLICT DO NOTHING`, id); err != nil {
+				log.Printf("search: requeue series %s: %v", id, err)
+			}
+		}
+	}
+	return indexed, nil
+}
+
+// StartWorker vacía search_queue al arrancar y luego cada `interval`, hasta que ctx
+// se cancele. Mientras los lotes salgan llenos sigue de inmediato con el siguiente.
+func (s *Service) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for {
+				n, err := s.ProcessQueue(ctx, queueBatch)
+				if err != nil {
+					log.Printf("search: %v", err)
+				}
+				if err != nil || n < queueBatch {
+					break
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
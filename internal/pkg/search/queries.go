@@ -0,0 +1,200 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// maxQueryLength largo máximo de una búsqueda registrada (search_queries.query).
+const maxQueryLength = 200
+
+// QueryLog búsqueda de la app para search_queries.
+type QueryLog struct {
+	ID          uuid.UUID
+	Query       string
+	Locale      string
+	ResultCount int
+	ProducerID  *uuid.UUID
+	UserID      *uuid.UUID
+	DeviceID    *uuid.UUID
+}
+
+// QueryStat búsqueda agregada del reporte del admin.
+type QueryStat struct {
+	Query      string    `json:"query"`
+	Searches   int       `json:"searches"`
+	Clicks     int       `json:"clicks"`
+	AvgResults float64   `json:"avg_results"`
+	LastAt     time.Time `json:"last_searched_at"`
+}
+
+// BlockedQuery búsqueda excluida de las sugerencias.
+type BlockedQuery struct {
+	Query     string     `json:"query"`
+	BlockedBy *uuid.UUID `json:"blocked_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// accentFolds letras acentuadas de español y portugués → sin acento.
+var accentFolds = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+}
+
+// Normalize lleva una búsqueda a minúsculas, sin acentos ni signos y con un solo
+// espacio entre palabras ("¡Amor  Prohibído!" → "amor prohibido"), para agrupar
+// búsquedas equivalentes y comparar prefijos de sugerencias.
+func Normalize(q string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(q) {
+		if folded, ok := accentFolds[r]; ok {
+			r = folded
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// LogQuery registra una búsqueda de la app.
+func (s *Service) LogQuery(ctx context.Context, q QueryLog) error {
+	query := truncateRunes(strings.TrimSpace(q.Query), maxQueryLength)
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO search_queries (id, query, normalized_query, locale, result_count, producer_id, user_id, device_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		q.ID, query, truncateRunes(Normalize(query), maxQueryLength), q.Locale, q.ResultCount,
+		q.ProducerID, q.UserID, q.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to log search: %w", err)
+	}
+	return nil
+}
+
+// LogClick registra la serie que se abrió desde los resultados de la búsqueda
+// searchID (solo la primera). Devuelve false si la búsqueda no existe o ya tenía clic.
+func (s *Service) LogClick(ctx context.Context, searchID, seriesID uuid.UUID, position *int) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE search_queries
+		SET clicked_series_id = $2, clicked_position = $3, clicked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND clicked_at IS NULL`, searchID, seriesID, position)
+	if err != nil {
+		return false, fmt.Errorf("failed to log search click: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// QueryStats agrupa las búsquedas de los últimos days días por texto normalizado,
+// de la más frecuente a la menos. zeroResults deja solo las que no encontraron nada.
+// producerID limita a las búsquedas hechas en la app de ese productor.
+func (s *Service) QueryStats(ctx context.Context, days, limit int, zeroResults bool, producerID *uuid.UUID) ([]QueryStat, error) {
+	query := `
+		SELECT normalized_query, COUNT(*), COUNT(clicked_at), AVG(result_count)::float8, MAX(created_at)
+		FROM search_queries
+		WHERE created_at > NOW() - make_interval(days => $1)`
+	args := []interface{}{days, limit}
+	if zeroResults {
+		query += ` AND result_count = 0`
+	}
+	if producerID != nil {
+		args = append(args, *producerID)
+		query += ` AND producer_id = $3`
+	}
+	query += `
+		GROUP BY normalized_query
+		ORDER BY COUNT(*) DESC, MAX(created_at) DESC
+		LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []QueryStat
+	for rows.Next() {
+		var st QueryStat
+		if err := rows.Scan(&st.Query, &st.Searches, &st.Clicks, &st.AvgResults, &st.LastAt); err != nil {
+			return nil, fmt.Errorf("failed to scan search stat: %w", err)
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// BlockedQueries lista las búsquedas excluidas de las sugerencias, de la más nueva
+// a la más vieja.
+func (s *Service) BlockedQueries(ctx context.Context) ([]BlockedQuery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT normalized_query, blocked_by, created_at
+		FROM blocked_search_queries
+		ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blocked queries: %w", err)
+	}
+	defer rows.Close()
+
+	var blocked []BlockedQuery
+	for rows.Next() {
+		var b BlockedQuery
+		if err := rows.Scan(&b.Query, &b.BlockedBy, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked query: %w", err)
+		}
+		blocked = append(blocked, b)
+	}
+	return blocked, rows.Err()
+}
+
+// BlockQuery excluye la búsqueda (normalizada) de las sugerencias y reconstruye el
+// índice de esta instancia, donde deja de sugerirse enseguida; las demás réplicas
+// la dejan de sugerir en su próxima reconstrucción (SEARCH_SUGGEST_REBUILD_MINUTES).
+// Devuelve la forma normalizada.
+func (s *Service) BlockQuery(ctx context.Context, query string, blockedBy *uuid.UUID) (string, error) {
+	normalized := truncateRunes(Normalize(query), maxQueryLength)
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO blocked_search_queries (normalized_query, blocked_by)
+		VALUES ($1, $2)
+		ON CONFLICT (normalized_query) DO NOTHING`, normalized, blockedBy); err != nil {
+		return "", fmt.Errorf("failed to block query: %w", err)
+	}
+	if err := s.RebuildSuggestions(ctx); err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
+
+// UnblockQuery vuelve a permitir la búsqueda en las sugerencias (desde la próxima
+// reconstrucción). Devuelve false si no estaba bloqueada.
+func (s *Service) UnblockQuery(ctx context.Context, query string) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM blocked_search_queries WHERE normalized_query = $1`, Normalize(query))
+	if err != nil {
+		return false, fmt.Errorf("failed to unblock query: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
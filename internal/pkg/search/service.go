@@ -0,0 +1,229 @@
+// Package search implementa la búsqueda de series de la app: full-text por idioma
+// (tsvector con unaccent y stemming de español, portugués o inglés) sobre título,
+// productor, géneros y etiquetas, títulos de episodios y descripción, más similitud
+// de trigramas (pg_trgm) para tolerar errores de tipeo. Los resultados se ordenan
+// por relevancia ponderada por popularidad y traen fragmentos con las coincidencias
+// resaltadas.
+//
+// El índice (series_search) lo mantiene el worker de este paquete a partir de la
+// cola search_queue que llenan los triggers de la migración 0014.
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/translations"
+)
+
+const (
+	// similarityWeight peso de la similitud de trigramas frente al rank full-text.
+	similarityWeight = 0.5
+	// popularityWeight cuánto multiplica la popularidad (vistas + unlocks × 2 de los
+	// últimos popularityDays días, en escala logarítmica) a la relevancia.
+	popularityWeight = 0.1
+	popularityDays   = 30
+	// MarkStart y MarkEnd delimitan las coincidencias en los fragmentos resaltados.
+	MarkStart = "<mark>"
+	MarkEnd   = "</mark>"
+)
+
+// textSearchConfigs configuración de texto (migración 0014) por idioma base.
+var textSearchConfigs = map[string]string{
+	"es": "qenti_es",
+	"pt": "qenti_pt",
+	"en": "qenti_en",
+}
+
+// textSearchConfig devuelve la configuración de texto del locale; los idiomas sin
+// stemming propio usan qenti_simple (solo minúsculas y sin acentos).
+func textSearchConfig(locale string) string {
+	base, _, _ := strings.Cut(locale, "-")
+	if cfg, ok := textSearchConfigs[base]; ok {
+		return cfg
+	}
+	return "qenti_simple"
+}
+
+// Query parámetros de una búsqueda.
+type Query struct {
+	Text string
+	// Locales cadena de fallback del idioma pedido; se busca en todos sus índices
+	Locales    []string
+	ProducerID *uuid.UUID
+	// Genres y Tags slugs: la serie tiene que tener alguno de cada lista pedida
+	Genres []string
+	Tags   []string
+	Limit  int
+}
+
+// Highlights fragmentos con las coincidencias entre MarkStart y MarkEnd, en el
+// idioma del índice que coincidió. Description y Episodes solo vienen si coinciden.
+// El texto va escapado como HTML (lo carga cada productor): se puede mostrar como
+// HTML sin riesgo, y las únicas etiquetas son las de MarkStart y MarkEnd.
+type Highlights struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Episodes    string `json:"episodes,omitempty"`
+}
+
+// Result serie encontrada, con su puntaje y los fragmentos resaltados.
+type Result struct {
+	models.Series
+	Score         float64    `json:"score"`
+	MatchedLocale string     `json:"matched_locale"`
+	Highlights    Highlights `json:"highlights"`
+}
+
+type Service struct {
+	db       *sql.DB
+	resolver *translations.Resolver
+	suggest  suggester
+}
+
+func NewService(db *sql.DB, cfg config.LocalizationConfig) *Service {
+	return &Service{db: db, resolver: translations.NewResolver(cfg)}
+}
+
+// prefixQuery arma una consulta to_tsquery con cada palabra como prefijo
+// ("amor prohib" → "amor:* & prohib:*") para buscar mientras se escribe. Solo deja
+// letras y dígitos, así que el resultado no necesita escaparse.
+func prefixQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// Search busca series activas por q en los índices de los locales de la consulta
+// (el pedido, sus fallbacks y el idioma por defecto). Cada serie aparece una vez,
+// con el índice que mejor coincidió.
+func (s *Service) Search(ctx context.Context, q Query) ([]Result, error) {
+	locales := q.Locales
+	if len(locales) == 0 {
+		locales = []string{s.resolver.DefaultLocale()}
+	}
+	if q.Limit <= 0 {
+		q.Limit = 30
+	}
+
+	// $1 texto, $2 tsquery con prefijos, $3 locales, $4 límite, $5.. configuraciones
+	args := []interface{}{q.Text, prefixQuery(q.Text), pq.Array(locales), q.Limit}
+	seen := make(map[string]bool)
+	var fullText []string
+	for _, l := range locales {
+		cfg := textSearchConfig(l)
+		if seen[cfg] {
+			continue
+		}
+		seen[cfg] = true
+		args = append(args, cfg)
+		n := strconv.Itoa(len(args))
+		// Con la configuración constante por cláusula el índice GIN de document aplica
+		fullText = append(fullText, `(d.config = $`+n+`::regconfig AND d.document @@ to_tsquery($`+n+`::regconfig, $2))`)
+	}
+	seriesFilter := ""
+	if q.ProducerID != nil {
+		args = append(args, *q.ProducerID)
+		seriesFilter += ` AND s.producer_id = $` + strconv.Itoa(len(args))
+	}
+	if len(q.Genres) > 0 {
+		args = append(args, pq.Array(q.Genres))
+		seriesFilter += ` AND EXISTS (SELECT 1 FROM series_genres sg JOIN genres g ON g.id = sg.genre_id
+		                             WHERE sg.series_id = s.id AND g.is_active = TRUE AND g.slug = ANY($` + strconv.Itoa(len(args)) + `))`
+	}
+	if len(q.Tags) > 0 {
+		args = append(args, pq.Array(q.Tags))
+		seriesFilter += ` AND EXISTS (SELECT 1 FROM series_tags st JOIN tags t ON t.id = st.tag_id
+		                             WHERE st.series_id = s.id AND t.slug = ANY($` + strconv.Itoa(len(args)) + `))`
+	}
+
+	query := `
+		WITH input AS (SELECT lower(unaccent($1)) AS text),
+		matches AS (
+			SELECT DISTINCT ON (d.series_id)
+			       d.series_id, d.locale, d.config, d.title, d.description, d.episode_titles,
+			       ts_rank_cd(d.document, to_tsquery(d.config, $2)) +
+			       ` + strconv.FormatFloat(similarityWeight, 'f', -1, 64) + ` * GREATEST(similarity(d.search_text, input.text),
+			                                 word_similarity(input.text, d.search_text)) AS relevance
+			FROM series_search d, input
+			WHERE d.locale = ANY($3)
+			  AND (` + strings.Join(fullText, " OR ") + `
+			       OR d.search_text % input.text OR input.text <% d.search_text)
+			ORDER BY d.series_id, relevance DESC
+		),
+		ranked AS (
+			SELECT m.*, s.id, s.title AS series_title, s.description AS series_description,
+			       s.horizontal_poster, s.vertical_poster, s.horizontal_poster_image_id, s.vertical_poster_image_id,
+			       s.is_active, s.created_at, s.updated_at,
+			       m.relevance * (1 + ` + strconv.FormatFloat(popularityWeight, 'f', -1, 64) + ` * ln(1 + COALESCE(pop.score, 0))) AS score
+			FROM matches m
+			JOIN series s ON s.id = m.series_id AND s.is_active = TRUE` + seriesFilter + `
+			LEFT JOIN (
+				SELECT series_id, SUM(views + (unlocks_coin + unlocks_ad + unlocks_sub) * 2) AS score
+				FROM series_daily_rollups
+				WHERE day > CURRENT_DATE - ` + strconv.Itoa(popularityDays) + `
+				GROUP BY series_id
+			) pop ON pop.series_id = m.series_id
+			ORDER BY score DESC, s.title
+			LIMIT $4
+		)
+		SELECT r.id, r.series_title, r.series_description, r.horizontal_poster, r.vertical_poster,
+		       r.horizontal_poster_image_id, r.vertical_poster_image_id, r.is_active, r.created_at, r.updated_at,
+		       r.score, r.locale,
+		       ts_headline(r.config, ` + escapeHTML("r.title") + `, to_tsquery(r.config, $2),
+		                   'StartSel=` + MarkStart + `, StopSel=` + MarkEnd + `, HighlightAll=true'),
+		       ts_headline(r.config, ` + escapeHTML("r.description") + `, to_tsquery(r.config, $2),
+		                   'StartSel=` + MarkStart + `, StopSel=` + MarkEnd + `, MinWords=8, MaxWords=25, MaxFragments=2, FragmentDelimiter=" … "'),
+		       ts_headline(r.config, ` + escapeHTML("r.episode_titles") + `, to_tsquery(r.config, $2),
+		                   'StartSel=` + MarkStart + `, StopSel=` + MarkEnd + `, MinWords=3, MaxWords=15, MaxFragments=3, FragmentDelimiter="` + episodeSeparator + `"')
+		FROM ranked r
+		ORDER BY r.score DESC, r.series_title`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search series: %w", err)
+	}
+	defer rows.Close()
+
+	var results []Result
+	for rows.Next() {
+		var r Result
+		var description, episodes string
+		if err := rows.Scan(
+			&r.ID, &r.Title, &r.Description, &r.HorizontalPoster, &r.VerticalPoster,
+			&r.HorizontalPosterImageID, &r.VerticalPosterImageID, &r.IsActive, &r.CreatedAt, &r.UpdatedAt,
+			&r.Score, &r.MatchedLocale, &r.Highlights.Title, &description, &episodes,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		// Sin coincidencia ts_headline devuelve el comienzo del texto: no es un fragmento útil
+		if strings.Contains(description, MarkStart) {
+			r.Highlights.Description = description
+		}
+		if strings.Contains(episodes, MarkStart) {
+			r.Highlights.Episodes = episodes
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// escapeHTML expresión SQL que escapa &, <, > y " en column, para que ts_headline
+// no devuelva el HTML que haya en los textos (el parser de Postgres deja las
+// entidades como están y no afecta las palabras).
+func escapeHTML(column string) string {
+	return `replace(replace(replace(replace(` + column +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')`
+}
@@ -0,0 +1,355 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SuggestionSeries título de una serie (trae SeriesID).
+	SuggestionSeries = "series"
+	// SuggestionQuery búsqueda frecuente que encontró resultados.
+	SuggestionQuery = "query"
+	// SuggestionGenre y SuggestionTag género o etiqueta con series (traen Slug, para
+	// filtrar con ?genre= o ?tag=).
+	SuggestionGenre = "genre"
+	SuggestionTag   = "tag"
+
+	// suggestQueryDays días de búsquedas que se consideran populares.
+	suggestQueryDays = 30
+	// suggestMinSearches personas distintas (usuarios o devices) que tienen que haber
+	// buscado algo para sugerirlo: una sola no puede meter texto en el autocompletado.
+	suggestMinSearches = 3
+	// suggestMaxQueries búsquedas populares que entran al índice.
+	suggestMaxQueries = 2000
+)
+
+// Suggestion sugerencia de autocompletado.
+type Suggestion struct {
+	Text     string     `json:"text"`
+	Kind     string     `json:"kind"` // SuggestionSeries | SuggestionQuery | SuggestionGenre | SuggestionTag
+	SeriesID *uuid.UUID `json:"series_id,omitempty"`
+	Slug     string     `json:"slug,omitempty"`
+}
+
+// suggestEntry una clave del índice: el texto normalizado desde el comienzo de una
+// de sus palabras, para que "prohib" sugiera "El amor prohibido".
+type suggestEntry struct {
+	key        string
+	suggestion Suggestion
+	locale     string // "" = todos los idiomas
+	producerID *uuid.UUID
+	weight     float64
+	// atStart la clave es el texto completo (el prefijo coincide con la primera palabra)
+	atStart bool
+}
+
+// score peso de la entrada para ordenar: coincidir con el comienzo suma.
+func (e *suggestEntry) score() float64 {
+	if e.atStart {
+		return e.weight + 0.5
+	}
+	return e.weight
+}
+
+// suggestIndex índice inmutable ordenado por clave; se reemplaza entero al reconstruirlo.
+type suggestIndex struct {
+	entries []suggestEntry
+}
+
+// suggester guarda el índice vigente de sugerencias.
+type suggester struct {
+	mu    sync.RWMutex
+	index *suggestIndex
+}
+
+func (sg *suggester) get() *suggestIndex {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.index
+}
+
+func (sg *suggester) set(index *suggestIndex) {
+	sg.mu.Lock()
+	sg.index = index
+	sg.mu.Unlock()
+}
+
+// addEntries agrega una clave por cada palabra de text.
+func addEntries(entries []suggestEntry, text string, e suggestEntry) []suggestEntry {
+	normalized := Normalize(text)
+	for i := 0; i < len(normalized); {
+		e.key, e.atStart = normalized[i:], i == 0
+		entries = append(entries, e)
+		next := strings.IndexByte(normalized[i:], ' ')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return entries
+}
+
+// RebuildSuggestions reconstruye en memoria el índice de sugerencias: títulos de las
+// series activas en cada idioma indexado (ponderados por popularidad), géneros y
+// etiquetas con series activas (ponderados por cuántas) y las búsquedas frecuentes
+// con resultados de los últimos 30 días.
+func (s *Service) RebuildSuggestions(ctx context.Context) error {
+	var entries []suggestEntry
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT d.series_id, d.locale, d.title, s.producer_id, COALESCE(pop.score, 0)
+		FROM series_search d
+		JOIN series s ON s.id = d.series_id AND s.is_active = TRUE
+		LEFT JOIN (
+			SELECT series_id, SUM(views + (unlocks_coin + unlocks_ad + unlocks_sub) * 2) AS score
+			FROM series_daily_rollups
+			WHERE day > CURRENT_DATE - $1::int
+			GROUP BY series_id
+		) pop ON pop.series_id = d.series_id`, popularityDays)
+	if err != nil {
+		return fmt.Errorf("failed to load series suggestions: %w", err)
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var locale, title string
+		var producerID *uuid.UUID
+		var popularity float64
+		if err := rows.Scan(&id, &locale, &title, &producerID, &popularity); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan series suggestion: %w", err)
+		}
+		seriesID := id
+		entries = addEntries(entries, title, suggestEntry{
+			suggestion: Suggestion{Text: title, Kind: SuggestionSeries, SeriesID: &seriesID},
+			locale:     locale,
+			producerID: producerID,
+			// Las series pesan más que las búsquedas con la misma popularidad
+			weight: 2 + math.Log1p(popularity),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load series suggestions: %w", err)
+	}
+
+	// Géneros en el idioma por defecto y en cada traducción, por productor
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT g.slug, g.name, g.translations, s.producer_id, COUNT(*)
+		FROM genres g
+		JOIN series_genres sg ON sg.genre_id = g.id
+		JOIN series s ON s.id = sg.series_id AND s.is_active = TRUE
+		WHERE g.is_active = TRUE
+		GROUP BY g.id, s.producer_id`)
+	if err != nil {
+		return fmt.Errorf("failed to load genre suggestions: %w", err)
+	}
+	for rows.Next() {
+		var slug, name string
+		var rawTranslations []byte
+		var producerID *uuid.UUID
+		var count int
+		if err := rows.Scan(&slug, &name, &rawTranslations, &producerID, &count); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan genre suggestion: %w", err)
+		}
+		names := map[string]string{}
+		if err := json.Unmarshal(rawTranslations, &names); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decode genre translations: %w", err)
+		}
+		names[s.resolver.DefaultLocale()] = name
+		for locale, text := range names {
+			entries = addEntries(entries, text, suggestEntry{
+				suggestion: Suggestion{Text: text, Kind: SuggestionGenre, Slug: slug},
+				locale:     locale,
+				producerID: producerID,
+				weight:     1 + math.Log1p(float64(count)),
+			})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load genre suggestions: %w", err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT t.slug, t.name, s.producer_id, COUNT(*)
+		FROM tags t
+		JOIN series_tags st ON st.tag_id = t.id
+		JOIN series s ON s.id = st.series_id AND s.is_active = TRUE
+		GROUP BY t.id, s.producer_id`)
+	if err != nil {
+		return fmt.Errorf("failed to load tag suggestions: %w", err)
+	}
+	for rows.Next() {
+		var slug, name string
+		var producerID *uuid.UUID
+		var count int
+		if err := rows.Scan(&slug, &name, &producerID, &count); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan tag suggestion: %w", err)
+		}
+		entries = addEntries(entries, name, suggestEntry{
+			suggestion: Suggestion{Text: name, Kind: SuggestionTag, Slug: slug},
+			producerID: producerID,
+			weight:     math.Log1p(float64(count)),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load tag suggestions: %w", err)
+	}
+
+	// Se sugiere la forma más buscada de cada búsqueda normalizada, salvo las bloqueadas.
+	// Las búsquedas anónimas sin device no cuentan como persona.
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT q.normalized_query, q.producer_id, mode() WITHIN GROUP (ORDER BY q.query), COUNT(*)
+		FROM search_queries q
+		WHERE q.created_at > NOW() - make_interval(days => $1) AND q.result_count > 0
+		  AND NOT EXISTS (SELECT 1 FROM blocked_search_queries b WHERE b.normalized_query = q.normalized_query)
+		GROUP BY q.normalized_query, q.producer_id
+		HAVING COUNT(DISTINCT COALESCE(q.user_id::text, q.device_id::text)) >= $2
+		ORDER BY COUNT(*) DESC
+		LIMIT $3`, suggestQueryDays, suggestMinSearches, suggestMaxQueries)
+	if err != nil {
+		return fmt.Errorf("failed to load query suggestions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var normalized, query string
+		var producerID *uuid.UUID
+		var count int
+		if err := rows.Scan(&normalized, &producerID, &query, &count); err != nil {
+			return fmt.Errorf("failed to scan query suggestion: %w", err)
+		}
+		entries = addEntries(entries, query, suggestEntry{
+			suggestion: Suggestion{Text: strings.TrimSpace(query), Kind: SuggestionQuery},
+			producerID: producerID,
+			weight:     math.Log1p(float64(count)),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load query suggestions: %w", err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	s.suggest.set(&suggestIndex{entries: entries})
+	return nil
+}
+
+// Suggest devuelve hasta limit sugerencias cuyo texto tenga una palabra que empiece
+// con prefix: series y géneros en los locales pedidos, etiquetas y búsquedas
+// frecuentes, de la más popular a la menos. Con producerID, solo las de la app de
+// ese productor. Lee solo el índice en memoria; antes de la primera reconstrucción
+// no devuelve nada.
+func (s *Service) Suggest(prefix string, locales []string, producerID *uuid.UUID, limit int) []Suggestion {
+	index := s.suggest.get()
+	key := Normalize(prefix)
+	if index == nil || key == "" {
+		return nil
+	}
+	if len(locales) == 0 {
+		locales = []string{s.resolver.DefaultLocale()}
+	}
+	// Rango de claves con el prefijo (el índice está ordenado)
+	start := sort.Search(len(index.entries), func(i int) bool { return index.entries[i].key >= key })
+	var matches []suggestEntry
+	for i := start; i < len(index.entries) && strings.HasPrefix(index.entries[i].key, key); i++ {
+		e := index.entries[i]
+		if e.locale != "" && !containsLocale(locales, e.locale) {
+			continue
+		}
+		if producerID != nil && (e.producerID == nil || *e.producerID != *producerID) {
+			continue
+		}
+		matches = append(matches, e)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if si, sj := matches[i].score(), matches[j].score(); si != sj {
+			return si > sj
+		}
+		// Con igual peso, primero el idioma pedido
+		return localeRank(locales, matches[i].locale) < localeRank(locales, matches[j].locale)
+	})
+
+	// Una sola sugerencia por serie o término (la del idioma preferido) y por texto
+	result := make([]Suggestion, 0, limit)
+	seenSeries := make(map[uuid.UUID]bool)
+	seenTerms := make(map[string]bool)
+	seenText := make(map[string]bool)
+	for _, e := range matches {
+		if len(result) >= limit {
+			break
+		}
+		if e.suggestion.SeriesID != nil {
+			if seenSeries[*e.suggestion.SeriesID] {
+				continue
+			}
+			seenSeries[*e.suggestion.SeriesID] = true
+		}
+		if e.suggestion.Slug != "" {
+			term := e.suggestion.Kind + ":" + e.suggestion.Slug
+			if seenTerms[term] {
+				continue
+			}
+			seenTerms[term] = true
+		}
+		text := Normalize(e.suggestion.Text)
+		if seenText[text] {
+			continue
+		}
+		seenText[text] = true
+		result = append(result, e.suggestion)
+	}
+	return result
+}
+
+func containsLocale(locales []string, locale string) bool {
+	return localeRank(locales, locale) < len(locales)
+}
+
+// localeRank posición del locale en la cadena (len si no está; "" va primero).
+func localeRank(locales []string, locale string) int {
+	if locale == "" {
+		return 0
+	}
+	for i, l := range locales {
+		if l == locale {
+			return i
+		}
+	}
+	return len(locales)
+}
+
+// StartSuggestWorker reconstruye el índice de sugerencias al arrancar y luego cada
+// `interval`, hasta que ctx se cancele. Los errores solo se loguean y se sigue
+// usando el índice anterior.
+func (s *Service) StartSuggestWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		if err := s.RebuildSuggestions(ctx); err != nil {
+			log.Printf("search: rebuild suggestions: %v", err)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.RebuildSuggestions(ctx); err != nil {
+					log.Printf("search: rebuild suggestions: %v", err)
+				}
+			}
+		}
+	}()
+}
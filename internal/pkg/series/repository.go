@@ -4,22 +4,38 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/qenti/qenti/internal/pkg/cache"
 	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/pagination"
 )
 
+// CacheTag tag de las entradas del cache que dependen de los datos de las series
+// (los rankings de trending); Create, Update y Delete las invalidan.
+const CacheTag = "series"
+
 type Repository struct {
-	db *sql.DB
+	db    *sql.DB
+	cache *cache.Cache
+}
+
+func NewRepository(db *sql.DB, cacheStore *cache.Cache) *Repository {
+	return &Repository{db: db, cache: cacheStore}
 }
 
-func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db: db}
+// invalidate descarta del cache lo que depende de las series.
+func (r *Repository) invalidate(ctx context.Context) {
+	if err := r.cache.Invalidate(ctx, CacheTag); err != nil {
+		log.Printf("series: invalidate cache: %v", err)
+	}
 }
 
 // GetAll retorna todas las series activas
 func (r *Repository) GetAll(ctx context.Context) ([]models.Series, error) {
-	query := `SELECT id, title, description, horizontal_poster, vertical_poster, 
+	query := `SELECT id, title, description, horizontal_poster, vertical_poster, horizontal_poster_image_id, vertical_poster_image_id, 
 	          is_active, created_at, updated_at 
 	          FROM series WHERE is_active = TRUE ORDER BY created_at DESC`
 	
@@ -34,7 +50,7 @@ func (r *Repository) GetAll(ctx context.Context) ([]models.Series, error) {
 		var s models.Series
 		err := rows.Scan(
 			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+			&s.VerticalPoster, &s.HorizontalPosterImageID, &s.VerticalPosterImageID, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan series: %w", err)
@@ -48,13 +64,13 @@ func (r *Repository) GetAll(ctx context.Context) ([]models.Series, error) {
 // GetByID retorna una serie por ID
 func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Series, error) {
 	var s models.Series
-	query := `SELECT id, title, description, horizontal_poster, vertical_poster, 
+	query := `SELECT id, title, description, horizontal_poster, vertical_poster, horizontal_poster_image_id, vertical_poster_image_id, 
 	          is_active, created_at, updated_at 
 	          FROM series WHERE id = $1`
 	
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-		&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+		&s.VerticalPoster, &s.HorizontalPosterImageID, &s.VerticalPosterImageID, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
 	)
 	
 	if err == sql.ErrNoRows {
@@ -70,17 +86,20 @@ func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Series,
 // Create crea una nueva serie
 func (r *Repository) Create(ctx context.Context, series *models.Series) error {
 	series.ID = uuid.New()
-	query := `INSERT INTO series (id, title, description, horizontal_poster, vertical_poster, is_active, producer_id) 
-	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at, updated_at`
+	query := `INSERT INTO series (id, title, description, horizontal_poster, vertical_poster, is_active, producer_id,
+	                              horizontal_poster_image_id, vertical_poster_image_id) 
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING created_at, updated_at`
 
 	err := r.db.QueryRowContext(ctx, query,
 		series.ID, series.Title, series.Description,
 		series.HorizontalPoster, series.VerticalPoster, series.IsActive, series.ProducerID,
+		series.HorizontalPosterImageID, series.VerticalPosterImageID,
 	).Scan(&series.CreatedAt, &series.UpdatedAt)
 	
 	if err != nil {
 		return fmt.Errorf("failed to create series: %w", err)
 	}
+	r.invalidate(ctx)
 	
 	return nil
 }
@@ -89,12 +108,14 @@ func (r *Repository) Create(ctx context.Context, series *models.Series) error {
 func (r *Repository) Update(ctx context.Context, series *models.Series) error {
 	query := `UPDATE series 
 	          SET title = $1, description = $2, horizontal_poster = $3, 
-	              vertical_poster = $4, is_active = $5, updated_at = CURRENT_TIMESTAMP 
-	          WHERE id = $6 RETURNING updated_at`
+	              vertical_poster = $4, is_active = $5, horizontal_poster_image_id = $6,
+	              vertical_poster_image_id = $7, updated_at = CURRENT_TIMESTAMP 
+	          WHERE id = $8 RETURNING updated_at`
 	
 	err := r.db.QueryRowContext(ctx, query,
 		series.Title, series.Description, series.HorizontalPoster,
-		series.VerticalPoster, series.IsActive, series.ID,
+		series.VerticalPoster, series.IsActive, series.HorizontalPosterImageID,
+		series.VerticalPosterImageID, series.ID,
 	).Scan(&series.UpdatedAt)
 	
 	if err == sql.ErrNoRows {
@@ -103,6 +124,7 @@ func (r *Repository) Update(ctx context.Context, series *models.Series) error {
 	if err != nil {
 		return fmt.Errorf("failed to update series: %w", err)
 	}
+	r.invalidate(ctx)
 	
 	return nil
 }
@@ -123,74 +145,28 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
 	if rowsAffected == 0 {
 		return fmt.Errorf("series not found")
 	}
+	r.invalidate(ctx)
 	
 	return nil
 }
 
-// GetTrending devuelve series ordenadas por score = views*1 + unlocks*2 en los últimos `days` días.
-// Garantiza al menos `limit` resultados aunque no haya actividad reciente (rellena con series recientes).
-func (r *Repository) GetTrending(ctx context.Context, limit, days int) ([]models.Series, error) {
-	query := `
-		SELECT s.id, s.title, s.description, s.horizontal_poster, s.vertical_poster,
-		       s.is_active, s.created_at, s.updated_at
-		FROM series s
-		LEFT JOIN (
-			SELECT e.series_id, COUNT(v.id) AS view_count
-			FROM views v
-			JOIN episodes e ON e.id = v.episode_id
-			WHERE v.created_at > NOW() - ($2 || ' days')::INTERVAL
-			GROUP BY e.series_id
-		) vc ON vc.series_id = s.id
-		LEFT JOIN (
-			SELECT e.series_id, COUNT(u.id) AS unlock_count
-			FROM unlocks u
-			JOIN episodes e ON e.id = u.episode_id
-			WHERE u.unlocked_at > NOW() - ($2 || ' days')::INTERVAL
-			GROUP BY e.series_id
-		) uc ON uc.series_id = s.id
-		WHERE s.is_active = TRUE
-		ORDER BY (COALESCE(vc.view_count, 0) + COALESCE(uc.unlock_count, 0) * 2) DESC,
-		         s.created_at DESC
-		LIMIT $1
-	`
-	rows, err := r.db.QueryContext(ctx, query, limit, days)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query trending: %w", err)
-	}
-	defer rows.Close()
-
-	var result []models.Series
-	for rows.Next() {
-		var s models.Series
-		if err := rows.Scan(
-			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan trending series: %w", err)
-		}
-		result = append(result, s)
-	}
-	return result, nil
-}
-
-// GetAllAdmin retorna las series para el panel de admin.
+// GetAllAdmin retorna una página de las series para el panel de admin (trae
+// page.FetchLimit filas, ver pagination.Slice), de la más nueva a la más vieja.
 // Si producerID != nil filtra por productor; si es nil devuelve todas (super_admin).
-func (r *Repository) GetAllAdmin(ctx context.Context, producerID *uuid.UUID) ([]models.Series, error) {
-	var (
-		rows *sql.Rows
-		err  error
-	)
-	if producerID == nil {
-		query := `SELECT id, title, description, horizontal_poster, vertical_poster,
-		          is_active, producer_id, created_at, updated_at
-		          FROM series ORDER BY created_at DESC`
-		rows, err = r.db.QueryContext(ctx, query)
-	} else {
-		query := `SELECT id, title, description, horizontal_poster, vertical_poster,
-		          is_active, producer_id, created_at, updated_at
-		          FROM series WHERE producer_id = $1 ORDER BY created_at DESC`
-		rows, err = r.db.QueryContext(ctx, query, *producerID)
-	}
+func (r *Repository) GetAllAdmin(ctx context.Context, producerID *uuid.UUID, page pagination.Page) ([]models.Series, error) {
+	query := `SELECT id, title, description, horizontal_poster, vertical_poster, horizontal_poster_image_id, vertical_poster_image_id,
+	                 is_active, producer_id, created_at, updated_at
+	          FROM series WHERE TRUE`
+	var args []interface{}
+	if producerID != nil {
+		args = append(args, *producerID)
+		query += ` AND producer_id = $1`
+	}
+	query += page.Where(&args, "created_at", "id") + pagination.OrderBy("created_at", "id")
+	args = append(args, page.FetchLimit())
+	query += fmt.Sprintf(` LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query series for admin: %w", err)
 	}
@@ -201,7 +177,7 @@ func (r *Repository) GetAllAdmin(ctx context.Context, producerID *uuid.UUID) ([]
 		var s models.Series
 		if err := rows.Scan(
 			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.ProducerID, &s.CreatedAt, &s.UpdatedAt,
+			&s.VerticalPoster, &s.HorizontalPosterImageID, &s.VerticalPosterImageID, &s.IsActive, &s.ProducerID, &s.CreatedAt, &s.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan series: %w", err)
 		}
@@ -231,208 +207,53 @@ func (r *Repository) BelongsToProducer(ctx context.Context, seriesID uuid.UUID,
 	return count > 0, nil
 }
 
-// Search busca series por título o descripción (case-insensitive).
-func (r *Repository) Search(ctx context.Context, q string, limit int) ([]models.Series, error) {
-	query := `
-		SELECT id, title, description, horizontal_poster, vertical_poster,
-		       is_active, created_at, updated_at
-		FROM series
-		WHERE is_active = TRUE
-		  AND (title ILIKE '%' || $1 || '%' OR description ILIKE '%' || $1 || '%')
-		ORDER BY
-		  CASE WHEN title ILIKE $1 || '%' THEN 0
-		       WHEN title ILIKE '%' || $1 || '%' THEN 1
-		       ELSE 2 END,
-		  title ASC
-		LIMIT $2
-	`
-	rows, err := r.db.QueryContext(ctx, query, q, limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search series: %w", err)
-	}
-	defer rows.Close()
-
-	var result []models.Series
-	for rows.Next() {
-		var s models.Series
-		if err := rows.Scan(
-			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan search result: %w", err)
-		}
-		result = append(result, s)
-	}
-	return result, nil
-}
-
 // ── Variantes filtradas por productor (tenant isolation) ──────────────────────
 
 // GetAllFiltered retorna series activas, opcionalmente filtradas por productor.
 // Si producerID es nil devuelve todas (comportamiento legacy / super_admin).
 func (r *Repository) GetAllFiltered(ctx context.Context, producerID *uuid.UUID) ([]models.Series, error) {
-	var (
-		rows *sql.Rows
-		err  error
-	)
-	if producerID == nil {
-		rows, err = r.db.QueryContext(ctx,
-			`SELECT id, title, description, horizontal_poster, vertical_poster,
-			        is_active, created_at, updated_at
-			 FROM series WHERE is_active = TRUE ORDER BY created_at DESC`)
-	} else {
-		rows, err = r.db.QueryContext(ctx,
-			`SELECT id, title, description, horizontal_poster, vertical_poster,
-			        is_active, created_at, updated_at
-			 FROM series WHERE is_active = TRUE AND producer_id = $1 ORDER BY created_at DESC`,
-			*producerID)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to query series: %w", err)
-	}
-	defer rows.Close()
-
-	var result []models.Series
-	for rows.Next() {
-		var s models.Series
-		if err := rows.Scan(
-			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan series: %w", err)
-		}
-		result = append(result, s)
-	}
-	return result, nil
+	return r.List(ctx, ListFilter{ProducerID: producerID})
 }
 
-// GetTrendingFiltered retorna series trending, opcionalmente filtradas por productor.
-func (r *Repository) GetTrendingFiltered(ctx context.Context, limit, days int, producerID *uuid.UUID) ([]models.Series, error) {
-	var (
-		rows *sql.Rows
-		err  error
-	)
-	base := `
-		SELECT s.id, s.title, s.description, s.horizontal_poster, s.vertical_poster,
-		       s.is_active, s.created_at, s.updated_at
-		FROM series s
-		LEFT JOIN (
-			SELECT e.series_id, COUNT(v.id) AS view_count
-			FROM views v
-			JOIN episodes e ON e.id = v.episode_id
-			WHERE v.created_at > NOW() - ($2 || ' days')::INTERVAL
-			GROUP BY e.series_id
-		) vc ON vc.series_id = s.id
-		LEFT JOIN (
-			SELECT e.series_id, COUNT(u.id) AS unlock_count
-			FROM unlocks u
-			JOIN episodes e ON e.id = u.episode_id
-			WHERE u.unlocked_at > NOW() - ($2 || ' days')::INTERVAL
-			GROUP BY e.series_id
-		) uc ON uc.series_id = s.id
-		WHERE s.is_active = TRUE`
-
-	if producerID == nil {
-		rows, err = r.db.QueryContext(ctx, base+`
-		ORDER BY (COALESCE(vc.view_count, 0) + COALESCE(uc.unlock_count, 0) * 2) DESC,
-		         s.created_at DESC
-		LIMIT $1`, limit, days)
-	} else {
-		rows, err = r.db.QueryContext(ctx, base+`
-		  AND s.producer_id = $3
-		ORDER BY (COALESCE(vc.view_count, 0) + COALESCE(uc.unlock_count, 0) * 2) DESC,
-		         s.created_at DESC
-		LIMIT $1`, limit, days, *producerID)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to query trending: %w", err)
-	}
-	defer rows.Close()
-
-	var result []models.Series
-	for rows.Next() {
-		var s models.Series
-		if err := rows.Scan(
-			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan trending series: %w", err)
-		}
-		result = append(result, s)
-	}
-	return result, nil
+// ListFilter filtros del catálogo de la app. Genres y Tags son slugs: la serie
+// tiene que tener alguno de los géneros y alguna de las etiquetas pedidas.
+// Page.Limit 0 trae todas; si no, Page.FetchLimit (ver pagination.Slice).
+type ListFilter struct {
+	ProducerID *uuid.UUID
+	Genres     []string
+	Tags       []string
+	Page       pagination.Page
 }
 
-// SearchFiltered busca series por texto, opcionalmente filtrado por productor.
-func (r *Repository) SearchFiltered(ctx context.Context, q string, limit int, producerID *uuid.UUID) ([]models.Series, error) {
-	var (
-		rows *sql.Rows
-		err  error
-	)
-	if producerID == nil {
-		rows, err = r.db.QueryContext(ctx, `
-			SELECT id, title, description, horizontal_poster, vertical_poster,
-			       is_active, created_at, updated_at
-			FROM series
-			WHERE is_active = TRUE
-			  AND (title ILIKE '%' || $1 || '%' OR description ILIKE '%' || $1 || '%')
-			ORDER BY
-			  CASE WHEN title ILIKE $1 || '%' THEN 0
-			       WHEN title ILIKE '%' || $1 || '%' THEN 1
-			       ELSE 2 END, title ASC
-			LIMIT $2`, q, limit)
-	} else {
-		rows, err = r.db.QueryContext(ctx, `
-			SELECT id, title, description, horizontal_poster, vertical_poster,
-			       is_active, created_at, updated_at
-			FROM series
-			WHERE is_active = TRUE
-			  AND producer_id = $3
-			  AND (title ILIKE '%' || $1 || '%' OR description ILIKE '%' || $1 || '%')
-			ORDER BY
-			  CASE WHEN title ILIKE $1 || '%' THEN 0
-			       WHEN title ILIKE '%' || $1 || '%' THEN 1
-			       ELSE 2 END, title ASC
-			LIMIT $2`, q, limit, *producerID)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to search series: %w", err)
-	}
-	defer rows.Close()
-
-	var result []models.Series
-	for rows.Next() {
-		var s models.Series
-		if err := rows.Scan(
-			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan search result: %w", err)
-		}
-		result = append(result, s)
+// List retorna las series activas que cumplen el filtro, de la más nueva a la más vieja.
+func (r *Repository) List(ctx context.Context, f ListFilter) ([]models.Series, error) {
+	query := `SELECT id, title, description, horizontal_poster, vertical_poster, horizontal_poster_image_id, vertical_poster_image_id,
+	                 is_active, created_at, updated_at
+	          FROM series s WHERE is_active = TRUE`
+	var args []interface{}
+	if f.ProducerID != nil {
+		args = append(args, *f.ProducerID)
+		query += fmt.Sprintf(` AND producer_id = $%d`, len(args))
+	}
+	if len(f.Genres) > 0 {
+		args = append(args, pq.Array(f.Genres))
+		query += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM series_genres sg JOIN genres g ON g.id = sg.genre_id
+		                                   WHERE sg.series_id = s.id AND g.is_active = TRUE AND g.slug = ANY($%d))`, len(args))
+	}
+	if len(f.Tags) > 0 {
+		args = append(args, pq.Array(f.Tags))
+		query += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM series_tags st JOIN tags t ON t.id = st.tag_id
+		                                   WHERE st.series_id = s.id AND t.slug = ANY($%d))`, len(args))
+	}
+	query += f.Page.Where(&args, "created_at", "id") + pagination.OrderBy("created_at", "id")
+	if f.Page.Limit > 0 {
+		args = append(args, f.Page.FetchLimit())
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
 	}
-	return result, nil
-}
 
-// GetMostViewed retorna series ordenadas por total de vistas histórico (all-time).
-func (r *Repository) GetMostViewed(ctx context.Context, limit int) ([]models.Series, error) {
-	query := `
-		SELECT s.id, s.title, s.description, s.horizontal_poster, s.vertical_poster,
-		       s.is_active, s.created_at, s.updated_at
-		FROM series s
-		LEFT JOIN (
-			SELECT e.series_id, COUNT(v.id) AS total_views
-			FROM views v
-			JOIN episodes e ON e.id = v.episode_id
-			GROUP BY e.series_id
-		) vc ON vc.series_id = s.id
-		WHERE s.is_active = TRUE
-		ORDER BY COALESCE(vc.total_views, 0) DESC, s.created_at DESC
-		LIMIT $1
-	`
-	rows, err := r.db.QueryContext(ctx, query, limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query most viewed: %w", err)
+		return nil, fmt.Errorf("failed to query series: %w", err)
 	}
 	defer rows.Close()
 
@@ -441,9 +262,9 @@ func (r *Repository) GetMostViewed(ctx context.Context, limit int) ([]models.Ser
 		var s models.Series
 		if err := rows.Scan(
 			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+			&s.VerticalPoster, &s.HorizontalPosterImageID, &s.VerticalPosterImageID, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
 		); err != nil {
-			return nil, fmt.Errorf("failed to scan most viewed: %w", err)
+			return nil, fmt.Errorf("failed to scan series: %w", err)
 		}
 		result = append(result, s)
 	}
@@ -454,7 +275,7 @@ func (r *Repository) GetMostViewed(ctx context.Context, limit int) ([]models.Ser
 // ordenadas por fecha de creación descendente.
 func (r *Repository) GetNewReleases(ctx context.Context, limit, days int) ([]models.Series, error) {
 	query := `
-		SELECT id, title, description, horizontal_poster, vertical_poster,
+		SELECT id, title, description, horizontal_poster, vertical_poster, horizontal_poster_image_id, vertical_poster_image_id,
 		       is_active, created_at, updated_at
 		FROM series
 		WHERE is_active = TRUE
@@ -473,7 +294,7 @@ func (r *Repository) GetNewReleases(ctx context.Context, limit, days int) ([]mod
 		var s models.Series
 		if err := rows.Scan(
 			&s.ID, &s.Title, &s.Description, &s.HorizontalPoster,
-			&s.VerticalPoster, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+			&s.VerticalPoster, &s.HorizontalPosterImageID, &s.VerticalPosterImageID, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan new releases: %w", err)
 		}
@@ -14,7 +14,7 @@ import (
 	"github.com/qenti/qenti/internal/config"
 )
 
-// BunnyProvider implementa VideoProvider usando Bunny.net Stream.
+// BunnyProvider implementa VideoProvider (y TrackProvider, solo subtítulos) usando Bunny.net Stream.
 type BunnyProvider struct {
 	cfg    config.BunnyConfig
 	client *http.Client
@@ -62,7 +62,7 @@ func (p *BunnyProvider) CreateVideo(title string) (*UploadResult, error) {
 	}
 
 	uploadURL := fmt.Sprintf("https://video.bunnycdn.com/library/%s/videos/%s", p.cfg.StreamLibraryID, result.GUID)
-	return &UploadResult{ExternalID: result.GUID, UploadURL: uploadURL}, nil
+	return &UploadResult{ExternalID: result.GUID, UploadURL: uploadURL, UploadMethod: UploadMethodPUT}, nil
 }
 
 // UploadVideo sube los bytes al endpoint de Bunny por PUT.
@@ -103,23 +103,87 @@ func (p *BunnyProvider) GetPlaybackURL(externalID string, expirationMinutes int)
 		return "", fmt.Errorf("bunny: BUNNY_CDN_HOSTNAME not configured")
 	}
 
-	baseURL := fmt.Sprintf("https://%s/%s/playlist.m3u8", p.cfg.CDNHostname, externalID)
+	return p.cdnURL(fmt.Sprintf("/%s/playlist.m3u8", externalID), expirationMinutes), nil
+}
+
+// cdnURL arma la URL del CDN para path, con token si la biblioteca lo exige.
+func (p *BunnyProvider) cdnURL(path string, expirationMinutes int) string {
+	baseURL := "https://" + p.cfg.CDNHostname + path
 
 	if p.cfg.SecurityKey == "" {
 		// tokenAuthEnabled=false en Bunny → devolver URL directa sin token
-		return baseURL, nil
+		return baseURL
 	}
 
 	// tokenAuthEnabled=true → generar token SHA256 (Bunny Advanced Token Auth)
 	// Formula: token = Base64Url_NoPadding(SHA256(securityKey + path + expiry))
 	expiry := time.Now().Add(time.Duration(expirationMinutes) * time.Minute).Unix()
 	expiryStr := strconv.FormatInt(expiry, 10)
-	path := fmt.Sprintf("/%s/playlist.m3u8", externalID)
 
 	h := sha256.Sum256([]byte(p.cfg.SecurityKey + path + expiryStr))
 	token := base64.RawURLEncoding.EncodeToString(h[:])
 
-	return fmt.Sprintf("%s?token=%s&expires=%s", baseURL, token, expiryStr), nil
+	return fmt.Sprintf("%s?token=%s&expires=%s", baseURL, token, expiryStr)
+}
+
+// SupportsTrack: Bunny Stream acepta subtítulos (captions) pero no audio
+// alternativo por API (solo el que venga dentro del archivo original).
+func (p *BunnyProvider) SupportsTrack(kind string) bool { return kind == TrackKindSubtitles }
+
+// UploadTrack sube los subtítulos como caption del video; Bunny los agrega también
+// al playlist HLS y los sirve en /<id>/captions/<idioma>.vtt.
+func (p *BunnyProvider) UploadTrack(externalID string, track TrackFile, data io.Reader, length int64) error {
+	if track.Kind != TrackKindSubtitles {
+		return fmt.Errorf("bunny: %s tracks are not supported", track.Kind)
+	}
+	raw, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("bunny: read captions: %w", err)
+	}
+	payload, _ := json.Marshal(map[string]string{
+		"srclang":      track.Language,
+		"label":        track.Label,
+		"captionsFile": base64.StdEncoding.EncodeToString(raw),
+	})
+	return p.captionsRequest("POST", externalID, track.Language, bytes.NewReader(payload))
+}
+
+func (p *BunnyProvider) GetTrackURL(externalID string, track TrackFile, expirationMinutes int) (string, error) {
+	if p.cfg.CDNHostname == "" {
+		return "", fmt.Errorf("bunny: BUNNY_CDN_HOSTNAME not configured")
+	}
+	return p.cdnURL(fmt.Sprintf("/%s/captions/%s.vtt", externalID, track.Language), expirationMinutes), nil
+}
+
+func (p *BunnyProvider) DeleteTrack(externalID string, track TrackFile) error {
+	return p.captionsRequest("DELETE", externalID, track.Language, nil)
+}
+
+func (p *BunnyProvider) captionsRequest(method, externalID, language string, body io.Reader) error {
+	url := fmt.Sprintf("https://video.bunnycdn.com/library/%s/videos/%s/captions/%s", p.cfg.StreamLibraryID, externalID, language)
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return fmt.Errorf("bunny: captions request: %w", err)
+	}
+	req.Header.Set("AccessKey", p.cfg.StreamAPIKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bunny: captions execute: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && method == "DELETE" {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		raw, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("bunny: captions API %d: %s", resp.StatusCode, string(raw))
+	}
+	return nil
 }
 
 // DeleteVideo elimina el video de la biblioteca de Bunny Stream.
@@ -183,3 +247,57 @@ func (p *BunnyProvider) CompleteUpload(externalID string) error {
 	defer resp.Body.Close()
 	return nil
 }
+
+// GetVideoStatus consulta el estado de codificación del video en Bunny Stream.
+// Códigos de Bunny: 0 created, 1 uploaded, 2 processing, 3 transcoding, 4 finished,
+// 5 error, 6 upload failed, 7 JIT segmenting, 8 JIT playlists created.
+func (p *BunnyProvider) GetVideoStatus(externalID string) (*VideoStatus, error) {
+	url := fmt.Sprintf("https://video.bunnycdn.com/library/%s/videos/%s", p.cfg.StreamLibraryID, externalID)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("bunny: status request: %w", err)
+	}
+	req.Header.Set("AccessKey", p.cfg.StreamAPIKey)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("bunny: status execute: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &VideoStatus{Status: VideoStatusFailed, Detail: "video not found in library"}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("bunny: status API %d: %s", resp.StatusCode, string(body))
+	}
+
+	var video struct {
+		Status         int `json:"status"`
+		EncodeProgress int `json:"encodeProgress"`
+		Length         int `json:"length"`
+		Width          int `json:"width"`
+		Height         int `json:"height"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&video); err != nil {
+		return nil, fmt.Errorf("bunny: decode status: %w", err)
+	}
+
+	status := &VideoStatus{Progress: video.EncodeProgress}
+	switch video.Status {
+	case 0:
+		status.Status = VideoStatusUploading
+	case 4, 8:
+		status.Status = VideoStatusReady
+		status.Media = &VideoMedia{DurationSeconds: float64(video.Length), Width: video.Width, Height: video.Height}
+	case 5:
+		status.Status, status.Detail = VideoStatusFailed, "encoding failed"
+	case 6:
+		status.Status, status.Detail = VideoStatusFailed, "upload failed"
+	default:
+		status.Status = VideoStatusProcessing
+	}
+	return status, nil
+}
@@ -1,52 +1,569 @@
 package storage
 
 import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/qenti/qenti/internal/config"
+)
+
+const (
+	// cloudflareUploadURLTTL vigencia de las URLs de upload directo (máximo de Cloudflare: 6h)
+	cloudflareUploadURLTTL = time.Hour
+	// cloudflareBasicUploadLimit límite de Cloudflare para uploads básicos (POST multipart);
+	// archivos más grandes requieren TUS
+	cloudflareBasicUploadLimit = 200 << 20
+	// cloudflareTUSChunkSize tamaño de cada PATCH TUS (Cloudflare exige múltiplos de 256 KiB, mínimo 5 MiB)
+	cloudflareTUSChunkSize = 50 << 20
+	// cloudflareMetaUploadURL / cloudflareMetaUploadTUS claves del meta del video donde
+	// se guarda la URL de upload pendiente, para que otra instancia pueda retomarla
+	cloudflareMetaUploadURL = "qenti_upload_url"
+	cloudflareMetaUploadTUS = "qenti_upload_tus"
 )
 
-// CloudflareProvider es un stub para Cloudflare Stream.
-// Implementar reemplazando los métodos con las llamadas reales a la API de Cloudflare.
+// cloudflarePendingUpload URL de upload de un video creado y aún no subido.
+type cloudflarePendingUpload struct {
+	url     string
+	tus     bool
+	expires time.Time
+}
+
+// CloudflareProvider implementa VideoProvider (y TUSProvider, ChunkStreamer) usando Cloudflare Stream.
 // Documentación: https://developers.cloudflare.com/stream/
+//
+// CreateVideo reserva un "direct creator upload" (URL de un solo uso para POST multipart);
+// CreateTUSUpload hace lo mismo con una URL TUS reanudable para archivos grandes.
+// Cloudflare no permite subir a un video ya creado por otra vía, así que UploadVideo
+// usa la URL pendiente del video: la que quedó en memoria al crearlo o, si el video
+// se creó en otra instancia o antes de un reinicio, la que se guardó en su meta.
 type CloudflareProvider struct {
-	AccountID string
-	APIToken  string
+	cfg         config.CloudflareConfig
+	maxDuration int
+	client      *http.Client
+
+	signingKey    *rsa.PrivateKey
+	signingKeyErr error
+
+	mu      sync.Mutex
+	pending map[string]cloudflarePendingUpload
 }
 
-func NewCloudflareProvider(accountID, apiToken string) *CloudflareProvider {
-	return &CloudflareProvider{AccountID: accountID, APIToken: apiToken}
+func NewCloudflareProvider(cfg config.CloudflareConfig, maxDurationSeconds int) *CloudflareProvider {
+	cfg.APIBaseURL = strings.TrimRight(cfg.APIBaseURL, "/")
+	if cfg.APIBaseURL == "" {
+		cfg.APIBaseURL = "https://api.cloudflare.com/client/v4"
+	}
+	p := &CloudflareProvider{
+		cfg:         cfg,
+		maxDuration: maxDurationSeconds,
+		client: &http.Client{
+			Timeout: 30 * time.Minute,
+		},
+		pending: map[string]cloudflarePendingUpload{},
+	}
+	if cfg.SigningKeyID != "" || cfg.SigningKeyPEM != "" {
+		p.signingKey, p.signingKeyErr = parseRSAPrivateKey(cfg.SigningKeyPEM)
+	}
+	return p
 }
 
 func (p *CloudflareProvider) ProviderName() string { return "cloudflare" }
 
+// signedURLs indica si los videos se crean exigiendo URL firmada.
+func (p *CloudflareProvider) signedURLs() bool {
+	return p.cfg.SigningKeyID != "" && p.signingKey != nil
+}
+
+// CreateVideo reserva un direct creator upload: el cliente sube el archivo por POST
+// multipart (campo "file") a UploadURL, sin pasar por la API.
 func (p *CloudflareProvider) CreateVideo(title string) (*UploadResult, error) {
-	// TODO: POST https://api.cloudflare.com/client/v4/accounts/{account_id}/stream
-	// Retorna UID del video + URL de upload TUS
-	return nil, fmt.Errorf("cloudflare provider: not yet implemented")
+	expires := time.Now().Add(cloudflareUploadURLTTL)
+	payload := map[string]interface{}{
+		"maxDurationSeconds": p.maxDuration,
+		"expiry":             expires.UTC().Format(time.RFC3339),
+		"requireSignedURLs":  p.signedURLs(),
+		"meta":               map[string]string{"name": title},
+	}
+	var result struct {
+		UID       string `json:"uid"`
+		UploadURL string `json:"uploadURL"`
+	}
+	if err := p.api("POST", "/stream/direct_upload", payload, &result); err != nil {
+		return nil, fmt.Errorf("cloudflare: create video: %w", err)
+	}
+
+	p.remember(result.UID, title, cloudflarePendingUpload{url: result.UploadURL, expires: expires})
+	return &UploadResult{ExternalID: result.UID, UploadURL: result.UploadURL, UploadMethod: UploadMethodPOST}, nil
+}
+
+// CreateTUSUpload reserva un direct creator upload TUS de `length` bytes.
+func (p *CloudflareProvider) CreateTUSUpload(title string, length int64) (*UploadResult, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("cloudflare: TUS upload requires the file size")
+	}
+	expires := time.Now().Add(cloudflareUploadURLTTL)
+	metadata := []string{
+		"name " + base64.StdEncoding.EncodeToString([]byte(title)),
+		"maxdurationseconds " + base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(p.maxDuration))),
+		"expiry " + base64.StdEncoding.EncodeToString([]byte(expires.UTC().Format(time.RFC3339))),
+	}
+	if p.signedURLs() {
+		metadata = append(metadata, "requiresignedurls")
+	}
+
+	req, err := http.NewRequest("POST", p.accountURL("/stream?direct_user=true"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cloudflare: create TUS request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.cfg.APIToken)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Length", strconv.FormatInt(length, 10))
+	req.Header.Set("Upload-Metadata", strings.Join(metadata, ","))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cloudflare: create TUS execute: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("cloudflare: create TUS API %d: %s", resp.StatusCode, string(body))
+	}
+	uid, location := resp.Header.Get("Stream-Media-Id"), resp.Header.Get("Location")
+	if uid == "" || location == "" {
+		return nil, fmt.Errorf("cloudflare: create TUS: missing stream-media-id or location header")
+	}
+
+	p.remember(uid, title, cloudflarePendingUpload{url: location, tus: true, expires: expires})
+	return &UploadResult{ExternalID: uid, UploadURL: location, UploadMethod: UploadMethodTUS}, nil
 }
 
+// UploadVideo sube los bytes desde el servidor a la URL pendiente del video
+// (POST multipart o PATCHes TUS según cómo se creó).
 func (p *CloudflareProvider) UploadVideo(externalID string, data io.Reader, contentType string, contentLength int64) error {
-	// TODO: subida TUS o multipart a Cloudflare Stream
-	return fmt.Errorf("cloudflare provider: not yet implemented")
+	upload, err := p.pendingUpload(externalID)
+	if err != nil {
+		return err
+	}
+
+	if upload.tus {
+		err = p.uploadTUS(upload.url, data, contentLength)
+	} else {
+		if contentLength > cloudflareBasicUploadLimit {
+			return fmt.Errorf("cloudflare: %d bytes exceeds the basic upload limit; use a TUS upload", contentLength)
+		}
+		err = p.uploadBasic(upload.url, data, contentType)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	delete(p.pending, externalID)
+	p.mu.Unlock()
+	return nil
+}
+
+// uploadBasic envía el video como formulario multipart sin cargarlo entero en memoria.
+func (p *CloudflareProvider) uploadBasic(uploadURL string, data io.Reader, contentType string) error {
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="file"; filename="video"`)
+		header.Set("Content-Type", contentType)
+		part, err := form.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, data)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest("POST", uploadURL, pr)
+	if err != nil {
+		pr.Close()
+		return fmt.Errorf("cloudflare: upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudflare: upload execute: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("cloudflare: upload API %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// uploadTUS envía el video en PATCHes de cloudflareTUSChunkSize, retomando desde
+// el Upload-Offset que confirma el servidor.
+func (p *CloudflareProvider) uploadTUS(location string, data io.Reader, length int64) error {
+	if length <= 0 {
+		return fmt.Errorf("cloudflare: TUS upload requires the file size")
+	}
+	var offset int64
+	for offset < length {
+		chunk := length - offset
+		if chunk > cloudflareTUSChunkSize {
+			chunk = cloudflareTUSChunkSize
+		}
+		if err := p.tusPatch(location, offset, io.LimitReader(data, chunk), chunk); err != nil {
+			return err
+		}
+		offset += chunk
+	}
+	return nil
+}
+
+// tusPatch envía n bytes a partir de offset y verifica el Upload-Offset que confirma
+// el servidor.
+func (p *CloudflareProvider) tusPatch(location string, offset int64, data io.Reader, n int64) error {
+	req, err := http.NewRequest("PATCH", location, data)
+	if err != nil {
+		return fmt.Errorf("cloudflare: TUS request: %w", err)
+	}
+	req.ContentLength = n
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudflare: TUS execute: %w", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("cloudflare: TUS API %d: %s", resp.StatusCode, string(body))
+	}
+
+	next, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || next != offset+n {
+		return fmt.Errorf("cloudflare: TUS offset mismatch: sent up to %d, server at %q", offset+n, resp.Header.Get("Upload-Offset"))
+	}
+	return nil
+}
+
+// cloudflareChunkState estado de un upload por partes (ChunkStreamer) contra una URL TUS.
+type cloudflareChunkState struct {
+	VideoID  string `json:"video_id"`
+	Location string `json:"location"`
+}
+
+// ChunkSize tamaño de las partes reenviadas por TUS (múltiplo de 256 KiB, mínimo 5 MiB).
+func (p *CloudflareProvider) ChunkSize() int64 { return cloudflareTUSChunkSize }
+
+// StartChunkedUpload reserva un video con URL TUS; cada parte que completa la API
+// se reenvía como un PATCH.
+func (p *CloudflareProvider) StartChunkedUpload(title, contentType string, length int64) (string, string, error) {
+	upload, err := p.CreateTUSUpload(title, length)
+	if err != nil {
+		return "", "", err
+	}
+	state, _ := json.Marshal(cloudflareChunkState{VideoID: upload.ExternalID, Location: upload.UploadURL})
+	return upload.ExternalID, string(state), nil
+}
+
+// WriteChunk envía la parte que empieza en offset a la URL TUS del video.
+func (p *CloudflareProvider) WriteChunk(state string, offset int64, data io.Reader, n int64) (string, error) {
+	var st cloudflareChunkState
+	if err := json.Unmarshal([]byte(state), &st); err != nil || st.Location == "" {
+		return "", fmt.Errorf("cloudflare: invalid chunked upload state")
+	}
+	if err := p.tusPatch(st.Location, offset, data, n); err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// FinishChunkedUpload no requiere llamada: Cloudflare cierra el upload TUS al
+// recibir el último byte.
+func (p *CloudflareProvider) FinishChunkedUpload(state string) error {
+	var st cloudflareChunkState
+	if err := json.Unmarshal([]byte(state), &st); err != nil {
+		return fmt.Errorf("cloudflare: invalid chunked upload state")
+	}
+	p.mu.Lock()
+	delete(p.pending, st.VideoID)
+	p.mu.Unlock()
+	return nil
 }
 
+// AbortChunkedUpload elimina el video reservado.
+func (p *CloudflareProvider) AbortChunkedUpload(state string) error {
+	var st cloudflareChunkState
+	if err := json.Unmarshal([]byte(state), &st); err != nil {
+		return fmt.Errorf("cloudflare: invalid chunked upload state")
+	}
+	p.mu.Lock()
+	delete(p.pending, st.VideoID)
+	p.mu.Unlock()
+	return p.DeleteVideo(st.VideoID)
+}
+
+// GetPlaybackURL genera la URL HLS. Con clave de firma configurada, el UID se
+// reemplaza por un token RS256 firmado localmente (sin llamar a la API).
 func (p *CloudflareProvider) GetPlaybackURL(externalID string, expirationMinutes int) (string, error) {
-	// TODO: Return HLS URL con token firmado
-	// https://customer-{code}.cloudflarestream.com/{uid}/manifest/video.m3u8
-	return fmt.Sprintf("https://customer-REPLACE.cloudflarestream.com/%s/manifest/video.m3u8", externalID), nil
+	if externalID == "" {
+		return "", fmt.Errorf("cloudflare: empty video ID")
+	}
+	host := "videodelivery.net"
+	if p.cfg.CustomerCode != "" {
+		host = fmt.Sprintf("customer-%s.cloudflarestream.com", p.cfg.CustomerCode)
+	}
+	if p.cfg.SigningKeyID == "" {
+		return fmt.Sprintf("https://%s/%s/manifest/video.m3u8", host, externalID), nil
+	}
+
+	token, err := p.SignToken(externalID, time.Duration(expirationMinutes)*time.Minute)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://%s/%s/manifest/video.m3u8", host, token), nil
+}
+
+// SignToken firma un token de reproducción para el video con vigencia ttl
+// (https://developers.cloudflare.com/stream/viewing-videos/securing-your-stream/).
+func (p *CloudflareProvider) SignToken(externalID string, ttl time.Duration) (string, error) {
+	if p.signingKeyErr != nil {
+		return "", fmt.Errorf("cloudflare: invalid signing key: %w", p.signingKeyErr)
+	}
+	if p.signingKey == nil || p.cfg.SigningKeyID == "" {
+		return "", fmt.Errorf("cloudflare: CLOUDFLARE_STREAM_SIGNING_KEY_ID/PEM not configured")
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": externalID,
+		"kid": p.cfg.SigningKeyID,
+		"exp": now.Add(ttl).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(), // tolerancia de reloj
+	})
+	token.Header["kid"] = p.cfg.SigningKeyID
+	signed, err := token.SignedString(p.signingKey)
+	if err != nil {
+		return "", fmt.Errorf("cloudflare: sign token: %w", err)
+	}
+	return signed, nil
 }
 
+// DeleteVideo elimina el video de Cloudflare Stream.
 func (p *CloudflareProvider) DeleteVideo(externalID string) error {
-	// TODO: DELETE https://api.cloudflare.com/client/v4/accounts/{account_id}/stream/{identifier}
-	return fmt.Errorf("cloudflare provider: not yet implemented")
+	if err := p.api("DELETE", "/stream/"+externalID, nil, nil); err != nil {
+		return fmt.Errorf("cloudflare: delete video: %w", err)
+	}
+	p.mu.Lock()
+	delete(p.pending, externalID)
+	p.mu.Unlock()
+	return nil
 }
 
 func (p *CloudflareProvider) CompleteUpload(externalID string) error {
-	// Cloudflare no requiere un paso explícito de complete
+	// Cloudflare no requiere un paso explícito de complete: codifica al recibir el archivo
 	return nil
 }
 
+// ValidateConnection verifica las credenciales listando un video de la cuenta y,
+// si está configurada, que la clave de firma sea válida.
 func (p *CloudflareProvider) ValidateConnection() error {
-	// TODO: GET https://api.cloudflare.com/client/v4/accounts/{account_id}/stream
-	return fmt.Errorf("cloudflare provider: not yet implemented")
+	if p.cfg.AccountID == "" || p.cfg.APIToken == "" {
+		return fmt.Errorf("cloudflare: CLOUDFLARE_ACCOUNT_ID/CLOUDFLARE_API_TOKEN not configured")
+	}
+	if p.signingKeyErr != nil {
+		return fmt.Errorf("cloudflare: invalid signing key: %w", p.signingKeyErr)
+	}
+	var videos []json.RawMessage
+	if err := p.api("GET", "/stream?limit=1", nil, &videos); err != nil {
+		return fmt.Errorf("cloudflare: validate: %w", err)
+	}
+	return nil
+}
+
+// GetVideoStatus consulta el estado del video en Cloudflare Stream
+// (status.state: pendingupload, downloading, queued, inprogress, ready, error).
+func (p *CloudflareProvider) GetVideoStatus(externalID string) (*VideoStatus, error) {
+	var video struct {
+		ReadyToStream bool `json:"readyToStream"`
+		Status        struct {
+			State           string `json:"state"`
+			PctComplete     string `json:"pctComplete"`
+			ErrorReasonText string `json:"errorReasonText"`
+		} `json:"status"`
+		Duration float64 `json:"duration"` // -1 mientras no se conoce
+		Input    struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"input"`
+	}
+	if err := p.api("GET", "/stream/"+externalID, nil, &video); err != nil {
+		return nil, fmt.Errorf("cloudflare: video status: %w", err)
+	}
+
+	pct, _ := strconv.ParseFloat(video.Status.PctComplete, 64)
+	status := &VideoStatus{Progress: int(pct)}
+	switch {
+	case video.Status.State == "error":
+		status.Status, status.Detail = VideoStatusFailed, video.Status.ErrorReasonText
+	case video.ReadyToStream || video.Status.State == "ready":
+		status.Status = VideoStatusReady
+		status.Media = &VideoMedia{
+			DurationSeconds: max(video.Duration, 0),
+			Width:           max(video.Input.Width, 0),
+			Height:          max(video.Input.Height, 0),
+		}
+	case video.Status.State == "pendingupload":
+		status.Status = VideoStatusUploading
+	default:
+		status.Status = VideoStatusProcessing
+	}
+	return status, nil
+}
+
+func (p *CloudflareProvider) accountURL(path string) string {
+	return fmt.Sprintf("%s/accounts/%s%s", p.cfg.APIBaseURL, p.cfg.AccountID, path)
+}
+
+// api ejecuta una llamada JSON a la API v4 de la cuenta y decodifica `result` en out.
+func (p *CloudflareProvider) api(method, path string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		b, _ := json.Marshal(payload)
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, p.accountURL(path), body)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.cfg.APIToken)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := p.client
+	if method == "GET" {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	var envelope struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		Result json.RawMessage `json:"result"`
+	}
+	decodeErr := json.Unmarshal(raw, &envelope)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || decodeErr != nil || !envelope.Success {
+		if len(envelope.Errors) > 0 {
+			return fmt.Errorf("API %d: %s (code %d)", resp.StatusCode, envelope.Errors[0].Message, envelope.Errors[0].Code)
+		}
+		return fmt.Errorf("API %d: %s", resp.StatusCode, string(raw))
+	}
+	if out != nil && len(envelope.Result) > 0 {
+		if err := json.Unmarshal(envelope.Result, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// remember guarda la URL de upload pendiente en memoria y en el meta del video.
+// Si no se puede guardar el meta, el upload sigue funcionando desde esta instancia.
+func (p *CloudflareProvider) remember(uid, title string, upload cloudflarePendingUpload) {
+	p.mu.Lock()
+	now := time.Now()
+	for id, u := range p.pending {
+		if now.After(u.expires) {
+			delete(p.pending, id)
+		}
+	}
+	p.pending[uid] = upload
+	p.mu.Unlock()
+
+	payload := map[string]interface{}{
+		"meta": map[string]string{
+			"name":                  title,
+			cloudflareMetaUploadURL: upload.url,
+			cloudflareMetaUploadTUS: strconv.FormatBool(upload.tus),
+		},
+	}
+	if err := p.api("POST", "/stream/"+uid, payload, nil); err != nil {
+		// No crítico, pero otra instancia no podrá retomar este upload
+		log.Printf("cloudflare: save pending upload of %s in video meta: %v", uid, err)
+	}
+}
+
+// pendingUpload devuelve la URL de upload pendiente del video: la de memoria o,
+// si no está, la guardada en su meta mientras el video siga esperando el archivo.
+func (p *CloudflareProvider) pendingUpload(uid string) (cloudflarePendingUpload, error) {
+	p.mu.Lock()
+	u, ok := p.pending[uid]
+	p.mu.Unlock()
+	if ok && time.Now().Before(u.expires) {
+		return u, nil
+	}
+
+	var video struct {
+		Status struct {
+			State string `json:"state"`
+		} `json:"status"`
+		UploadExpiry *time.Time             `json:"uploadExpiry"`
+		Meta         map[string]interface{} `json:"meta"`
+	}
+	if err := p.api("GET", "/stream/"+uid, nil, &video); err != nil {
+		return cloudflarePendingUpload{}, fmt.Errorf("cloudflare: pending upload for video %s: %w", uid, err)
+	}
+	url, _ := video.Meta[cloudflareMetaUploadURL].(string)
+	if video.Status.State != "pendingupload" || url == "" {
+		return cloudflarePendingUpload{}, fmt.Errorf("cloudflare: no pending upload for video %s", uid)
+	}
+	u = cloudflarePendingUpload{url: url, expires: time.Now().Add(cloudflareUploadURLTTL)}
+	u.tus, _ = strconv.ParseBool(fmt.Sprint(video.Meta[cloudflareMetaUploadTUS]))
+	if video.UploadExpiry != nil {
+		if time.Now().After(*video.UploadExpiry) {
+			return cloudflarePendingUpload{}, fmt.Errorf("cloudflare: upload URL of video %s expired", uid)
+		}
+		u.expires = *video.UploadExpiry
+	}
+	return u, nil
+}
+
+// parseRSAPrivateKey acepta el PEM tal cual o en base64 (formato en que lo
+// devuelve POST /stream/keys; cómodo también para variables de entorno).
+func parseRSAPrivateKey(value string) (*rsa.PrivateKey, error) {
+	pemBytes := []byte(value)
+	if !strings.Contains(value, "-----BEGIN") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("signing key is neither PEM nor base64 PEM: %w", err)
+		}
+		pemBytes = decoded
+	}
+	return jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
 }
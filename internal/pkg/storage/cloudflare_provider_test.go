@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/qenti/qenti/internal/config"
+)
+
+// fakeStream servidor con las rutas de la API de Cloudflare Stream que usa el
+// proveedor, más las URLs de upload que devuelve.
+type fakeStream struct {
+	t   *testing.T
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	requests []string
+	meta     map[string]map[string]string
+	uploads  map[string][]byte
+	videos   map[string]string // uid -> JSON del video en GET /stream/:uid
+}
+
+func newFakeStream(t *testing.T) *fakeStream {
+	f := &fakeStream{
+		t:       t,
+		meta:    map[string]map[string]string{},
+		uploads: map[string][]byte{},
+		videos:  map[string]string{},
+	}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeStream) provider(cfg config.CloudflareConfig) *CloudflareProvider {
+	cfg.AccountID, cfg.APIToken, cfg.APIBaseURL = "acc", "token", f.srv.URL+"/client/v4"
+	return NewCloudflareProvider(cfg, 600)
+}
+
+func (f *fakeStream) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+
+	const api = "/client/v4/accounts/acc"
+	if strings.HasPrefix(r.URL.Path, api) && r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"success":false,"errors":[{"code":10000,"message":"Authentication error"}]}`)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, api)
+	switch {
+	case r.Method == "POST" && path == "/stream/direct_upload":
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload["maxDurationSeconds"] != float64(600) || payload["requireSignedURLs"] != false {
+			f.t.Errorf("direct_upload payload = %v", payload)
+		}
+		streamSuccess(w, fmt.Sprintf(`{"uid":"vid1","uploadURL":%q}`, f.srv.URL+"/upload/vid1"))
+	case r.Method == "POST" && path == "/stream" && r.URL.Query().Get("direct_user") == "true":
+		if r.Header.Get("Tus-Resumable") != "1.0.0" || r.Header.Get("Upload-Length") != "11" {
+			f.t.Errorf("TUS create headers = %v", r.Header)
+		}
+		if !strings.Contains(r.Header.Get("Upload-Metadata"), "maxdurationseconds NjAw") {
+			f.t.Errorf("Upload-Metadata = %q", r.Header.Get("Upload-Metadata"))
+		}
+		w.Header().Set("Stream-Media-Id", "vid2")
+		w.Header().Set("Location", f.srv.URL+"/tus/vid2")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "POST" && strings.HasPrefix(path, "/stream/"):
+		var payload struct {
+			Meta map[string]string `json:"meta"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		f.meta[strings.TrimPrefix(path, "/stream/")] = payload.Meta
+		streamSuccess(w, `{}`)
+	case r.Method == "GET" && path == "/stream":
+		streamSuccess(w, `[]`)
+	case r.Method == "GET" && strings.HasPrefix(path, "/stream/"):
+		video, ok := f.videos[strings.TrimPrefix(path, "/stream/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"success":false,"errors":[{"code":10005,"message":"Video not found"}]}`)
+			return
+		}
+		streamSuccess(w, video)
+	case r.Method == "DELETE" && strings.HasPrefix(path, "/stream/"):
+		streamSuccess(w, `""`)
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/upload/"):
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			f.t.Errorf("basic upload without file field: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		f.uploads[strings.TrimPrefix(r.URL.Path, "/upload/")] = data
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "PATCH" && strings.HasPrefix(r.URL.Path, "/tus/"):
+		uid := strings.TrimPrefix(r.URL.Path, "/tus/")
+		if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+			f.t.Errorf("TUS PATCH content type = %q", r.Header.Get("Content-Type"))
+		}
+		if got := r.Header.Get("Upload-Offset"); got != fmt.Sprint(len(f.uploads[uid])) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.uploads[uid] = append(f.uploads[uid], data...)
+		w.Header().Set("Upload-Offset", fmt.Sprint(len(f.uploads[uid])))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func streamSuccess(w http.ResponseWriter, result string) {
+	fmt.Fprintf(w, `{"success":true,"errors":[],"result":%s}`, result)
+}
+
+func TestCloudflareDirectUpload(t *testing.T) {
+	f := newFakeStream(t)
+	p := f.provider(config.CloudflareConfig{})
+
+	result, err := p.CreateVideo("Episodio 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExternalID != "vid1" || result.UploadMethod != UploadMethodPOST || result.UploadURL != f.srv.URL+"/upload/vid1" {
+		t.Errorf("CreateVideo = %+v", result)
+	}
+	if meta := f.meta["vid1"]; meta["name"] != "Episodio 1" || meta[cloudflareMetaUploadURL] != result.UploadURL || meta[cloudflareMetaUploadTUS] != "false" {
+		t.Errorf("video meta = %v", meta)
+	}
+
+	if err := p.UploadVideo("vid1", strings.NewReader("video bytes"), "video/mp4", 11); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(f.uploads["vid1"]); got != "video bytes" {
+		t.Errorf("uploaded %q", got)
+	}
+	if _, ok := p.pending["vid1"]; ok {
+		t.Error("pending upload should be forgotten after uploading")
+	}
+	if err := p.UploadVideo("vid1", strings.NewReader("x"), "video/mp4", 1); err == nil {
+		t.Error("a second upload to the same video should fail")
+	}
+}
+
+func TestCloudflareTUSUpload(t *testing.T) {
+	f := newFakeStream(t)
+	p := f.provider(config.CloudflareConfig{})
+
+	if _, err := p.CreateTUSUpload("Episodio 2", 0); err == nil {
+		t.Error("TUS upload without length should fail")
+	}
+	result, err := p.CreateTUSUpload("Episodio 2", 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExternalID != "vid2" || result.UploadMethod != UploadMethodTUS || result.UploadURL != f.srv.URL+"/tus/vid2" {
+		t.Errorf("CreateTUSUpload = %+v", result)
+	}
+	if err := p.UploadVideo("vid2", strings.NewReader("video bytes"), "video/mp4", 11); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(f.uploads["vid2"]); got != "video bytes" {
+		t.Errorf("uploaded %q", got)
+	}
+}
+
+func TestCloudflareChunkedUpload(t *testing.T) {
+	f := newFakeStream(t)
+	p := f.provider(config.CloudflareConfig{})
+
+	videoID, state, err := p.StartChunkedUpload("Episodio 2", "video/mp4", 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if videoID != "vid2" {
+		t.Errorf("videoID = %q", videoID)
+	}
+	for _, part := range []struct {
+		offset int64
+		data   string
+	}{{0, "video "}, {6, "bytes"}} {
+		if state, err = p.WriteChunk(state, part.offset, strings.NewReader(part.data), int64(len(part.data))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// El servidor confirma otro offset: la parte no se da por enviada
+	if _, err := p.WriteChunk(state, 3, strings.NewReader("xx"), 2); err == nil {
+		t.Error("a PATCH at the wrong offset should fail")
+	}
+	if err := p.FinishChunkedUpload(state); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(f.uploads["vid2"]); got != "video bytes" {
+		t.Errorf("uploaded %q", got)
+	}
+}
+
+func TestCloudflareUploadVideoFromAnotherInstance(t *testing.T) {
+	f := newFakeStream(t)
+	expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	f.videos["vid3"] = fmt.Sprintf(`{"uid":"vid3","status":{"state":"pendingupload"},"uploadExpiry":%q,"meta":{%q:%q,%q:"true"}}`,
+		expiry, cloudflareMetaUploadURL, f.srv.URL+"/tus/vid3", cloudflareMetaUploadTUS)
+	f.videos["vid4"] = fmt.Sprintf(`{"uid":"vid4","status":{"state":"ready"},"meta":{%q:%q}}`,
+		cloudflareMetaUploadURL, f.srv.URL+"/upload/vid4")
+
+	// Proveedor sin la URL en memoria (reinicio u otra réplica)
+	p := f.provider(config.CloudflareConfig{})
+	if err := p.UploadVideo("vid3", strings.NewReader("video bytes"), "video/mp4", 11); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(f.uploads["vid3"]); got != "video bytes" {
+		t.Errorf("uploaded %q", got)
+	}
+	for _, uid := range []string{"vid4", "missing"} {
+		if err := p.UploadVideo(uid, strings.NewReader("x"), "video/mp4", 1); err == nil {
+			t.Errorf("UploadVideo(%s) should fail without a pending upload", uid)
+		}
+	}
+}
+
+func TestCloudflareSignToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	p := NewCloudflareProvider(config.CloudflareConfig{SigningKeyID: "key1", SigningKeyPEM: keyPEM, CustomerCode: "abc"}, 600)
+
+	signed, err := p.SignToken("vid1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) { return &key.PublicKey, nil },
+		jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	if token.Header["kid"] != "key1" || claims["sub"] != "vid1" || claims["kid"] != "key1" {
+		t.Errorf("token header %v, claims %v", token.Header, claims)
+	}
+	if exp, _ := claims.GetExpirationTime(); exp == nil || time.Until(exp.Time) > time.Hour || time.Until(exp.Time) < 59*time.Minute {
+		t.Errorf("exp = %v", exp)
+	}
+
+	playback, err := p.GetPlaybackURL("vid1", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(playback, "https://customer-abc.cloudflarestream.com/") || strings.Contains(playback, "/vid1/") {
+		t.Errorf("signed playback URL = %q", playback)
+	}
+
+	unsigned := NewCloudflareProvider(config.CloudflareConfig{}, 600)
+	if _, err := unsigned.SignToken("vid1", time.Hour); err == nil {
+		t.Error("SignToken without a key should fail")
+	}
+	if url, _ := unsigned.GetPlaybackURL("vid1", 60); url != "https://videodelivery.net/vid1/manifest/video.m3u8" {
+		t.Errorf("unsigned playback URL = %q", url)
+	}
+	broken := NewCloudflareProvider(config.CloudflareConfig{SigningKeyID: "key1", SigningKeyPEM: "not a key"}, 600)
+	if _, err := broken.SignToken("vid1", time.Hour); err == nil || !strings.Contains(err.Error(), "invalid signing key") {
+		t.Errorf("SignToken with a broken key err = %v", err)
+	}
+}
+
+func TestCloudflareDeleteVideo(t *testing.T) {
+	f := newFakeStream(t)
+	p := f.provider(config.CloudflareConfig{})
+	p.remember("vid1", "Episodio 1", cloudflarePendingUpload{url: "u", expires: time.Now().Add(time.Hour)})
+
+	if err := p.DeleteVideo("vid1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.pending["vid1"]; ok {
+		t.Error("deleted video should not keep its pending upload")
+	}
+	if got := f.requests[len(f.requests)-1]; got != "DELETE /client/v4/accounts/acc/stream/vid1" {
+		t.Errorf("last request = %q", got)
+	}
+}
+
+func TestCloudflareValidateConnection(t *testing.T) {
+	f := newFakeStream(t)
+	if err := f.provider(config.CloudflareConfig{}).ValidateConnection(); err != nil {
+		t.Errorf("ValidateConnection = %v", err)
+	}
+	if err := NewCloudflareProvider(config.CloudflareConfig{}, 600).ValidateConnection(); err == nil {
+		t.Error("ValidateConnection without credentials should fail")
+	}
+
+	wrongToken := f.provider(config.CloudflareConfig{})
+	wrongToken.cfg.APIToken = "wrong"
+	if err := wrongToken.ValidateConnection(); err == nil || !strings.Contains(err.Error(), "Authentication error (code 10000)") {
+		t.Errorf("ValidateConnection with a wrong token err = %v", err)
+	}
+}
+
+func TestCloudflareGetVideoStatus(t *testing.T) {
+	f := newFakeStream(t)
+	p := f.provider(config.CloudflareConfig{})
+	tests := []struct {
+		video        string
+		wantStatus   string
+		wantProgress int
+		wantDetail   string
+	}{
+		{`{"status":{"state":"pendingupload"}}`, VideoStatusUploading, 0, ""},
+		{`{"status":{"state":"inprogress","pctComplete":"42.5"}}`, VideoStatusProcessing, 42, ""},
+		{`{"status":{"state":"queued"}}`, VideoStatusProcessing, 0, ""},
+		{`{"readyToStream":true,"status":{"state":"inprogress"}}`, VideoStatusReady, 0, ""},
+		{`{"status":{"state":"ready","pctComplete":"100.000000"}}`, VideoStatusReady, 100, ""},
+		{`{"status":{"state":"error","errorReasonText":"Duration exceeds limit"}}`, VideoStatusFailed, 0, "Duration exceeds limit"},
+	}
+	for i, tt := range tests {
+		uid := fmt.Sprintf("v%d", i)
+		f.videos[uid] = tt.video
+		got, err := p.GetVideoStatus(uid)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.video, err)
+		}
+		if got.Status != tt.wantStatus || got.Progress != tt.wantProgress || got.Detail != tt.wantDetail {
+			t.Errorf("%s: got %+v", tt.video, got)
+		}
+	}
+	if _, err := p.GetVideoStatus("missing"); err == nil || !strings.Contains(err.Error(), "Video not found") {
+		t.Errorf("missing video err = %v", err)
+	}
+}
+
+func TestCloudflareGetVideoStatusMedia(t *testing.T) {
+	f := newFakeStream(t)
+	p := f.provider(config.CloudflareConfig{})
+	tests := []struct {
+		video string
+		want  *VideoMedia
+	}{
+		{`{"status":{"state":"inprogress"},"duration":-1,"input":{"width":-1,"height":-1}}`, nil},
+		{`{"readyToStream":true,"status":{"state":"ready"},"duration":95.4,"input":{"width":1080,"height":1920}}`, &VideoMedia{DurationSeconds: 95.4, Width: 1080, Height: 1920}},
+		{`{"readyToStream":true,"status":{"state":"ready"},"duration":-1,"input":{"width":-1,"height":-1}}`, &VideoMedia{}},
+	}
+	for i, tt := range tests {
+		uid := fmt.Sprintf("m%d", i)
+		f.videos[uid] = tt.video
+		got, err := p.GetVideoStatus(uid)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.video, err)
+		}
+		if (got.Media == nil) != (tt.want == nil) || (got.Media != nil && *got.Media != *tt.want) {
+			t.Errorf("%s: media = %+v, want %+v", tt.video, got.Media, tt.want)
+		}
+	}
+}
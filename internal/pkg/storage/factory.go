@@ -17,11 +17,14 @@ func NewProvider(cfg *config.Config) (VideoProvider, error) {
 		// Default: Bunny.net
 		return NewBunnyProvider(cfg.Bunny), nil
 	case "cloudflare":
-		return NewCloudflareProvider(
-			cfg.Cloudflare.AccountID,
-			cfg.Cloudflare.APIToken,
-		), nil
+		return NewCloudflareProvider(cfg.Cloudflare, cfg.VideoUpload.MaxDurationSeconds), nil
+	case "local":
+		// Disco + URLs firmadas servidas por la propia API (desarrollo/self-hosting)
+		return NewLocalProvider(cfg.LocalStorage), nil
+	case "s3":
+		// Cualquier bucket compatible con S3 (AWS, MinIO, R2, ...)
+		return NewS3Provider(cfg.S3)
 	default:
-		return nil, fmt.Errorf("storage: unknown CDN_PROVIDER=%q (valid: bunny, cloudflare)", cfg.CDNProvider)
+		return nil, fmt.Errorf("storage: unknown CDN_PROVIDER=%q (valid: bunny, cloudflare, local, s3)", cfg.CDNProvider)
 	}
 }
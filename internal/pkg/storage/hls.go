@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RemuxHLS empaqueta input (archivo o URL) en HLS dentro de dir sin recodificar:
+// copia el primer video y el primer audio (si hay) en segmentos MPEG-TS de ~4 s y
+// escribe master.m3u8 + index.m3u8. Requiere ffmpeg.
+func RemuxHLS(ctx context.Context, ffmpegPath, input, dir string) error {
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", input,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", "4",
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "seg_%04d.ts"),
+		"-master_pl_name", "master.m3u8",
+		filepath.Join(dir, "index.m3u8"),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+)
+
+// LocalMediaPath prefijo de las rutas de la API que sirven y reciben los videos
+// del proveedor local.
+const LocalMediaPath = "/api/v1/media/local"
+
+// localUploadURLTTL vigencia de la URL de upload directo que devuelve CreateVideo.
+const localUploadURLTTL = 6 * time.Hour
+
+const (
+	localOriginalFile = "original"
+	localMetaFile     = "meta.json"
+	localHLSDir       = "hls"
+	localHLSMaster    = "hls/master.m3u8"
+)
+
+var (
+	// ErrInvalidSignature la firma de la URL no corresponde al video/método.
+	ErrInvalidSignature = errors.New("storage: invalid signature")
+	// ErrURLExpired la URL firmada ya venció.
+	ErrURLExpired = errors.New("storage: url expired")
+	// ErrVideoNotFound el video (o el archivo pedido) no existe en disco.
+	ErrVideoNotFound = errors.New("storage: video not found")
+)
+
+// localMeta metadatos de un video guardados junto al archivo (meta.json).
+type localMeta struct {
+	Title       string    `json:"title"`
+	ContentType string    `json:"content_type,omitempty"`
+	Size        int64     `json:"size,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UploadedAt  time.Time `json:"uploaded_at,omitempty"`
+}
+
+// LocalProvider implementa VideoProvider guardando los videos en disco: cada video
+// es un directorio <Dir>/<id>/ con el archivo original y su meta.json. La propia API
+// los sirve (y recibe uploads directos) en LocalMediaPath con URLs firmadas por
+// HMAC que expiran, de modo que el flujo upload→stream completo funciona sin red.
+//
+// Formato de URL: <base>/api/v1/media/local/<expires>/<firma>/<id>/<archivo> (ver mediaSigner).
+type LocalProvider struct {
+	cfg    config.LocalStorageConfig
+	signer mediaSigner
+}
+
+func NewLocalProvider(cfg config.LocalStorageConfig) *LocalProvider {
+	cfg.PublicBaseURL = strings.TrimRight(cfg.PublicBaseURL, "/")
+	return &LocalProvider{cfg: cfg, signer: mediaSigner{key: []byte(cfg.SigningKey)}}
+}
+
+func (p *LocalProvider) ProviderName() string { return "local" }
+
+// CreateVideo crea el directorio del video y retorna una URL firmada a la que el
+// cliente puede hacer PUT directamente.
+func (p *LocalProvider) CreateVideo(title string) (*UploadResult, error) {
+	id := uuid.New().String()
+	if err := os.MkdirAll(p.videoDir(id), 0o755); err != nil {
+		return nil, fmt.Errorf("local: create video dir: %w", err)
+	}
+	if err := p.writeMeta(id, &localMeta{Title: title, CreatedAt: time.Now().UTC()}); err != nil {
+		return nil, err
+	}
+	return &UploadResult{
+		ExternalID:   id,
+		UploadURL:    p.SignedURL("PUT", id, "", localUploadURLTTL),
+		UploadMethod: UploadMethodPUT,
+	}, nil
+}
+
+// UploadVideo escribe los bytes en un archivo temporal y lo renombra al terminar,
+// para que una lectura concurrente nunca vea un original a medio escribir.
+func (p *LocalProvider) UploadVideo(externalID string, data io.Reader, contentType string, contentLength int64) error {
+	meta, err := p.readMeta(externalID)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(p.videoDir(externalID), localOriginalFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("local: create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op tras el rename
+
+	written, err := io.Copy(tmp, data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("local: write video: %w", err)
+	}
+	if contentLength > 0 && written != contentLength {
+		return fmt.Errorf("local: incomplete upload: got %d of %d bytes", written, contentLength)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(p.videoDir(externalID), localOriginalFile)); err != nil {
+		return fmt.Errorf("local: store video: %w", err)
+	}
+
+	// Un original nuevo invalida las renditions HLS publicadas para el anterior
+	if err := os.RemoveAll(filepath.Join(p.videoDir(externalID), localHLSDir)); err != nil {
+		return fmt.Errorf("local: drop stale HLS: %w", err)
+	}
+
+	meta.ContentType = contentType
+	meta.Size = written
+	meta.UploadedAt = time.Now().UTC()
+	return p.writeMeta(externalID, meta)
+}
+
+// GetPlaybackURL genera la URL firmada servida por la propia API: el master HLS si
+// el video tiene renditions publicadas (PublishHLS), si no el original. Los
+// playlists usan rutas relativas, que heredan la firma del path.
+func (p *LocalProvider) GetPlaybackURL(externalID string, expirationMinutes int) (string, error) {
+	ttl := time.Duration(expirationMinutes) * time.Minute
+	if _, err := p.Stat(externalID, localHLSMaster); err == nil {
+		return p.SignedURL("GET", externalID, localHLSMaster, ttl), nil
+	}
+	if _, err := p.Stat(externalID, localOriginalFile); err != nil {
+		return "", err
+	}
+	return p.SignedURL("GET", externalID, localOriginalFile, ttl), nil
+}
+
+// PublishHLS copia el contenido de dir como las renditions HLS del video. Se arma
+// en un directorio temporal y se reemplaza el anterior con un rename, para que la
+// reproducción nunca vea una escalera a medio copiar.
+func (p *LocalProvider) PublishHLS(externalID string, dir string) error {
+	if _, err := p.readMeta(externalID); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "master.m3u8")); err != nil {
+		return fmt.Errorf("local: HLS dir has no master.m3u8")
+	}
+
+	tmp, err := os.MkdirTemp(p.videoDir(externalID), localHLSDir+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("local: create HLS dir: %w", err)
+	}
+	defer os.RemoveAll(tmp) // no-op tras el rename
+
+	err = filepath.WalkDir(dir, func(full string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, full)
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(tmp, rel), 0o755)
+		}
+		return copyFile(full, filepath.Join(tmp, rel))
+	})
+	if err != nil {
+		return fmt.Errorf("local: copy HLS: %w", err)
+	}
+
+	final := filepath.Join(p.videoDir(externalID), localHLSDir)
+	if err := os.RemoveAll(final); err != nil {
+		return fmt.Errorf("local: drop previous HLS: %w", err)
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		return fmt.Errorf("local: store HLS: %w", err)
+	}
+	return nil
+}
+
+// SupportsTrack: subtítulos y audio alternativo se guardan como archivos del video.
+func (p *LocalProvider) SupportsTrack(kind string) bool { return true }
+
+// UploadTrack guarda la pista en <Dir>/<id>/tracks/ (temporal + rename, como el original).
+func (p *LocalProvider) UploadTrack(externalID string, track TrackFile, data io.Reader, length int64) error {
+	if _, err := p.readMeta(externalID); err != nil {
+		return err
+	}
+	full, err := p.filePath(externalID, track.Name())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return fmt.Errorf("local: create track dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(full), "track.*.tmp")
+	if err != nil {
+		return fmt.Errorf("local: create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op tras el rename
+
+	_, err = io.Copy(tmp, data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("local: write track: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), full); err != nil {
+		return fmt.Errorf("local: store track: %w", err)
+	}
+	return nil
+}
+
+// GetTrackURL firma la pista con el mismo token de path que la reproducción.
+func (p *LocalProvider) GetTrackURL(externalID string, track TrackFile, expirationMinutes int) (string, error) {
+	if _, err := p.Stat(externalID, track.Name()); err != nil {
+		return "", err
+	}
+	return p.SignedURL("GET", externalID, track.Name(), time.Duration(expirationMinutes)*time.Minute), nil
+}
+
+func (p *LocalProvider) DeleteTrack(externalID string, track TrackFile) error {
+	full, err := p.filePath(externalID, track.Name())
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("local: delete track: %w", err)
+	}
+	return nil
+}
+
+// DeleteVideo borra el directorio del video. Borrar un video inexistente no es error.
+func (p *LocalProvider) DeleteVideo(externalID string) error {
+	if _, err := uuid.Parse(externalID); err != nil {
+		return ErrVideoNotFound
+	}
+	if err := os.RemoveAll(p.videoDir(externalID)); err != nil {
+		return fmt.Errorf("local: delete video: %w", err)
+	}
+	return nil
+}
+
+// CompleteUpload verifica que el original haya llegado (no hay encoding que disparar).
+func (p *LocalProvider) CompleteUpload(externalID string) error {
+	_, err := p.Stat(externalID, localOriginalFile)
+	return err
+}
+
+// SourceURL ruta en disco del original (el TTL no aplica).
+func (p *LocalProvider) SourceURL(externalID string, ttl time.Duration) (string, error) {
+	return p.filePath(externalID, localOriginalFile)
+}
+
+// GetVideoStatus: los videos locales se sirven tal cual, están listos en cuanto
+// llega el original.
+func (p *LocalProvider) GetVideoStatus(externalID string) (*VideoStatus, error) {
+	if _, err := p.readMeta(externalID); errors.Is(err, ErrVideoNotFound) {
+		return &VideoStatus{Status: VideoStatusFailed, Detail: "video not found"}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err := p.Stat(externalID, localOriginalFile); errors.Is(err, ErrVideoNotFound) {
+		return &VideoStatus{Status: VideoStatusUploading}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &VideoStatus{Status: VideoStatusReady}, nil
+}
+
+// ValidateConnection verifica la configuración y que el directorio sea escribible.
+func (p *LocalProvider) ValidateConnection() error {
+	if p.cfg.SigningKey == "" {
+		return fmt.Errorf("local: LOCAL_STORAGE_SIGNING_KEY not configured")
+	}
+	if p.cfg.PublicBaseURL == "" {
+		return fmt.Errorf("local: LOCAL_STORAGE_BASE_URL not configured")
+	}
+	if err := os.MkdirAll(p.cfg.Dir, 0o755); err != nil {
+		return fmt.Errorf("local: create storage dir: %w", err)
+	}
+	f, err := os.CreateTemp(p.cfg.Dir, ".validate-*")
+	if err != nil {
+		return fmt.Errorf("local: storage dir not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// SignedURL arma la URL firmada para method (GET reproducción, PUT upload) sobre
+// el archivo name del video ("" para el upload) con vigencia ttl.
+func (p *LocalProvider) SignedURL(method, externalID, name string, ttl time.Duration) string {
+	u := p.cfg.PublicBaseURL + LocalMediaPath + p.signer.signedPath(method, externalID, ttl)
+	if name != "" {
+		u += "/" + name
+	}
+	return u
+}
+
+// Verify valida la firma y el vencimiento de una URL generada por SignedURL.
+func (p *LocalProvider) Verify(method, externalID, expires, signature string) error {
+	return p.signer.verify(method, externalID, expires, signature)
+}
+
+// Stat devuelve la info del archivo name del video, validando que la ruta quede
+// dentro del directorio del video (meta.json no es accesible).
+func (p *LocalProvider) Stat(externalID, name string) (os.FileInfo, error) {
+	full, err := p.filePath(externalID, name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(full)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, ErrVideoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("local: stat %s: %w", name, err)
+	}
+	return info, nil
+}
+
+// Open abre el archivo name del video para servirlo y devuelve su content type.
+func (p *LocalProvider) Open(externalID, name string) (*os.File, string, error) {
+	if _, err := p.Stat(externalID, name); err != nil {
+		return nil, "", err
+	}
+	full, _ := p.filePath(externalID, name)
+	f, err := os.Open(full)
+	if err != nil {
+		return nil, "", fmt.Errorf("local: open %s: %w", name, err)
+	}
+
+	contentType := hlsContentTypes[strings.ToLower(path.Ext(name))]
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	if name == localOriginalFile {
+		if meta, err := p.readMeta(externalID); err == nil {
+			contentType = meta.ContentType
+		}
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return f, contentType, nil
+}
+
+func (p *LocalProvider) videoDir(externalID string) string {
+	return filepath.Join(p.cfg.Dir, externalID)
+}
+
+// filePath resuelve name dentro del directorio del video; rechaza IDs que no sean
+// UUID, rutas que escapen del directorio y el meta.json.
+func (p *LocalProvider) filePath(externalID, name string) (string, error) {
+	if _, err := uuid.Parse(externalID); err != nil {
+		return "", ErrVideoNotFound
+	}
+	clean := path.Clean("/" + name)[1:]
+	if clean == "" || clean == localMetaFile || strings.Contains(clean, ".tmp") {
+		return "", ErrVideoNotFound
+	}
+	return filepath.Join(p.videoDir(externalID), filepath.FromSlash(clean)), nil
+}
+
+func (p *LocalProvider) readMeta(externalID string) (*localMeta, error) {
+	if _, err := uuid.Parse(externalID); err != nil {
+		return nil, ErrVideoNotFound
+	}
+	body, err := os.ReadFile(filepath.Join(p.videoDir(externalID), localMetaFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrVideoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("local: read meta: %w", err)
+	}
+	var meta localMeta
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, fmt.Errorf("local: decode meta: %w", err)
+	}
+	return &meta, nil
+}
+
+func (p *LocalProvider) writeMeta(externalID string, meta *localMeta) error {
+	body, _ := json.MarshalIndent(meta, "", "  ")
+	if err := os.WriteFile(filepath.Join(p.videoDir(externalID), localMetaFile), body, 0o644); err != nil {
+		return fmt.Errorf("local: write meta: %w", err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/qenti/qenti/internal/config"
+)
+
+// LocalImagesPath prefijo de la ruta de la API que sirve las imágenes del
+// ObjectStore local.
+const LocalImagesPath = "/api/v1/media/images"
+
+// ErrObjectNotFound no existe un objeto con esa clave.
+var ErrObjectNotFound = errors.New("storage: object not found")
+
+// ObjectStore guarda archivos bajo una clave compartida por todas las instancias:
+// las variantes de las imágenes (públicas, servidas en URL) y los ZIP de
+// exportación de datos (privados, la API los lee con Get). A diferencia de
+// VideoProvider no hay firma ni estados: las claves no cambian de contenido.
+type ObjectStore interface {
+	Put(key string, data io.Reader, length int64, contentType string) error
+	// Get abre el objeto; ErrObjectNotFound si no existe.
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	URL(key string) string
+}
+
+// NewObjectStore construye el ObjectStore de las imágenes según cfg.Images.Storage.
+func NewObjectStore(cfg *config.Config) (ObjectStore, error) {
+	switch cfg.Images.Storage {
+	case "local", "":
+		return NewLocalObjectStore(cfg.Images.Dir, cfg.Images.BaseURL), nil
+	case "s3":
+		// Mismo bucket que los videos del proveedor s3, bajo images/
+		return NewS3ObjectStore(cfg.S3, cfg.Images.BaseURL)
+	default:
+		return nil, fmt.Errorf("storage: unknown IMAGES_STORAGE=%q (valid: local, s3)", cfg.Images.Storage)
+	}
+}
+
+// NewExportStore construye el ObjectStore privado de las exportaciones de datos
+// según cfg.Privacy.ExportStorage. En local usa PRIVACY_EXPORT_DIR, que la API no
+// sirve, así que con varias instancias tiene que ser un volumen compartido; en s3
+// van al bucket de S3Config bajo exports/ y solo se leen firmando con las
+// credenciales (el bucket no debe ser público).
+func NewExportStore(cfg *config.Config) (ObjectStore, error) {
+	switch cfg.Privacy.ExportStorage {
+	case "local", "":
+		return NewLocalObjectStore(cfg.Privacy.ExportDir, ""), nil
+	case "s3":
+		client, err := newS3Client(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		return &S3ObjectStore{s3: client}, nil
+	default:
+		return nil, fmt.Errorf("storage: unknown PRIVACY_EXPORT_STORAGE=%q (valid: local, s3)", cfg.Privacy.ExportStorage)
+	}
+}
+
+// LocalObjectStore guarda los archivos en disco bajo dir; la API los sirve en
+// LocalImagesPath.
+type LocalObjectStore struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalObjectStore(dir, baseURL string) *LocalObjectStore {
+	return &LocalObjectStore{dir: dir, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Dir directorio raíz que hay que servir en LocalImagesPath.
+func (s *LocalObjectStore) Dir() string { return s.dir }
+
+func (s *LocalObjectStore) Put(key string, data io.Reader, length int64, contentType string) error {
+	file := s.file(key)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("local: create object dir: %w", err)
+	}
+	// Se escribe a un temporal y se renombra para no servir archivos a medias
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".put-*")
+	if err != nil {
+		return fmt.Errorf("local: create object: %w", err)
+	}
+	_, err = io.Copy(tmp, data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("local: write object %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *LocalObjectStore) Get(key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.file(key))
+	if os.IsNotExist(err) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("local: open object %s: %w", key, err)
+	}
+	return f, nil
+}
+
+func (s *LocalObjectStore) Delete(key string) error {
+	if err := os.Remove(s.file(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("local: delete object %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *LocalObjectStore) URL(key string) string {
+	return s.baseURL + LocalImagesPath + "/" + key
+}
+
+func (s *LocalObjectStore) file(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+// S3ObjectStore guarda los archivos en el bucket de S3Config; las URLs se arman
+// con baseURL (CDN o bucket público), no se firman.
+type S3ObjectStore struct {
+	s3      *s3Client
+	baseURL string
+}
+
+func NewS3ObjectStore(cfg config.S3Config, baseURL string) (*S3ObjectStore, error) {
+	client, err := newS3Client(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("s3: IMAGES_BASE_URL is required with IMAGES_STORAGE=s3")
+	}
+	return &S3ObjectStore{s3: client, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+func (s *S3ObjectStore) Put(key string, data io.Reader, length int64, contentType string) error {
+	if err := s.s3.putObject(key, data, length, contentType); err != nil {
+		return fmt.Errorf("s3: put object %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3ObjectStore) Get(key string) (io.ReadCloser, error) {
+	body, err := s.s3.getObject(key)
+	var s3Err *s3Error
+	if errors.As(err, &s3Err) && s3Err.Status == http.StatusNotFound {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("s3: get object %s: %w", key, err)
+	}
+	return body, nil
+}
+
+func (s *S3ObjectStore) Delete(key string) error {
+	if err := s.s3.deleteObject(key); err != nil {
+		return fmt.Errorf("s3: delete object %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3ObjectStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
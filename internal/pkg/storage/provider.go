@@ -2,7 +2,11 @@
 // Swappear el CDN solo requiere una nueva implementación de VideoProvider.
 package storage
 
-import "io"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // UploadResult contiene el ID externo del video y la URL de upload directo (si aplica).
 type UploadResult struct {
@@ -11,10 +15,22 @@ type UploadResult struct {
 	// UploadURL es la URL a la que el cliente puede hacer PUT directamente (upload directo).
 	// Vacío si el proveedor no soporta upload directo.
 	UploadURL string
+	// UploadMethod cómo debe usar el cliente UploadURL: UploadMethodPUT (default si vacío),
+	// UploadMethodPOST o UploadMethodTUS.
+	UploadMethod string
 }
 
+const (
+	// UploadMethodPUT cuerpo crudo del video por PUT.
+	UploadMethodPUT = "PUT"
+	// UploadMethodPOST formulario multipart por POST con el video en el campo "file".
+	UploadMethodPOST = "POST"
+	// UploadMethodTUS protocolo TUS (reanudable) contra UploadURL.
+	UploadMethodTUS = "TUS"
+)
+
 // VideoProvider es la interfaz que debe implementar cualquier proveedor de hosting de video.
-// Implementaciones actuales: BunnyProvider.  Futuros: CloudflareStreamProvider, MuxProvider, S3Provider.
+// Implementaciones actuales: BunnyProvider, CloudflareProvider, LocalProvider, S3Provider.  Futuros: MuxProvider.
 type VideoProvider interface {
 	// CreateVideo reserva un slot en el proveedor y devuelve metadatos de upload.
 	CreateVideo(title string) (*UploadResult, error)
@@ -38,3 +54,159 @@ type VideoProvider interface {
 	// ValidateConnection verifica que las credenciales son correctas y el servicio está accesible.
 	ValidateConnection() error
 }
+
+// MultipartUpload upload multiparte prefirmado: el cliente sube cada parte por PUT
+// directo al bucket y luego confirma con los ETag de cada respuesta.
+type MultipartUpload struct {
+	ExternalID string          `json:"video_id"`
+	UploadID   string          `json:"upload_id"`
+	PartSize   int64           `json:"part_size"`
+	Parts      []PresignedPart `json:"parts"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+}
+
+// PresignedPart URL prefirmada para subir una parte (1-based).
+type PresignedPart struct {
+	PartNumber int    `json:"part_number"`
+	URL        string `json:"url"`
+}
+
+// CompletedPart parte subida, con el ETag que devolvió el bucket.
+type CompletedPart struct {
+	PartNumber int    `json:"part_number" binding:"required"`
+	ETag       string `json:"etag" binding:"required"`
+}
+
+// MultipartProvider lo implementan los proveedores que aceptan uploads multiparte
+// directos al almacenamiento (sin pasar los bytes por la API).
+type MultipartProvider interface {
+	// CreateMultipartUpload reserva un video de `length` bytes y devuelve las URLs de cada parte.
+	CreateMultipartUpload(title, contentType string, length int64) (*MultipartUpload, error)
+	// CompleteMultipartUpload ensambla las partes subidas.
+	CompleteMultipartUpload(externalID, uploadID string, parts []CompletedPart) error
+	// AbortMultipartUpload descarta el upload y las partes ya subidas.
+	AbortMultipartUpload(externalID, uploadID string) error
+}
+
+// HLSPublisher lo implementan los proveedores que almacenan renditions HLS generadas
+// por la API en lugar de codificar ellos mismos (LocalProvider, S3Provider).
+type HLSPublisher interface {
+	// PublishHLS sube el contenido de dir (master.m3u8, playlists, segmentos, póster)
+	// como las renditions HLS del video.
+	PublishHLS(externalID, dir string) error
+}
+
+// HLSPackager lo implementan los proveedores que empaquetan en HLS el original ya
+// subido (S3Provider con S3_PACKAGE_HLS). El empaquetado corre como un trabajo
+// persistente (paquete hlspackage) para reintentarse si falla o la instancia se cae.
+type HLSPackager interface {
+	// PackageHLS remuxa el original del video a HLS y lo publica.
+	PackageHLS(ctx context.Context, externalID string) error
+	// SetPackageQueue registra la cola en la que CompleteUpload encola el empaquetado.
+	SetPackageQueue(enqueue func(ctx context.Context, externalID string) error)
+}
+
+// TUSProvider lo implementan los proveedores que aceptan uploads reanudables (TUS)
+// directos del cliente; requiere conocer el tamaño total de antemano.
+type TUSProvider interface {
+	// CreateTUSUpload reserva un video y devuelve la URL TUS a la que el cliente sube
+	// los `length` bytes (UploadMethod = UploadMethodTUS).
+	CreateTUSUpload(title string, length int64) (*UploadResult, error)
+}
+
+// ChunkStreamer lo implementan los proveedores que aceptan el video en partes
+// secuenciales enviadas desde el servidor (uploads reanudables de la API): cada
+// parte se reenvía apenas se completa en lugar de esperar el archivo entero. El
+// estado es opaco para el llamador, que lo persiste entre partes.
+type ChunkStreamer interface {
+	// ChunkSize tamaño de cada parte salvo la última.
+	ChunkSize() int64
+	// StartChunkedUpload reserva un video de `length` bytes y devuelve su ID y el estado inicial.
+	StartChunkedUpload(title, contentType string, length int64) (externalID, state string, err error)
+	// WriteChunk envía los n bytes que empiezan en offset (múltiplo de ChunkSize) y
+	// devuelve el estado actualizado.
+	WriteChunk(state string, offset int64, data io.Reader, n int64) (string, error)
+	// FinishChunkedUpload cierra el upload una vez enviadas todas las partes.
+	FinishChunkedUpload(state string) error
+	// AbortChunkedUpload descarta el upload y el video reservado.
+	AbortChunkedUpload(state string) error
+}
+
+// Tipos de pista adicional de un video (TrackFile.Kind).
+const (
+	TrackKindSubtitles = "subtitles" // subtítulos WebVTT
+	TrackKindAudio     = "audio"     // audio alternativo en otro idioma
+)
+
+// TrackFile pista adicional de un video: subtítulos o audio en otro idioma.
+type TrackFile struct {
+	Kind string // TrackKind*
+	// Language código BCP 47 (es-419, pt-BR, en)
+	Language    string
+	Label       string
+	ContentType string
+	// Ext extensión del archivo sin punto (vtt, m4a, mp3, aac)
+	Ext string
+}
+
+// Name ruta del archivo de la pista relativa al video: tracks/<kind>/<language>.<ext>.
+func (t TrackFile) Name() string {
+	return "tracks/" + t.Kind + "/" + t.Language + "." + t.Ext
+}
+
+// TrackProvider lo implementan los proveedores que guardan pistas adicionales junto
+// al video y sirven cada una como archivo aparte (subtítulos sidecar, audio
+// alternativo), con la URL firmada igual que la de reproducción.
+type TrackProvider interface {
+	// SupportsTrack indica si el proveedor acepta pistas de ese tipo (TrackKind*).
+	SupportsTrack(kind string) bool
+	// UploadTrack sube (o reemplaza) la pista del video.
+	UploadTrack(externalID string, track TrackFile, data io.Reader, length int64) error
+	// GetTrackURL genera la URL de la pista (firmada si aplica) con TTL en minutos.
+	GetTrackURL(externalID string, track TrackFile, expirationMinutes int) (string, error)
+	// DeleteTrack borra la pista. Borrar una pista inexistente no es error.
+	DeleteTrack(externalID string, track TrackFile) error
+}
+
+// Estados del ciclo de vida del video de un episodio (episodes.video_status).
+const (
+	VideoStatusCreated    = "created"    // sin video todavía
+	VideoStatusUploading  = "uploading"  // upload iniciado, el proveedor aún no tiene el archivo completo
+	VideoStatusProcessing = "processing" // archivo recibido, el proveedor lo está codificando
+	VideoStatusReady      = "ready"      // reproducible
+	VideoStatusFailed     = "failed"     // el upload o la codificación fallaron
+)
+
+// VideoStatus estado de un video según el proveedor.
+type VideoStatus struct {
+	Status string `json:"status"` // VideoStatus*
+	// Progress avance de la codificación 0-100 (si el proveedor lo informa)
+	Progress int `json:"progress,omitempty"`
+	// Detail mensaje del proveedor cuando Status es VideoStatusFailed
+	Detail string `json:"detail,omitempty"`
+	// Media datos del video que informa el proveedor (Bunny, Cloudflare) cuando
+	// Status es VideoStatusReady; nil si no los informa
+	Media *VideoMedia `json:"-"`
+}
+
+// VideoMedia duración y dimensiones de un video según el proveedor. Los valores
+// desconocidos quedan en cero.
+type VideoMedia struct {
+	DurationSeconds float64
+	Width           int
+	Height          int
+}
+
+// StatusProvider lo implementan los proveedores que pueden informar en qué etapa
+// está un video. Para el resto, un video subido se considera listo.
+type StatusProvider interface {
+	GetVideoStatus(externalID string) (*VideoStatus, error)
+}
+
+// SourceProvider lo implementan los proveedores que guardan el original tal cual
+// lo subió el cliente (S3, local), para poder inspeccionarlo con ffprobe.
+type SourceProvider interface {
+	// SourceURL devuelve una ubicación legible del original: una URL firmada con
+	// vigencia ttl o una ruta en disco.
+	SourceURL(externalID string, ttl time.Duration) (string, error)
+}
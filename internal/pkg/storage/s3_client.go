@@ -0,0 +1,374 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3EmptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" // sha256("")
+	s3TimeFormat      = "20060102T150405Z"
+)
+
+// s3Client cliente mínimo de la API de S3 (AWS, MinIO, R2, Spaces, ...) firmado con
+// AWS Signature V4. Cubre solo las operaciones que usa S3Provider.
+type s3Client struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+}
+
+// s3Error respuesta de error de S3 (<Error><Code>...</Code><Message>...</Message></Error>).
+type s3Error struct {
+	Status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	return fmt.Sprintf("S3 %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// objectURL URL del objeto key (path-style: endpoint/bucket/key; virtual-hosted: bucket.endpoint/key).
+// El path va codificado como se firma (RawPath): el encoding de net/url deja sin
+// escapar caracteres como '+' o '=' y la firma no coincidiría con lo enviado.
+func (c *s3Client) objectURL(key string) *url.URL {
+	u := *c.endpoint
+	if c.pathStyle {
+		u.Path = "/" + c.bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = c.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = s3CanonicalPath(u.Path)
+	return &u
+}
+
+// do ejecuta una operación firmada. body se envía como UNSIGNED-PAYLOAD salvo que
+// sea un *bytes.Reader (cuerpos XML chicos), que se firma con su hash.
+func (c *s3Client) do(method, key string, query url.Values, body io.Reader, length int64, headers map[string]string) (*http.Response, error) {
+	req, err := c.newRequest(time.Now().UTC(), method, key, query, body, length, headers)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.Do(req)
+}
+
+// newRequest arma el request de do firmado con la hora now.
+func (c *s3Client) newRequest(now time.Time, method, key string, query url.Values, body io.Reader, length int64, headers map[string]string) (*http.Request, error) {
+	u := c.objectURL(key)
+	u.RawQuery = s3CanonicalQuery(query)
+
+	payloadHash := s3EmptyPayload
+	switch b := body.(type) {
+	case nil:
+	case *bytes.Reader:
+		raw, _ := io.ReadAll(b)
+		sum := sha256.Sum256(raw)
+		payloadHash = hex.EncodeToString(sum[:])
+		body, length = bytes.NewReader(raw), int64(len(raw))
+	default:
+		payloadHash = s3UnsignedPayload
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = length
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	canonicalHeaders := "host:" + u.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + now.Format(s3TimeFormat) + "\n"
+	signature := c.signature(now, method, u.RawPath, u.RawQuery, canonicalHeaders, strings.Join(signed, ";"), payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, c.scope(now), strings.Join(signed, ";"), signature))
+	return req, nil
+}
+
+// presign genera una URL prefirmada (query-string SigV4) válida por ttl (máximo 7 días).
+func (c *s3Client) presign(method, key string, query url.Values, ttl time.Duration) string {
+	return c.presignAt(time.Now().UTC(), method, key, query, ttl)
+}
+
+func (c *s3Client) presignAt(now time.Time, method, key string, query url.Values, ttl time.Duration) string {
+	if ttl > 7*24*time.Hour {
+		ttl = 7 * 24 * time.Hour
+	}
+	u := c.objectURL(key)
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", c.accessKey+"/"+c.scope(now))
+	q.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalQuery := s3CanonicalQuery(q)
+	signature := c.signature(now, method, u.RawPath, canonicalQuery, "host:"+u.Host+"\n", "host", s3UnsignedPayload)
+	u.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+	return u.String()
+}
+
+func (c *s3Client) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + c.region + "/s3/aws4_request"
+}
+
+func (c *s3Client) signature(now time.Time, method, path, query, headers, signedHeaders, payloadHash string) string {
+	canonicalRequest := strings.Join([]string{method, path, query, headers, signedHeaders, payloadHash}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format(s3TimeFormat) + "\n" + c.scope(now) + "\n" + hex.EncodeToString(hash[:])
+
+	key := s3HMAC([]byte("AWS4"+c.secretKey), now.Format("20060102"))
+	key = s3HMAC(key, c.region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	return hex.EncodeToString(s3HMAC(key, stringToSign))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape codifica según SigV4: todo salvo A-Z a-z 0-9 - _ . ~ (y '/' si keepSlash).
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || (keepSlash && ch == '/') {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func s3CanonicalPath(p string) string {
+	return s3Escape(p, true)
+}
+
+// s3CanonicalQuery query ordenada por clave y valor, con el encoding estricto de SigV4.
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Check devuelve un *s3Error si la respuesta no es 2xx (y cierra el body en ese caso).
+func s3Check(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	e := &s3Error{Status: resp.StatusCode}
+	raw, _ := io.ReadAll(resp.Body)
+	if xml.Unmarshal(raw, e) != nil || e.Code == "" {
+		e.Code, e.Message = http.StatusText(resp.StatusCode), string(raw)
+	}
+	return nil, e
+}
+
+func (c *s3Client) putObject(key string, body io.Reader, length int64, contentType string) error {
+	resp, err := s3Check(c.do("PUT", key, nil, body, length, map[string]string{"Content-Type": contentType}))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *s3Client) getObject(key string) (io.ReadCloser, error) {
+	resp, err := s3Check(c.do("GET", key, nil, nil, 0, nil))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// headObject indica si el objeto existe.
+func (c *s3Client) headObject(key string) (bool, error) {
+	resp, err := c.do("HEAD", key, nil, nil, 0, nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return true, nil
+	}
+	return false, &s3Error{Status: resp.StatusCode, Code: http.StatusText(resp.StatusCode)}
+}
+
+func (c *s3Client) headBucket() error {
+	resp, err := s3Check(c.do("HEAD", "", nil, nil, 0, nil))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *s3Client) deleteObject(key string) error {
+	resp, err := s3Check(c.do("DELETE", key, nil, nil, 0, nil))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listKeys lista todas las claves bajo prefix (ListObjectsV2 paginado).
+func (c *s3Client) listKeys(prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := s3Check(c.do("GET", "", q, nil, 0, nil))
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode list: %w", err)
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, obj.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (c *s3Client) createMultipartUpload(key, contentType string) (string, error) {
+	resp, err := s3Check(c.do("POST", key, url.Values{"uploads": {""}}, nil, 0, map[string]string{"Content-Type": contentType}))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("decode multipart upload: %v", err)
+	}
+	return result.UploadID, nil
+}
+
+// completeMultipartUpload ensambla las partes. S3 puede responder 200 con un
+// <Error> en el cuerpo, por eso se revisa también el XML.
+func (c *s3Client) completeMultipartUpload(key, uploadID string, parts []CompletedPart) error {
+	type part struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	payload := struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}{}
+	sorted := append([]CompletedPart(nil), parts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PartNumber < sorted[j].PartNumber })
+	for _, p := range sorted {
+		payload.Parts = append(payload.Parts, part{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+	body, _ := xml.Marshal(payload)
+
+	resp, err := s3Check(c.do("POST", key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body), 0,
+		map[string]string{"Content-Type": "application/xml"}))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	if bytes.Contains(raw, []byte("<Error>")) {
+		e := &s3Error{Status: resp.StatusCode}
+		xml.Unmarshal(raw, e)
+		return e
+	}
+	return nil
+}
+
+// uploadPart sube una parte de un upload multiparte y devuelve su ETag.
+func (c *s3Client) uploadPart(key, uploadID string, partNumber int, body io.Reader, length int64) (string, error) {
+	q := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+	resp, err := s3Check(c.do("PUT", key, q, body, length, nil))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("upload part %d: missing ETag", partNumber)
+	}
+	return etag, nil
+}
+
+func (c *s3Client) abortMultipartUpload(key, uploadID string) error {
+	resp, err := s3Check(c.do("DELETE", key, url.Values{"uploadId": {uploadID}}, nil, 0, nil))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
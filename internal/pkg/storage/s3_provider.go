@@ -0,0 +1,598 @@
+package storage
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+)
+
+// S3MediaPath prefijo de las rutas de la API que sirven los playlists HLS del
+// proveedor S3 con las URLs de los segmentos firmadas.
+const S3MediaPath = "/api/v1/media/s3"
+
+const (
+	s3OriginalFile   = "original"
+	s3HLSDir         = "hls"
+	s3HLSMaster      = "master.m3u8"
+	s3UploadURLTTL   = 6 * time.Hour
+	s3MinPartSize    = 16 << 20
+	s3MaxParts       = 10000
+	s3HLSCheckTTL    = time.Minute
+	s3PackageTimeout = 30 * time.Minute
+)
+
+// hlsURIAttr atributos URI="..." de los tags HLS (EXT-X-MAP, EXT-X-MEDIA, ...).
+var hlsURIAttr = regexp.MustCompile(`URI="([^"]+)"`)
+
+// hlsContentTypes content types de los archivos de una salida HLS.
+var hlsContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+	".m4s":  "video/iso.segment",
+	".mp4":  "video/mp4",
+	".aac":  "audio/aac",
+	".vtt":  "text/vtt",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".jpg":  "image/jpeg",
+	".webp": "image/webp",
+}
+
+// s3HLSState cache de si un video ya tiene HLS publicado (los negativos expiran).
+type s3HLSState struct {
+	ready   bool
+	checked time.Time
+}
+
+// S3Provider implementa VideoProvider (y MultipartProvider/HLSPublisher/HLSPackager/ChunkStreamer/TrackProvider) sobre
+// cualquier bucket compatible con S3. Cada video vive bajo <Prefix><id>/: el
+// archivo original y, una vez empaquetado, hls/master.m3u8 con sus playlists y
+// segmentos.
+//
+// Como una URL prefirmada cubre un solo objeto, con HLS la reproducción pasa por
+// la API: GetPlaybackURL devuelve S3MediaPath/<expires>/<firma>/<id>/master.m3u8 y
+// ese handler reescribe cada segmento a una URL firmada (SigV4 o CloudFront). Los
+// playlists hijos se dejan relativos y heredan el token del path.
+type S3Provider struct {
+	cfg    config.S3Config
+	s3     *s3Client
+	signer mediaSigner
+
+	cloudFrontKey *rsa.PrivateKey
+
+	hlsState sync.Map // id → s3HLSState
+
+	// packageQueue encola el empaquetado HLS (ver SetPackageQueue)
+	packageQueue func(ctx context.Context, externalID string) error
+}
+
+func NewS3Provider(cfg config.S3Config) (*S3Provider, error) {
+	client, err := newS3Client(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PublicBaseURL = strings.TrimRight(cfg.PublicBaseURL, "/")
+	cfg.PlaylistBaseURL = strings.TrimRight(cfg.PlaylistBaseURL, "/")
+
+	p := &S3Provider{
+		cfg:    cfg,
+		s3:     client,
+		signer: mediaSigner{key: []byte(cfg.PlaylistSigningKey)},
+	}
+
+	switch cfg.PlaybackMode {
+	case "presigned":
+	case "public":
+		if cfg.PublicBaseURL == "" {
+			return nil, fmt.Errorf("s3: S3_PUBLIC_BASE_URL is required with S3_PLAYBACK_MODE=public")
+		}
+	case "cloudfront":
+		if cfg.PublicBaseURL == "" || cfg.CloudFrontKeyPairID == "" {
+			return nil, fmt.Errorf("s3: S3_PUBLIC_BASE_URL and CLOUDFRONT_KEY_PAIR_ID are required with S3_PLAYBACK_MODE=cloudfront")
+		}
+		if p.cloudFrontKey, err = parseRSAPrivateKey(cfg.CloudFrontPrivateKey); err != nil {
+			return nil, fmt.Errorf("s3: invalid CLOUDFRONT_PRIVATE_KEY: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("s3: unknown S3_PLAYBACK_MODE=%q (valid: presigned, cloudfront, public)", cfg.PlaybackMode)
+	}
+	return p, nil
+}
+
+// newS3Client arma el cliente del bucket de cfg (endpoint vacío = AWS en cfg.Region).
+func newS3Client(cfg config.S3Config) (*s3Client, error) {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3: invalid S3_ENDPOINT %q", cfg.Endpoint)
+	}
+	return &s3Client{
+		endpoint:  endpoint,
+		region:    cfg.Region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		pathStyle: cfg.ForcePathStyle,
+		client:    &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
+
+func (p *S3Provider) ProviderName() string { return "s3" }
+
+// key clave de un archivo del video dentro del bucket.
+func (p *S3Provider) key(externalID, name string) string {
+	return p.cfg.Prefix + externalID + "/" + name
+}
+
+// CreateVideo reserva un ID y devuelve una URL prefirmada para subir el original
+// por PUT directo al bucket (hasta 5 GB; para más, o reanudable, usar multipart).
+func (p *S3Provider) CreateVideo(title string) (*UploadResult, error) {
+	id := uuid.New().String()
+	return &UploadResult{
+		ExternalID:   id,
+		UploadURL:    p.s3.presign("PUT", p.key(id, s3OriginalFile), nil, s3UploadURLTTL),
+		UploadMethod: UploadMethodPUT,
+	}, nil
+}
+
+// UploadVideo sube el original desde el servidor en un solo PUT.
+func (p *S3Provider) UploadVideo(externalID string, data io.Reader, contentType string, contentLength int64) error {
+	if contentLength <= 0 {
+		return fmt.Errorf("s3: upload requires the content length")
+	}
+	if err := p.s3.putObject(p.key(externalID, s3OriginalFile), data, contentLength, contentType); err != nil {
+		return fmt.Errorf("s3: upload: %w", err)
+	}
+	return p.dropHLS(externalID)
+}
+
+// CreateMultipartUpload inicia un upload multiparte del original y prefirma la URL
+// de cada parte. El tamaño de parte crece con el archivo para no pasar de 10.000 partes.
+func (p *S3Provider) CreateMultipartUpload(title, contentType string, length int64) (*MultipartUpload, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("s3: multipart upload requires the file size")
+	}
+	partSize := int64(s3MinPartSize)
+	if min := (length + s3MaxParts - 1) / s3MaxParts; min > partSize {
+		partSize = (min + 1<<20 - 1) / (1 << 20) * (1 << 20) // redondeado a MiB
+	}
+	partCount := int((length + partSize - 1) / partSize)
+
+	id := uuid.New().String()
+	key := p.key(id, s3OriginalFile)
+	uploadID, err := p.s3.createMultipartUpload(key, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("s3: create multipart upload: %w", err)
+	}
+
+	upload := &MultipartUpload{
+		ExternalID: id,
+		UploadID:   uploadID,
+		PartSize:   partSize,
+		Parts:      make([]PresignedPart, 0, partCount),
+		ExpiresAt:  time.Now().Add(s3UploadURLTTL),
+	}
+	for n := 1; n <= partCount; n++ {
+		q := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {uploadID}}
+		upload.Parts = append(upload.Parts, PresignedPart{PartNumber: n, URL: p.s3.presign("PUT", key, q, s3UploadURLTTL)})
+	}
+	return upload, nil
+}
+
+// CompleteMultipartUpload ensambla las partes en el original.
+func (p *S3Provider) CompleteMultipartUpload(externalID, uploadID string, parts []CompletedPart) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("s3: no parts to complete")
+	}
+	if err := p.s3.completeMultipartUpload(p.key(externalID, s3OriginalFile), uploadID, parts); err != nil {
+		return fmt.Errorf("s3: complete multipart upload: %w", err)
+	}
+	return p.dropHLS(externalID)
+}
+
+// AbortMultipartUpload descarta el upload y las partes ya subidas.
+func (p *S3Provider) AbortMultipartUpload(externalID, uploadID string) error {
+	if err := p.s3.abortMultipartUpload(p.key(externalID, s3OriginalFile), uploadID); err != nil {
+		return fmt.Errorf("s3: abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+// s3ChunkState estado de un upload por partes (ChunkStreamer): un multiparte del
+// original cuyas partes envía la API a medida que las recibe.
+type s3ChunkState struct {
+	VideoID  string          `json:"video_id"`
+	UploadID string          `json:"upload_id"`
+	Parts    []CompletedPart `json:"parts"`
+}
+
+// ChunkSize tamaño de las partes reenviadas (mínimo de S3 salvo la última).
+func (p *S3Provider) ChunkSize() int64 { return s3MinPartSize }
+
+// StartChunkedUpload inicia un upload multiparte del original de un video nuevo.
+func (p *S3Provider) StartChunkedUpload(title, contentType string, length int64) (string, string, error) {
+	if length > s3MinPartSize*s3MaxParts {
+		return "", "", fmt.Errorf("s3: %d bytes exceeds the chunked upload limit", length)
+	}
+	id := uuid.New().String()
+	uploadID, err := p.s3.createMultipartUpload(p.key(id, s3OriginalFile), contentType)
+	if err != nil {
+		return "", "", fmt.Errorf("s3: create multipart upload: %w", err)
+	}
+	state, _ := json.Marshal(s3ChunkState{VideoID: id, UploadID: uploadID})
+	return id, string(state), nil
+}
+
+// WriteChunk sube la parte que empieza en offset y agrega su ETag al estado.
+func (p *S3Provider) WriteChunk(state string, offset int64, data io.Reader, n int64) (string, error) {
+	var st s3ChunkState
+	if err := json.Unmarshal([]byte(state), &st); err != nil || st.UploadID == "" {
+		return "", fmt.Errorf("s3: invalid chunked upload state")
+	}
+	partNumber := int(offset/s3MinPartSize) + 1
+	etag, err := p.s3.uploadPart(p.key(st.VideoID, s3OriginalFile), st.UploadID, partNumber, data, n)
+	if err != nil {
+		return "", fmt.Errorf("s3: upload part: %w", err)
+	}
+	st.Parts = append(st.Parts, CompletedPart{PartNumber: partNumber, ETag: etag})
+	next, _ := json.Marshal(st)
+	return string(next), nil
+}
+
+// FinishChunkedUpload ensambla las partes enviadas.
+func (p *S3Provider) FinishChunkedUpload(state string) error {
+	var st s3ChunkState
+	if err := json.Unmarshal([]byte(state), &st); err != nil {
+		return fmt.Errorf("s3: invalid chunked upload state")
+	}
+	return p.CompleteMultipartUpload(st.VideoID, st.UploadID, st.Parts)
+}
+
+// AbortChunkedUpload descarta el multiparte y las partes ya subidas.
+func (p *S3Provider) AbortChunkedUpload(state string) error {
+	var st s3ChunkState
+	if err := json.Unmarshal([]byte(state), &st); err != nil {
+		return fmt.Errorf("s3: invalid chunked upload state")
+	}
+	return p.AbortMultipartUpload(st.VideoID, st.UploadID)
+}
+
+// CompleteUpload verifica que el original esté en el bucket y, si PackageHLS está
+// activo, encola su empaquetado en HLS. El original se puede reproducir mientras tanto.
+func (p *S3Provider) CompleteUpload(externalID string) error {
+	exists, err := p.s3.headObject(p.key(externalID, s3OriginalFile))
+	if err != nil {
+		return fmt.Errorf("s3: complete upload: %w", err)
+	}
+	if !exists {
+		return ErrVideoNotFound
+	}
+	if p.cfg.PackageHLS {
+		if p.packageQueue == nil {
+			log.Printf("s3: package HLS %s: no package queue configured", externalID)
+			return nil
+		}
+		if err := p.packageQueue(context.Background(), externalID); err != nil {
+			log.Printf("s3: package HLS %s: %v", externalID, err) // no crítico
+		}
+	}
+	return nil
+}
+
+// SetPackageQueue registra la cola de trabajos en la que CompleteUpload encola el
+// empaquetado HLS, para que se reintente si falla o la instancia se reinicia.
+func (p *S3Provider) SetPackageQueue(enqueue func(ctx context.Context, externalID string) error) {
+	p.packageQueue = enqueue
+}
+
+// GetVideoStatus: el original es reproducible (el HLS es una mejora opcional), así
+// que el video está listo en cuanto existe en el bucket.
+func (p *S3Provider) GetVideoStatus(externalID string) (*VideoStatus, error) {
+	exists, err := p.s3.headObject(p.key(externalID, s3OriginalFile))
+	if err != nil {
+		return nil, fmt.Errorf("s3: video status: %w", err)
+	}
+	if !exists {
+		return &VideoStatus{Status: VideoStatusUploading}, nil
+	}
+	return &VideoStatus{Status: VideoStatusReady}, nil
+}
+
+// SourceURL URL firmada de lectura del original subido.
+func (p *S3Provider) SourceURL(externalID string, ttl time.Duration) (string, error) {
+	return p.s3.presign("GET", p.key(externalID, s3OriginalFile), nil, ttl), nil
+}
+
+// PackageHLS remuxa el original a HLS (sin recodificar) y lo publica. Un original
+// que ya no está en el bucket devuelve ErrVideoNotFound.
+func (p *S3Provider) PackageHLS(ctx context.Context, externalID string) error {
+	exists, err := p.s3.headObject(p.key(externalID, s3OriginalFile))
+	if err != nil {
+		return fmt.Errorf("s3: package HLS: %w", err)
+	}
+	if !exists {
+		return ErrVideoNotFound
+	}
+
+	dir, err := os.MkdirTemp("", "qenti-hls-*")
+	if err != nil {
+		return fmt.Errorf("s3: package HLS: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, s3PackageTimeout)
+	defer cancel()
+	source := p.s3.presign("GET", p.key(externalID, s3OriginalFile), nil, s3PackageTimeout)
+	if err := RemuxHLS(ctx, p.cfg.FFmpegPath, source, dir); err != nil {
+		return fmt.Errorf("s3: package HLS: %w", err)
+	}
+	return p.PublishHLS(externalID, dir)
+}
+
+// PublishHLS sube el contenido de dir bajo <id>/hls/. master.m3u8 se sube al final,
+// así la reproducción pasa a HLS solo cuando todos los segmentos ya están.
+func (p *S3Provider) PublishHLS(externalID, dir string) error {
+	var files []string
+	err := filepath.WalkDir(dir, func(full string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, full)
+		if filepath.ToSlash(rel) != s3HLSMaster {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("s3: read HLS dir: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, s3HLSMaster)); err != nil {
+		return fmt.Errorf("s3: HLS dir has no %s", s3HLSMaster)
+	}
+	files = append(files, s3HLSMaster)
+
+	for _, rel := range files {
+		if err := p.putFile(p.key(externalID, s3HLSDir+"/"+rel), filepath.Join(dir, filepath.FromSlash(rel))); err != nil {
+			return fmt.Errorf("s3: publish %s: %w", rel, err)
+		}
+	}
+	p.hlsState.Store(externalID, s3HLSState{ready: true, checked: time.Now()})
+	return nil
+}
+
+func (p *S3Provider) putFile(key, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	contentType := hlsContentTypes[strings.ToLower(path.Ext(file))]
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return p.s3.putObject(key, f, info.Size(), contentType)
+}
+
+// hasHLS indica si el video tiene HLS publicado (con cache en memoria).
+func (p *S3Provider) hasHLS(externalID string) (bool, error) {
+	if v, ok := p.hlsState.Load(externalID); ok {
+		state := v.(s3HLSState)
+		if state.ready || time.Since(state.checked) < s3HLSCheckTTL {
+			return state.ready, nil
+		}
+	}
+	ready, err := p.s3.headObject(p.key(externalID, s3HLSDir+"/"+s3HLSMaster))
+	if err != nil {
+		return false, err
+	}
+	p.hlsState.Store(externalID, s3HLSState{ready: ready, checked: time.Now()})
+	return ready, nil
+}
+
+// GetPlaybackURL devuelve el master HLS si ya está empaquetado y, si no, el original
+// (MP4 progresivo), firmados según PlaybackMode.
+func (p *S3Provider) GetPlaybackURL(externalID string, expirationMinutes int) (string, error) {
+	if externalID == "" {
+		return "", fmt.Errorf("s3: empty video ID")
+	}
+	ttl := time.Duration(expirationMinutes) * time.Minute
+
+	hls, err := p.hasHLS(externalID)
+	if err != nil {
+		return "", fmt.Errorf("s3: playback: %w", err)
+	}
+	switch {
+	case hls && p.cfg.PlaybackMode == "public":
+		return p.cfg.PublicBaseURL + "/" + p.key(externalID, s3HLSDir+"/"+s3HLSMaster), nil
+	case hls:
+		return p.cfg.PlaylistBaseURL + S3MediaPath + p.signer.signedPath("GET", externalID, ttl) + "/" + s3HLSMaster, nil
+	}
+	return p.objectURL(p.key(externalID, s3OriginalFile), ttl)
+}
+
+// objectURL URL de reproducción de un objeto según PlaybackMode.
+func (p *S3Provider) objectURL(key string, ttl time.Duration) (string, error) {
+	switch p.cfg.PlaybackMode {
+	case "public":
+		return p.cfg.PublicBaseURL + "/" + key, nil
+	case "cloudfront":
+		return p.cloudFrontURL(p.cfg.PublicBaseURL+"/"+key, time.Now().Add(ttl))
+	}
+	return p.s3.presign("GET", key, nil, ttl), nil
+}
+
+// cloudFrontURL firma rawURL con una política canned de CloudFront (RSA-SHA1).
+func (p *S3Provider) cloudFrontURL(rawURL string, expires time.Time) (string, error) {
+	policy := fmt.Sprintf(`{"Statement":[{"Resource":"%s","Condition":{"DateLessThan":{"AWS:EpochTime":%d}}}]}`, rawURL, expires.Unix())
+	hash := sha1.Sum([]byte(policy))
+	sig, err := rsa.SignPKCS1v15(nil, p.cloudFrontKey, crypto.SHA1, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("s3: sign CloudFront URL: %w", err)
+	}
+	encoded := strings.NewReplacer("+", "-", "=", "_", "/", "~").Replace(base64.StdEncoding.EncodeToString(sig))
+	return fmt.Sprintf("%s?Expires=%d&Signature=%s&Key-Pair-Id=%s", rawURL, expires.Unix(), encoded, p.cfg.CloudFrontKeyPairID), nil
+}
+
+// VerifyPlaylist valida el token del path de un playlist servido por la API.
+func (p *S3Provider) VerifyPlaylist(externalID, expires, signature string) error {
+	return p.signer.verify("GET", externalID, expires, signature)
+}
+
+// Playlist lee el playlist name (relativo a hls/) y reescribe las URIs de segmentos
+// y demás archivos a URLs firmadas con vigencia ttl. Las URIs de otros playlists se
+// dejan relativas para que sigan pasando por la API.
+func (p *S3Provider) Playlist(externalID, name string, ttl time.Duration) ([]byte, error) {
+	if _, err := uuid.Parse(externalID); err != nil {
+		return nil, ErrVideoNotFound
+	}
+	name = path.Clean("/" + name)[1:]
+	if !strings.HasSuffix(name, ".m3u8") {
+		return nil, ErrVideoNotFound
+	}
+
+	body, err := p.s3.getObject(p.key(externalID, s3HLSDir+"/"+name))
+	var s3Err *s3Error
+	if errors.As(err, &s3Err) && s3Err.Status == 404 {
+		return nil, ErrVideoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("s3: read playlist: %w", err)
+	}
+	defer body.Close()
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("s3: read playlist: %w", err)
+	}
+
+	var signErr error
+	sign := func(uri string) string {
+		if strings.Contains(uri, "://") || strings.HasSuffix(strings.SplitN(uri, "?", 2)[0], ".m3u8") {
+			return uri
+		}
+		rel := path.Join(path.Dir(name), uri)
+		if strings.HasPrefix(rel, "../") {
+			return uri // fuera de hls/: no se firma
+		}
+		signed, err := p.objectURL(p.key(externalID, s3HLSDir+"/"+rel), ttl)
+		if err != nil {
+			signErr = err
+		}
+		return signed
+	}
+
+	lines := strings.Split(string(raw), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			lines[i] = hlsURIAttr.ReplaceAllStringFunc(line, func(attr string) string {
+				return `URI="` + sign(hlsURIAttr.FindStringSubmatch(attr)[1]) + `"`
+			})
+		default:
+			lines[i] = sign(trimmed)
+		}
+	}
+	if signErr != nil {
+		return nil, signErr
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// SupportsTrack: subtítulos y audio alternativo se guardan como objetos del video.
+func (p *S3Provider) SupportsTrack(kind string) bool { return true }
+
+// UploadTrack guarda la pista en <Prefix><id>/tracks/; DeleteVideo la borra con el resto.
+func (p *S3Provider) UploadTrack(externalID string, track TrackFile, data io.Reader, length int64) error {
+	if _, err := uuid.Parse(externalID); err != nil {
+		return ErrVideoNotFound
+	}
+	if err := p.s3.putObject(p.key(externalID, track.Name()), data, length, track.ContentType); err != nil {
+		return fmt.Errorf("s3: upload track: %w", err)
+	}
+	return nil
+}
+
+// GetTrackURL firma la pista según PlaybackMode, como los segmentos HLS.
+func (p *S3Provider) GetTrackURL(externalID string, track TrackFile, expirationMinutes int) (string, error) {
+	return p.objectURL(p.key(externalID, track.Name()), time.Duration(expirationMinutes)*time.Minute)
+}
+
+func (p *S3Provider) DeleteTrack(externalID string, track TrackFile) error {
+	if err := p.s3.deleteObject(p.key(externalID, track.Name())); err != nil {
+		return fmt.Errorf("s3: delete track: %w", err)
+	}
+	return nil
+}
+
+// DeleteVideo borra todos los objetos del video (original y HLS).
+func (p *S3Provider) DeleteVideo(externalID string) error {
+	if _, err := uuid.Parse(externalID); err != nil {
+		return ErrVideoNotFound
+	}
+	if err := p.deletePrefix(p.key(externalID, "")); err != nil {
+		return fmt.Errorf("s3: delete video: %w", err)
+	}
+	p.hlsState.Delete(externalID)
+	return nil
+}
+
+// dropHLS borra el HLS de un original reemplazado, para no reproducir el anterior
+// hasta que se vuelva a empaquetar.
+func (p *S3Provider) dropHLS(externalID string) error {
+	p.hlsState.Delete(externalID)
+	if err := p.deletePrefix(p.key(externalID, s3HLSDir+"/")); err != nil {
+		return fmt.Errorf("s3: drop stale HLS: %w", err)
+	}
+	return nil
+}
+
+func (p *S3Provider) deletePrefix(prefix string) error {
+	keys, err := p.s3.listKeys(prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := p.s3.deleteObject(key); err != nil {
+			return fmt.Errorf("delete %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ValidateConnection verifica la configuración y el acceso al bucket.
+func (p *S3Provider) ValidateConnection() error {
+	if p.cfg.Bucket == "" || p.cfg.AccessKeyID == "" || p.cfg.SecretAccessKey == "" {
+		return fmt.Errorf("s3: S3_BUCKET/S3_ACCESS_KEY_ID/S3_SECRET_ACCESS_KEY not configured")
+	}
+	if err := p.s3.headBucket(); err != nil {
+		return fmt.Errorf("s3: validate: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,464 @@
+// Package taxonomy guarda los géneros (curados por el super_admin) y las etiquetas
+// libres de las series, sus asignaciones y los conteos que usa la app para armar
+// filas por género y filtrar el catálogo.
+package taxonomy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/search"
+)
+
+const (
+	// MaxSeriesGenres y MaxSeriesTags límites de términos por serie.
+	MaxSeriesGenres = 5
+	MaxSeriesTags   = 20
+	// MaxTagLength largo máximo (en caracteres) del nombre de una etiqueta.
+	MaxTagLength = 60
+)
+
+var (
+	// ErrSlugTaken ya hay un género con ese slug.
+	ErrSlugTaken = errors.New("slug already in use")
+	// ErrUnknownGenre algún slug de género no existe o el género está inactivo.
+	ErrUnknownGenre = errors.New("unknown genre")
+)
+
+// Slugify arma el slug de un género o etiqueta: minúsculas, sin acentos y con
+// guiones entre palabras ("Segunda Oportunidad" → "segunda-oportunidad").
+func Slugify(name string) string {
+	return strings.ReplaceAll(search.Normalize(name), " ", "-")
+}
+
+// LocalizedName devuelve el nombre del género en el primer idioma de la cadena que
+// tenga traducción, o el del idioma por defecto.
+func LocalizedName(g *models.Genre, chain []string) string {
+	for _, l := range chain {
+		if name := g.Translations[l]; name != "" {
+			return name
+		}
+	}
+	return g.Name
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// uuidArray pasa los IDs como text[] (castear a uuid[] en la consulta).
+func uuidArray(ids []uuid.UUID) interface{} {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+	return pq.Array(keys)
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+const (
+	genreColumns = `id, slug, name, translations, position, is_active, created_at, updated_at`
+	// genreSelect genreColumns con el alias g, para las consultas con joins
+	genreSelect = `g.id, g.slug, g.name, g.translations, g.position, g.is_active, g.created_at, g.updated_at`
+)
+
+// scanGenre lee las columnas de genreColumns y después las de extra.
+func scanGenre(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.Genre, error) {
+	var g models.Genre
+	var translations []byte
+	dest := []interface{}{&g.ID, &g.Slug, &g.Name, &translations, &g.Position, &g.IsActive, &g.CreatedAt, &g.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(translations, &g.Translations); err != nil {
+		return nil, fmt.Errorf("failed to decode genre translations: %w", err)
+	}
+	if g.Translations == nil {
+		g.Translations = map[string]string{}
+	}
+	return &g, nil
+}
+
+// ListGenres lista los géneros por posición y nombre; activeOnly deja afuera los inactivos.
+func (r *Repository) ListGenres(ctx context.Context, activeOnly bool) ([]models.Genre, error) {
+	query := `SELECT ` + genreColumns + ` FROM genres`
+	if activeOnly {
+		query += ` WHERE is_active = TRUE`
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY position, name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list genres: %w", err)
+	}
+	defer rows.Close()
+
+	var list []models.Genre
+	for rows.Next() {
+		g, err := scanGenre(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan genre: %w", err)
+		}
+		list = append(list, *g)
+	}
+	return list, rows.Err()
+}
+
+// GetGenre devuelve el género o nil si no existe.
+func (r *Repository) GetGenre(ctx context.Context, id uuid.UUID) (*models.Genre, error) {
+	g, err := scanGenre(r.db.QueryRowContext(ctx, `SELECT `+genreColumns+` FROM genres WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get genre: %w", err)
+	}
+	return g, nil
+}
+
+// CreateGenre crea el género y completa ID y fechas. ErrSlugTaken si el slug ya existe.
+func (r *Repository) CreateGenre(ctx context.Context, g *models.Genre) error {
+	translations, err := json.Marshal(g.Translations)
+	if err != nil {
+		return fmt.Errorf("failed to encode genre translations: %w", err)
+	}
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO genres (slug, name, translations, position, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		g.Slug, g.Name, translations, g.Position, g.IsActive,
+	).Scan(&g.ID, &g.CreatedAt, &g.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create genre: %w", err)
+	}
+	return nil
+}
+
+// UpdateGenre guarda todos los campos editables del género. ErrSlugTaken si el
+// nuevo slug ya existe.
+func (r *Repository) UpdateGenre(ctx context.Context, g *models.Genre) error {
+	translations, err := json.Marshal(g.Translations)
+	if err != nil {
+		return fmt.Errorf("failed to encode genre translations: %w", err)
+	}
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE genres
+		SET slug = $2, name = $3, translations = $4, position = $5, is_active = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`,
+		g.ID, g.Slug, g.Name, translations, g.Position, g.IsActive,
+	).Scan(&g.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update genre: %w", err)
+	}
+	return nil
+}
+
+// DeleteGenre borra el género (y sus asignaciones). Devuelve false si no existía.
+func (r *Repository) DeleteGenre(ctx context.Context, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM genres WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete genre: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GenreCounts lista los géneros activos que tienen series activas (del productor,
+// si se indica) con su cantidad y el nombre en el idioma de la cadena.
+func (r *Repository) GenreCounts(ctx context.Context, chain []string, producerID *uuid.UUID) ([]models.Term, error) {
+	query := `
+		SELECT ` + genreSelect + `, COUNT(*) AS series_count
+		FROM genres g
+		JOIN series_genres sg ON sg.genre_id = g.id
+		JOIN series s ON s.id = sg.series_id AND s.is_active = TRUE
+		WHERE g.is_active = TRUE`
+	args := []interface{}{}
+	if producerID != nil {
+		args = append(args, *producerID)
+		query += ` AND s.producer_id = $1`
+	}
+	query += `
+		GROUP BY g.id
+		ORDER BY g.position, g.name`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count genres: %w", err)
+	}
+	defer rows.Close()
+
+	var list []models.Term
+	for rows.Next() {
+		var count int
+		g, err := scanGenre(rows, &count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan genre: %w", err)
+		}
+		list = append(list, models.Term{ID: g.ID, Slug: g.Slug, Name: LocalizedName(g, chain), SeriesCount: count})
+	}
+	return list, rows.Err()
+}
+
+// ListTags lista las etiquetas en uso (en series del productor, si se indica) cuyo
+// slug empiece con prefix, de la más usada a la menos.
+func (r *Repository) ListTags(ctx context.Context, prefix string, producerID *uuid.UUID, limit int) ([]models.Term, error) {
+	query := `
+		SELECT t.id, t.slug, t.name, COUNT(*) AS series_count
+		FROM tags t
+		JOIN series_tags st ON st.tag_id = t.id
+		JOIN series s ON s.id = st.series_id
+		WHERE t.slug LIKE $1 || '%'`
+	args := []interface{}{Slugify(prefix), limit}
+	if producerID != nil {
+		args = append(args, *producerID)
+		query += ` AND s.producer_id = $3`
+	}
+	query += `
+		GROUP BY t.id
+		ORDER BY COUNT(*) DESC, t.slug
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var list []models.Term
+	for rows.Next() {
+		var t models.Term
+		if err := rows.Scan(&t.ID, &t.Slug, &t.Name, &t.SeriesCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// DeleteTag borra la etiqueta de todas las series. Devuelve false si no existía.
+func (r *Repository) DeleteTag(ctx context.Context, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tag: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// SetSeriesTaxonomy reemplaza los géneros (por slug) y las etiquetas (por nombre) de
+// la serie. Las etiquetas nuevas se crean; las que ya existen con el mismo slug se
+// reutilizan con su nombre original. ErrUnknownGenre si algún género no existe o
+// está inactivo.
+func (r *Repository) SetSeriesTaxonomy(ctx context.Context, seriesID uuid.UUID, genreSlugs, tagNames []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var genreIDs []string
+	if len(genreSlugs) > 0 {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id FROM genres WHERE slug = ANY($1) AND is_active = TRUE`, pq.Array(genreSlugs))
+		if err != nil {
+			return fmt.Errorf("failed to find genres: %w", err)
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan genre: %w", err)
+			}
+			genreIDs = append(genreIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to find genres: %w", err)
+		}
+		if len(genreIDs) != len(genreSlugs) {
+			return ErrUnknownGenre
+		}
+	}
+
+	var tagIDs []string
+	for _, name := range tagNames {
+		var id string
+		// DO UPDATE (sin cambios) para que RETURNING devuelva también las existentes
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO tags (slug, name) VALUES ($1, $2)
+			ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+			RETURNING id`, Slugify(name), name,
+		).Scan(&id); err != nil {
+			return fmt.Errorf("failed to upsert tag: %w", err)
+		}
+		tagIDs = append(tagIDs, id)
+	}
+
+	// Solo se tocan las asignaciones que cambian (cada cambio reencola la serie en la búsqueda)
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM series_genres WHERE series_id = $1 AND genre_id <> ALL($2::uuid[])`,
+		seriesID, pq.Array(genreIDs)); err != nil {
+		return fmt.Errorf("failed to remove series genres: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO series_genres (series_id, genre_id)
+		SELECT $1, unnest($2::uuid[]) ON CONFLICT DO NOTHING`,
+		seriesID, pq.Array(genreIDs)); err != nil {
+		return fmt.Errorf("failed to add series genres: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM series_tags WHERE series_id = $1 AND tag_id <> ALL($2::uuid[])`,
+		seriesID, pq.Array(tagIDs)); err != nil {
+		return fmt.Errorf("failed to remove series tags: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO series_tags (series_id, tag_id)
+		SELECT $1, unnest($2::uuid[]) ON CONFLICT DO NOTHING`,
+		seriesID, pq.Array(tagIDs)); err != nil {
+		return fmt.Errorf("failed to add series tags: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit series taxonomy: %w", err)
+	}
+	return nil
+}
+
+// SeriesTerms devuelve los géneros (activos, con el nombre en el idioma de la cadena)
+// y las etiquetas de cada serie.
+func (r *Repository) SeriesTerms(ctx context.Context, chain []string, ids []uuid.UUID) (genres, tags map[uuid.UUID][]models.Term, err error) {
+	genres = make(map[uuid.UUID][]models.Term)
+	tags = make(map[uuid.UUID][]models.Term)
+	if len(ids) == 0 {
+		return genres, tags, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+genreSelect+`, sg.series_id
+		FROM series_genres sg JOIN genres g ON g.id = sg.genre_id
+		WHERE sg.series_id = ANY($1::uuid[]) AND g.is_active = TRUE
+		ORDER BY g.position, g.name`, uuidArray(ids))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list series genres: %w", err)
+	}
+	for rows.Next() {
+		var seriesID uuid.UUID
+		g, err := scanGenre(rows, &seriesID)
+		if err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan series genre: %w", err)
+		}
+		genres[seriesID] = append(genres[seriesID], models.Term{ID: g.ID, Slug: g.Slug, Name: LocalizedName(g, chain)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to list series genres: %w", err)
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT st.series_id, t.id, t.slug, t.name
+		FROM series_tags st JOIN tags t ON t.id = st.tag_id
+		WHERE st.series_id = ANY($1::uuid[])
+		ORDER BY t.name`, uuidArray(ids))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list series tags: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seriesID uuid.UUID
+		var t models.Term
+		if err := rows.Scan(&seriesID, &t.ID, &t.Slug, &t.Name); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan series tag: %w", err)
+		}
+		tags[seriesID] = append(tags[seriesID], t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to list series tags: %w", err)
+	}
+	return genres, tags, nil
+}
+
+// Attach completa Genres y Tags de las series.
+func (r *Repository) Attach(ctx context.Context, chain []string, list []models.Series) error {
+	ids := make([]uuid.UUID, len(list))
+	for i := range list {
+		ids[i] = list[i].ID
+	}
+	genres, tags, err := r.SeriesTerms(ctx, chain, ids)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		list[i].Genres = genres[list[i].ID]
+		list[i].Tags = tags[list[i].ID]
+	}
+	return nil
+}
+
+// AttachOne completa Genres y Tags de una serie.
+func (r *Repository) AttachOne(ctx context.Context, chain []string, s *models.Series) error {
+	list := []models.Series{*s}
+	if err := r.Attach(ctx, chain, list); err != nil {
+		return err
+	}
+	s.Genres, s.Tags = list[0].Genres, list[0].Tags
+	return nil
+}
+
+// SimilarityScores puntúa las series por cuántos géneros (2 puntos) y etiquetas
+// (1 punto) comparten con las series seeds, contando cada término tantas veces como
+// aparezca entre ellas. Las seeds no se puntúan; las series sin nada en común no aparecen.
+func (r *Repository) SimilarityScores(ctx context.Context, seeds []uuid.UUID) (map[uuid.UUID]int, error) {
+	scores := make(map[uuid.UUID]int)
+	if len(seeds) == 0 {
+		return scores, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		WITH terms AS (
+			SELECT series_id, genre_id AS term, 2 AS weight FROM series_genres
+			UNION ALL
+			SELECT series_id, tag_id, 1 FROM series_tags
+		),
+		seed AS (
+			SELECT term, weight, COUNT(*) AS n FROM terms
+			WHERE series_id = ANY($1::uuid[])
+			GROUP BY term, weight
+		)
+		SELECT t.series_id, SUM(seed.weight * seed.n)
+		FROM terms t JOIN seed ON seed.term = t.term
+		WHERE t.series_id <> ALL($1::uuid[])
+		GROUP BY t.series_id`, uuidArray(seeds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to score similar series: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var score int
+		if err := rows.Scan(&id, &score); err != nil {
+			return nil, fmt.Errorf("failed to scan similar series: %w", err)
+		}
+		scores[id] = score
+	}
+	return scores, rows.Err()
+}
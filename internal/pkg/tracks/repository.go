@@ -0,0 +1,162 @@
+// Package tracks gestiona las pistas adicionales de los episodios: subtítulos
+// (SRT o WebVTT, se validan y se guardan siempre en WebVTT) y audio alternativo en
+// otros idiomas. Los archivos se guardan en el proveedor de video junto al video
+// del episodio (storage.TrackProvider) y la reproducción los ofrece con URLs
+// firmadas igual que el stream principal.
+package tracks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+// Track pista de un episodio.
+type Track struct {
+	ID        uuid.UUID `json:"id"`
+	EpisodeID uuid.UUID `json:"episode_id"`
+	Kind      string    `json:"kind"`     // storage.TrackKind*
+	Language  string    `json:"language"` // BCP 47
+	Label     string    `json:"label"`
+	IsDefault bool      `json:"is_default"`
+	// VideoID video del proveedor junto al que se guardó la pista
+	VideoID     string     `json:"video_id"`
+	ContentType string     `json:"content_type"`
+	Ext         string     `json:"-"`
+	SizeBytes   int64      `json:"size_bytes"`
+	CueCount    *int       `json:"cue_count,omitempty"` // solo subtítulos
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// File describe el archivo de la pista para el proveedor.
+func (t *Track) File() storage.TrackFile {
+	return storage.TrackFile{Kind: t.Kind, Language: t.Language, Label: t.Label, ContentType: t.ContentType, Ext: t.Ext}
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const trackColumns = `id, episode_id, kind, language, label, is_default, video_id, content_type, ext,
+	size_bytes, cue_count, created_by, created_at, updated_at`
+
+func scanTrack(row interface{ Scan(...interface{}) error }) (*Track, error) {
+	var t Track
+	var cues sql.NullInt64
+	if err := row.Scan(&t.ID, &t.EpisodeID, &t.Kind, &t.Language, &t.Label, &t.IsDefault, &t.VideoID,
+		&t.ContentType, &t.Ext, &t.SizeBytes, &cues, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if cues.Valid {
+		n := int(cues.Int64)
+		t.CueCount = &n
+	}
+	return &t, nil
+}
+
+// ListByEpisode lista las pistas del episodio: subtítulos primero, luego audio, por idioma.
+func (r *Repository) ListByEpisode(ctx context.Context, episodeID uuid.UUID) ([]Track, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+trackColumns+` FROM episode_tracks
+		WHERE episode_id = $1 ORDER BY kind DESC, is_default DESC, language`, episodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracks: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Track
+	for rows.Next() {
+		t, err := scanTrack(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan track: %w", err)
+		}
+		list = append(list, *t)
+	}
+	return list, rows.Err()
+}
+
+// Get devuelve una pista por ID, o nil si no existe.
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*Track, error) {
+	t, err := scanTrack(r.db.QueryRowContext(ctx,
+		`SELECT `+trackColumns+` FROM episode_tracks WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get track: %w", err)
+	}
+	return t, nil
+}
+
+// Upsert guarda la pista, reemplazando la del mismo tipo e idioma si existía, y
+// devuelve la anterior (para borrar su archivo si cambió de nombre o de video).
+// Si la pista es la default de su tipo, las demás dejan de serlo.
+func (r *Repository) Upsert(ctx context.Context, t *Track) (*Track, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	previous, err := scanTrack(tx.QueryRowContext(ctx, `
+		SELECT `+trackColumns+` FROM episode_tracks
+		WHERE episode_id = $1 AND kind = $2 AND language = $3
+		FOR UPDATE`, t.EpisodeID, t.Kind, t.Language))
+	if err == sql.ErrNoRows {
+		previous = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get track: %w", err)
+	}
+
+	saved, err := scanTrack(tx.QueryRowContext(ctx, `
+		INSERT INTO episode_tracks (id, episode_id, kind, language, label, is_default, video_id,
+		                            content_type, ext, size_bytes, cue_count, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (episode_id, kind, language) DO UPDATE SET
+			label = EXCLUDED.label, is_default = EXCLUDED.is_default, video_id = EXCLUDED.video_id,
+			content_type = EXCLUDED.content_type, ext = EXCLUDED.ext, size_bytes = EXCLUDED.size_bytes,
+			cue_count = EXCLUDED.cue_count, created_by = EXCLUDED.created_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING `+trackColumns,
+		t.ID, t.EpisodeID, t.Kind, t.Language, t.Label, t.IsDefault, t.VideoID,
+		t.ContentType, t.Ext, t.SizeBytes, t.CueCount, t.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save track: %w", err)
+	}
+	if saved.IsDefault {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE episode_tracks SET is_default = FALSE, updated_at = CURRENT_TIMESTAMP
+			WHERE episode_id = $1 AND kind = $2 AND id <> $3 AND is_default`,
+			saved.EpisodeID, saved.Kind, saved.ID); err != nil {
+			return nil, fmt.Errorf("failed to reset default track: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit track: %w", err)
+	}
+	*t = *saved
+	return previous, nil
+}
+
+// Delete borra la pista del episodio y la devuelve, o nil si no existe.
+func (r *Repository) Delete(ctx context.Context, episodeID, id uuid.UUID) (*Track, error) {
+	t, err := scanTrack(r.db.QueryRowContext(ctx, `
+		DELETE FROM episode_tracks WHERE id = $1 AND episode_id = $2
+		RETURNING `+trackColumns, id, episodeID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete track: %w", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,224 @@
+package tracks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+var (
+	// ErrNotSupported el proveedor de video no guarda pistas de ese tipo.
+	ErrNotSupported = errors.New("tracks: not supported by the video provider")
+	// ErrNoVideo el episodio todavía no tiene video al que asociar la pista.
+	ErrNoVideo = errors.New("tracks: episode has no video")
+	// ErrInvalidLanguage el idioma no es un código BCP 47 aceptado.
+	ErrInvalidLanguage = errors.New("tracks: invalid language")
+	// ErrInvalidAudio el archivo no es audio AAC (M4A/ADTS) ni MP3.
+	ErrInvalidAudio = errors.New("tracks: invalid audio")
+)
+
+// language idioma con región opcional (es, es-419, pt-BR, en-US).
+var language = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:-([a-zA-Z]{2}|[0-9]{3}))?$`)
+
+// languageLabels nombre por defecto de los idiomas de los mercados principales.
+var languageLabels = map[string]string{
+	"es":     "Español",
+	"es-419": "Español (Latinoamérica)",
+	"es-ES":  "Español (España)",
+	"es-MX":  "Español (México)",
+	"pt":     "Português",
+	"pt-BR":  "Português (Brasil)",
+	"en":     "English",
+	"en-US":  "English (US)",
+}
+
+// Upload datos de una pista nueva.
+type Upload struct {
+	Kind      string
+	Language  string
+	Label     string
+	IsDefault bool
+	CreatedBy *uuid.UUID
+}
+
+// PlaybackTrack pista tal como la recibe el reproductor, con su URL firmada.
+type PlaybackTrack struct {
+	ID          uuid.UUID `json:"id"`
+	Kind        string    `json:"kind"`
+	Language    string    `json:"language"`
+	Label       string    `json:"label"`
+	IsDefault   bool      `json:"is_default"`
+	ContentType string    `json:"content_type"`
+	URL         string    `json:"url"`
+}
+
+type Service struct {
+	repo     *Repository
+	provider storage.VideoProvider
+}
+
+func NewService(db *sql.DB, provider storage.VideoProvider) *Service {
+	return &Service{repo: NewRepository(db), provider: provider}
+}
+
+// Repo expone el repositorio para los handlers de consulta.
+func (s *Service) Repo() *Repository {
+	return s.repo
+}
+
+// Supports indica si el proveedor de video acepta pistas del tipo kind.
+func (s *Service) Supports(kind string) bool {
+	tp, ok := s.provider.(storage.TrackProvider)
+	return ok && tp.SupportsTrack(kind)
+}
+
+// NormalizeLanguage valida un código de idioma y lo lleva a la forma canónica
+// (idioma en minúsculas, región en mayúsculas: pt-br → pt-BR).
+func NormalizeLanguage(code string) (string, error) {
+	m := language.FindStringSubmatch(strings.TrimSpace(code))
+	if m == nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLanguage, code)
+	}
+	if m[2] == "" {
+		return strings.ToLower(m[1]), nil
+	}
+	return strings.ToLower(m[1]) + "-" + strings.ToUpper(m[2]), nil
+}
+
+// Upload valida el archivo (subtítulos SRT/WebVTT, que se guardan en WebVTT, o
+// audio AAC/MP3), lo sube junto al video actual del episodio y registra la pista,
+// reemplazando la del mismo tipo e idioma.
+func (s *Service) Upload(ctx context.Context, episode *models.Episode, in Upload, data []byte) (*Track, error) {
+	tp, ok := s.provider.(storage.TrackProvider)
+	if !ok || !tp.SupportsTrack(in.Kind) {
+		return nil, ErrNotSupported
+	}
+	if episode.VideoIDBunny == "" {
+		return nil, ErrNoVideo
+	}
+	lang, err := NormalizeLanguage(in.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Track{
+		ID:        uuid.New(),
+		EpisodeID: episode.ID,
+		Kind:      in.Kind,
+		Language:  lang,
+		Label:     strings.TrimSpace(in.Label),
+		IsDefault: in.IsDefault,
+		VideoID:   episode.VideoIDBunny,
+		CreatedBy: in.CreatedBy,
+	}
+	if t.Label == "" {
+		t.Label = languageLabels[lang]
+	}
+	if t.Label == "" {
+		t.Label = lang
+	}
+
+	switch in.Kind {
+	case storage.TrackKindSubtitles:
+		vtt, cues, err := ToWebVTT(data)
+		if err != nil {
+			return nil, err
+		}
+		data, t.CueCount = vtt, &cues
+		t.ContentType, t.Ext = "text/vtt", "vtt"
+	case storage.TrackKindAudio:
+		if t.Ext, t.ContentType = detectAudio(data); t.Ext == "" {
+			return nil, ErrInvalidAudio
+		}
+	default:
+		return nil, ErrNotSupported
+	}
+	t.SizeBytes = int64(len(data))
+
+	if err := tp.UploadTrack(t.VideoID, t.File(), bytes.NewReader(data), t.SizeBytes); err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.Upsert(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	// El archivo anterior queda huérfano si tenía otra extensión o era de otro video
+	if previous != nil && (previous.VideoID != t.VideoID || previous.File().Name() != t.File().Name()) {
+		if err := tp.DeleteTrack(previous.VideoID, previous.File()); err != nil {
+			log.Printf("tracks: delete replaced track %s: %v", previous.ID, err)
+		}
+	}
+	return t, nil
+}
+
+// Delete borra la pista del episodio y su archivo. Devuelve false si no existía.
+func (s *Service) Delete(ctx context.Context, episodeID, id uuid.UUID) (bool, error) {
+	t, err := s.repo.Delete(ctx, episodeID, id)
+	if err != nil || t == nil {
+		return false, err
+	}
+	if tp, ok := s.provider.(storage.TrackProvider); ok {
+		if err := tp.DeleteTrack(t.VideoID, t.File()); err != nil {
+			log.Printf("tracks: delete track file %s: %v", t.ID, err)
+		}
+	}
+	return true, nil
+}
+
+// Playback lista las pistas del video actual del episodio con sus URLs firmadas
+// por expirationMinutes. Las pistas de un video anterior no se ofrecen.
+func (s *Service) Playback(ctx context.Context, episode *models.Episode, expirationMinutes int) ([]PlaybackTrack, error) {
+	tp, ok := s.provider.(storage.TrackProvider)
+	if !ok {
+		return nil, nil
+	}
+	list, err := s.repo.ListByEpisode(ctx, episode.ID)
+	if err != nil {
+		return nil, err
+	}
+	var result []PlaybackTrack
+	for _, t := range list {
+		if t.VideoID != episode.VideoIDBunny || !tp.SupportsTrack(t.Kind) {
+			continue
+		}
+		url, err := tp.GetTrackURL(t.VideoID, t.File(), expirationMinutes)
+		if err != nil {
+			log.Printf("tracks: sign track %s: %v", t.ID, err)
+			continue
+		}
+		result = append(result, PlaybackTrack{
+			ID:          t.ID,
+			Kind:        t.Kind,
+			Language:    t.Language,
+			Label:       t.Label,
+			IsDefault:   t.IsDefault,
+			ContentType: t.ContentType,
+			URL:         url,
+		})
+	}
+	return result, nil
+}
+
+// detectAudio identifica el audio por los magic bytes: M4A (contenedor MP4), AAC
+// ADTS o MP3. Devuelve "" si no es ninguno.
+func detectAudio(data []byte) (ext, contentType string) {
+	switch {
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		return "m4a", "audio/mp4"
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xF6 == 0xF0: // ADTS: sync + layer 00
+		return "aac", "audio/aac"
+	case bytes.HasPrefix(data, []byte("ID3")),
+		len(data) >= 2 && data[0] == 0xFF && data[1]&0xE6 == 0xE2: // sync + layer III
+		return "mp3", "audio/mpeg"
+	}
+	return "", ""
+}
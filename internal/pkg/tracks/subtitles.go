@@ -0,0 +1,221 @@
+package tracks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidSubtitles el archivo no es un SRT ni un WebVTT válido.
+var ErrInvalidSubtitles = errors.New("tracks: invalid subtitles")
+
+var (
+	// cueTiming línea de tiempos de un cue: "00:01:02.500 --> 00:01:04.000 [ajustes]".
+	// Acepta la coma de SRT y las horas opcionales de WebVTT.
+	cueTiming = regexp.MustCompile(`^\s*((?:\d+:)?\d{2}:\d{2}[.,]\d{3})\s+-->\s+((?:\d+:)?\d{2}:\d{2}[.,]\d{3})(?:\s.*)?$`)
+	// srtFormatting etiquetas de SRT que WebVTT no soporta: <font ...> y los
+	// overrides de posición de ASS ({\an8}).
+	srtFormatting = regexp.MustCompile(`(?i)</?font[^>]*>|\{\\[^}]*\}`)
+)
+
+// ToWebVTT valida un archivo de subtítulos SRT o WebVTT y lo devuelve en WebVTT
+// (UTF-8, saltos de línea LF) junto con la cantidad de cues. Los archivos que no
+// son UTF-8 se leen como Windows-1252, la codificación habitual de los SRT en español
+// y portugués.
+func ToWebVTT(data []byte) ([]byte, int, error) {
+	text := normalizeText(data)
+	blocks := splitBlocks(text)
+	if len(blocks) == 0 {
+		return nil, 0, fmt.Errorf("%w: empty file", ErrInvalidSubtitles)
+	}
+	if isWebVTT(blocks[0].lines[0]) {
+		return parseWebVTT(blocks)
+	}
+	return parseSRT(blocks)
+}
+
+// block grupo de líneas no vacías consecutivas; line es el número de la primera.
+type block struct {
+	line  int
+	lines []string
+}
+
+func splitBlocks(text string) []block {
+	var blocks []block
+	var current *block
+	for i, l := range strings.Split(text, "\n") {
+		if strings.TrimSpace(l) == "" {
+			current = nil
+			continue
+		}
+		if current == nil {
+			blocks = append(blocks, block{line: i + 1})
+			current = &blocks[len(blocks)-1]
+		}
+		current.lines = append(current.lines, l)
+	}
+	return blocks
+}
+
+func isWebVTT(first string) bool {
+	return first == "WEBVTT" || strings.HasPrefix(first, "WEBVTT ") || strings.HasPrefix(first, "WEBVTT\t")
+}
+
+// parseWebVTT valida los cues y devuelve el archivo normalizado; los bloques
+// NOTE, STYLE y REGION se conservan tal cual.
+func parseWebVTT(blocks []block) ([]byte, int, error) {
+	var out bytes.Buffer
+	out.WriteString(strings.Join(blocks[0].lines, "\n"))
+	cues := 0
+	for _, b := range blocks[1:] {
+		first := b.lines[0]
+		if first == "NOTE" || strings.HasPrefix(first, "NOTE ") || strings.HasPrefix(first, "NOTE\t") ||
+			first == "STYLE" || first == "REGION" {
+			out.WriteString("\n\n" + strings.Join(b.lines, "\n"))
+			continue
+		}
+		timing := 0
+		if !strings.Contains(first, "-->") {
+			timing = 1 // identificador del cue
+		}
+		if timing >= len(b.lines) {
+			return nil, 0, fmt.Errorf("%w: line %d: cue without timing", ErrInvalidSubtitles, b.line)
+		}
+		if _, _, err := parseTiming(b.lines[timing]); err != nil {
+			return nil, 0, fmt.Errorf("%w: line %d: %v", ErrInvalidSubtitles, b.line+timing, err)
+		}
+		out.WriteString("\n\n" + strings.Join(b.lines[:timing+1], "\n"))
+		// "-->" en el texto cortaría el cue en los reproductores
+		for _, l := range b.lines[timing+1:] {
+			out.WriteString("\n" + strings.ReplaceAll(l, "-->", "->"))
+		}
+		cues++
+	}
+	if cues == 0 {
+		return nil, 0, fmt.Errorf("%w: no cues", ErrInvalidSubtitles)
+	}
+	out.WriteString("\n")
+	return out.Bytes(), cues, nil
+}
+
+// parseSRT convierte los bloques "índice / tiempos / texto" de un SRT en cues
+// WebVTT (el índice queda como identificador del cue).
+func parseSRT(blocks []block) ([]byte, int, error) {
+	var out bytes.Buffer
+	out.WriteString("WEBVTT")
+	cues := 0
+	for _, b := range blocks {
+		lines := b.lines
+		timing := 0
+		if _, err := strconv.Atoi(strings.TrimSpace(lines[0])); err == nil {
+			timing = 1
+		}
+		if timing >= len(lines) {
+			return nil, 0, fmt.Errorf("%w: line %d: cue without timing", ErrInvalidSubtitles, b.line)
+		}
+		start, end, err := parseTiming(lines[timing])
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: line %d: %v", ErrInvalidSubtitles, b.line+timing, err)
+		}
+
+		out.WriteString("\n\n")
+		if timing == 1 {
+			out.WriteString(strings.TrimSpace(lines[0]) + "\n")
+		}
+		// Las coordenadas X1:.. Y1:.. de algunos SRT no tienen equivalente y se descartan
+		out.WriteString(formatTimestamp(start) + " --> " + formatTimestamp(end))
+		for _, l := range lines[timing+1:] {
+			l = strings.ReplaceAll(srtFormatting.ReplaceAllString(l, ""), "-->", "->")
+			out.WriteString("\n" + l)
+		}
+		cues++
+	}
+	if cues == 0 {
+		return nil, 0, fmt.Errorf("%w: no cues", ErrInvalidSubtitles)
+	}
+	out.WriteString("\n")
+	return out.Bytes(), cues, nil
+}
+
+// parseTiming lee una línea de tiempos y devuelve inicio y fin en milisegundos.
+func parseTiming(line string) (start, end int64, err error) {
+	m := cueTiming.FindStringSubmatch(line)
+	if m == nil {
+		return 0, 0, fmt.Errorf("invalid cue timing %q", line)
+	}
+	if start, err = parseTimestamp(m[1]); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseTimestamp(m[2]); err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("cue ends before it starts (%s --> %s)", m[1], m[2])
+	}
+	return start, end, nil
+}
+
+// parseTimestamp lee [hh:]mm:ss.mmm (o con coma) en milisegundos.
+func parseTimestamp(ts string) (int64, error) {
+	ts = strings.Replace(ts, ",", ".", 1)
+	parts := strings.Split(ts, ":")
+	var h, m int64
+	var err error
+	if len(parts) == 3 {
+		if h, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", ts)
+		}
+		parts = parts[1:]
+	}
+	if m, err = strconv.ParseInt(parts[0], 10, 64); err != nil || m > 59 {
+		return 0, fmt.Errorf("invalid timestamp %q", ts)
+	}
+	sec, ms, _ := strings.Cut(parts[1], ".")
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil || s > 59 {
+		return 0, fmt.Errorf("invalid timestamp %q", ts)
+	}
+	millis, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", ts)
+	}
+	return ((h*60+m)*60+s)*1000 + millis, nil
+}
+
+func formatTimestamp(ms int64) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// normalizeText quita el BOM, pasa a UTF-8 y unifica los saltos de línea.
+func normalizeText(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	text := string(data)
+	if !utf8.Valid(data) {
+		text = decodeWindows1252(data)
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// windows1252 caracteres de 0x80-0x9F en Windows-1252 (el resto coincide con Latin-1).
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+func decodeWindows1252(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data) + len(data)/8)
+	for _, c := range data {
+		if c >= 0x80 && c <= 0x9F {
+			b.WriteRune(windows1252[c-0x80])
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
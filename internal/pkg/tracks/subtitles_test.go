@@ -0,0 +1,150 @@
+package tracks
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestToWebVTT(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		cues  int
+	}{
+		{
+			name:  "srt with comma decimals",
+			input: "1\r\n00:00:01,000 --> 00:00:02,500\r\nHola\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nChau\r\n",
+			want:  "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.500\nHola\n\n2\n00:00:03.000 --> 00:00:04.000\nChau\n",
+			cues:  2,
+		},
+		{
+			name:  "srt with BOM, no index and coordinates",
+			input: "\xef\xbb\xbf00:00:01,000 --> 00:00:02,000 X1:10 X2:20 Y1:30 Y2:40\nHola\n",
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHola\n",
+			cues:  1,
+		},
+		{
+			name:  "srt formatting is stripped",
+			input: "1\n00:00:01,000 --> 00:00:02,000\n{\\an8}<font color=\"#ffff00\">Arriba</font> <i>ok</i>\n",
+			want:  "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nArriba <i>ok</i>\n",
+			cues:  1,
+		},
+		{
+			name:  "srt arrow in cue text",
+			input: "1\n00:00:01,000 --> 00:00:02,000\nA --> B\n",
+			want:  "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nA -> B\n",
+			cues:  1,
+		},
+		{
+			name:  "srt in windows-1252",
+			input: "1\n00:00:01,000 --> 00:00:02,000\n\xbfQu\xe9 pas\xf3? \x93ma\xf1ana\x94 \x80\n",
+			want:  "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\n¿Qué pasó? “mañana” €\n",
+			cues:  1,
+		},
+		{
+			name:  "webvtt without hours",
+			input: "WEBVTT\n\n00:01.000 --> 00:02.000\nHola\n\nid-2\n01:02.000 --> 01:03.500 align:start\nChau\n",
+			want:  "WEBVTT\n\n00:01.000 --> 00:02.000\nHola\n\nid-2\n01:02.000 --> 01:03.500 align:start\nChau\n",
+			cues:  2,
+		},
+		{
+			name:  "webvtt keeps note and style blocks",
+			input: "WEBVTT - Español\n\nNOTE traducido a mano\n\nSTYLE\n::cue { color: yellow }\n\n00:00:01.000 --> 00:00:02.000\nHola\n",
+			want:  "WEBVTT - Español\n\nNOTE traducido a mano\n\nSTYLE\n::cue { color: yellow }\n\n00:00:01.000 --> 00:00:02.000\nHola\n",
+			cues:  1,
+		},
+		{
+			name:  "webvtt arrow in cue text",
+			input: "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nA --> B\n",
+			want:  "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nA -> B\n",
+			cues:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cues, err := ToWebVTT([]byte(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want || cues != tt.cues {
+				t.Errorf("ToWebVTT = %q (%d cues), want %q (%d cues)", got, cues, tt.want, tt.cues)
+			}
+		})
+	}
+}
+
+func TestToWebVTTRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", "\n\n  \n"},
+		{"webvtt header only", "WEBVTT\n"},
+		{"srt cue without timing", "1\n"},
+		{"srt bad timing", "1\n00:00:01 --> 00:00:02\nHola\n"},
+		{"srt ends before start", "1\n00:00:05,000 --> 00:00:02,000\nHola\n"},
+		{"webvtt cue without timing", "WEBVTT\n\nid\nHola\n"},
+		{"not subtitles", "<html><body>hola</body></html>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ToWebVTT([]byte(tt.input)); !errors.Is(err, ErrInvalidSubtitles) {
+				t.Errorf("err = %v, want ErrInvalidSubtitles", err)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"00:00:01.000", 1000, false},
+		{"00:00:01,250", 1250, false},
+		{"01:02:03.004", 3723004, false},
+		{"123:00:00.000", 442800000, false},
+		{"02:03.500", 123500, false},
+		{"00:60:00.000", 0, true},
+		{"00:00:60.000", 0, true},
+		{"aa:00.000", 0, true},
+		{"00:00.abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTimestamp(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestParseSRTKeepsIndexAsIdentifier(t *testing.T) {
+	blocks := splitBlocks("7\n00:00:01,000 --> 00:00:02,000\nuno\ndos\n\n00:00:03,000 --> 00:00:04,000\ntres")
+	got, cues, err := parseSRT(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "WEBVTT\n\n7\n00:00:01.000 --> 00:00:02.000\nuno\ndos\n\n00:00:03.000 --> 00:00:04.000\ntres\n"
+	if string(got) != want || cues != 2 {
+		t.Errorf("parseSRT = %q (%d cues)", got, cues)
+	}
+}
+
+func TestDecodeWindows1252(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want string
+	}{
+		{[]byte("plain ascii"), "plain ascii"},
+		{[]byte{0xe1, 0xe9, 0xed, 0xf3, 0xfa, 0xf1, 0xc7, 0xe3}, "áéíóúñÇã"},
+		{[]byte{0x80, 0x85, 0x91, 0x92, 0x96, 0x97, 0x99}, "€…‘’–—™"},
+		{[]byte{0x81, 0x8d}, "\u0081\u008d"}, // sin asignar en Windows-1252
+	}
+	for _, tt := range tests {
+		if got := decodeWindows1252(tt.in); got != tt.want {
+			t.Errorf("decodeWindows1252(% x) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/pagination"
 )
 
 type Repository struct {
@@ -52,6 +53,39 @@ func (r *Repository) Create(ctx context.Context, tx *Transaction) error {
 	return nil
 }
 
+// ListUserHistory retorna una página del historial de transacciones de un usuario,
+// de la más nueva a la más vieja (trae page.FetchLimit filas, ver pagination.Slice).
+func (r *Repository) ListUserHistory(ctx context.Context, userID uuid.UUID, page pagination.Page) ([]Transaction, error) {
+	query := `SELECT id, user_id, type, amount, episode_id, method, created_at
+	          FROM transactions
+	          WHERE user_id = $1`
+	args := []interface{}{userID}
+	query += page.Where(&args, "created_at", "id") + pagination.OrderBy("created_at", "id")
+	args = append(args, page.FetchLimit())
+	query += fmt.Sprintf(` LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var tx Transaction
+		var episodeID sql.NullString
+		if err := rows.Scan(&tx.ID, &tx.UserID, &tx.Type, &tx.Amount, &episodeID, &tx.Method, &tx.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		if episodeID.Valid {
+			epID, _ := uuid.Parse(episodeID.String)
+			tx.EpisodeID = &epID
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, rows.Err()
+}
+
 // GetUserHistory retorna el historial de transacciones de un usuario
 func (r *Repository) GetUserHistory(ctx context.Context, userID uuid.UUID, limit int) ([]Transaction, error) {
 	query := `SELECT id, user_id, type, amount, episode_id, method, created_at 
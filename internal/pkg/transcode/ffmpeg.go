@@ -0,0 +1,227 @@
+package transcode
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Archivos de la salida de RunHLS.
+const (
+	MasterPlaylist = "master.m3u8"
+	PosterFile     = "poster.jpg"
+)
+
+// Rendition un escalón de la escalera vertical 9:16.
+type Rendition struct {
+	Name         string // p.ej. "720p" (el lado corto, como en horizontal)
+	Width        int
+	Height       int
+	VideoBitrate int // bps
+	AudioBitrate int // bps
+}
+
+// BuildLadder arma la escalera a partir de los anchos configurados (lado corto),
+// descartando los que superan la resolución del original para no escalar hacia
+// arriba. Siempre queda al menos el escalón más chico.
+func BuildLadder(widths []int, source *MediaInfo) []Rendition {
+	sorted := append([]int(nil), widths...)
+	sort.Ints(sorted)
+
+	short := source.Width
+	if source.Height < short {
+		short = source.Height
+	}
+
+	var ladder []Rendition
+	for _, w := range sorted {
+		if w <= 0 || (w > short && len(ladder) > 0) {
+			continue
+		}
+		w = even(w)
+		h := even(w * 16 / 9)
+		r := Rendition{
+			Name:  fmt.Sprintf("%dp", w),
+			Width: w, Height: h,
+			// ~0.07 bits por pixel a 30 fps: 360p ≈ 480 kbps, 1080p ≈ 4.3 Mbps
+			VideoBitrate: max(400_000, w*h*30*7/100),
+			AudioBitrate: 96_000,
+		}
+		if w >= 720 {
+			r.AudioBitrate = 128_000
+		}
+		ladder = append(ladder, r)
+	}
+	return ladder
+}
+
+func even(n int) int {
+	return n &^ 1
+}
+
+// fitFilter escala al cuadro WxH sin deformar y rellena con negro (letterbox para
+// originales que no son 9:16).
+func fitFilter(w, h int) string {
+	return fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1", w, h, w, h)
+}
+
+// hlsArgs argumentos de ffmpeg para codificar todos los escalones en una sola
+// pasada: split del video, un encoder H.264 por escalón con keyframes cada 2 s
+// (segmentos alineados entre renditions) y el master generado por ffmpeg.
+func hlsArgs(src, dir string, ladder []Rendition, hasAudio bool) []string {
+	args := []string{"-hide_banner", "-nostats", "-loglevel", "error", "-y", "-i", src}
+
+	var filter strings.Builder
+	fmt.Fprintf(&filter, "[0:v:0]split=%d", len(ladder))
+	for i := range ladder {
+		fmt.Fprintf(&filter, "[s%d]", i)
+	}
+	for i, r := range ladder {
+		fmt.Fprintf(&filter, ";[s%d]%s[v%d]", i, fitFilter(r.Width, r.Height), i)
+	}
+	args = append(args, "-filter_complex", filter.String())
+
+	streamMap := make([]string, 0, len(ladder))
+	for i, r := range ladder {
+		n := strconv.Itoa(i)
+		args = append(args,
+			"-map", "[v"+n+"]",
+			"-c:v:"+n, "libx264",
+			"-b:v:"+n, strconv.Itoa(r.VideoBitrate),
+			"-maxrate:v:"+n, strconv.Itoa(r.VideoBitrate*107/100),
+			"-bufsize:v:"+n, strconv.Itoa(r.VideoBitrate*3/2),
+		)
+		entry := "v:" + n
+		if hasAudio {
+			args = append(args, "-map", "0:a:0", "-c:a:"+n, "aac", "-b:a:"+n, strconv.Itoa(r.AudioBitrate))
+			entry += ",a:" + n
+		}
+		streamMap = append(streamMap, entry+",name:"+r.Name)
+	}
+
+	return append(args,
+		"-preset", "veryfast", "-profile:v", "main", "-pix_fmt", "yuv420p",
+		"-force_key_frames", "expr:gte(t,n_forced*2)", "-sc_threshold", "0",
+		"-ac", "2",
+		"-f", "hls",
+		"-hls_time", "4",
+		"-hls_playlist_type", "vod",
+		"-hls_flags", "independent_segments",
+		"-hls_segment_filename", filepath.Join(dir, "%v", "seg_%04d.ts"),
+		"-master_pl_name", MasterPlaylist,
+		"-var_stream_map", strings.Join(streamMap, " "),
+		"-progress", "pipe:1",
+		filepath.Join(dir, "%v", "index.m3u8"),
+	)
+}
+
+// mp4Args argumentos para un único MP4 normalizado (para proveedores que codifican
+// por su cuenta y solo necesitan un original sano).
+func mp4Args(src, out string, r Rendition, hasAudio bool) []string {
+	args := []string{"-hide_banner", "-nostats", "-loglevel", "error", "-y", "-i", src,
+		"-map", "0:v:0", "-vf", fitFilter(r.Width, r.Height),
+		"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main", "-pix_fmt", "yuv420p",
+		"-crf", "21", "-maxrate", strconv.Itoa(r.VideoBitrate * 2), "-bufsize", strconv.Itoa(r.VideoBitrate * 3),
+	}
+	if hasAudio {
+		args = append(args, "-map", "0:a:0", "-c:a", "aac", "-b:a", strconv.Itoa(r.AudioBitrate), "-ac", "2")
+	}
+	return append(args, "-movflags", "+faststart", "-progress", "pipe:1", out)
+}
+
+// RunHLS codifica src en la escalera dentro de dir (dir/<nombre>/index.m3u8 +
+// segmentos y dir/master.m3u8). onProgress recibe el avance 0-1.
+func RunHLS(ctx context.Context, ffmpegPath, src, dir string, ladder []Rendition, info *MediaInfo, onProgress func(float64)) error {
+	for _, r := range ladder {
+		if err := os.MkdirAll(filepath.Join(dir, r.Name), 0o755); err != nil {
+			return fmt.Errorf("create rendition dir: %w", err)
+		}
+	}
+	return runFFmpeg(ctx, ffmpegPath, hlsArgs(src, dir, ladder, info.HasAudio), info.DurationSeconds, onProgress)
+}
+
+// RunMP4 codifica src en un único MP4 con las dimensiones de r.
+func RunMP4(ctx context.Context, ffmpegPath, src, out string, r Rendition, info *MediaInfo, onProgress func(float64)) error {
+	return runFFmpeg(ctx, ffmpegPath, mp4Args(src, out, r, info.HasAudio), info.DurationSeconds, onProgress)
+}
+
+// Poster extrae un cuadro de src (al 10% de la duración, máx. 3 s, para evitar
+// fundidos de entrada) como JPEG vertical de r.
+func Poster(ctx context.Context, ffmpegPath, src, out string, r Rendition, info *MediaInfo) error {
+	at := info.DurationSeconds / 10
+	if at > 3 {
+		at = 3
+	}
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-ss", strconv.FormatFloat(at, 'f', 2, 64),
+		"-i", src,
+		"-frames:v", "1",
+		"-vf", fitFilter(r.Width, r.Height),
+		"-q:v", "3",
+		out,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg poster: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Frame extrae el cuadro de src en el segundo `at` como JPEG de `width` px de ancho
+// (alto proporcional).
+func Frame(ctx context.Context, ffmpegPath, src, out string, at float64, width int) error {
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-ss", strconv.FormatFloat(at, 'f', 2, 64),
+		"-i", src,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:-2", even(width)),
+		"-q:v", "3",
+		out,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg frame: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// runFFmpeg ejecuta ffmpeg leyendo el reporte de -progress por stdout para
+// informar el avance sobre la duración total.
+func runFFmpeg(ctx context.Context, ffmpegPath string, args []string, duration float64, onProgress func(float64)) error {
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("ffmpeg: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("ffmpeg: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		// out_time_us (out_time_ms también viene en microsegundos en ffmpeg)
+		if !ok || (key != "out_time_us" && key != "out_time_ms") || onProgress == nil || duration <= 0 {
+			continue
+		}
+		us, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || us < 0 {
+			continue
+		}
+		onProgress(min(1, float64(us)/1e6/duration))
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package transcode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrNotVideo el archivo no se pudo leer como video (ffprobe falló o no hay stream de video).
+var ErrNotVideo = errors.New("transcode: file is not a readable video")
+
+// MediaInfo datos del original relevantes para la transcodificación. Width y
+// Height son las dimensiones de visualización (ya aplicada la rotación de la
+// metadata, típica de videos grabados con el celular).
+type MediaInfo struct {
+	DurationSeconds float64 `json:"duration_seconds"`
+	Width           int     `json:"width"`
+	Height          int     `json:"height"`
+	FrameRate       float64 `json:"frame_rate,omitempty"`
+	VideoCodec      string  `json:"video_codec"`
+	AudioCodec      string  `json:"audio_codec,omitempty"`
+	HasAudio        bool    `json:"has_audio"`
+	BitRate         int64   `json:"bit_rate,omitempty"`
+}
+
+// Vertical indica si el video es vertical (o cuadrado).
+func (m *MediaInfo) Vertical() bool {
+	return m.Height >= m.Width
+}
+
+// commonRatios relaciones de aspecto a las que se redondean las dimensiones (los
+// encoders suelen recortar algunos píxeles, p. ej. 1080x1916).
+var commonRatios = [][2]int{{9, 16}, {16, 9}, {1, 1}, {4, 5}, {5, 4}, {3, 4}, {4, 3}, {2, 3}, {3, 2}, {9, 21}, {21, 9}}
+
+// AspectRatio devuelve la relación de aspecto como "W:H" (ej. "9:16"): la común más
+// cercana si difiere menos de 1%, si no la fracción reducida.
+func (m *MediaInfo) AspectRatio() string {
+	if m.Width <= 0 || m.Height <= 0 {
+		return ""
+	}
+	ratio := float64(m.Width) / float64(m.Height)
+	for _, r := range commonRatios {
+		if common := float64(r[0]) / float64(r[1]); math.Abs(ratio-common)/common < 0.01 {
+			return fmt.Sprintf("%d:%d", r[0], r[1])
+		}
+	}
+	a, b := m.Width, m.Height
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return fmt.Sprintf("%d:%d", m.Width/a, m.Height/a)
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType    string            `json:"codec_type"`
+		CodecName    string            `json:"codec_name"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		AvgFrameRate string            `json:"avg_frame_rate"`
+		Duration     string            `json:"duration"`
+		Tags         map[string]string `json:"tags"`
+		SideDataList []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		BitRate  string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// Probe lee con ffprobe la duración, dimensiones y codecs de file. Devuelve
+// ErrNotVideo si el archivo no es un video legible.
+func Probe(ctx context.Context, ffprobePath, file string) (*MediaInfo, error) {
+	cmd := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format", "-show_streams",
+		file,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%w: %s", ErrNotVideo, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("ffprobe: decode output: %w", err)
+	}
+
+	info := &MediaInfo{}
+	info.DurationSeconds, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	info.BitRate, _ = strconv.ParseInt(probe.Format.BitRate, 10, 64)
+	for _, s := range probe.Streams {
+		switch s.CodecType {
+		case "video":
+			if info.VideoCodec != "" || s.Width == 0 {
+				continue // solo el primer stream de video real (no carátulas sin dimensiones)
+			}
+			info.VideoCodec = s.CodecName
+			info.Width, info.Height = s.Width, s.Height
+			info.FrameRate = parseRate(s.AvgFrameRate)
+			if rotated(s.Tags["rotate"], s.SideDataList) {
+				info.Width, info.Height = info.Height, info.Width
+			}
+			if info.DurationSeconds == 0 {
+				info.DurationSeconds, _ = strconv.ParseFloat(s.Duration, 64)
+			}
+		case "audio":
+			if !info.HasAudio {
+				info.HasAudio = true
+				info.AudioCodec = s.CodecName
+			}
+		}
+	}
+	if info.VideoCodec == "" {
+		return nil, fmt.Errorf("%w: no video stream", ErrNotVideo)
+	}
+	if info.DurationSeconds <= 0 {
+		return nil, fmt.Errorf("%w: unknown duration", ErrNotVideo)
+	}
+	return info, nil
+}
+
+// rotated indica si la metadata gira el video ±90° (tag "rotate" en ffmpeg viejos,
+// display matrix en side_data_list en los nuevos).
+func rotated(tag string, sideData []struct {
+	Rotation float64 `json:"rotation"`
+}) bool {
+	deg, _ := strconv.ParseFloat(tag, 64)
+	for _, sd := range sideData {
+		if sd.Rotation != 0 {
+			deg = sd.Rotation
+		}
+	}
+	r := int(deg) % 180
+	return r == 90 || r == -90
+}
+
+// parseRate convierte "30000/1001" en 29.97.
+func parseRate(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		f, _ := strconv.ParseFloat(rate, 64)
+		return f
+	}
+	n, _ := strconv.ParseFloat(num, 64)
+	d, _ := strconv.ParseFloat(den, 64)
+	if d == 0 {
+		return 0
+	}
+	return n / d
+}
@@ -0,0 +1,193 @@
+// Package transcode implementa la transcodificación propia de episodios: el upload
+// se guarda en disco, ffprobe valida el archivo y un worker lo convierte con ffmpeg
+// en una escalera HLS vertical (9:16) + póster que entrega al VideoProvider.
+package transcode
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/qenti/qenti/internal/pkg/jobqueue"
+)
+
+// Estados de transcode_jobs.
+const (
+	StatusQueued      = "queued"
+	StatusTranscoding = "transcoding"
+	StatusPublishing  = "publishing"
+	StatusCompleted   = "completed"
+	StatusFailed      = "failed"
+)
+
+// Job es un trabajo de transcodificación de un episodio.
+type Job struct {
+	ID              uuid.UUID  `json:"id"`
+	EpisodeID       uuid.UUID  `json:"episode_id"`
+	CreatedBy       *uuid.UUID `json:"created_by,omitempty"`
+	SourcePath      string     `json:"-"`
+	Status          string     `json:"status"`
+	Progress        float64    `json:"progress"` // 0-100, solo de la etapa de encoding
+	DurationSeconds float64    `json:"duration_seconds,omitempty"`
+	Width           int        `json:"width,omitempty"`
+	Height          int        `json:"height,omitempty"`
+	Renditions      []string   `json:"renditions,omitempty"`
+	ExternalID      string     `json:"video_id,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	Attempts        int        `json:"attempts"`
+	CreatedAt       time.Time  `json:"created_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type Repository struct {
+	db    *sql.DB
+	queue *jobqueue.Queue
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db, queue: jobqueue.New(db, jobqueue.Table{
+		Name:      "transcode_jobs",
+		Key:       "id",
+		Noun:      "transcode job",
+		Running:   []string{StatusTranscoding, StatusPublishing},
+		OnClaim:   "progress = 0, error = NULL, started_at = NOW()",
+		OnFail:    "completed_at = NOW()",
+		OnRequeue: "progress = 0",
+	})}
+}
+
+const jobColumns = `id, episode_id, created_by, source_path, status, progress, COALESCE(duration_seconds, 0),
+	COALESCE(width, 0), COALESCE(height, 0), renditions, COALESCE(external_id, ''), COALESCE(error, ''),
+	attempts, created_at, started_at, completed_at, updated_at`
+
+func scanJob(row interface{ Scan(...interface{}) error }) (*Job, error) {
+	var j Job
+	var renditions pq.StringArray
+	err := row.Scan(&j.ID, &j.EpisodeID, &j.CreatedBy, &j.SourcePath, &j.Status, &j.Progress, &j.DurationSeconds,
+		&j.Width, &j.Height, &renditions, &j.ExternalID, &j.Error,
+		&j.Attempts, &j.CreatedAt, &j.StartedAt, &j.CompletedAt, &j.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	j.Renditions = renditions
+	return &j, nil
+}
+
+// Create encola un trabajo con los datos del probe del original.
+func (r *Repository) Create(ctx context.Context, job *Job) error {
+	created, err := scanJob(r.db.QueryRowContext(ctx, `
+		INSERT INTO transcode_jobs (id, episode_id, created_by, source_path, duration_seconds, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+jobColumns,
+		job.ID, job.EpisodeID, job.CreatedBy, job.SourcePath, job.DurationSeconds, job.Width, job.Height))
+	if err != nil {
+		return fmt.Errorf("failed to create transcode job: %w", err)
+	}
+	*job = *created
+	return nil
+}
+
+// Get devuelve un trabajo por ID, o nil si no existe.
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*Job, error) {
+	job, err := scanJob(r.db.QueryRowContext(ctx,
+		`SELECT `+jobColumns+` FROM transcode_jobs WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcode job: %w", err)
+	}
+	return job, nil
+}
+
+// ListByEpisode lista los trabajos de un episodio, el más reciente primero.
+func (r *Repository) ListByEpisode(ctx context.Context, episodeID uuid.UUID, limit int) ([]Job, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+jobColumns+` FROM transcode_jobs
+		WHERE episode_id = $1
+		ORDER BY created_at DESC LIMIT $2`, episodeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcode jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcode job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// Claim toma el trabajo encolado más antiguo y lo marca 'transcoding'. SKIP LOCKED
+// permite varios workers e instancias. Devuelve nil si no hay trabajo.
+func (r *Repository) Claim(ctx context.Context) (*Job, error) {
+	var job *Job
+	ok, err := r.queue.Claim(ctx, jobColumns, func(row jobqueue.Row) (err error) {
+		job, err = scanJob(row)
+		return err
+	})
+	if err != nil || !ok {
+		return nil, err
+	}
+	return job, nil
+}
+
+// UpdateProgress registra el avance del encoding.
+func (r *Repository) UpdateProgress(ctx context.Context, id uuid.UUID, progress float64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE transcode_jobs SET progress = $2, updated_at = NOW() WHERE id = $1`, id, progress)
+	if err != nil {
+		return fmt.Errorf("failed to update transcode progress: %w", err)
+	}
+	return nil
+}
+
+// Touch renueva el heartbeat de un trabajo en curso (ver RequeueStale).
+func (r *Repository) Touch(ctx context.Context, id uuid.UUID) error {
+	return r.queue.Touch(ctx, id)
+}
+
+// SetPublishing marca el fin del encoding y el inicio de la entrega al proveedor.
+func (r *Repository) SetPublishing(ctx context.Context, id uuid.UUID, renditions []string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE transcode_jobs
+		SET status = 'publishing', progress = 100, renditions = $2, updated_at = NOW()
+		WHERE id = $1`, id, pq.Array(renditions))
+	if err != nil {
+		return fmt.Errorf("failed to update transcode job: %w", err)
+	}
+	return nil
+}
+
+// Complete marca el trabajo como terminado con el ID del video en el proveedor.
+func (r *Repository) Complete(ctx context.Context, id uuid.UUID, externalID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE transcode_jobs
+		SET status = 'completed', external_id = $2, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1`, id, externalID)
+	if err != nil {
+		return fmt.Errorf("failed to complete transcode job: %w", err)
+	}
+	return nil
+}
+
+// Fail marca el trabajo como fallido.
+func (r *Repository) Fail(ctx context.Context, id uuid.UUID, cause error) error {
+	return r.queue.Fail(ctx, id, cause)
+}
+
+// RequeueStale re-encola los trabajos en curso sin heartbeat desde hace más de
+// staleAfter (la instancia que los procesaba murió). Tras maxAttempts intentos se
+// marcan como fallidos. Devuelve cuántos re-encoló.
+func (r *Repository) RequeueStale(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int64, error) {
+	return r.queue.RequeueStale(ctx, staleAfter, maxAttempts)
+}
@@ -0,0 +1,386 @@
+package transcode
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/storage"
+	"github.com/qenti/qenti/internal/pkg/videostatus"
+)
+
+// ErrTooLong el original supera VideoUploadConfig.MaxDurationSeconds.
+var ErrTooLong = errors.New("transcode: video exceeds max duration")
+
+const (
+	// staleAfter sin heartbeat durante este tiempo un trabajo en curso se re-encola.
+	staleAfter = 10 * time.Minute
+	// heartbeatInterval cada cuánto se renueva el heartbeat de un trabajo en curso.
+	heartbeatInterval = time.Minute
+	// maxAttempts intentos antes de dar por perdido un trabajo huérfano.
+	maxAttempts = 3
+	// progressInterval frecuencia máxima con que se persiste el avance.
+	progressInterval = 2 * time.Second
+)
+
+// Service encola y procesa trabajos de transcodificación. Los handlers guardan el
+// original con Enqueue y el worker (StartWorker) lo codifica y lo entrega al
+// VideoProvider: los que implementan storage.HLSPublisher reciben la escalera HLS
+// y el póster; el resto, un MP4 normalizado que codifican por su cuenta.
+type Service struct {
+	repo         *Repository
+	episodesRepo *episodes.Repository
+	provider     storage.VideoProvider
+	videoStatus  *videostatus.Service
+	cfg          config.TranscodeConfig
+	maxDuration  int
+	wake         chan struct{}
+}
+
+func NewService(db *sql.DB, provider storage.VideoProvider, videoStatus *videostatus.Service, cfg config.TranscodeConfig, maxDurationSeconds int) *Service {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	return &Service{
+		repo:         NewRepository(db),
+		episodesRepo: episodes.NewRepository(db),
+		provider:     provider,
+		videoStatus:  videoStatus,
+		cfg:          cfg,
+		maxDuration:  maxDurationSeconds,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Repo expone el repositorio para los handlers de consulta.
+func (s *Service) Repo() *Repository {
+	return s.repo
+}
+
+// Enqueue guarda el original en WorkDir, lo valida con ffprobe y encola el trabajo.
+// Falla con ErrNotVideo si el archivo no es un video legible y con ErrTooLong si
+// supera la duración máxima; en ambos casos el archivo se descarta.
+func (s *Service) Enqueue(ctx context.Context, episodeID uuid.UUID, createdBy *uuid.UUID, src io.Reader) (*Job, error) {
+	job := &Job{ID: uuid.New(), EpisodeID: episodeID, CreatedBy: createdBy}
+	dir := s.jobDir(job.ID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("transcode: create work dir: %w", err)
+	}
+	job.SourcePath = filepath.Join(dir, "source")
+
+	stored := false
+	defer func() {
+		if !stored {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	f, err := os.Create(job.SourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("transcode: create source: %w", err)
+	}
+	_, err = io.Copy(f, src)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transcode: store source: %w", err)
+	}
+
+	info, err := s.probe(ctx, job.SourcePath)
+	if err != nil {
+		return nil, err
+	}
+	job.DurationSeconds, job.Width, job.Height = info.DurationSeconds, info.Width, info.Height
+
+	if err := s.repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	stored = true
+
+	// Un episodio sin video publicado pasa a 'processing' (uno publicado se sigue
+	// sirviendo hasta que el nuevo esté listo)
+	if episode, err := s.episodesRepo.GetByID(ctx, episodeID); err == nil &&
+		(episode.VideoStatus == storage.VideoStatusCreated || episode.VideoStatus == storage.VideoStatusFailed) {
+		if err := s.videoStatus.Set(ctx, episodeID, episode.VideoIDBunny, storage.VideoStatusProcessing, ""); err != nil {
+			log.Printf("transcode: %v", err)
+		}
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// probe lee el original y aplica el límite de duración.
+func (s *Service) probe(ctx context.Context, file string) (*MediaInfo, error) {
+	info, err := Probe(ctx, s.cfg.FFprobePath, file)
+	if err != nil {
+		return nil, err
+	}
+	if s.maxDuration > 0 && info.DurationSeconds > float64(s.maxDuration) {
+		return nil, fmt.Errorf("%w: %.0fs > %ds", ErrTooLong, info.DurationSeconds, s.maxDuration)
+	}
+	return info, nil
+}
+
+// StartWorker lanza cfg.Workers goroutines que procesan la cola; se despiertan con
+// cada Enqueue o cada `interval`. Además re-encola los trabajos huérfanos de
+// instancias caídas. Corre hasta que ctx se cancele.
+func (s *Service) StartWorker(ctx context.Context, interval time.Duration) {
+	wake := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := s.repo.RequeueStale(ctx, staleAfter, maxAttempts); err != nil {
+				log.Printf("transcode: %v", err)
+			} else if n > 0 {
+				log.Printf("transcode: requeued %d stale jobs", n)
+			}
+			// Despierta a todos los workers libres; los ocupados no bloquean
+			for i := 0; i < s.cfg.Workers; i++ {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+
+	for i := 0; i < s.cfg.Workers; i++ {
+		go func() {
+			for {
+				s.ProcessQueue(ctx)
+				select {
+				case <-ctx.Done():
+					return
+				case <-wake:
+				}
+			}
+		}()
+	}
+}
+
+// ProcessQueue procesa trabajos encolados hasta vaciar la cola.
+func (s *Service) ProcessQueue(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := s.repo.Claim(ctx)
+		if err != nil {
+			log.Printf("transcode: %v", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		err = s.process(ctx, job)
+		if err != nil && ctx.Err() != nil {
+			return // apagado: RequeueStale lo retoma con el original intacto
+		}
+		if err != nil {
+			log.Printf("transcode: job %s failed: %v", job.ID, err)
+			if err := s.repo.Fail(context.Background(), job.ID, err); err != nil {
+				log.Printf("transcode: %v", err)
+			}
+			s.failEpisode(job, err)
+		}
+		os.RemoveAll(s.jobDir(job.ID))
+	}
+}
+
+// process codifica el original de job y lo entrega al proveedor.
+func (s *Service) process(ctx context.Context, job *Job) error {
+	stop := s.heartbeat(ctx, job.ID)
+	defer stop()
+
+	episode, err := s.episodesRepo.GetByID(ctx, job.EpisodeID)
+	if err != nil {
+		return fmt.Errorf("load episode: %w", err)
+	}
+	info, err := s.probe(ctx, job.SourcePath)
+	if err != nil {
+		return err
+	}
+	ladder := BuildLadder(s.cfg.Ladder, info)
+	if len(ladder) == 0 {
+		return fmt.Errorf("empty ladder (TRANSCODE_LADDER)")
+	}
+
+	outDir := filepath.Join(s.jobDir(job.ID), "out")
+	os.RemoveAll(outDir) // restos de un intento anterior
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
+	}
+	onProgress := s.progressReporter(ctx, job.ID)
+
+	publisher, hls := s.provider.(storage.HLSPublisher)
+	var renditions []string
+	output := ""
+	if hls {
+		if err := RunHLS(ctx, s.cfg.FFmpegPath, job.SourcePath, outDir, ladder, info, onProgress); err != nil {
+			return err
+		}
+		if err := Poster(ctx, s.cfg.FFmpegPath, job.SourcePath, filepath.Join(outDir, PosterFile), ladder[len(ladder)-1], info); err != nil {
+			return err
+		}
+		for _, r := range ladder {
+			renditions = append(renditions, r.Name)
+		}
+	} else {
+		top := ladder[len(ladder)-1]
+		output = filepath.Join(outDir, "video.mp4")
+		if err := RunMP4(ctx, s.cfg.FFmpegPath, job.SourcePath, output, top, info, onProgress); err != nil {
+			return err
+		}
+		renditions = []string{top.Name}
+	}
+
+	if err := s.repo.SetPublishing(ctx, job.ID, renditions); err != nil {
+		return err
+	}
+	video, err := s.provider.CreateVideo(episode.Title)
+	if err != nil {
+		return fmt.Errorf("create provider video: %w", err)
+	}
+	if hls {
+		// El original queda como respaldo (y descarga) junto a las renditions
+		if err := s.uploadFile(video.ExternalID, job.SourcePath, sniffVideoType(job.SourcePath)); err != nil {
+			return err
+		}
+		if err := publisher.PublishHLS(video.ExternalID, outDir); err != nil {
+			return fmt.Errorf("publish HLS: %w", err)
+		}
+	} else {
+		if err := s.uploadFile(video.ExternalID, output, "video/mp4"); err != nil {
+			return err
+		}
+		s.provider.CompleteUpload(video.ExternalID) // no crítico
+	}
+
+	if err := s.episodesRepo.UpdateVideoID(ctx, job.EpisodeID, video.ExternalID); err != nil {
+		return err
+	}
+	if _, err := s.videoStatus.SyncEpisode(ctx, job.EpisodeID); err != nil {
+		log.Printf("transcode: sync video status of episode %s: %v", job.EpisodeID, err) // el poller reintenta
+	}
+	if old := episode.VideoIDBunny; old != "" && old != video.ExternalID {
+		if err := s.provider.DeleteVideo(old); err != nil { // no crítico
+			log.Printf("transcode: delete previous video %s: %v", old, err)
+		}
+	}
+	// Se recarga el episodio: ya tiene el video nuevo y pudo editarse durante el encoding
+	if fresh, err := s.episodesRepo.GetByID(ctx, job.EpisodeID); err == nil && fresh.Duration == 0 {
+		fresh.Duration = int(math.Round(info.DurationSeconds))
+		if err := s.episodesRepo.Update(ctx, fresh); err != nil {
+			log.Printf("transcode: set episode %s duration: %v", fresh.ID, err)
+		}
+	}
+	return s.repo.Complete(ctx, job.ID, video.ExternalID)
+}
+
+// failEpisode marca el video del episodio como fallido si el trabajo era su única
+// fuente (sigue en 'processing' sin video); un video ya publicado no se toca.
+func (s *Service) failEpisode(job *Job, cause error) {
+	ctx := context.Background()
+	episode, err := s.episodesRepo.GetByID(ctx, job.EpisodeID)
+	if err != nil || episode.VideoIDBunny != "" || episode.VideoStatus != storage.VideoStatusProcessing {
+		return
+	}
+	if err := s.videoStatus.Set(ctx, episode.ID, "", storage.VideoStatusFailed, "transcode: "+cause.Error()); err != nil {
+		log.Printf("transcode: %v", err)
+	}
+}
+
+func (s *Service) uploadFile(externalID, file, contentType string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("open output: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat output: %w", err)
+	}
+	if err := s.provider.UploadVideo(externalID, f, contentType, info.Size()); err != nil {
+		return fmt.Errorf("upload to provider: %w", err)
+	}
+	return nil
+}
+
+// heartbeat renueva updated_at del trabajo mientras se procesa (las subidas al
+// proveedor pueden tardar más que staleAfter sin reportar avance).
+func (s *Service) heartbeat(ctx context.Context, id uuid.UUID) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.repo.Touch(ctx, id); err != nil {
+					log.Printf("transcode: %v", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// progressReporter persiste el avance de ffmpeg como máximo cada progressInterval.
+func (s *Service) progressReporter(ctx context.Context, id uuid.UUID) func(float64) {
+	var last time.Time
+	return func(p float64) {
+		if time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+		if err := s.repo.UpdateProgress(ctx, id, math.Round(p*1000)/10); err != nil {
+			log.Printf("transcode: %v", err)
+		}
+	}
+}
+
+func (s *Service) jobDir(id uuid.UUID) string {
+	return filepath.Join(s.cfg.WorkDir, id.String())
+}
+
+// sniffVideoType detecta el content type del original; si no es reconocible como
+// video se asume MP4 (ffprobe ya validó que lo es).
+func sniffVideoType(file string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return "video/mp4"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	if ct := http.DetectContentType(head[:n]); strings.HasPrefix(ct, "video/") {
+		return ct
+	}
+	return "video/mp4"
+}
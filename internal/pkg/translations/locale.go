@@ -0,0 +1,152 @@
+package translations
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qenti/qenti/internal/config"
+)
+
+// maxRequestedLocales idiomas de Accept-Language que se consideran, en orden de preferencia.
+const maxRequestedLocales = 4
+
+// localePattern idioma, script y región opcionales (es, pt-BR, es-419, zh-Hant-TW).
+var localePattern = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_]([a-zA-Z]{4}))?(?:[-_]([a-zA-Z]{2}|[0-9]{3}))?$`)
+
+// NormalizeLocale valida un código BCP 47 y lo lleva a la forma canónica (pt_br →
+// pt-BR, zh-hant → zh-Hant). Acepta el guion bajo de los locales de Android/Java.
+func NormalizeLocale(code string) (string, bool) {
+	m := localePattern.FindStringSubmatch(strings.TrimSpace(code))
+	if m == nil {
+		return "", false
+	}
+	locale := strings.ToLower(m[1])
+	if m[2] != "" {
+		locale += "-" + strings.ToUpper(m[2][:1]) + strings.ToLower(m[2][1:])
+	}
+	if m[3] != "" {
+		locale += "-" + strings.ToUpper(m[3])
+	}
+	return locale, true
+}
+
+// baseLanguage devuelve el idioma sin script ni región (pt-BR → pt).
+func baseLanguage(locale string) string {
+	base, _, _ := strings.Cut(locale, "-")
+	return base
+}
+
+// ParseAcceptLanguage devuelve los locales del header Accept-Language ordenados por
+// peso (q), descartando los inválidos, "*" y los de q=0.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	var list []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		locale, ok := NormalizeLocale(tag)
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		list = append(list, weighted{locale, q})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+
+	result := make([]string, 0, len(list))
+	for _, w := range list {
+		result = append(result, w.locale)
+	}
+	return result
+}
+
+// Resolver arma la cadena de fallback de un pedido: los locales pedidos, para cada
+// uno su cadena configurada (o su idioma base) y, al final, el idioma por defecto,
+// que es el de las columnas de series y episodios.
+type Resolver struct {
+	defaultLocale string
+	fallbacks     map[string][]string
+}
+
+// NewResolver lee DEFAULT_LOCALE y LOCALE_FALLBACKS ("pt-BR:pt:es"); las cadenas con
+// locales inválidos se ignoran.
+func NewResolver(cfg config.LocalizationConfig) *Resolver {
+	def, ok := NormalizeLocale(cfg.DefaultLocale)
+	if !ok {
+		def = "es"
+	}
+	r := &Resolver{defaultLocale: def, fallbacks: make(map[string][]string)}
+	for _, entry := range cfg.Fallbacks {
+		var chain []string
+		for _, code := range strings.Split(entry, ":") {
+			locale, ok := NormalizeLocale(code)
+			if !ok {
+				chain = nil
+				break
+			}
+			chain = append(chain, locale)
+		}
+		if len(chain) > 1 {
+			r.fallbacks[chain[0]] = chain[1:]
+		}
+	}
+	return r
+}
+
+// DefaultLocale idioma de las columnas de series y episodios.
+func (r *Resolver) DefaultLocale() string {
+	return r.defaultLocale
+}
+
+// Chain devuelve los locales a probar en orden, terminando siempre en el idioma por
+// defecto. Ej. con "pt-BR:pt:es": [pt-BR] → [pt-BR pt es]; sin cadena configurada,
+// [en-US] → [en-US en es].
+func (r *Resolver) Chain(requested []string) []string {
+	chain := make([]string, 0, 4)
+	seen := make(map[string]bool)
+	add := func(locale string) bool {
+		if !seen[locale] {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+		return locale == r.defaultLocale
+	}
+
+	if len(requested) > maxRequestedLocales {
+		requested = requested[:maxRequestedLocales]
+	}
+	for _, locale := range requested {
+		if add(locale) {
+			return chain
+		}
+		fallbacks, ok := r.fallbacks[locale]
+		if !ok {
+			fallbacks = r.fallbacks[baseLanguage(locale)]
+			if base := baseLanguage(locale); base != locale {
+				fallbacks = append([]string{base}, fallbacks...)
+			}
+		}
+		for _, f := range fallbacks {
+			// Llegar al idioma por defecto corta la cadena: sus textos son las columnas base
+			if add(f) {
+				return chain
+			}
+		}
+	}
+	add(r.defaultLocale)
+	return chain
+}
@@ -0,0 +1,192 @@
+// Package translations guarda los títulos y descripciones de series y episodios en
+// otros idiomas y los aplica a las respuestas de la app según el locale pedido
+// (Accept-Language o ?lang=), con una cadena de fallback configurable que termina
+// en el idioma de las columnas base (DEFAULT_LOCALE).
+package translations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SeriesTranslation textos de una serie en un locale. Un campo nil no está traducido
+// y cae al siguiente locale de la cadena.
+type SeriesTranslation struct {
+	SeriesID    uuid.UUID  `json:"series_id"`
+	Locale      string     `json:"locale"`
+	Title       *string    `json:"title"`
+	Description *string    `json:"description"`
+	UpdatedBy   *uuid.UUID `json:"updated_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// EpisodeTranslation título de un episodio en un locale.
+type EpisodeTranslation struct {
+	EpisodeID uuid.UUID  `json:"episode_id"`
+	Locale    string     `json:"locale"`
+	Title     *string    `json:"title"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const (
+	seriesTranslationColumns  = `series_id, locale, title, description, updated_by, created_at, updated_at`
+	episodeTranslationColumns = `episode_id, locale, title, updated_by, created_at, updated_at`
+)
+
+// uuidArray pasa los IDs como text[] (castear a uuid[] en la consulta).
+func uuidArray(ids []uuid.UUID) interface{} {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+	return pq.Array(keys)
+}
+
+func scanSeriesTranslation(row interface{ Scan(...interface{}) error }) (*SeriesTranslation, error) {
+	var t SeriesTranslation
+	if err := row.Scan(&t.SeriesID, &t.Locale, &t.Title, &t.Description, &t.UpdatedBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func scanEpisodeTranslation(row interface{ Scan(...interface{}) error }) (*EpisodeTranslation, error) {
+	var t EpisodeTranslation
+	if err := row.Scan(&t.EpisodeID, &t.Locale, &t.Title, &t.UpdatedBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListSeries lista las traducciones de una serie por locale.
+func (r *Repository) ListSeries(ctx context.Context, seriesID uuid.UUID) ([]SeriesTranslation, error) {
+	return r.findSeries(ctx, `WHERE series_id = $1 ORDER BY locale`, seriesID)
+}
+
+// FindSeries devuelve las traducciones de las series ids en los locales indicados.
+func (r *Repository) FindSeries(ctx context.Context, ids []uuid.UUID, locales []string) ([]SeriesTranslation, error) {
+	if len(ids) == 0 || len(locales) == 0 {
+		return nil, nil
+	}
+	return r.findSeries(ctx, `WHERE series_id = ANY($1::uuid[]) AND locale = ANY($2)`, uuidArray(ids), pq.Array(locales))
+}
+
+func (r *Repository) findSeries(ctx context.Context, where string, args ...interface{}) ([]SeriesTranslation, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+seriesTranslationColumns+` FROM series_translations `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series translations: %w", err)
+	}
+	defer rows.Close()
+
+	var list []SeriesTranslation
+	for rows.Next() {
+		t, err := scanSeriesTranslation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan series translation: %w", err)
+		}
+		list = append(list, *t)
+	}
+	return list, rows.Err()
+}
+
+// UpsertSeries crea o reemplaza la traducción de la serie en t.Locale.
+func (r *Repository) UpsertSeries(ctx context.Context, t *SeriesTranslation) error {
+	saved, err := scanSeriesTranslation(r.db.QueryRowContext(ctx, `
+		INSERT INTO series_translations (series_id, locale, title, description, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (series_id, locale) DO UPDATE SET
+			title = EXCLUDED.title, description = EXCLUDED.description,
+			updated_by = EXCLUDED.updated_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING `+seriesTranslationColumns,
+		t.SeriesID, t.Locale, t.Title, t.Description, t.UpdatedBy))
+	if err != nil {
+		return fmt.Errorf("failed to save series translation: %w", err)
+	}
+	*t = *saved
+	return nil
+}
+
+// DeleteSeries borra la traducción de la serie en locale. Devuelve false si no existía.
+func (r *Repository) DeleteSeries(ctx context.Context, seriesID uuid.UUID, locale string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM series_translations WHERE series_id = $1 AND locale = $2`, seriesID, locale)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete series translation: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListEpisode lista las traducciones de un episodio por locale.
+func (r *Repository) ListEpisode(ctx context.Context, episodeID uuid.UUID) ([]EpisodeTranslation, error) {
+	return r.findEpisodes(ctx, `WHERE episode_id = $1 ORDER BY locale`, episodeID)
+}
+
+// FindEpisodes devuelve las traducciones de los episodios ids en los locales indicados.
+func (r *Repository) FindEpisodes(ctx context.Context, ids []uuid.UUID, locales []string) ([]EpisodeTranslation, error) {
+	if len(ids) == 0 || len(locales) == 0 {
+		return nil, nil
+	}
+	return r.findEpisodes(ctx, `WHERE episode_id = ANY($1::uuid[]) AND locale = ANY($2)`, uuidArray(ids), pq.Array(locales))
+}
+
+func (r *Repository) findEpisodes(ctx context.Context, where string, args ...interface{}) ([]EpisodeTranslation, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+episodeTranslationColumns+` FROM episode_translations `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list episode translations: %w", err)
+	}
+	defer rows.Close()
+
+	var list []EpisodeTranslation
+	for rows.Next() {
+		t, err := scanEpisodeTranslation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan episode translation: %w", err)
+		}
+		list = append(list, *t)
+	}
+	return list, rows.Err()
+}
+
+// UpsertEpisode crea o reemplaza la traducción del episodio en t.Locale.
+func (r *Repository) UpsertEpisode(ctx context.Context, t *EpisodeTranslation) error {
+	saved, err := scanEpisodeTranslation(r.db.QueryRowContext(ctx, `
+		INSERT INTO episode_translations (episode_id, locale, title, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (episode_id, locale) DO UPDATE SET
+			title = EXCLUDED.title, updated_by = EXCLUDED.updated_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING `+episodeTranslationColumns,
+		t.EpisodeID, t.Locale, t.Title, t.UpdatedBy))
+	if err != nil {
+		return fmt.Errorf("failed to save episode translation: %w", err)
+	}
+	*t = *saved
+	return nil
+}
+
+// DeleteEpisode borra la traducción del episodio en locale. Devuelve false si no existía.
+func (r *Repository) DeleteEpisode(ctx context.Context, episodeID uuid.UUID, locale string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM episode_translations WHERE episode_id = $1 AND locale = $2`, episodeID, locale)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete episode translation: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
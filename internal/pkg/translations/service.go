@@ -0,0 +1,175 @@
+package translations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/models"
+)
+
+// SeriesText textos resueltos de una serie.
+type SeriesText struct {
+	Title       string
+	Description string
+}
+
+type Service struct {
+	repo     *Repository
+	resolver *Resolver
+}
+
+func NewService(db *sql.DB, cfg config.LocalizationConfig) *Service {
+	return &Service{repo: NewRepository(db), resolver: NewResolver(cfg)}
+}
+
+// Repo expone el repositorio para los handlers de administración.
+func (s *Service) Repo() *Repository {
+	return s.repo
+}
+
+// Resolver devuelve el resolver de cadenas de fallback.
+func (s *Service) Resolver() *Resolver {
+	return s.resolver
+}
+
+// lookupLocales locales de la cadena que hay que buscar en las tablas de traducción:
+// todos menos el idioma por defecto, que son las columnas base.
+func (s *Service) lookupLocales(chain []string) []string {
+	locales := make([]string, 0, len(chain))
+	for _, l := range chain {
+		if l == s.resolver.defaultLocale {
+			break
+		}
+		locales = append(locales, l)
+	}
+	return locales
+}
+
+// SeriesTexts resuelve título y descripción de las series ids según la cadena. Solo
+// incluye las series con al menos un campo traducido; cada campo cae por separado al
+// siguiente locale (un título en pt-BR puede venir con la descripción en pt).
+func (s *Service) SeriesTexts(ctx context.Context, chain []string, ids []uuid.UUID) (map[uuid.UUID]SeriesText, error) {
+	locales := s.lookupLocales(chain)
+	list, err := s.repo.FindSeries(ctx, ids, locales)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	byKey := make(map[uuid.UUID]map[string]SeriesTranslation, len(list))
+	for _, t := range list {
+		if byKey[t.SeriesID] == nil {
+			byKey[t.SeriesID] = make(map[string]SeriesTranslation)
+		}
+		byKey[t.SeriesID][t.Locale] = t
+	}
+
+	result := make(map[uuid.UUID]SeriesText, len(byKey))
+	for id, translations := range byKey {
+		var text SeriesText
+		for _, l := range locales {
+			t, ok := translations[l]
+			if !ok {
+				continue
+			}
+			if text.Title == "" && t.Title != nil && *t.Title != "" {
+				text.Title = *t.Title
+			}
+			if text.Description == "" && t.Description != nil && *t.Description != "" {
+				text.Description = *t.Description
+			}
+		}
+		result[id] = text
+	}
+	return result, nil
+}
+
+// EpisodeTitles resuelve el título de los episodios ids según la cadena. Solo incluye
+// los episodios traducidos.
+func (s *Service) EpisodeTitles(ctx context.Context, chain []string, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	locales := s.lookupLocales(chain)
+	list, err := s.repo.FindEpisodes(ctx, ids, locales)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	rank := make(map[string]int, len(locales))
+	for i, l := range locales {
+		rank[l] = i
+	}
+	result := make(map[uuid.UUID]string, len(list))
+	best := make(map[uuid.UUID]int, len(list))
+	for _, t := range list {
+		if t.Title == nil || *t.Title == "" {
+			continue
+		}
+		if r, ok := best[t.EpisodeID]; ok && r <= rank[t.Locale] {
+			continue
+		}
+		best[t.EpisodeID] = rank[t.Locale]
+		result[t.EpisodeID] = *t.Title
+	}
+	return result, nil
+}
+
+// LocalizeSeries reemplaza en su lugar título y descripción de las series por los del
+// locale pedido; lo no traducido queda en el idioma por defecto.
+func (s *Service) LocalizeSeries(ctx context.Context, chain []string, list []models.Series) error {
+	if len(list) == 0 || len(s.lookupLocales(chain)) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(list))
+	for i := range list {
+		ids[i] = list[i].ID
+	}
+	texts, err := s.SeriesTexts(ctx, chain, ids)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		applySeriesText(&list[i], texts[list[i].ID])
+	}
+	return nil
+}
+
+// LocalizeOneSeries es LocalizeSeries para una sola serie.
+func (s *Service) LocalizeOneSeries(ctx context.Context, chain []string, series *models.Series) error {
+	if len(s.lookupLocales(chain)) == 0 {
+		return nil
+	}
+	texts, err := s.SeriesTexts(ctx, chain, []uuid.UUID{series.ID})
+	if err != nil {
+		return err
+	}
+	applySeriesText(series, texts[series.ID])
+	return nil
+}
+
+func applySeriesText(series *models.Series, text SeriesText) {
+	if text.Title != "" {
+		series.Title = text.Title
+	}
+	if text.Description != "" {
+		series.Description = text.Description
+	}
+}
+
+// LocalizeEpisodes reemplaza en su lugar el título de los episodios por el del locale pedido.
+func (s *Service) LocalizeEpisodes(ctx context.Context, chain []string, list []models.Episode) error {
+	if len(list) == 0 || len(s.lookupLocales(chain)) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(list))
+	for i := range list {
+		ids[i] = list[i].ID
+	}
+	titles, err := s.EpisodeTitles(ctx, chain, ids)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		if title, ok := titles[list[i].ID]; ok {
+			list[i].Title = title
+		}
+	}
+	return nil
+}
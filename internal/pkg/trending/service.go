@@ -0,0 +1,191 @@
+// Package trending calcula los rankings de series de la app a partir de
+// series_daily_rollups: trending (vistas, finalizaciones y unlocks de los últimos
+// días, cada día con menos peso cuanto más viejo) y más vistas de todos los
+// tiempos. Cada ranking (y su lista de series ya armada) se guarda en el cache por
+// tenant durante CacheSeconds; los cambios de series desde el admin lo invalidan.
+package trending
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/cache"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/series"
+)
+
+// Pesos de cada tipo de actividad en el score de trending.
+const (
+	viewWeight       = 1
+	completionWeight = 2
+	unlockWeight     = 3
+)
+
+// Score posición de una serie en un ranking.
+type Score struct {
+	SeriesID uuid.UUID
+	Score    float64
+}
+
+type Service struct {
+	db         *sql.DB
+	cfg        config.TrendingConfig
+	cache      *cache.Cache
+	seriesRepo *series.Repository
+}
+
+func NewService(db *sql.DB, cfg config.TrendingConfig, cacheStore *cache.Cache, seriesRepo *series.Repository) *Service {
+	return &Service{db: db, cfg: cfg, cache: cacheStore, seriesRepo: seriesRepo}
+}
+
+// WindowDays días de actividad que cuentan para Trending.
+func (s *Service) WindowDays() int {
+	return s.cfg.WindowDays
+}
+
+// Trending devuelve las series activas con actividad en los últimos WindowDays días
+// (de la app del productor si producerID no es nil), de mayor a menor score: la
+// actividad de cada día vale views + completions×2 + unlocks×3 y pierde la mitad
+// cada HalfLifeHours.
+func (s *Service) Trending(ctx context.Context, producerID *uuid.UUID) ([]Score, error) {
+	halfLife := s.cfg.HalfLifeHours
+	if halfLife <= 0 {
+		halfLife = 48
+	}
+	return s.ranking(ctx, "trending", producerID, fmt.Sprintf(`
+		SUM((r.views * %d + r.completions * %d + (r.unlocks_coin + r.unlocks_ad + r.unlocks_sub) * %d)
+		    * POWER(0.5, (CURRENT_DATE - r.day) * 24.0 / $1))`,
+		viewWeight, completionWeight, unlockWeight),
+		`r.day > CURRENT_DATE - $2::int`, halfLife, s.cfg.WindowDays)
+}
+
+// MostViewed devuelve las series activas con vistas (de la app del productor si
+// producerID no es nil), de más a menos vistas de todos los tiempos.
+func (s *Service) MostViewed(ctx context.Context, producerID *uuid.UUID) ([]Score, error) {
+	return s.ranking(ctx, "most_viewed", producerID, `SUM(r.views)`, `TRUE`)
+}
+
+// TrendingSeries devuelve las series activas (de la app del productor si producerID
+// no es nil) en el orden de Trending, completando con las más nuevas hasta limit.
+func (s *Service) TrendingSeries(ctx context.Context, producerID *uuid.UUID, limit int) ([]models.Series, error) {
+	return s.rankedSeries(ctx, "trending", producerID, limit, s.Trending)
+}
+
+// MostViewedSeries como TrendingSeries en el orden de MostViewed.
+func (s *Service) MostViewedSeries(ctx context.Context, producerID *uuid.UUID, limit int) ([]models.Series, error) {
+	return s.rankedSeries(ctx, "most_viewed", producerID, limit, s.MostViewed)
+}
+
+// Rank ordena allSeries según ranking y completa con el resto en su orden (de la
+// más nueva a la más vieja) hasta limit. Las series del ranking que no están en
+// allSeries (otro tenant) se omiten.
+func Rank(allSeries []models.Series, ranking []Score, limit int) []models.Series {
+	byID := make(map[uuid.UUID]int, len(allSeries))
+	for i, s := range allSeries {
+		byID[s.ID] = i
+	}
+	result := make([]models.Series, 0, limit)
+	added := make(map[uuid.UUID]bool, limit)
+	for _, sc := range ranking {
+		if len(result) >= limit {
+			return result
+		}
+		if i, ok := byID[sc.SeriesID]; ok {
+			result = append(result, allSeries[i])
+			added[sc.SeriesID] = true
+		}
+	}
+	for _, s := range allSeries {
+		if len(result) >= limit {
+			break
+		}
+		if !added[s.ID] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// rankedSeries arma con Rank la lista de series del ranking, o la devuelve del cache.
+func (s *Service) rankedSeries(ctx context.Context, name string, producerID *uuid.UUID, limit int, ranking func(context.Context, *uuid.UUID) ([]Score, error)) ([]models.Series, error) {
+	load := func(ctx context.Context) ([]models.Series, error) {
+		scores, err := ranking(ctx, producerID)
+		if err != nil {
+			return nil, err
+		}
+		allSeries, err := s.seriesRepo.GetAllFiltered(ctx, producerID)
+		if err != nil {
+			return nil, err
+		}
+		return Rank(allSeries, scores, limit), nil
+	}
+	return cached(ctx, s, s.cacheKey(name+"_series", producerID)+":"+strconv.Itoa(limit), load)
+}
+
+// ranking ordena las series por el agregado score de sus rollups que cumplen where,
+// o devuelve el ranking en cache. args son los parámetros de score y where.
+func (s *Service) ranking(ctx context.Context, name string, producerID *uuid.UUID, score, where string, args ...interface{}) ([]Score, error) {
+	return cached(ctx, s, s.cacheKey(name, producerID), func(ctx context.Context) ([]Score, error) {
+		return s.query(ctx, name, producerID, score, where, args...)
+	})
+}
+
+// cacheKey clave del cache de name en el tenant producerID.
+func (s *Service) cacheKey(name string, producerID *uuid.UUID) string {
+	key := "trending:" + name
+	if producerID != nil {
+		key += ":" + producerID.String()
+	}
+	return key
+}
+
+// cached devuelve load del cache durante CacheSeconds (sin cache si es 0). Las
+// entradas llevan el tag de las series: activar, desactivar o mover una de
+// productor cambia los rankings.
+func cached[T any](ctx context.Context, s *Service, key string, load func(context.Context) (T, error)) (T, error) {
+	if s.cfg.CacheSeconds <= 0 {
+		return load(ctx)
+	}
+	return cache.GetOrLoad(ctx, s.cache, key, time.Duration(s.cfg.CacheSeconds)*time.Second, []string{series.CacheTag}, load)
+}
+
+// query calcula el ranking en la DB.
+func (s *Service) query(ctx context.Context, name string, producerID *uuid.UUID, score, where string, args ...interface{}) ([]Score, error) {
+	query := `
+		SELECT r.series_id, ` + score + `::float8 AS score
+		FROM series_daily_rollups r
+		JOIN series s ON s.id = r.series_id AND s.is_active = TRUE
+		WHERE ` + where
+	if producerID != nil {
+		args = append(args, *producerID)
+		query += fmt.Sprintf(` AND s.producer_id = $%d`, len(args))
+	}
+	query += `
+		GROUP BY r.series_id
+		HAVING ` + score + ` > 0
+		ORDER BY score DESC, MAX(s.created_at) DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank %s series: %w", name, err)
+	}
+	defer rows.Close()
+
+	var ranking []Score
+	for rows.Next() {
+		var sc Score
+		if err := rows.Scan(&sc.SeriesID, &sc.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan %s series: %w", name, err)
+		}
+		ranking = append(ranking, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to rank %s series: %w", name, err)
+	}
+	return ranking, nil
+}
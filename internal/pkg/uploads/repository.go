@@ -0,0 +1,245 @@
+// Package uploads implementa los uploads reanudables de video (protocolo TUS 1.0.0)
+// del admin: el archivo se arma en disco parte por parte, el offset confirmado se
+// persiste para retomar tras un corte y, completo, se entrega al VideoProvider (o a
+// la transcodificación propia). Con proveedores storage.ChunkStreamer cada parte se
+// reenvía apenas se completa.
+package uploads
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Estados de upload_sessions.
+const (
+	StatusUploading  = "uploading"
+	StatusFinalizing = "finalizing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+	StatusExpired    = "expired"
+	StatusAborted    = "aborted"
+)
+
+// Session es un upload reanudable del video de un episodio.
+type Session struct {
+	ID             uuid.UUID  `json:"id"`
+	EpisodeID      uuid.UUID  `json:"episode_id"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	Length         int64      `json:"upload_length"`
+	Offset         int64      `json:"upload_offset"`
+	Filename       string     `json:"filename,omitempty"`
+	ContentType    string     `json:"content_type"`
+	Status         string     `json:"status"`
+	VideoID        string     `json:"video_id,omitempty"`
+	ProviderState  string     `json:"-"`
+	ProviderOffset int64      `json:"provider_offset"`
+	StreamError    string     `json:"stream_error,omitempty"`
+	TranscodeJobID *uuid.UUID `json:"transcode_job_id,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const sessionColumns = `id, episode_id, created_by, upload_length, upload_offset, COALESCE(filename, ''), content_type,
+	status, COALESCE(video_id, ''), COALESCE(provider_state, ''), provider_offset, COALESCE(stream_error, ''),
+	transcode_job_id, COALESCE(error, ''), created_at, updated_at, expires_at`
+
+func scanSession(row interface{ Scan(...interface{}) error }) (*Session, error) {
+	var s Session
+	err := row.Scan(&s.ID, &s.EpisodeID, &s.CreatedBy, &s.Length, &s.Offset, &s.Filename, &s.ContentType,
+		&s.Status, &s.VideoID, &s.ProviderState, &s.ProviderOffset, &s.StreamError,
+		&s.TranscodeJobID, &s.Error, &s.CreatedAt, &s.UpdatedAt, &s.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func scanSessions(rows *sql.Rows) ([]Session, error) {
+	defer rows.Close()
+	var sessions []Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan upload session: %w", err)
+		}
+		sessions = append(sessions, *s)
+	}
+	return sessions, rows.Err()
+}
+
+// Create registra una sesión nueva que expira tras ttl sin actividad.
+func (r *Repository) Create(ctx context.Context, s *Session, ttl time.Duration) error {
+	created, err := scanSession(r.db.QueryRowContext(ctx, `
+		INSERT INTO upload_sessions (id, episode_id, created_by, upload_length, filename, content_type, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NOW() + make_interval(secs => $7))
+		RETURNING `+sessionColumns,
+		s.ID, s.EpisodeID, s.CreatedBy, s.Length, s.Filename, s.ContentType, ttl.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to create upload session: %w", err)
+	}
+	*s = *created
+	return nil
+}
+
+// Get devuelve una sesión por ID, o nil si no existe.
+func (r *Repository) Get(ctx context.Context, id uuid.UUID) (*Session, error) {
+	s, err := scanSession(r.db.QueryRowContext(ctx,
+		`SELECT `+sessionColumns+` FROM upload_sessions WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload session: %w", err)
+	}
+	return s, nil
+}
+
+// Advance confirma los bytes recibidos (de `from` a `to`) y renueva la expiración.
+// Solo aplica si el offset persistido sigue siendo `from`; devuelve la sesión
+// actualizada o nil si otro request la modificó.
+func (r *Repository) Advance(ctx context.Context, id uuid.UUID, from, to int64, ttl time.Duration) (*Session, error) {
+	s, err := scanSession(r.db.QueryRowContext(ctx, `
+		UPDATE upload_sessions
+		SET upload_offset = $3, updated_at = NOW(), expires_at = NOW() + make_interval(secs => $4)
+		WHERE id = $1 AND upload_offset = $2 AND status = 'uploading'
+		RETURNING `+sessionColumns, id, from, to, ttl.Seconds()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to advance upload session: %w", err)
+	}
+	return s, nil
+}
+
+// SetStream registra el estado del upload por partes en el proveedor.
+func (r *Repository) SetStream(ctx context.Context, id uuid.UUID, videoID, state string, providerOffset int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE upload_sessions
+		SET video_id = NULLIF($2, ''), provider_state = NULLIF($3, ''), provider_offset = $4
+		WHERE id = $1`, id, videoID, state, providerOffset)
+	if err != nil {
+		return fmt.Errorf("failed to update upload stream: %w", err)
+	}
+	return nil
+}
+
+// DisableStream descarta el upload por partes: la sesión se entregará completa al
+// finalizar.
+func (r *Repository) DisableStream(ctx context.Context, id uuid.UUID, cause error) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE upload_sessions
+		SET video_id = NULL, provider_state = NULL, provider_offset = 0, stream_error = $2
+		WHERE id = $1`, id, cause.Error())
+	if err != nil {
+		return fmt.Errorf("failed to disable upload stream: %w", err)
+	}
+	return nil
+}
+
+// StartFinalizing pasa a 'finalizing' una sesión con todos los bytes recibidos.
+// Devuelve false si ya no estaba subiendo (otro request la tomó).
+func (r *Repository) StartFinalizing(ctx context.Context, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE upload_sessions SET status = 'finalizing', updated_at = NOW()
+		WHERE id = $1 AND status = 'uploading' AND upload_offset = upload_length`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to finalize upload session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Touch renueva el heartbeat de una sesión que se está finalizando.
+func (r *Repository) Touch(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE upload_sessions SET updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to touch upload session: %w", err)
+	}
+	return nil
+}
+
+// Complete marca la sesión como entregada, con el video resultante o el trabajo de
+// transcodificación que lo generará.
+func (r *Repository) Complete(ctx context.Context, id uuid.UUID, videoID string, jobID *uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE upload_sessions
+		SET status = 'completed', video_id = NULLIF($2, ''), transcode_job_id = $3, provider_state = NULL,
+			error = NULL, updated_at = NOW()
+		WHERE id = $1`, id, videoID, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to complete upload session: %w", err)
+	}
+	return nil
+}
+
+// Fail marca la sesión como fallida.
+func (r *Repository) Fail(ctx context.Context, id uuid.UUID, cause error) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE upload_sessions SET status = 'failed', error = $2, provider_state = NULL, updated_at = NOW()
+		WHERE id = $1`, id, cause.Error())
+	if err != nil {
+		return fmt.Errorf("failed to mark upload session failed: %w", err)
+	}
+	return nil
+}
+
+// Abort cancela una sesión que sigue subiendo. Devuelve la sesión cancelada (para
+// limpiar el archivo y el upload en el proveedor) o nil si no estaba subiendo.
+func (r *Repository) Abort(ctx context.Context, id uuid.UUID) (*Session, error) {
+	s, err := scanSession(r.db.QueryRowContext(ctx, `
+		UPDATE upload_sessions SET status = 'aborted', updated_at = NOW()
+		WHERE id = $1 AND status = 'uploading'
+		RETURNING `+sessionColumns, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to abort upload session: %w", err)
+	}
+	return s, nil
+}
+
+// ExpireStale marca como vencidas las sesiones incompletas sin actividad y las
+// devuelve para limpiarlas.
+func (r *Repository) ExpireStale(ctx context.Context) ([]Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE upload_sessions SET status = 'expired', updated_at = NOW()
+		WHERE status = 'uploading' AND expires_at < NOW()
+		RETURNING `+sessionColumns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire upload sessions: %w", err)
+	}
+	return scanSessions(rows)
+}
+
+// ClaimStaleFinalizing toma las sesiones en 'finalizing' sin heartbeat desde hace
+// más de staleAfter (la instancia que las entregaba murió) renovando su heartbeat.
+func (r *Repository) ClaimStaleFinalizing(ctx context.Context, staleAfter time.Duration) ([]Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE upload_sessions SET updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM upload_sessions
+			WHERE status = 'finalizing' AND updated_at < NOW() - make_interval(secs => $1)
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+sessionColumns, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim stale upload sessions: %w", err)
+	}
+	return scanSessions(rows)
+}
@@ -0,0 +1,586 @@
+package uploads
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/config"
+	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/media"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/storage"
+	"github.com/qenti/qenti/internal/pkg/transcode"
+	"github.com/qenti/qenti/internal/pkg/videostatus"
+)
+
+var (
+	// ErrNotFound la sesión no existe.
+	ErrNotFound = errors.New("uploads: session not found")
+	// ErrBusy otro request está escribiendo en la sesión.
+	ErrBusy = errors.New("uploads: session is busy")
+	// ErrOffsetMismatch el Upload-Offset del request no coincide con el persistido.
+	ErrOffsetMismatch = errors.New("uploads: offset mismatch")
+	// ErrClosed la sesión ya no acepta datos (completa, vencida o cancelada).
+	ErrClosed = errors.New("uploads: session is closed")
+)
+
+const (
+	// finalizeStaleAfter sin heartbeat durante este tiempo una entrega en curso se retoma.
+	finalizeStaleAfter = 10 * time.Minute
+	// heartbeatInterval cada cuánto se renueva el heartbeat de una entrega en curso.
+	heartbeatInterval = time.Minute
+	// streamWait espera entre intentos de tomar el reenvío de partes de una sesión.
+	streamWait = time.Second
+)
+
+// Service administra los uploads reanudables. Las partes se guardan en
+// VideoUploadConfig.ResumableDir; con varias instancias ese directorio debe ser
+// compartido para que cualquiera pueda retomar una sesión. Los locks por sesión
+// son advisory locks de Postgres, así que también valen entre instancias.
+type Service struct {
+	db           *sql.DB
+	repo         *Repository
+	episodesRepo *episodes.Repository
+	provider     storage.VideoProvider
+	videoStatus  *videostatus.Service
+	transcode    *transcode.Service
+	media        *media.Service
+	dir          string
+	ttl          time.Duration
+}
+
+// NewService crea el servicio; transcodeService y mediaService son nil si la
+// transcodificación propia o el análisis de los uploads están deshabilitados.
+func NewService(db *sql.DB, provider storage.VideoProvider, videoStatus *videostatus.Service, transcodeService *transcode.Service, mediaService *media.Service, cfg config.VideoUploadConfig) *Service {
+	if cfg.ResumableExpiryHours < 1 {
+		cfg.ResumableExpiryHours = 24
+	}
+	return &Service{
+		db:           db,
+		repo:         NewRepository(db),
+		episodesRepo: episodes.NewRepository(db),
+		provider:     provider,
+		videoStatus:  videoStatus,
+		transcode:    transcodeService,
+		media:        mediaService,
+		dir:          cfg.ResumableDir,
+		ttl:          time.Duration(cfg.ResumableExpiryHours) * time.Hour,
+	}
+}
+
+// Repo expone el repositorio para los handlers de consulta.
+func (s *Service) Repo() *Repository {
+	return s.repo
+}
+
+// Create abre una sesión de `length` bytes para el video del episodio.
+func (s *Service) Create(ctx context.Context, episode *models.Episode, createdBy *uuid.UUID, length int64, filename, contentType string) (*Session, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("uploads: create dir: %w", err)
+	}
+	session := &Session{
+		ID:          uuid.New(),
+		EpisodeID:   episode.ID,
+		CreatedBy:   createdBy,
+		Length:      length,
+		Filename:    filename,
+		ContentType: contentType,
+	}
+	f, err := os.Create(s.partPath(session.ID))
+	if err != nil {
+		return nil, fmt.Errorf("uploads: create part file: %w", err)
+	}
+	f.Close()
+
+	if err := s.repo.Create(ctx, session, s.ttl); err != nil {
+		os.Remove(s.partPath(session.ID))
+		return nil, err
+	}
+	if err := s.videoStatus.MarkUploading(ctx, episode); err != nil {
+		log.Printf("uploads: %v", err)
+	}
+	return session, nil
+}
+
+// Write agrega al archivo los bytes de data a partir de offset, que debe ser el
+// offset confirmado de la sesión. Los bytes recibidos se confirman aunque la
+// conexión se corte a mitad de camino, para que el cliente retome desde ahí. Con
+// el archivo completo la sesión pasa a 'finalizing' y se entrega en segundo plano.
+func (s *Service) Write(ctx context.Context, id uuid.UUID, offset int64, data io.Reader) (*Session, error) {
+	unlock, ok, err := s.tryLock(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrBusy
+	}
+	defer unlock()
+
+	session, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrNotFound
+	}
+	if session.Status != StatusUploading {
+		return session, ErrClosed
+	}
+	if offset != session.Offset {
+		return session, ErrOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.partPath(id), os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("uploads: open part file: %w", err)
+	}
+	// Descarta bytes escritos y nunca confirmados (p. ej. un corte antes de persistir)
+	if err := f.Truncate(session.Offset); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("uploads: truncate part file: %w", err)
+	}
+	if _, err := f.Seek(session.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("uploads: seek part file: %w", err)
+	}
+	n, copyErr := io.Copy(f, io.LimitReader(data, session.Length-session.Offset))
+	if err := f.Sync(); copyErr == nil {
+		copyErr = err
+	}
+	if err := f.Close(); copyErr == nil {
+		copyErr = err
+	}
+
+	if n > 0 {
+		// El request puede estar cancelado (cliente desconectado): se persiste igual
+		updated, err := s.repo.Advance(context.Background(), id, session.Offset, session.Offset+n, s.ttl)
+		if err != nil {
+			return nil, err
+		}
+		if updated == nil {
+			return session, ErrClosed
+		}
+		session = updated
+	}
+	if copyErr != nil {
+		return session, fmt.Errorf("uploads: receive data: %w", copyErr)
+	}
+
+	if session.Offset == session.Length {
+		ok, err := s.repo.StartFinalizing(context.Background(), id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			session.Status = StatusFinalizing
+			go s.finalize(id)
+		}
+	} else if n > 0 {
+		s.streamAsync(id)
+	}
+	return session, nil
+}
+
+// Terminate cancela una sesión que sigue subiendo y descarta lo recibido.
+func (s *Service) Terminate(ctx context.Context, id uuid.UUID) error {
+	unlock, ok, err := s.tryLock(ctx, id.String())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrBusy
+	}
+	defer unlock()
+
+	session, err := s.repo.Abort(ctx, id)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		existing, err := s.repo.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return ErrNotFound
+		}
+		return ErrClosed
+	}
+	go s.discard(session.ID, session.EpisodeID, "upload aborted")
+	return nil
+}
+
+// StartWorker cada `interval` descarta las sesiones vencidas y retoma las entregas
+// huérfanas de instancias caídas, hasta que ctx se cancele.
+func (s *Service) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.sweep(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Service) sweep(ctx context.Context) {
+	expired, err := s.repo.ExpireStale(ctx)
+	if err != nil {
+		log.Printf("uploads: %v", err)
+	}
+	for _, session := range expired {
+		go s.discard(session.ID, session.EpisodeID, "upload expired")
+	}
+
+	stale, err := s.repo.ClaimStaleFinalizing(ctx, finalizeStaleAfter)
+	if err != nil {
+		log.Printf("uploads: %v", err)
+	}
+	for _, session := range stale {
+		log.Printf("uploads: resuming delivery of session %s", session.ID)
+		go s.finalize(session.ID)
+	}
+}
+
+// streamAsync reenvía al proveedor las partes completas que falten, si no hay ya
+// un reenvío en curso para la sesión (ese mismo retoma lo nuevo).
+func (s *Service) streamAsync(id uuid.UUID) {
+	if _, ok := s.streamer(); !ok {
+		return
+	}
+	ctx := context.Background()
+	unlock, ok, err := s.tryLock(ctx, streamKey(id))
+	if err != nil {
+		log.Printf("uploads: session %s: %v", id, err)
+	}
+	if !ok {
+		return
+	}
+	go func() {
+		defer unlock()
+		session, err := s.repo.Get(ctx, id)
+		if err != nil || session == nil {
+			return
+		}
+		if err := s.pump(ctx, session); err != nil {
+			log.Printf("uploads: session %s: stream to provider: %v", id, err)
+		}
+	}()
+}
+
+// pump envía al proveedor las partes recibidas desde provider_offset. Requiere el
+// lock de reenvío de la sesión. Si el proveedor falla, el reenvío se descarta y la
+// sesión se entrega completa al finalizar.
+func (s *Service) pump(ctx context.Context, session *Session) error {
+	streamer, ok := s.streamer()
+	if !ok || session.StreamError != "" {
+		return nil
+	}
+	chunk := streamer.ChunkSize()
+	for {
+		fresh, err := s.repo.Get(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+		if fresh == nil {
+			return ErrNotFound
+		}
+		*session = *fresh
+		if session.StreamError != "" || session.ProviderOffset >= session.Offset {
+			return nil
+		}
+		if session.Status != StatusUploading && session.Status != StatusFinalizing {
+			return nil
+		}
+		n := min(chunk, session.Offset-session.ProviderOffset)
+		if n < chunk && session.Offset < session.Length {
+			return nil // parte incompleta: se espera a recibir más
+		}
+
+		if session.ProviderState == "" {
+			title := session.Filename
+			if episode, err := s.episodesRepo.GetByID(ctx, session.EpisodeID); err == nil {
+				title = episode.Title
+			}
+			videoID, state, err := streamer.StartChunkedUpload(title, session.ContentType, session.Length)
+			if err != nil {
+				return s.disableStream(ctx, session, err)
+			}
+			if err := s.repo.SetStream(ctx, session.ID, videoID, state, 0); err != nil {
+				streamer.AbortChunkedUpload(state)
+				return err
+			}
+			session.VideoID, session.ProviderState = videoID, state
+		}
+
+		f, err := os.Open(s.partPath(session.ID))
+		if err != nil {
+			return fmt.Errorf("uploads: open part file: %w", err)
+		}
+		state, err := streamer.WriteChunk(session.ProviderState, session.ProviderOffset,
+			io.NewSectionReader(f, session.ProviderOffset, n), n)
+		f.Close()
+		if err != nil {
+			return s.disableStream(ctx, session, err)
+		}
+		if err := s.repo.SetStream(ctx, session.ID, session.VideoID, state, session.ProviderOffset+n); err != nil {
+			return err
+		}
+	}
+}
+
+// disableStream aborta el upload por partes en el proveedor y lo descarta.
+func (s *Service) disableStream(ctx context.Context, session *Session, cause error) error {
+	if streamer, ok := s.streamer(); ok && session.ProviderState != "" {
+		if err := streamer.AbortChunkedUpload(session.ProviderState); err != nil {
+			log.Printf("uploads: session %s: abort chunked upload: %v", session.ID, err)
+		}
+	}
+	if err := s.repo.DisableStream(ctx, session.ID, cause); err != nil {
+		return err
+	}
+	session.VideoID, session.ProviderState, session.ProviderOffset, session.StreamError = "", "", 0, cause.Error()
+	return cause
+}
+
+// finalize entrega el archivo completo y libera el disco.
+func (s *Service) finalize(id uuid.UUID) {
+	ctx := context.Background()
+	stop := s.heartbeat(ctx, id)
+	defer stop()
+
+	// Espera a que termine un reenvío de partes en curso
+	defer s.waitLock(ctx, streamKey(id))()
+
+	session, err := s.repo.Get(ctx, id)
+	if err != nil || session == nil || session.Status != StatusFinalizing {
+		return
+	}
+	if err := s.deliver(ctx, session); err != nil {
+		log.Printf("uploads: session %s failed: %v", id, err)
+		if err := s.repo.Fail(ctx, id, err); err != nil {
+			log.Printf("uploads: %v", err)
+		}
+		s.revertEpisode(session.EpisodeID, storage.VideoStatusFailed, "upload: "+err.Error())
+	}
+	os.Remove(s.partPath(id))
+}
+
+// deliver valida el archivo (MEDIA_PROBE_ENABLED), lo encola en la transcodificación
+// propia o lo sube al proveedor y lo asigna al episodio.
+func (s *Service) deliver(ctx context.Context, session *Session) error {
+	var info *transcode.MediaInfo
+	if s.media != nil {
+		var err error
+		if info, err = s.media.Inspect(ctx, s.partPath(session.ID)); err != nil {
+			if streamer, ok := s.streamer(); ok && session.ProviderState != "" {
+				streamer.AbortChunkedUpload(session.ProviderState)
+			}
+			return err
+		}
+	}
+
+	if s.transcode != nil {
+		f, err := os.Open(s.partPath(session.ID))
+		if err != nil {
+			return fmt.Errorf("open part file: %w", err)
+		}
+		job, err := s.transcode.Enqueue(ctx, session.EpisodeID, session.CreatedBy, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		s.applyMedia(ctx, session, info)
+		return s.repo.Complete(ctx, session.ID, "", &job.ID)
+	}
+
+	episode, err := s.episodesRepo.GetByID(ctx, session.EpisodeID)
+	if err != nil {
+		return fmt.Errorf("load episode: %w", err)
+	}
+	videoID, err := s.upload(ctx, session, episode.Title)
+	if err != nil {
+		return err
+	}
+	if err := s.episodesRepo.UpdateVideoID(ctx, episode.ID, videoID); err != nil {
+		return err
+	}
+	s.provider.CompleteUpload(videoID) // no crítico
+	if _, err := s.videoStatus.SyncEpisode(ctx, episode.ID); err != nil {
+		log.Printf("uploads: sync video status of episode %s: %v", episode.ID, err) // el poller reintenta
+	}
+	if old := episode.VideoIDBunny; old != "" && old != videoID {
+		if err := s.provider.DeleteVideo(old); err != nil { // no crítico
+			log.Printf("uploads: delete previous video %s: %v", old, err)
+		}
+	}
+	s.applyMedia(ctx, session, info)
+	return s.repo.Complete(ctx, session.ID, videoID, nil)
+}
+
+// applyMedia registra los datos del video y extrae las portadas antes de que se
+// borre el archivo (no crítico).
+func (s *Service) applyMedia(ctx context.Context, session *Session, info *transcode.MediaInfo) {
+	if info == nil {
+		return
+	}
+	if err := s.media.Apply(ctx, session.EpisodeID, s.partPath(session.ID), info); err != nil {
+		log.Printf("uploads: session %s: %v", session.ID, err)
+	}
+}
+
+// upload completa el upload por partes si lo hay; si no (o falla), sube el
+// archivo entero a un video nuevo.
+func (s *Service) upload(ctx context.Context, session *Session, title string) (string, error) {
+	if streamer, ok := s.streamer(); ok && session.StreamError == "" {
+		err := s.pump(ctx, session)
+		if err == nil && session.ProviderOffset == session.Length {
+			if err = streamer.FinishChunkedUpload(session.ProviderState); err == nil {
+				return session.VideoID, nil
+			}
+			s.disableStream(ctx, session, err)
+		}
+		log.Printf("uploads: session %s: chunked upload incomplete (%v), sending whole file", session.ID, err)
+	}
+
+	video, err := s.provider.CreateVideo(title)
+	if err != nil {
+		return "", fmt.Errorf("create provider video: %w", err)
+	}
+	f, err := os.Open(s.partPath(session.ID))
+	if err != nil {
+		return "", fmt.Errorf("open part file: %w", err)
+	}
+	defer f.Close()
+	if err := s.provider.UploadVideo(video.ExternalID, f, session.ContentType, session.Length); err != nil {
+		s.provider.DeleteVideo(video.ExternalID)
+		return "", fmt.Errorf("upload to provider: %w", err)
+	}
+	return video.ExternalID, nil
+}
+
+// discard limpia una sesión cancelada o vencida: aborta el upload por partes y
+// borra el archivo.
+func (s *Service) discard(id, episodeID uuid.UUID, reason string) {
+	ctx := context.Background()
+	defer s.waitLock(ctx, streamKey(id))()
+
+	if session, err := s.repo.Get(ctx, id); err == nil && session != nil && session.ProviderState != "" {
+		if streamer, ok := s.streamer(); ok {
+			if err := streamer.AbortChunkedUpload(session.ProviderState); err != nil {
+				log.Printf("uploads: session %s: abort chunked upload: %v", id, err)
+			}
+		}
+		if err := s.repo.SetStream(ctx, id, "", "", 0); err != nil {
+			log.Printf("uploads: %v", err)
+		}
+	}
+	os.Remove(s.partPath(id))
+	s.revertEpisode(episodeID, storage.VideoStatusCreated, reason)
+}
+
+// revertEpisode deja el estado del video del episodio en status si esta sesión era
+// su única fuente (sigue 'uploading' sin video); un video ya publicado no se toca.
+func (s *Service) revertEpisode(episodeID uuid.UUID, status, detail string) {
+	ctx := context.Background()
+	episode, err := s.episodesRepo.GetByID(ctx, episodeID)
+	if err != nil || episode.VideoIDBunny != "" || episode.VideoStatus != storage.VideoStatusUploading {
+		return
+	}
+	if err := s.videoStatus.Set(ctx, episodeID, "", status, detail); err != nil {
+		log.Printf("uploads: %v", err)
+	}
+}
+
+// streamer devuelve el proveedor como ChunkStreamer. Con transcodificación propia
+// el archivo se necesita entero en disco y no se reenvía por partes.
+func (s *Service) streamer() (storage.ChunkStreamer, bool) {
+	if s.transcode != nil {
+		return nil, false
+	}
+	streamer, ok := s.provider.(storage.ChunkStreamer)
+	return streamer, ok
+}
+
+// heartbeat renueva updated_at de la sesión mientras se entrega.
+func (s *Service) heartbeat(ctx context.Context, id uuid.UUID) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.repo.Touch(ctx, id); err != nil {
+					log.Printf("uploads: %v", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// tryLock toma sin esperar el advisory lock de key en una conexión dedicada, que
+// lo retiene hasta llamar a unlock; si la instancia se cae, Postgres lo libera al
+// cortarse la conexión. ok=false si lo tiene otro request, de esta u otra instancia
+// (los requests concurrentes sobre la misma sesión reciben ErrBusy).
+func (s *Service) tryLock(ctx context.Context, key string) (unlock func(), ok bool, err error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("uploads: lock connection: %w", err)
+	}
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, key).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("uploads: lock %s: %w", key, err)
+		}
+		return nil, false, nil
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, key); err != nil {
+			log.Printf("uploads: unlock %s: %v", key, err)
+			// La conexión no vuelve al pool con el lock tomado: se descarta
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, true, nil
+}
+
+// waitLock espera hasta tomar el advisory lock de key y devuelve su unlock.
+func (s *Service) waitLock(ctx context.Context, key string) (unlock func()) {
+	for {
+		unlock, ok, err := s.tryLock(ctx, key)
+		if err != nil {
+			log.Printf("uploads: %v", err)
+		}
+		if ok {
+			return unlock
+		}
+		time.Sleep(streamWait)
+	}
+}
+
+func (s *Service) partPath(id uuid.UUID) string {
+	return filepath.Join(s.dir, id.String()+".part")
+}
+
+func streamKey(id uuid.UUID) string {
+	return "stream:" + id.String()
+}
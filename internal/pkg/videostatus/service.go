@@ -0,0 +1,185 @@
+// Package videostatus sigue el ciclo de vida del video de cada episodio
+// (created → uploading → processing → ready | failed). Los proveedores codifican
+// de forma asíncrona después del upload: el estado se actualiza con sus webhooks y
+// con un poller de respaldo, y la notificación de episodio nuevo se envía recién
+// cuando el video queda reproducible.
+package videostatus
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/models"
+	"github.com/qenti/qenti/internal/pkg/notifications"
+	"github.com/qenti/qenti/internal/pkg/series"
+	"github.com/qenti/qenti/internal/pkg/storage"
+)
+
+// pollBatch episodios consultados por pasada del poller.
+const pollBatch = 100
+
+type Service struct {
+	episodesRepo *episodes.Repository
+	seriesRepo   *series.Repository
+	provider     storage.VideoProvider
+	notifService *notifications.Service
+	mediaCheck   func(ctx context.Context, videoID string, status *storage.VideoStatus) error
+	onChange     func()
+}
+
+func NewService(db *sql.DB, seriesRepo *series.Repository, provider storage.VideoProvider, notifService *notifications.Service) *Service {
+	return &Service{
+		episodesRepo: episodes.NewRepository(db),
+		seriesRepo:   seriesRepo,
+		provider:     provider,
+		notifService: notifService,
+	}
+}
+
+// SetMediaCheck registra la validación (duración, orientación) que debe pasar un
+// video cuando el proveedor lo da por listo; si falla, el video queda 'failed'.
+// Cubre los uploads directos al proveedor, que no pasan por el servidor.
+func (s *Service) SetMediaCheck(check func(ctx context.Context, videoID string, status *storage.VideoStatus) error) {
+	s.mediaCheck = check
+}
+
+// SetOnChange registra una función que se llama cada vez que cambia el estado del
+// video de un episodio (p. ej. vaciar el cache de respuestas del catálogo, que
+// solo lista episodios listos).
+func (s *Service) SetOnChange(fn func()) {
+	s.onChange = fn
+}
+
+// Set registra el estado del video videoID del episodio; si queda listo por
+// primera vez, notifica a los fans de la serie. Los estados de un video que el
+// episodio ya no tiene asignado se ignoran.
+func (s *Service) Set(ctx context.Context, episodeID uuid.UUID, videoID, status, detail string) error {
+	updated, firstReady, err := s.episodesRepo.SetVideoStatus(ctx, episodeID, videoID, status, detail)
+	if err != nil {
+		return err
+	}
+	if updated {
+		log.Printf("videostatus: episode %s video %q → %s %s", episodeID, videoID, status, detail)
+		if s.onChange != nil {
+			s.onChange()
+		}
+	}
+	if firstReady {
+		go s.notifyNewEpisode(episodeID)
+	}
+	return nil
+}
+
+// Sync consulta al proveedor el estado del video del episodio y lo registra. Los
+// proveedores que no implementan storage.StatusProvider se consideran listos en
+// cuanto el video está subido.
+func (s *Service) Sync(ctx context.Context, episode *models.Episode) (*storage.VideoStatus, error) {
+	if episode.VideoIDBunny == "" {
+		return &storage.VideoStatus{Status: episode.VideoStatus}, nil
+	}
+	status := &storage.VideoStatus{Status: storage.VideoStatusReady}
+	if sp, ok := s.provider.(storage.StatusProvider); ok {
+		var err error
+		if status, err = sp.GetVideoStatus(episode.VideoIDBunny); err != nil {
+			return nil, err
+		}
+	}
+	if status.Status == storage.VideoStatusReady && episode.VideoStatus != storage.VideoStatusReady && s.mediaCheck != nil {
+		if err := s.mediaCheck(ctx, episode.VideoIDBunny, status); err != nil {
+			status = &storage.VideoStatus{Status: storage.VideoStatusFailed, Detail: err.Error()}
+		}
+	}
+	if err := s.Set(ctx, episode.ID, episode.VideoIDBunny, status.Status, status.Detail); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// SyncEpisode es Sync a partir del ID del episodio.
+func (s *Service) SyncEpisode(ctx context.Context, episodeID uuid.UUID) (*storage.VideoStatus, error) {
+	episode, err := s.episodesRepo.GetByID(ctx, episodeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sync(ctx, episode)
+}
+
+// SyncVideo es Sync a partir del ID externo del video (webhooks). Devuelve nil si
+// ningún episodio tiene ese video.
+func (s *Service) SyncVideo(ctx context.Context, videoID string) (*models.Episode, error) {
+	episode, err := s.episodesRepo.GetByVideoID(ctx, videoID)
+	if err != nil || episode == nil {
+		return nil, err
+	}
+	if _, err := s.Sync(ctx, episode); err != nil {
+		return nil, err
+	}
+	return episode, nil
+}
+
+// MarkUploading registra que se inició un upload para un episodio que todavía no
+// tiene video reproducible (uno ya publicado se sigue sirviendo hasta el reemplazo).
+func (s *Service) MarkUploading(ctx context.Context, episode *models.Episode) error {
+	if episode.VideoStatus != storage.VideoStatusCreated && episode.VideoStatus != storage.VideoStatusFailed {
+		return nil
+	}
+	return s.Set(ctx, episode.ID, episode.VideoIDBunny, storage.VideoStatusUploading, "")
+}
+
+// StartWorker consulta cada `interval` el estado de los videos que siguen subiendo
+// o en proceso (respaldo de webhooks perdidos o no configurados), hasta que ctx se
+// cancele.
+func (s *Service) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Poll(ctx, interval)
+			}
+		}
+	}()
+}
+
+// Poll sincroniza los videos pendientes sin novedades desde hace más de olderThan.
+func (s *Service) Poll(ctx context.Context, olderThan time.Duration) {
+	pending, err := s.episodesRepo.ListPendingVideos(ctx, olderThan, pollBatch)
+	if err != nil {
+		log.Printf("videostatus: %v", err)
+		return
+	}
+	for i := range pending {
+		ep := &pending[i]
+		if _, err := s.Sync(ctx, ep); err != nil {
+			log.Printf("videostatus: sync episode %s: %v", ep.ID, err)
+		}
+		// Sin cambios, Set no toca la fila: se renueva para rotar la cola
+		if err := s.episodesRepo.TouchVideoStatus(ctx, ep.ID); err != nil {
+			log.Printf("videostatus: %v", err)
+		}
+	}
+}
+
+// notifyNewEpisode avisa a los fans de la serie que hay un episodio nuevo (best-effort).
+func (s *Service) notifyNewEpisode(episodeID uuid.UUID) {
+	if s.notifService == nil {
+		return
+	}
+	bgCtx := context.Background()
+	ep, err := s.episodesRepo.GetByID(bgCtx, episodeID)
+	if err != nil || ep == nil {
+		return
+	}
+	seriesTitle := "Nueva actualización"
+	if sr, err := s.seriesRepo.GetByID(bgCtx, ep.SeriesID); err == nil && sr != nil {
+		seriesTitle = sr.Title
+	}
+	s.notifService.NotifyNewEpisode(bgCtx, ep.SeriesID, seriesTitle, ep.EpisodeNumber, ep.Title)
+}
@@ -107,9 +107,52 @@ func (r *Repository) UpdateWatchProgress(ctx context.Context, userID, episodeID
 	return nil
 }
 
+// RecordDeviceView registra la vista de un invitado (sin cuenta) identificado por device ID.
+// Hay una sola fila por dispositivo y episodio (idx_views_device_episode); si ya existía
+// solo se refresca updated_at.
+func (r *Repository) RecordDeviceView(ctx context.Context, deviceID, episodeID uuid.UUID) error {
+	query := `
+		INSERT INTO views (user_id, device_id, episode_id, watched_seconds, completed, updated_at)
+		VALUES (NULL, $1, $2, 0, FALSE, NOW())
+		ON CONFLICT (device_id, episode_id) WHERE user_id IS NULL AND device_id IS NOT NULL
+		DO UPDATE SET updated_at = NOW()
+	`
+	if _, err := r.db.ExecContext(ctx, query, deviceID, episodeID); err != nil {
+		return fmt.Errorf("failed to record device view: %w", err)
+	}
+	return nil
+}
+
+// UpdateDeviceWatchProgress es UpdateWatchProgress para un invitado identificado por device ID.
+func (r *Repository) UpdateDeviceWatchProgress(ctx context.Context, deviceID, episodeID uuid.UUID, watchedSeconds int, completed bool) error {
+	query := `
+		INSERT INTO views (user_id, device_id, episode_id, watched_seconds, completed, updated_at)
+		VALUES (NULL, $1, $2, $3, $4, NOW())
+		ON CONFLICT (device_id, episode_id) WHERE user_id IS NULL AND device_id IS NOT NULL
+		DO UPDATE SET
+			watched_seconds = EXCLUDED.watched_seconds,
+			completed       = EXCLUDED.completed,
+			updated_at      = NOW()
+	`
+	if _, err := r.db.ExecContext(ctx, query, deviceID, episodeID, watchedSeconds, completed); err != nil {
+		return fmt.Errorf("failed to update device watch progress: %w", err)
+	}
+	return nil
+}
+
 // GetContinueWatching devuelve las series en curso del usuario (mayor episodio visto, no completado).
 // Devuelve máximo `limit` items ordenados por última actividad DESC.
 func (r *Repository) GetContinueWatching(ctx context.Context, userID uuid.UUID, limit int) ([]ContinueWatchingItem, error) {
+	return r.continueWatching(ctx, "v.user_id = $1", userID, limit)
+}
+
+// GetContinueWatchingForDevice es GetContinueWatching para un invitado identificado por device ID.
+func (r *Repository) GetContinueWatchingForDevice(ctx context.Context, deviceID uuid.UUID, limit int) ([]ContinueWatchingItem, error) {
+	return r.continueWatching(ctx, "v.user_id IS NULL AND v.device_id = $1", deviceID, limit)
+}
+
+// continueWatching ejecuta la consulta de "continuar viendo" filtrando por `owner` ($1 = ownerID).
+func (r *Repository) continueWatching(ctx context.Context, owner string, ownerID uuid.UUID, limit int) ([]ContinueWatchingItem, error) {
 	query := `
 		SELECT series_id, series_title, vertical_poster,
 		       episode_id, episode_number, episode_title, duration,
@@ -129,7 +172,7 @@ func (r *Repository) GetContinueWatching(ctx context.Context, userID uuid.UUID,
 			FROM views v
 			JOIN episodes e ON e.id = v.episode_id
 			JOIN series   s ON s.id = e.series_id
-			WHERE v.user_id  = $1
+			WHERE ` + owner + `
 			  AND v.watched_seconds > 0
 			  AND v.completed = FALSE
 			  AND s.is_active = TRUE
@@ -138,7 +181,7 @@ func (r *Repository) GetContinueWatching(ctx context.Context, userID uuid.UUID,
 		ORDER BY last_watched DESC
 		LIMIT $2
 	`
-	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	rows, err := r.db.QueryContext(ctx, query, ownerID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query continue watching: %w", err)
 	}
@@ -158,4 +201,3 @@ func (r *Repository) GetContinueWatching(ctx context.Context, userID uuid.UUID,
 	}
 	return result, nil
 }
-
@@ -1,9 +1,11 @@
 package router
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/qenti/qenti/api/v1/admin"
@@ -12,16 +14,34 @@ import (
 	"github.com/qenti/qenti/internal/config"
 	"github.com/qenti/qenti/internal/middleware"
 	"github.com/qenti/qenti/internal/pkg/auth"
+	"github.com/qenti/qenti/internal/pkg/bans"
+	"github.com/qenti/qenti/internal/pkg/cache"
 	"github.com/qenti/qenti/internal/pkg/episodes"
+	"github.com/qenti/qenti/internal/pkg/events"
+	"github.com/qenti/qenti/internal/pkg/feed"
+	"github.com/qenti/qenti/internal/pkg/guests"
+	"github.com/qenti/qenti/internal/pkg/hlspackage"
+	"github.com/qenti/qenti/internal/pkg/images"
 	"github.com/qenti/qenti/internal/pkg/invitations"
 	"github.com/qenti/qenti/internal/pkg/jwt"
+	"github.com/qenti/qenti/internal/pkg/media"
 	"github.com/qenti/qenti/internal/pkg/notifications"
 	"github.com/qenti/qenti/internal/pkg/payment"
+	"github.com/qenti/qenti/internal/pkg/privacy"
 	"github.com/qenti/qenti/internal/pkg/producers"
+	"github.com/qenti/qenti/internal/pkg/recommendations"
+	"github.com/qenti/qenti/internal/pkg/rollups"
+	"github.com/qenti/qenti/internal/pkg/search"
 	"github.com/qenti/qenti/internal/pkg/series"
 	"github.com/qenti/qenti/internal/pkg/storage"
+	"github.com/qenti/qenti/internal/pkg/taxonomy"
+	"github.com/qenti/qenti/internal/pkg/tracks"
+	"github.com/qenti/qenti/internal/pkg/transcode"
+	"github.com/qenti/qenti/internal/pkg/translations"
 	"github.com/qenti/qenti/internal/pkg/unlocks"
+	"github.com/qenti/qenti/internal/pkg/uploads"
 	"github.com/qenti/qenti/internal/pkg/users"
+	"github.com/qenti/qenti/internal/pkg/videostatus"
 )
 
 func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
@@ -36,9 +56,19 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 		}
 	}
 
+	// Cache de datos calientes (slugs, roles, bans, rankings): memoria o Redis según CACHE_BACKEND
+	cacheStore, err := cache.New(cfg.Cache)
+	if err != nil {
+		log.Fatalf("cache: %v", err)
+	}
+	log.Printf("Cache backend: %s", cfg.Cache.Backend)
+
 	// Inicializar servicios
-	authService := auth.NewService(db, firebaseService)
+	authService := auth.NewService(db, firebaseService, cacheStore)
 	jwtService := jwt.NewService(cfg.JWT.SecretKey)
+	// Los tokens de invitados fusionados en otra cuenta (POST /auth/link) dejan de valer
+	guestsRepo := guests.NewRepository(db, cacheStore)
+	jwtService.SetRevocationCheck(guestsRepo.TokenRevoked)
 	paymentService := payment.NewService(cfg.RevenueCat)
 
 	// Inicializar servicio de notificaciones (FCM via Firebase Admin SDK)
@@ -53,15 +83,94 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 	log.Printf("CDN provider: %s", videoProvider.ProviderName())
 
 	// Inicializar repositorios
-	seriesRepo := series.NewRepository(db)
+	seriesRepo := series.NewRepository(db, cacheStore)
 	episodesRepo := episodes.NewRepository(db)
 	usersRepo := users.NewRepository(db)
 	unlocksRepo := unlocks.NewRepository(db)
-	producersRepo := producers.NewRepository(db)
+	producersRepo := producers.NewRepository(db, cacheStore)
 	invitationsRepo := invitations.NewRepository(db)
 
+	// Telemetría: particiones mensuales + rollup diario de playback_events (cada hora)
+	events.NewRepository(db).StartWorker(context.Background(), time.Hour)
+
+	// Rollups diarios de analytics (dashboard + discovery); el primer refresh rellena
+	// el historial, los recálculos de rangos van por cmd/rollup
+	rollupsRepo := rollups.NewRepository(db)
+	if cfg.Rollup.IntervalMinutes > 0 {
+		rollupsRepo.StartWorker(context.Background(), time.Duration(cfg.Rollup.IntervalMinutes)*time.Minute)
+	}
+
+	// Privacidad (GDPR/LGPD): genera exportaciones y ejecuta borrados vencidos cada minuto
+	exportStore, err := storage.NewExportStore(cfg)
+	if err != nil {
+		log.Fatalf("privacy export storage: %v", err)
+	}
+	privacyService := privacy.NewService(db, cfg.Privacy, exportStore)
+	privacyService.StartWorker(context.Background(), time.Minute)
+
+	// Estado de codificación de los videos: webhooks de los proveedores + poller de respaldo
+	videoStatusService := videostatus.NewService(db, seriesRepo, videoProvider, notifService)
+	if cfg.VideoUpload.StatusPollSeconds > 0 {
+		videoStatusService.StartWorker(context.Background(), time.Duration(cfg.VideoUpload.StatusPollSeconds)*time.Second)
+	}
+
+	// Transcodificación propia (opcional): escalera HLS vertical con ffmpeg
+	var transcodeService *transcode.Service
+	if cfg.Transcode.Enabled {
+		transcodeService = transcode.NewService(db, videoProvider, videoStatusService, cfg.Transcode, cfg.VideoUpload.MaxDurationSeconds)
+		transcodeService.StartWorker(context.Background(), time.Minute)
+	}
+
+	// Empaquetado HLS de los originales en S3 (S3_PACKAGE_HLS): trabajos reintentables
+	if packager, ok := videoProvider.(storage.HLSPackager); ok && cfg.S3.PackageHLS {
+		hlspackage.NewService(db, packager).StartWorker(context.Background(), time.Minute)
+	}
+
+	// Análisis de los uploads (opcional): datos técnicos, validación (también de los
+	// uploads directos al proveedor, al quedar listos) y portadas candidatas
+	var mediaService *media.Service
+	if cfg.MediaProbe.Enabled {
+		mediaService = media.NewService(db, cfg.MediaProbe, cfg.VideoUpload.MaxDurationSeconds)
+		videoStatusService.SetMediaCheck(func(ctx context.Context, videoID string, status *storage.VideoStatus) error {
+			return mediaService.CheckUploaded(ctx, videoProvider, videoID, status)
+		})
+	}
+
+	// Uploads reanudables (TUS): sesiones en disco, entregadas al proveedor al completarse
+	uploadsService := uploads.NewService(db, videoProvider, videoStatusService, transcodeService, mediaService, cfg.VideoUpload)
+	uploadsService.StartWorker(context.Background(), time.Minute)
+
+	// Índice de búsqueda: reindexa las series que los triggers encolan en search_queue
+	// y reconstruye en memoria el índice de autocompletado
+	searchService := search.NewService(db, cfg.Localization)
+	searchService.StartWorker(context.Background(), time.Duration(cfg.Search.IndexIntervalSeconds)*time.Second)
+	searchService.StartSuggestWorker(context.Background(), time.Duration(cfg.Search.SuggestRebuildMinutes)*time.Minute)
+
+	// Recomendaciones: similitud entre series por filtrado colaborativo (series_similarity)
+	if cfg.Recommendations.RebuildMinutes > 0 {
+		recommendations.NewService(db, cfg.Recommendations).StartWorker(context.Background(), time.Duration(cfg.Recommendations.RebuildMinutes)*time.Minute)
+	}
+
+	// Imágenes subidas (posters, banners, logos): variantes en disco o en el bucket S3
+	imageStore, err := storage.NewObjectStore(cfg)
+	if err != nil {
+		log.Fatalf("image storage: %v", err)
+	}
+	imagesService := images.NewService(db, imageStore, cfg.Images)
+
 	// Inicializar handlers de Auth
-	authHandlers := authHandlers.NewHandlers(authService, jwtService, db, usersRepo, producersRepo, invitationsRepo, cfg.SuperAdminEmail)
+	authHandlers := authHandlers.NewHandlers(authService, jwtService, db, usersRepo, producersRepo, invitationsRepo, guestsRepo, cfg.SuperAdminEmail)
+
+	// Proveedor local: handlers que sirven/reciben los videos en disco
+	var localMediaHandlers *appHandlers.LocalMediaHandlers
+	if localProvider, ok := videoProvider.(*storage.LocalProvider); ok {
+		localMediaHandlers = appHandlers.NewLocalMediaHandlers(localProvider, cfg.VideoUpload.MaxFileSizeMB)
+	}
+	// Proveedor S3: playlists HLS con segmentos firmados servidos por la API
+	var s3MediaHandlers *appHandlers.S3MediaHandlers
+	if s3Provider, ok := videoProvider.(*storage.S3Provider); ok {
+		s3MediaHandlers = appHandlers.NewS3MediaHandlers(s3Provider)
+	}
 
 	// Inicializar handlers de App
 	appHandlers := appHandlers.NewHandlers(
@@ -72,6 +181,9 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 		videoProvider,
 		paymentService,
 		notifService,
+		searchService,
+		privacyService,
+		cacheStore,
 		db,
 		cfg,
 	)
@@ -81,7 +193,10 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 		seriesRepo,
 		episodesRepo,
 		videoProvider,
-		notifService,
+		videoStatusService,
+		transcodeService,
+		mediaService,
+		imagesService.Repo(),
 		cfg.VideoUpload.MaxFileSizeMB,
 		cfg.VideoUpload.WarnFileSizeMB,
 		cfg.EpisodeCliff.CliffStart,
@@ -90,19 +205,29 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 	)
 
 	// Inicializar handlers de Admin Users
-	adminUsersHandlers := admin.NewUsersHandlers(usersRepo, db)
+	adminUploadHandlers := admin.NewUploadHandlers(uploadsService, episodesRepo, cfg.VideoUpload.MaxFileSizeMB)
+	adminImageHandlers := admin.NewImageHandlers(imagesService)
+	adminTranslationHandlers := admin.NewTranslationHandlers(translations.NewService(db, cfg.Localization), seriesRepo, episodesRepo)
+	adminTrackHandlers := admin.NewTrackHandlers(tracks.NewService(db, videoProvider), episodesRepo, cfg.VideoUpload.MaxFileSizeMB)
+	adminSearchHandlers := admin.NewSearchHandlers(searchService)
+	adminTaxonomyHandlers := admin.NewTaxonomyHandlers(taxonomy.NewRepository(db), seriesRepo)
+	adminFeedHandlers := admin.NewFeedHandlers(feed.NewRepository(db), seriesRepo, taxonomy.NewRepository(db), images.NewRepository(db))
+
+	adminUsersHandlers := admin.NewUsersHandlers(usersRepo, bans.NewRepository(db, cacheStore), db)
 
 	// Inicializar handlers de Admin Dashboard
-	adminDashboardHandlers := admin.NewDashboardHandlers(db)
+	adminDashboardHandlers := admin.NewDashboardHandlers(db, rollupsRepo)
 
 	// Inicializar handlers de Producers (super_admin only)
-	adminProducersHandlers := admin.NewProducersHandlers(producersRepo, notifService)
+	adminProducersHandlers := admin.NewProducersHandlers(producersRepo, imagesService.Repo(), notifService, authService)
 	// Inicializar handlers de MyProducer (el propio productor gestiona sus datos)
-	adminMyProducerHandlers := admin.NewMyProducerHandlers(producersRepo)
+	adminMyProducerHandlers := admin.NewMyProducerHandlers(producersRepo, imagesService.Repo())
 	// Inicializar handlers de Invitations (tenant admin)
 	adminInvitationsHandlers := admin.NewInvitationsHandlers(invitationsRepo)
 	// Inicializar handlers de Team (gestión de equipo del tenant)
-	adminTeamHandlers := admin.NewTeamHandlers(db)
+	adminTeamHandlers := admin.NewTeamHandlers(db, authService)
+	// Inicializar handlers de Privacy (super_admin: solicitudes GDPR/LGPD)
+	adminPrivacyHandlers := admin.NewPrivacyHandlers(privacyService)
 
 	// Inicializar handlers de Webhook
 	webhookHandlers := admin.NewWebhookHandlers(
@@ -110,6 +235,8 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 		usersRepo,
 	)
 
+	videoWebhookHandlers := admin.NewVideoWebhookHandlers(videoStatusService, cfg.Bunny, cfg.Cloudflare)
+
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -120,15 +247,19 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 
 	// API v1 - Auth (unificado) con rate limiting
 	v1Auth := r.Group("/api/v1/auth")
-	v1Auth.Use(middleware.RateLimitMiddleware(5.0, 10)) // 5 requests por segundo, burst de 10
+	v1Auth.Use(middleware.RateLimitMiddleware(5.0, 10))                                      // 5 requests por segundo, burst de 10
+	v1Auth.Use(middleware.DeviceID(cfg.JWT.DeviceSecret, cfg.JWT.DeviceCookieSecure, false)) // para fusionar la actividad de invitado al hacer login
 	{
 		v1Auth.POST("/login", authHandlers.Login)
 		v1Auth.POST("/refresh", authHandlers.Refresh)
+		// Cuentas invitadas ligadas al device ID y su vinculación posterior con Firebase
+		v1Auth.POST("/guest", middleware.RateLimitMiddleware(1.0, 3), middleware.DeviceID(cfg.JWT.DeviceSecret, cfg.JWT.DeviceCookieSecure, true), authHandlers.Guest)
+		v1Auth.POST("/link", middleware.RequireAuth(jwtService), authHandlers.LinkAccount)
 		// Onboarding: primer usuario crea su productora (requiere JWT básico)
-		v1Auth.POST("/onboarding", middleware.RequireAuth(jwtService), authHandlers.Onboarding)
+		v1Auth.POST("/onboarding", middleware.RequireAuth(jwtService), middleware.RejectGuest(), authHandlers.Onboarding)
 		// Invitaciones: info pública (no requiere auth) + aceptar (requiere auth)
 		v1Auth.GET("/invite/:token", authHandlers.GetInviteInfo)
-		v1Auth.POST("/invite/accept", middleware.RequireAuth(jwtService), authHandlers.AcceptInvite)
+		v1Auth.POST("/invite/accept", middleware.RequireAuth(jwtService), middleware.RejectGuest(), authHandlers.AcceptInvite)
 		// Dev login: solo disponible si Firebase NO está configurado (FIREBASE_PROJECT_ID vacío)
 		if os.Getenv("FIREBASE_PROJECT_ID") == "" {
 			v1Auth.POST("/dev-login", authHandlers.DevLogin)
@@ -136,20 +267,49 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 		}
 	}
 
+	// Cache HTTP (ETag, Cache-Control y respuestas en memoria) de los endpoints
+	// públicos del catálogo; los cambios del admin y de estado de video lo vacían
+	responseCache := middleware.NewResponseCache(time.Duration(cfg.HTTPCache.TTLSeconds)*time.Second, cfg.HTTPCache.MaxAgeSeconds, cfg.HTTPCache.MaxEntries)
+	// Los webhooks de video y el poller cambian video_status fuera del admin
+	videoStatusService.SetOnChange(responseCache.Purge)
+	// Con Redis los Purge de una réplica vacían también las demás
+	if cfg.Cache.Backend == "redis" {
+		responseCache.SharePurges(context.Background(), cacheStore, 2*time.Second)
+	}
+
 	// API v1 - App endpoints
 	v1App := r.Group("/api/v1/app")
+	// Device ID anónimo firmado: identifica a los invitados (se emite si no viene uno válido)
+	deviceID := middleware.DeviceID(cfg.JWT.DeviceSecret, cfg.JWT.DeviceCookieSecure, true)
+	// Idioma de títulos y descripciones (?lang= o Accept-Language, con fallback)
+	v1App.Use(middleware.Locale(translations.NewResolver(cfg.Localization)))
 	{
 		// Endpoints públicos
-		v1App.GET("/feed", appHandlers.GetFeed)
-		v1App.GET("/series", appHandlers.GetSeries)
-		v1App.GET("/series/:id", appHandlers.GetSeriesByID)
+		// Sesión opcional: segmentación premium, recomendados y "continuar viendo"
+		v1App.GET("/feed", responseCache.CachePersonalized("X-Platform", "CF-IPCountry", "X-Country"), middleware.OptionalAuth(jwtService), middleware.DeviceID(cfg.JWT.DeviceSecret, cfg.JWT.DeviceCookieSecure, false), appHandlers.GetFeed)
+		v1App.GET("/series", responseCache.Cache(), appHandlers.GetSeries)
+		v1App.GET("/series/:id", responseCache.Cache(), appHandlers.GetSeriesByID)
 		v1App.GET("/series/:id/episodes", appHandlers.GetSeriesEpisodes)
-		v1App.GET("/trending", appHandlers.GetTrending)
-		v1App.GET("/search", appHandlers.Search)
+		v1App.GET("/series/:id/similar", appHandlers.GetSimilarSeries)
+		v1App.GET("/genres", appHandlers.GetGenres)
+		v1App.GET("/trending", responseCache.Cache(), appHandlers.GetTrending)
+		// Búsqueda: se registra con el usuario o el device si vienen (sin emitir device ID)
+		v1App.GET("/search", middleware.OptionalAuth(jwtService), middleware.DeviceID(cfg.JWT.DeviceSecret, cfg.JWT.DeviceCookieSecure, false), appHandlers.Search)
+		v1App.GET("/search/suggest", appHandlers.SuggestSearch)
+		v1App.POST("/search/click", middleware.RateLimitMiddleware(5.0, 20), appHandlers.ClickSearchResult)
 		v1App.GET("/most-viewed", appHandlers.GetMostViewed)
-		v1App.GET("/new-releases", appHandlers.GetNewReleases)
+		v1App.GET("/new-releases", responseCache.Cache(), appHandlers.GetNewReleases)
 		// Stream: auth opcional — episodios gratis accesibles sin login, pagos requieren auth
-		v1App.GET("/episodes/:id/stream", middleware.OptionalAuth(jwtService), appHandlers.GetEpisodeStream)
+		v1App.GET("/episodes/:id/stream", middleware.OptionalAuth(jwtService), deviceID, appHandlers.GetEpisodeStream)
+		// Telemetría de reproducción en lotes: auth opcional (invitados envían device_id)
+		v1App.POST("/events", middleware.OptionalAuth(jwtService), deviceID, middleware.RateLimitMiddleware(5.0, 20), appHandlers.IngestEvents)
+
+		// Progreso, continuar viendo y favoritos: auth opcional — los invitados usan su device ID
+		// y se fusionan en la cuenta al hacer login
+		v1App.POST("/episodes/:id/progress", middleware.AuthIfPresent(jwtService), deviceID, appHandlers.UpdateWatchProgress)
+		v1App.GET("/continue-watching", middleware.AuthIfPresent(jwtService), deviceID, appHandlers.GetContinueWatching)
+		v1App.GET("/favorites", middleware.AuthIfPresent(jwtService), deviceID, appHandlers.GetFavorites)
+		v1App.POST("/favorites/:series_id", middleware.AuthIfPresent(jwtService), deviceID, appHandlers.ToggleFavorite)
 
 		// Endpoints autenticados
 		v1AppAuth := v1App.Group("")
@@ -157,7 +317,6 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 		{
 			// Episodios (acciones que sí requieren identidad)
 			v1AppAuth.POST("/episodes/:id/unlock", middleware.RateLimitMiddleware(2.0, 5), appHandlers.UnlockEpisode)
-			v1AppAuth.POST("/episodes/:id/progress", appHandlers.UpdateWatchProgress)
 
 			// Anuncios con rate limiting más estricto
 			v1AppAuth.POST("/ads/unlock-episode", middleware.RateLimitMiddleware(1.0, 3), appHandlers.UnlockEpisodeWithAd)
@@ -170,9 +329,9 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 			v1AppAuth.GET("/wallet", appHandlers.GetWallet)
 			v1AppAuth.GET("/wallet/history", appHandlers.GetWalletHistory)
 
-			// Payment
-			v1AppAuth.GET("/payment/subscription-status", appHandlers.GetSubscriptionStatus)
-			v1AppAuth.GET("/payment/offer", appHandlers.GetOffer)
+			// Payment (requiere identidad real: no disponible para invitados)
+			v1AppAuth.GET("/payment/subscription-status", middleware.RejectGuest(), appHandlers.GetSubscriptionStatus)
+			v1AppAuth.GET("/payment/offer", middleware.RejectGuest(), appHandlers.GetOffer)
 
 			// Push notifications (FCM device token)
 			v1AppAuth.POST("/device-token", appHandlers.RegisterDeviceToken)
@@ -181,12 +340,13 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 			// Usuario
 			v1AppAuth.GET("/user/profile", appHandlers.GetUserProfile)
 
-			// Continuar viendo
-			v1AppAuth.GET("/continue-watching", appHandlers.GetContinueWatching)
-
-			// Favoritos
-			v1AppAuth.GET("/favorites", appHandlers.GetFavorites)
-			v1AppAuth.POST("/favorites/:series_id", appHandlers.ToggleFavorite)
+			// Privacidad: exportación de datos y borrado de cuenta
+			v1AppAuth.POST("/user/export", middleware.RateLimitMiddleware(0.1, 2), appHandlers.RequestDataExport)
+			v1AppAuth.GET("/user/exports", appHandlers.GetDataExports)
+			v1AppAuth.GET("/user/exports/:id/download", appHandlers.DownloadDataExport)
+			v1AppAuth.DELETE("/user", appHandlers.RequestAccountDeletion)
+			v1AppAuth.GET("/user/deletion", appHandlers.GetAccountDeletion)
+			v1AppAuth.POST("/user/deletion/cancel", appHandlers.CancelAccountDeletion)
 		}
 	}
 
@@ -194,11 +354,14 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 	v1Admin := r.Group("/api/v1/admin")
 	v1Admin.Use(middleware.RequireAdmin(jwtService, authService, usersRepo))
 	v1Admin.Use(middleware.RateLimitMiddleware(10.0, 20)) // Rate limit más generoso para admin
+	v1Admin.Use(responseCache.PurgeOnMutation())
 	{
 		// Dashboard
 		v1Admin.GET("/dashboard", adminDashboardHandlers.GetDashboard)
 		// Estado del productor — permite polling desde StatusScreen sin re-login
 		v1Admin.GET("/producer-status", adminDashboardHandlers.GetProducerStatus)
+		// Búsquedas de la app: más frecuentes y sin resultados
+		v1Admin.GET("/search/queries", adminSearchHandlers.GetSearchQueries)
 		v1Admin.GET("/my-producer", adminMyProducerHandlers.GetMyProducer)
 		v1Admin.PUT("/my-producer", adminMyProducerHandlers.UpdateMyProducer)
 
@@ -208,6 +371,21 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 		v1Admin.POST("/series", adminHandlers.CreateSeries)
 		v1Admin.PUT("/series/:id", adminHandlers.UpdateSeries)
 		v1Admin.DELETE("/series/:id", adminHandlers.DeleteSeries)
+		// Traducciones (títulos y descripciones en otros idiomas)
+		v1Admin.GET("/series/:id/translations", adminTranslationHandlers.ListSeriesTranslations)
+		v1Admin.PUT("/series/:id/translations/:locale", adminTranslationHandlers.PutSeriesTranslation)
+		v1Admin.DELETE("/series/:id/translations/:locale", adminTranslationHandlers.DeleteSeriesTranslation)
+		// Géneros y etiquetas (la gestión de géneros es del super_admin, más abajo)
+		v1Admin.GET("/series/:id/taxonomy", adminTaxonomyHandlers.GetSeriesTaxonomy)
+		v1Admin.PUT("/series/:id/taxonomy", adminTaxonomyHandlers.PutSeriesTaxonomy)
+		v1Admin.GET("/genres", adminTaxonomyHandlers.ListGenres)
+		v1Admin.GET("/tags", adminTaxonomyHandlers.ListTags)
+		// Home de la app: filas curadas, por género y algorítmicas
+		v1Admin.GET("/feed/rows", adminFeedHandlers.ListFeedRows)
+		v1Admin.POST("/feed/rows", adminFeedHandlers.CreateFeedRow)
+		v1Admin.PUT("/feed/rows/:id", adminFeedHandlers.UpdateFeedRow)
+		v1Admin.DELETE("/feed/rows/:id", adminFeedHandlers.DeleteFeedRow)
+		v1Admin.PUT("/feed/order", adminFeedHandlers.ReorderFeedRows)
 
 		// Episodes CRUD
 		v1Admin.GET("/episodes", adminHandlers.GetEpisodes)
@@ -215,11 +393,39 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 		v1Admin.POST("/episodes", adminHandlers.CreateEpisode)
 		v1Admin.PUT("/episodes/:id", adminHandlers.UpdateEpisode)
 		v1Admin.DELETE("/episodes/:id", adminHandlers.DeleteEpisode)
+		v1Admin.GET("/episodes/:id/translations", adminTranslationHandlers.ListEpisodeTranslations)
+		v1Admin.PUT("/episodes/:id/translations/:locale", adminTranslationHandlers.PutEpisodeTranslation)
+		v1Admin.DELETE("/episodes/:id/translations/:locale", adminTranslationHandlers.DeleteEpisodeTranslation)
 
 		// Video upload flow (específico por episodio)
 		v1Admin.POST("/episodes/:id/upload-url", adminHandlers.GetUploadURL)
 		v1Admin.POST("/episodes/:id/upload", adminHandlers.UploadVideo)
 		v1Admin.POST("/episodes/:id/complete", adminHandlers.CompleteUpload)
+		// Upload multiparte directo al bucket (proveedores que lo soportan, ej. S3)
+		v1Admin.POST("/episodes/:id/multipart", adminHandlers.CreateMultipartUpload)
+		v1Admin.POST("/episodes/:id/multipart/complete", adminHandlers.CompleteMultipartUpload)
+		v1Admin.POST("/episodes/:id/multipart/abort", adminHandlers.AbortMultipartUpload)
+		// Estado de codificación del video en el proveedor (consulta en vivo)
+		v1Admin.GET("/episodes/:id/video-status", adminHandlers.GetVideoStatus)
+		// Upload reanudable (protocolo TUS 1.0.0)
+		v1Admin.POST("/episodes/:id/uploads", adminUploadHandlers.CreateUpload)
+		v1Admin.HEAD("/uploads/:id", adminUploadHandlers.HeadUpload)
+		v1Admin.PATCH("/uploads/:id", adminUploadHandlers.PatchUpload)
+		v1Admin.DELETE("/uploads/:id", adminUploadHandlers.DeleteUpload)
+		v1Admin.GET("/uploads/:id", adminUploadHandlers.GetUpload)
+		// Transcodificación propia (TRANSCODE_ENABLED): estado de los trabajos
+		v1Admin.GET("/episodes/:id/transcode-jobs", adminHandlers.ListTranscodeJobs)
+		v1Admin.GET("/transcode/jobs/:id", adminHandlers.GetTranscodeJob)
+		// Portadas candidatas extraídas del video (MEDIA_PROBE_ENABLED)
+		v1Admin.GET("/episodes/:id/thumbnails", adminHandlers.ListEpisodeThumbnails)
+		v1Admin.PUT("/episodes/:id/cover", adminHandlers.SetEpisodeCover)
+		// Subtítulos y audios alternativos del episodio
+		v1Admin.GET("/episodes/:id/tracks", adminTrackHandlers.ListTracks)
+		v1Admin.POST("/episodes/:id/tracks", adminTrackHandlers.UploadTrack)
+		v1Admin.DELETE("/episodes/:id/tracks/:trackId", adminTrackHandlers.DeleteTrack)
+		// Imágenes (posters, banners, logos) referenciadas por ID desde series y productores
+		v1Admin.POST("/images", adminImageHandlers.UploadImage)
+		v1Admin.GET("/images/:id", adminImageHandlers.GetImage)
 
 		// Validación de servicios
 		v1Admin.GET("/validate/bunny", adminHandlers.ValidateBunnyConnection)
@@ -242,6 +448,7 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 	// API v1 - Super Admin: gestión de productores (sólo super_admin/admin)
 	v1SuperAdmin := r.Group("/api/v1/admin/producers")
 	v1SuperAdmin.Use(middleware.RequireSuperAdmin(jwtService))
+	v1SuperAdmin.Use(responseCache.PurgeOnMutation())
 	{
 		v1SuperAdmin.GET("", adminProducersHandlers.GetProducers)
 		v1SuperAdmin.GET("/:id", adminProducersHandlers.GetProducerByID)
@@ -253,9 +460,61 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, cfg *config.Config) {
 		v1SuperAdmin.PUT("/:id/suspend", adminProducersHandlers.SuspendProducer)
 	}
 
+	// API v1 - Super Admin: catálogo de géneros y limpieza de etiquetas
+	v1Taxonomy := r.Group("/api/v1/admin")
+	v1Taxonomy.Use(middleware.RequireSuperAdmin(jwtService))
+	v1Taxonomy.Use(responseCache.PurgeOnMutation())
+	{
+		v1Taxonomy.POST("/genres", adminTaxonomyHandlers.CreateGenre)
+		v1Taxonomy.PUT("/genres/:id", adminTaxonomyHandlers.UpdateGenre)
+		v1Taxonomy.DELETE("/genres/:id", adminTaxonomyHandlers.DeleteGenre)
+		v1Taxonomy.DELETE("/tags/:id", adminTaxonomyHandlers.DeleteTag)
+		// Búsquedas excluidas del autocompletado
+		v1Taxonomy.GET("/search/blocked-queries", adminSearchHandlers.GetBlockedQueries)
+		v1Taxonomy.POST("/search/blocked-queries", adminSearchHandlers.BlockQuery)
+		v1Taxonomy.DELETE("/search/blocked-queries", adminSearchHandlers.UnblockQuery)
+	}
+
+	// API v1 - Super Admin: solicitudes de titulares de datos (GDPR/LGPD)
+	v1Privacy := r.Group("/api/v1/admin/privacy")
+	v1Privacy.Use(middleware.RequireSuperAdmin(jwtService))
+	{
+		v1Privacy.GET("/exports", adminPrivacyHandlers.ListExports)
+		v1Privacy.GET("/exports/:id/download", adminPrivacyHandlers.DownloadExport)
+		v1Privacy.GET("/deletions", adminPrivacyHandlers.ListDeletions)
+		v1Privacy.POST("/users/:id/export", adminPrivacyHandlers.ExportUser)
+		v1Privacy.POST("/users/:id/delete", adminPrivacyHandlers.DeleteUser)
+		v1Privacy.POST("/users/:id/delete/cancel", adminPrivacyHandlers.CancelDeletion)
+	}
+
+	// Proveedor local: la propia API sirve y recibe los videos con URLs firmadas
+	if localMediaHandlers != nil {
+		media := r.Group(storage.LocalMediaPath)
+		{
+			media.GET("/:expires/:sig/:id/*file", localMediaHandlers.Serve)
+			media.HEAD("/:expires/:sig/:id/*file", localMediaHandlers.Serve)
+			media.PUT("/:expires/:sig/:id", localMediaHandlers.Upload)
+		}
+	}
+
+	if s3MediaHandlers != nil {
+		r.GET(storage.S3MediaPath+"/:expires/:sig/:id/*file", s3MediaHandlers.Playlist)
+	}
+
+	// Portadas candidatas de los episodios (públicas, como los posters)
+	if mediaService != nil {
+		r.Static(media.ThumbnailsPath, cfg.MediaProbe.ThumbnailDir)
+	}
+	// Variantes de las imágenes con IMAGES_STORAGE=local
+	if localImages, ok := imageStore.(*storage.LocalObjectStore); ok {
+		r.Static(storage.LocalImagesPath, localImages.Dir())
+	}
+
 	// Webhooks (sin autenticación estándar, usan firma propia)
 	webhooks := r.Group("/api/v1/webhooks")
 	{
 		webhooks.POST("/revenuecat", webhookHandlers.HandleRevenueCatWebhook)
+		webhooks.POST("/bunny", videoWebhookHandlers.HandleBunnyWebhook)
+		webhooks.POST("/cloudflare", videoWebhookHandlers.HandleCloudflareWebhook)
 	}
 }